@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// fakeStore serves the part of the store API gateway-api calls (menu and
+// orders, see STORE_API_URL), so a load test orders nothing real. The menu
+// has one item per room a party can take.
+type fakeStore struct {
+	items    int
+	latency  time.Duration
+	failRate float64
+
+	mu     sync.Mutex
+	seq    int
+	orders map[string]fakeOrder
+}
+
+type fakeOrder struct {
+	ID          string `json:"id"`
+	MenuItemID  string `json:"menu_item_id"`
+	MenuName    string `json:"menu_name"`
+	OrderNumber int    `json:"order_number"`
+	Status      string `json:"status"`
+}
+
+func runFakeStore(args []string) error {
+	fs := flag.NewFlagSet("fake-store", flag.ExitOnError)
+	addr := fs.String("addr", ":8090", "listen address")
+	items := fs.Int("items", 100, "menu items, i.e. parties that can run at once")
+	latency := fs.Duration("latency", 20*time.Millisecond, "delay added to every response")
+	failRate := fs.Float64("fail-rate", 0, "share of orders answered with 503")
+	_ = fs.Parse(args)
+
+	s := &fakeStore{items: *items, latency: *latency, failRate: *failRate, orders: make(map[string]fakeOrder)}
+	slog.Info("fake store listening", slog.String("addr", *addr), slog.Int("items", *items))
+	return http.ListenAndServe(*addr, s.handler())
+}
+
+func (s *fakeStore) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/stores/{store}/menu", s.menu)
+	mux.HandleFunc("POST /v1/stores/{store}/orders", s.postOrder)
+	mux.HandleFunc("GET /v1/stores/{store}/orders/{id}", s.getOrder)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(s.latency)
+		mux.ServeHTTP(w, r)
+	})
+}
+
+func itemID(i int) string { return fmt.Sprintf("load-%03d", i) }
+
+func (s *fakeStore) menu(w http.ResponseWriter, _ *http.Request) {
+	type item struct {
+		ID          string `json:"id"`
+		Name        string `json:"name"`
+		Description string `json:"description"`
+	}
+	menu := make([]item, s.items)
+	for i := range menu {
+		menu[i] = item{ID: itemID(i), Name: fmt.Sprintf("負荷試験 %d", i), Description: "loadbot"}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"menu": menu})
+}
+
+func (s *fakeStore) postOrder(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		MenuItemID string `json:"menu_item_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.MenuItemID == "" {
+		http.Error(w, "menu_item_id is required", http.StatusBadRequest)
+		return
+	}
+	if s.failRate > 0 && rand.Float64() < s.failRate {
+		http.Error(w, "injected failure", http.StatusServiceUnavailable)
+		return
+	}
+	s.mu.Lock()
+	s.seq++
+	o := fakeOrder{
+		ID:          fmt.Sprintf("fake-%d", s.seq),
+		MenuItemID:  body.MenuItemID,
+		MenuName:    body.MenuItemID,
+		OrderNumber: s.seq,
+		Status:      "pending",
+	}
+	s.orders[o.ID] = o
+	s.mu.Unlock()
+	writeJSON(w, http.StatusCreated, o)
+}
+
+func (s *fakeStore) getOrder(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	o, ok := s.orders[r.PathValue("id")]
+	s.mu.Unlock()
+	if !ok {
+		http.Error(w, "order not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"order": o})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPercentile(t *testing.T) {
+	ds := make([]time.Duration, 100)
+	for i := range ds {
+		ds[i] = time.Duration(i+1) * time.Millisecond
+	}
+	for p, want := range map[float64]time.Duration{50: 50 * time.Millisecond, 99: 99 * time.Millisecond, 100: 100 * time.Millisecond} {
+		if got := percentile(ds, p); got != want {
+			t.Errorf("p%v=%v, want %v", p, got, want)
+		}
+	}
+	if got := percentile(ds[:1], 50); got != time.Millisecond {
+		t.Errorf("single p50=%v", got)
+	}
+}
+
+// Every pattern sends something gateway-ws counts: never 0, never above 1.
+func TestPatterns_InRange(t *testing.T) {
+	const d = 10 * time.Second
+	for name, p := range patterns {
+		for t0 := time.Duration(0); t0 <= d; t0 += 50 * time.Millisecond {
+			for _, seed := range []float64{0, 0.5, 0.99} {
+				if v := p.value(t0, d, seed); v <= 0 || v > 1 {
+					t.Fatalf("%s(%v, seed %v)=%v", name, t0, seed, v)
+				}
+			}
+		}
+	}
+}
+
+func TestFakeStore(t *testing.T) {
+	srv := httptest.NewServer((&fakeStore{items: 3, orders: make(map[string]fakeOrder)}).handler())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/v1/stores/X/menu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var menu struct {
+		Menu []struct{ ID string }
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&menu)
+	resp.Body.Close()
+	if len(menu.Menu) != 3 || menu.Menu[2].ID != "load-002" {
+		t.Fatalf("menu=%+v", menu)
+	}
+
+	resp, err = http.Post(srv.URL+"/v1/stores/X/orders", "application/json", strings.NewReader(`{"menu_item_id":"load-001"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var o fakeOrder
+	_ = json.NewDecoder(resp.Body).Decode(&o)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated || o.ID == "" || o.MenuItemID != "load-001" {
+		t.Fatalf("status=%d order=%+v", resp.StatusCode, o)
+	}
+
+	resp, err = http.Get(srv.URL + "/v1/stores/X/orders/" + o.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("get order: %d", resp.StatusCode)
+	}
+}
+
+func TestReason(t *testing.T) {
+	for err, want := range map[error]string{
+		&phaseErr{phaseConfirm, &frameError{Code: "upstream_error"}}: "error frame upstream_error",
+		&phaseErr{phaseSession, &httpError{Status: 429}}:             "HTTP 429",
+		fmt.Errorf("dial: %w", errors.New("boom")):                   "error",
+	} {
+		if got := reason(err); got != want {
+			t.Errorf("reason(%v)=%q, want %q", err, got, want)
+		}
+	}
+}
@@ -0,0 +1,237 @@
+// Command loadbot simulates chanting parties against a running stack and
+// reports how it held up. Each party takes a menu item as its room and goes
+// through the whole flow, every member on its own connections:
+//
+//  1. POST /api/v1/sessions for a token (skipped with -no-auth)
+//  2. /ws/stay until start_time arrives
+//  3. /ws from start_time on, sending a loudness sample every 1/-rate,
+//     or -batch of them at a time
+//  4. /ws/confirm, "ready" from everyone, until the order arrives
+//
+// Run it against docker compose with the store API faked, so orders stay
+// local and the menu has a room per concurrent party:
+//
+//	docker compose -f docker-compose.yml -f docker-compose.loadtest.yml up -d --build
+//	go run ./cmd/loadbot -parties 50 -ramp 10s
+//
+// "loadbot fake-store" is that fake.
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"chantingkakigori/pkg/wsbinary"
+)
+
+type config struct {
+	base          *url.URL
+	parties       int
+	concurrency   int
+	ramp          time.Duration
+	partySize     int
+	rate          float64
+	chant         time.Duration
+	pattern       pattern
+	noAuth        bool
+	skipCountdown bool
+	timeout       time.Duration
+	grace         time.Duration
+	binary        bool
+	batch         int
+	audio         bool
+}
+
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == "fake-store" {
+		if err := runFakeStore(os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, "fake-store:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	var cfg config
+	base := flag.String("base", "http://localhost:8080", "edge URL; WebSockets use the same host")
+	flag.IntVar(&cfg.parties, "parties", 10, "parties to run")
+	flag.IntVar(&cfg.concurrency, "concurrency", 0, "most parties at once (0: all); also capped by the rooms available")
+	flag.DurationVar(&cfg.ramp, "ramp", 0, "spread party starts over this long")
+	flag.IntVar(&cfg.partySize, "party-size", 3, "members per party; /ws/stay starts at 3")
+	flag.Float64Var(&cfg.rate, "rate", 5, "samples per second per member (the UI sends 5)")
+	flag.DurationVar(&cfg.chant, "chant", 10*time.Second, "how long each member chants")
+	patternName := flag.String("pattern", "sine", "loudness pattern: "+strings.Join(patternNames(), ", "))
+	flag.BoolVar(&cfg.noAuth, "no-auth", false, "skip sessions and use generated rooms (services run with AUTH_DISABLED=true)")
+	flag.BoolVar(&cfg.skipCountdown, "skip-countdown", false, "chant as soon as start_time arrives instead of at it")
+	flag.DurationVar(&cfg.timeout, "timeout", 30*time.Second, "longest wait for any one server reply")
+	flag.DurationVar(&cfg.grace, "grace", time.Second, "how long to keep reading averages after the last sample")
+	flag.BoolVar(&cfg.binary, "binary", false, "chant over the binary /ws subprotocol instead of JSON")
+	flag.BoolVar(&cfg.audio, "audio", false, "send raw audio for the server to measure (needs WS_RAW_AUDIO=true on gateway-ws; implies -binary)")
+	flag.IntVar(&cfg.batch, "batch", 0, "send samples in batches of this many, stamped with the bot's clock (0: one bare sample per frame)")
+	flag.Parse()
+
+	u, err := url.Parse(*base)
+	if err != nil || u.Host == "" {
+		fmt.Fprintf(os.Stderr, "loadbot: invalid -base %q\n", *base)
+		os.Exit(2)
+	}
+	cfg.base = u
+	p, ok := patterns[*patternName]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "loadbot: unknown -pattern %q\n", *patternName)
+		os.Exit(2)
+	}
+	cfg.pattern = p
+	if cfg.parties <= 0 || cfg.partySize <= 0 || cfg.rate <= 0 {
+		fmt.Fprintln(os.Stderr, "loadbot: -parties, -party-size and -rate must be positive")
+		os.Exit(2)
+	}
+	if cfg.batch < 0 || cfg.batch > wsbinary.MaxBatch {
+		fmt.Fprintf(os.Stderr, "loadbot: -batch must be 0..%d\n", wsbinary.MaxBatch)
+		os.Exit(2)
+	}
+	if cfg.audio {
+		if cfg.batch > 0 {
+			fmt.Fprintln(os.Stderr, "loadbot: -audio sends one frame per sample; drop -batch")
+			os.Exit(2)
+		}
+		cfg.binary = true
+	}
+	if cfg.concurrency <= 0 || cfg.concurrency > cfg.parties {
+		cfg.concurrency = cfg.parties
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	st := newStats()
+	elapsed, err := run(ctx, &cfg, st)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "loadbot:", err)
+		os.Exit(1)
+	}
+	st.report(os.Stdout, elapsed)
+}
+
+// run starts the parties, at most one per room at a time, and waits for
+// them. Interrupting it lets the running parties fail and still reports.
+func run(ctx context.Context, cfg *config, st *stats) (time.Duration, error) {
+	rooms, err := cfg.rooms(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if len(rooms) < cfg.concurrency {
+		fmt.Fprintf(os.Stderr, "loadbot: %d rooms available, running at most %d parties at once\n", len(rooms), len(rooms))
+	}
+	free := make(chan string, len(rooms))
+	for _, r := range rooms {
+		free <- r
+	}
+	slots := make(chan struct{}, cfg.concurrency)
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := range cfg.parties {
+		if d := time.Until(start.Add(cfg.ramp * time.Duration(i) / time.Duration(cfg.parties))); d > 0 {
+			select {
+			case <-ctx.Done():
+			case <-time.After(d):
+			}
+		}
+		var room string
+		select {
+		case <-ctx.Done():
+		case slots <- struct{}{}:
+			select {
+			case <-ctx.Done():
+				<-slots
+			case room = <-free:
+			}
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			runParty(ctx, cfg, st, i, room)
+			free <- room
+			<-slots
+		}()
+	}
+	wg.Wait()
+	return time.Since(start), nil
+}
+
+// rooms lists the rooms parties may take. With sessions a room is a menu
+// item, so the fake store's menu sets how many parties can run at once.
+func (cfg *config) rooms(ctx context.Context) ([]string, error) {
+	if cfg.noAuth {
+		b := make([]byte, 4)
+		_, _ = rand.Read(b)
+		run := hex.EncodeToString(b)
+		rooms := make([]string, cfg.concurrency)
+		for i := range rooms {
+			rooms[i] = fmt.Sprintf("loadbot-%s-%d", run, i)
+		}
+		return rooms, nil
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, cfg.httpURL("/api/v1/stores/menu"), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch menu: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch menu: %w", &httpError{Status: resp.StatusCode})
+	}
+	var body struct {
+		Menu []struct {
+			ID string `json:"id"`
+		} `json:"menu"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("decode menu: %w", err)
+	}
+	var rooms []string
+	for _, it := range body.Menu {
+		if it.ID != "" {
+			rooms = append(rooms, it.ID)
+		}
+	}
+	if len(rooms) == 0 {
+		return nil, fmt.Errorf("the menu is empty")
+	}
+	sort.Strings(rooms)
+	return rooms, nil
+}
+
+func (cfg *config) httpURL(path string) string {
+	u := *cfg.base
+	u.Path = path
+	return u.String()
+}
+
+func (cfg *config) wsURL(path string, q url.Values) string {
+	u := *cfg.base
+	u.Scheme = "ws"
+	if cfg.base.Scheme == "https" {
+		u.Scheme = "wss"
+	}
+	u.Path = path
+	u.RawQuery = q.Encode()
+	return u.String()
+}
@@ -0,0 +1,486 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"chantingkakigori/pkg/wsbinary"
+
+	"github.com/gorilla/websocket"
+)
+
+// Phases of a party, as reported.
+const (
+	phaseSession = "session"
+	phaseStay    = "stay"
+	phaseChant   = "chant"
+	phaseSample  = "sample" // latency of one sample to the next average
+	phaseConfirm = "confirm"
+	phaseOrder   = "order" // latency of ready to the order
+)
+
+// frame is any server frame the flow cares about; the fields tell them
+// apart.
+type frame struct {
+	Type      string   `json:"type"`
+	Code      string   `json:"code"`
+	Message   string   `json:"message"`
+	StartTime *string  `json:"start_time"`
+	RoundID   string   `json:"round_id"`
+	Average   *float64 `json:"average"`
+	ID        string   `json:"id"`
+}
+
+// frameError is an error frame from the server.
+type frameError struct{ Code, Message string }
+
+func (e *frameError) Error() string { return e.Code + ": " + e.Message }
+
+// httpError is a non-2xx reply to a request or a WebSocket handshake.
+type httpError struct{ Status int }
+
+func (e *httpError) Error() string { return fmt.Sprintf("HTTP %d", e.Status) }
+
+type member struct {
+	cfg   *config
+	room  string
+	name  string
+	seed  float64
+	token string
+	// round is the party's round from /ws/stay, passed on to /ws and
+	// /ws/confirm once the party starts.
+	round string
+
+	mu     sync.Mutex
+	sent   int // samples
+	msgs   int // frames carrying them
+	frames int
+}
+
+// runParty takes one party through the flow. The first member to fail
+// fails the party; what it got through until then still counts.
+func runParty(ctx context.Context, cfg *config, st *stats, id int, room string) {
+	members := make([]*member, cfg.partySize)
+	for i := range members {
+		members[i] = &member{
+			cfg:  cfg,
+			room: room,
+			name: fmt.Sprintf("bot-%d-%d", id, i),
+			seed: float64(i) / float64(cfg.partySize),
+		}
+	}
+	if err := runPhases(ctx, cfg, st, members); err != nil {
+		st.partyFailed(err)
+		return
+	}
+	st.partyDone()
+}
+
+// phaseErr tags an error with the phase it ended.
+type phaseErr struct {
+	phase string
+	err   error
+}
+
+func (e *phaseErr) Error() string { return e.phase + ": " + e.err.Error() }
+func (e *phaseErr) Unwrap() error { return e.err }
+
+func runPhases(ctx context.Context, cfg *config, st *stats, members []*member) error {
+	if !cfg.noAuth {
+		if err := each(members, func(m *member) error { return m.session(ctx, st) }); err != nil {
+			return &phaseErr{phaseSession, err}
+		}
+	}
+
+	// everyone waits in the room together; the last one in sets the start
+	starts := make([]time.Time, len(members))
+	if err := eachIndexed(members, func(i int, m *member) (err error) {
+		starts[i], err = m.stay(ctx, st)
+		return err
+	}); err != nil {
+		return &phaseErr{phaseStay, err}
+	}
+
+	var dialed sync.WaitGroup
+	dialed.Add(len(members))
+	err := eachIndexed(members, func(i int, m *member) error { return m.chant(ctx, st, starts[i], &dialed) })
+	// every member sees the average of every sample in the room
+	sent, msgs, frames := 0, 0, 0
+	for _, m := range members {
+		m.mu.Lock()
+		sent += m.sent
+		msgs += m.msgs
+		frames += m.frames
+		m.mu.Unlock()
+	}
+	st.addFrames(sent, msgs*len(members), frames)
+	if err != nil {
+		return &phaseErr{phaseChant, err}
+	}
+
+	// join first, so the room is not ready before the party is in it
+	conns := make([]*websocket.Conn, len(members))
+	closeAll := func() {
+		for _, c := range conns {
+			if c != nil {
+				_ = c.Close()
+			}
+		}
+	}
+	defer closeAll()
+	if err := eachIndexed(members, func(i int, m *member) (err error) {
+		conns[i], err = m.dial(ctx, "/ws/confirm", nil)
+		return err
+	}); err != nil {
+		return &phaseErr{phaseConfirm, err}
+	}
+	stop := context.AfterFunc(ctx, closeAll)
+	defer stop()
+	if err := eachIndexed(members, func(i int, m *member) error { return m.confirm(st, conns[i]) }); err != nil {
+		return &phaseErr{phaseConfirm, err}
+	}
+	return nil
+}
+
+// each runs fn for every member at once and returns the first error.
+func each(members []*member, fn func(*member) error) error {
+	return eachIndexed(members, func(_ int, m *member) error { return fn(m) })
+}
+
+func eachIndexed(members []*member, fn func(int, *member) error) error {
+	errs := make([]error, len(members))
+	var wg sync.WaitGroup
+	for i, m := range members {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = fn(i, m)
+		}()
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *member) session(ctx context.Context, st *stats) error {
+	body, _ := json.Marshal(map[string]string{"menu_item_id": m.room})
+	ctx, cancel := context.WithTimeout(ctx, m.cfg.timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.cfg.httpURL("/api/v1/sessions"), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	start := time.Now()
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return &httpError{Status: resp.StatusCode}
+	}
+	var s struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&s); err != nil {
+		return err
+	}
+	st.observe(phaseSession, time.Since(start))
+	m.token = s.Token
+	return nil
+}
+
+func (m *member) dial(ctx context.Context, path string, q url.Values, subprotocols ...string) (*websocket.Conn, error) {
+	if q == nil {
+		q = url.Values{}
+	}
+	q.Set("room", m.room)
+	if m.token != "" {
+		q.Set("token", m.token)
+	}
+	if m.round != "" {
+		q.Set("round", m.round)
+	}
+	ctx, cancel := context.WithTimeout(ctx, m.cfg.timeout)
+	defer cancel()
+	dialer := *websocket.DefaultDialer
+	dialer.Subprotocols = subprotocols
+	c, resp, err := dialer.DialContext(ctx, m.cfg.wsURL(path, q), nil)
+	if errors.Is(err, websocket.ErrBadHandshake) && resp != nil {
+		return nil, &httpError{Status: resp.StatusCode}
+	}
+	return c, err
+}
+
+// read returns the next frame, waiting at most the configured timeout.
+func (m *member) read(c *websocket.Conn) (frame, error) {
+	_ = c.SetReadDeadline(time.Now().Add(m.cfg.timeout))
+	var f frame
+	_, data, err := c.ReadMessage()
+	if err != nil {
+		return f, err
+	}
+	if err := json.Unmarshal(data, &f); err != nil {
+		return f, fmt.Errorf("bad frame %q: %w", data, err)
+	}
+	if f.Type == "error" {
+		return f, &frameError{Code: f.Code, Message: f.Message}
+	}
+	return f, nil
+}
+
+// stay waits in /ws/stay until the room is full and returns the start time,
+// keeping the party's round for the later phases.
+func (m *member) stay(ctx context.Context, st *stats) (time.Time, error) {
+	c, err := m.dial(ctx, "/ws/stay", nil)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer c.Close()
+	stop := context.AfterFunc(ctx, func() { _ = c.Close() })
+	defer stop()
+	start := time.Now()
+	for {
+		f, err := m.read(c)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if f.StartTime == nil || *f.StartTime == "null" {
+			continue
+		}
+		st.observe(phaseStay, time.Since(start))
+		m.round = f.RoundID
+		return time.Parse(time.RFC3339, *f.StartTime)
+	}
+}
+
+// chant streams samples from startAt for the configured time and counts the
+// averages that come back, timing each sample to the first average after
+// it. It starts no earlier than the whole party is connected (dialed), so
+// every member can see every sample. With -binary it negotiates the compact
+// encoding, one sample per frame; with -audio it sends a tone as loud as
+// the sample instead.
+func (m *member) chant(ctx context.Context, st *stats, startAt time.Time, dialed *sync.WaitGroup) error {
+	var subprotocols []string
+	if m.cfg.binary {
+		subprotocols = []string{wsbinary.Subprotocol}
+	}
+	q := url.Values{"name": {m.name}}
+	if m.cfg.audio {
+		q.Set("device", "loadbot")
+	}
+	c, err := m.dial(ctx, "/ws", q, subprotocols...)
+	dialed.Done()
+	if err != nil {
+		return err
+	}
+	if m.cfg.binary && c.Subprotocol() != wsbinary.Subprotocol {
+		_ = c.Close()
+		return fmt.Errorf("server did not accept %s", wsbinary.Subprotocol)
+	}
+	defer c.Close()
+	stop := context.AfterFunc(ctx, func() { _ = c.Close() })
+	defer stop()
+
+	var pending []time.Time
+	readErr := make(chan error, 1)
+	go func() {
+		for {
+			msgType, data, err := c.ReadMessage()
+			if err != nil {
+				readErr <- err
+				return
+			}
+			st.chantBytes(0, len(data))
+			if msgType == websocket.BinaryMessage {
+				if _, err := wsbinary.ParseAverage(data); err != nil {
+					continue
+				}
+			} else {
+				var f frame
+				if json.Unmarshal(data, &f) != nil {
+					continue
+				}
+				if f.Type == "error" {
+					st.errorFrame(f.Code)
+					continue
+				}
+				if f.Average == nil {
+					continue
+				}
+			}
+			now := time.Now()
+			m.mu.Lock()
+			for _, t := range pending {
+				st.observe(phaseSample, now.Sub(t))
+			}
+			pending = pending[:0]
+			m.frames++
+			m.mu.Unlock()
+		}
+	}()
+
+	// members' ticks are spread over one interval, as real phones' are
+	interval := time.Duration(float64(time.Second) / m.cfg.rate)
+	dialed.Wait()
+	if m.cfg.skipCountdown {
+		startAt = time.Now()
+	}
+	select {
+	case <-time.After(time.Until(startAt) + time.Duration(m.seed*float64(interval))):
+	case err := <-readErr:
+		return err
+	}
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+	var batch samples
+	begin := time.Now()
+	for t := time.Duration(0); t < m.cfg.chant; t = time.Since(begin) {
+		select {
+		case <-tick.C:
+		case err := <-readErr:
+			return err
+		}
+		batch.add(time.Now(), m.cfg.pattern.value(t, m.cfg.chant, m.seed))
+		m.mu.Lock()
+		pending = append(pending, time.Now())
+		m.mu.Unlock()
+		if len(batch.values) < max(m.cfg.batch, 1) {
+			continue
+		}
+		if err := m.flush(c, st, &batch); err != nil {
+			return err
+		}
+	}
+	if len(batch.values) > 0 {
+		if err := m.flush(c, st, &batch); err != nil {
+			return err
+		}
+	}
+
+	// let the last averages arrive, then leave
+	select {
+	case <-time.After(m.cfg.grace):
+	case err := <-readErr:
+		return err
+	}
+	_ = c.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+	select {
+	case <-readErr:
+	case <-time.After(time.Second):
+	}
+	return nil
+}
+
+// samples are taken but not sent yet.
+type samples struct {
+	values []float64
+	times  []time.Time
+}
+
+func (s *samples) add(t time.Time, v float64) {
+	s.values = append(s.values, v)
+	s.times = append(s.times, t)
+}
+
+// flush sends s in one frame. With -batch the samples carry the member's
+// clock; without it the frame is a bare {"value"} or an untimed binary batch.
+func (m *member) flush(c *websocket.Conn, st *stats, s *samples) error {
+	m.mu.Lock()
+	seq := m.sent
+	m.sent += len(s.values)
+	m.msgs++
+	m.mu.Unlock()
+
+	msgType, b := websocket.TextMessage, []byte(nil)
+	switch {
+	case m.cfg.audio:
+		msgType = websocket.BinaryMessage
+		d := min(time.Duration(float64(time.Second)/m.cfg.rate), time.Second)
+		b = wsbinary.AppendAudio(nil, wsbinary.Audio{
+			Seq: uint32(seq), Codec: wsbinary.AudioPCM16, SampleRate: toneRate,
+			ClientTime: s.times[0].Add(-d).UnixMilli(), Data: tone(s.values[0], d),
+		})
+	case m.cfg.binary:
+		msgType = websocket.BinaryMessage
+		frame := wsbinary.Samples{Seq: uint32(seq)}
+		for i, v := range s.values {
+			frame.Values = append(frame.Values, float32(v))
+			if m.cfg.batch > 0 {
+				frame.ClientTimes = append(frame.ClientTimes, s.times[i].UnixMilli())
+			}
+		}
+		b = wsbinary.AppendSamples(nil, frame)
+	case m.cfg.batch > 0:
+		type sample struct {
+			ClientTS int64   `json:"client_ts"`
+			Value    float64 `json:"value"`
+		}
+		var batch []sample
+		for i, v := range s.values {
+			batch = append(batch, sample{s.times[i].UnixMilli(), v})
+		}
+		b, _ = json.Marshal(map[string]any{"samples": batch})
+	default:
+		b, _ = json.Marshal(map[string]float64{"value": s.values[0]})
+	}
+	s.values, s.times = s.values[:0], s.times[:0]
+	st.chantBytes(len(b), 0)
+	return c.WriteMessage(msgType, b)
+}
+
+// toneRate is the sample rate of -audio frames.
+const toneRate = 16000
+
+// tone returns d of a 997Hz tone as 16-bit PCM, at the level kakigori-ws's
+// default calibration reads as v: a sine measures 3dB under its peak, and
+// the calibration maps -60..-10 LUFS onto 0..1.
+func tone(v float64, d time.Duration) []byte {
+	amp := math.Pow(10, (50*v-57)/20)
+	n := int(d * toneRate / time.Second)
+	b := make([]byte, 0, 2*n)
+	for i := range n {
+		x := amp * math.Sin(2*math.Pi*997*float64(i)/toneRate)
+		b = binary.LittleEndian.AppendUint16(b, uint16(int16(math.Round(math.Max(-1, math.Min(1, x))*32767))))
+	}
+	return b
+}
+
+// confirm sends ready on a joined /ws/confirm connection and waits for the
+// order.
+func (m *member) confirm(st *stats, c *websocket.Conn) error {
+	start := time.Now()
+	if err := c.WriteMessage(websocket.TextMessage, []byte(`{"status":"ready"}`)); err != nil {
+		return err
+	}
+	for {
+		f, err := m.read(c)
+		var fe *frameError
+		if errors.As(err, &fe) && fe.Code == "rate_limited" {
+			continue
+		}
+		if err != nil {
+			st.order(false)
+			return err
+		}
+		if f.Type == "" && f.ID != "" {
+			st.observe(phaseOrder, time.Since(start))
+			st.order(true)
+			return nil
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"math"
+	"math/rand/v2"
+	"sort"
+	"time"
+)
+
+// pattern is the loudness a member sends at t into a chant of length d.
+// seed in [0, 1) sets the member apart, so a party is not in lockstep.
+type pattern func(t, d time.Duration, seed float64) float64
+
+var patterns = map[string]pattern{
+	// steady, like a room that found its rhythm
+	"constant": func(_, _ time.Duration, seed float64) float64 { return 0.55 + 0.1*seed },
+	// swelling and fading every two seconds
+	"sine": func(t, _ time.Duration, seed float64) float64 {
+		return 0.5 + 0.3*math.Sin(2*math.Pi*(t.Seconds()/2+seed))
+	},
+	// from a murmur to a shout over the chant
+	"ramp": func(t, d time.Duration, _ float64) float64 { return 0.1 + 0.85*float64(t)/float64(d) },
+	// noise
+	"random": func(time.Duration, time.Duration, float64) float64 { return 0.1 + 0.8*rand.Float64() },
+	// quiet, with a one-second shout every three
+	"burst": func(t, _ time.Duration, seed float64) float64 {
+		if math.Mod(t.Seconds()+3*seed, 3) < 1 {
+			return 0.9
+		}
+		return 0.2
+	},
+}
+
+// value is p at t, kept inside (0, 1]: the UI sends normalized volumes and
+// gateway-ws ignores 0.
+func (p pattern) value(t, d time.Duration, seed float64) float64 {
+	return math.Min(math.Max(p(t, d, seed), 0.01), 1)
+}
+
+func patternNames() []string {
+	names := make([]string, 0, len(patterns))
+	for n := range patterns {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// stats collects what the parties saw; every method is safe for concurrent
+// use.
+type stats struct {
+	mu        sync.Mutex
+	latencies map[string][]time.Duration
+	done      int
+	failures  map[string]*failure // by phase and reason
+	sent      int
+	expected  int
+	received  int
+	errFrames map[string]int // by code
+	bytesOut  int            // /ws payload, samples
+	bytesIn   int            // /ws payload, averages and the rest
+	ordersOK  int
+	ordersBad int
+}
+
+type failure struct {
+	n       int
+	example string
+}
+
+func newStats() *stats {
+	return &stats{
+		latencies: make(map[string][]time.Duration),
+		failures:  make(map[string]*failure),
+		errFrames: make(map[string]int),
+	}
+}
+
+func (s *stats) observe(phase string, d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latencies[phase] = append(s.latencies[phase], d)
+}
+
+func (s *stats) partyDone() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.done++
+}
+
+func (s *stats) partyFailed(err error) {
+	phase := "party"
+	var pe *phaseErr
+	if errors.As(err, &pe) {
+		phase = pe.phase
+	}
+	key := phase + ": " + reason(err)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, ok := s.failures[key]
+	if !ok {
+		f = &failure{example: strings.TrimSpace(err.Error())}
+		s.failures[key] = f
+	}
+	f.n++
+}
+
+// addFrames counts one party's chant: samples sent, average frames its
+// members should have seen, and those they did.
+func (s *stats) addFrames(sent, expected, received int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sent += sent
+	s.expected += expected
+	s.received += received
+}
+
+// chantBytes counts /ws payload bytes written and read.
+func (s *stats) chantBytes(out, in int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.bytesOut += out
+	s.bytesIn += in
+}
+
+func (s *stats) errorFrame(code string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.errFrames[code]++
+}
+
+func (s *stats) order(ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if ok {
+		s.ordersOK++
+	} else {
+		s.ordersBad++
+	}
+}
+
+// reason buckets an error for the report.
+func reason(err error) string {
+	var fe *frameError
+	var he *httpError
+	var ce *websocket.CloseError
+	var ne net.Error
+	switch {
+	case errors.As(err, &fe):
+		return "error frame " + fe.Code
+	case errors.As(err, &he):
+		return he.Error()
+	case errors.As(err, &ce):
+		return fmt.Sprintf("closed %d", ce.Code)
+	case errors.Is(err, context.Canceled):
+		return "interrupted"
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, os.ErrDeadlineExceeded),
+		errors.As(err, &ne) && ne.Timeout():
+		return "timeout"
+	default:
+		return "error"
+	}
+}
+
+// percentile is the nearest-rank p-th percentile of sorted ds.
+func percentile(ds []time.Duration, p float64) time.Duration {
+	if len(ds) == 0 {
+		return 0
+	}
+	i := int(math.Ceil(p/100*float64(len(ds)))) - 1
+	return ds[max(i, 0)]
+}
+
+func (s *stats) report(w io.Writer, elapsed time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	failed := 0
+	for _, f := range s.failures {
+		failed += f.n
+	}
+	fmt.Fprintf(w, "parties: %d completed, %d failed in %s\n\n", s.done, failed, elapsed.Round(time.Millisecond))
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "latency\tn\tp50\tp90\tp99\tmax")
+	for _, phase := range []string{phaseSession, phaseStay, phaseSample, phaseOrder} {
+		ds := slices.Clone(s.latencies[phase])
+		if len(ds) == 0 {
+			continue
+		}
+		slices.Sort(ds)
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\t%s\n", phase, len(ds),
+			ms(percentile(ds, 50)), ms(percentile(ds, 90)), ms(percentile(ds, 99)), ms(ds[len(ds)-1]))
+	}
+	_ = tw.Flush()
+
+	fmt.Fprintf(w, "\nsamples sent: %d\n", s.sent)
+	if s.expected > 0 {
+		dropped := max(s.expected-s.received, 0)
+		fmt.Fprintf(w, "average frames: %d of %d, %d dropped (%.2f%%)\n",
+			s.received, s.expected, dropped, 100*float64(dropped)/float64(s.expected))
+	}
+	if s.bytesOut+s.bytesIn > 0 {
+		fmt.Fprintf(w, "/ws payload: %s sent, %s received\n", kib(s.bytesOut), kib(s.bytesIn))
+	}
+	for _, code := range sortedKeys(s.errFrames) {
+		fmt.Fprintf(w, "error frames %s: %d\n", code, s.errFrames[code])
+	}
+	if n := s.ordersOK + s.ordersBad; n > 0 {
+		fmt.Fprintf(w, "orders: %d of %d succeeded (%.1f%%)\n", s.ordersOK, n, 100*float64(s.ordersOK)/float64(n))
+	}
+	if len(s.failures) > 0 {
+		fmt.Fprintln(w, "\nfailures:")
+		for _, key := range sortedKeys(s.failures) {
+			f := s.failures[key]
+			fmt.Fprintf(w, "  %s: %d (e.g. %s)\n", key, f.n, f.example)
+		}
+	}
+}
+
+func ms(d time.Duration) string {
+	return fmt.Sprintf("%.1fms", float64(d)/float64(time.Millisecond))
+}
+
+func kib(n int) string {
+	return fmt.Sprintf("%.1fKiB", float64(n)/1024)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
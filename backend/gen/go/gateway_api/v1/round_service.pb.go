@@ -0,0 +1,699 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.9
+// 	protoc        (unknown)
+// source: gateway_api/v1/round_service.proto
+
+package gatewayapiv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type RoundState int32
+
+const (
+	RoundState_ROUND_STATE_UNSPECIFIED RoundState = 0
+	RoundState_ROUND_STATE_LOBBY       RoundState = 1
+	RoundState_ROUND_STATE_COUNTDOWN   RoundState = 2
+	RoundState_ROUND_STATE_CHANTING    RoundState = 3
+	RoundState_ROUND_STATE_CONFIRMING  RoundState = 4
+	RoundState_ROUND_STATE_ORDERED     RoundState = 5
+	RoundState_ROUND_STATE_FAILED      RoundState = 6
+	RoundState_ROUND_STATE_EXPIRED     RoundState = 7
+)
+
+// Enum value maps for RoundState.
+var (
+	RoundState_name = map[int32]string{
+		0: "ROUND_STATE_UNSPECIFIED",
+		1: "ROUND_STATE_LOBBY",
+		2: "ROUND_STATE_COUNTDOWN",
+		3: "ROUND_STATE_CHANTING",
+		4: "ROUND_STATE_CONFIRMING",
+		5: "ROUND_STATE_ORDERED",
+		6: "ROUND_STATE_FAILED",
+		7: "ROUND_STATE_EXPIRED",
+	}
+	RoundState_value = map[string]int32{
+		"ROUND_STATE_UNSPECIFIED": 0,
+		"ROUND_STATE_LOBBY":       1,
+		"ROUND_STATE_COUNTDOWN":   2,
+		"ROUND_STATE_CHANTING":    3,
+		"ROUND_STATE_CONFIRMING":  4,
+		"ROUND_STATE_ORDERED":     5,
+		"ROUND_STATE_FAILED":      6,
+		"ROUND_STATE_EXPIRED":     7,
+	}
+)
+
+func (x RoundState) Enum() *RoundState {
+	p := new(RoundState)
+	*p = x
+	return p
+}
+
+func (x RoundState) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (RoundState) Descriptor() protoreflect.EnumDescriptor {
+	return file_gateway_api_v1_round_service_proto_enumTypes[0].Descriptor()
+}
+
+func (RoundState) Type() protoreflect.EnumType {
+	return &file_gateway_api_v1_round_service_proto_enumTypes[0]
+}
+
+func (x RoundState) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use RoundState.Descriptor instead.
+func (RoundState) EnumDescriptor() ([]byte, []int) {
+	return file_gateway_api_v1_round_service_proto_rawDescGZIP(), []int{0}
+}
+
+type RoundTransition struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	State         RoundState             `protobuf:"varint,1,opt,name=state,proto3,enum=gateway_api.v1.RoundState" json:"state,omitempty"`
+	AtUnixMs      int64                  `protobuf:"varint,2,opt,name=at_unix_ms,json=atUnixMs,proto3" json:"at_unix_ms,omitempty"`
+	Reason        string                 `protobuf:"bytes,3,opt,name=reason,proto3" json:"reason,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RoundTransition) Reset() {
+	*x = RoundTransition{}
+	mi := &file_gateway_api_v1_round_service_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RoundTransition) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RoundTransition) ProtoMessage() {}
+
+func (x *RoundTransition) ProtoReflect() protoreflect.Message {
+	mi := &file_gateway_api_v1_round_service_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RoundTransition.ProtoReflect.Descriptor instead.
+func (*RoundTransition) Descriptor() ([]byte, []int) {
+	return file_gateway_api_v1_round_service_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *RoundTransition) GetState() RoundState {
+	if x != nil {
+		return x.State
+	}
+	return RoundState_ROUND_STATE_UNSPECIFIED
+}
+
+func (x *RoundTransition) GetAtUnixMs() int64 {
+	if x != nil {
+		return x.AtUnixMs
+	}
+	return 0
+}
+
+func (x *RoundTransition) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+type Round struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// The menu item ID.
+	Room            string     `protobuf:"bytes,2,opt,name=room,proto3" json:"room,omitempty"`
+	State           RoundState `protobuf:"varint,3,opt,name=state,proto3,enum=gateway_api.v1.RoundState" json:"state,omitempty"`
+	CreatedAtUnixMs int64      `protobuf:"varint,4,opt,name=created_at_unix_ms,json=createdAtUnixMs,proto3" json:"created_at_unix_ms,omitempty"`
+	UpdatedAtUnixMs int64      `protobuf:"varint,5,opt,name=updated_at_unix_ms,json=updatedAtUnixMs,proto3" json:"updated_at_unix_ms,omitempty"`
+	// When the current state expires; 0 once the round has ended.
+	DeadlineUnixMs int64 `protobuf:"varint,6,opt,name=deadline_unix_ms,json=deadlineUnixMs,proto3" json:"deadline_unix_ms,omitempty"`
+	// When chanting starts; 0 until the countdown.
+	StartAtUnixMs int64 `protobuf:"varint,7,opt,name=start_at_unix_ms,json=startAtUnixMs,proto3" json:"start_at_unix_ms,omitempty"`
+	// Why the round failed or expired.
+	Reason        string             `protobuf:"bytes,8,opt,name=reason,proto3" json:"reason,omitempty"`
+	History       []*RoundTransition `protobuf:"bytes,9,rep,name=history,proto3" json:"history,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Round) Reset() {
+	*x = Round{}
+	mi := &file_gateway_api_v1_round_service_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Round) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Round) ProtoMessage() {}
+
+func (x *Round) ProtoReflect() protoreflect.Message {
+	mi := &file_gateway_api_v1_round_service_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Round.ProtoReflect.Descriptor instead.
+func (*Round) Descriptor() ([]byte, []int) {
+	return file_gateway_api_v1_round_service_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Round) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Round) GetRoom() string {
+	if x != nil {
+		return x.Room
+	}
+	return ""
+}
+
+func (x *Round) GetState() RoundState {
+	if x != nil {
+		return x.State
+	}
+	return RoundState_ROUND_STATE_UNSPECIFIED
+}
+
+func (x *Round) GetCreatedAtUnixMs() int64 {
+	if x != nil {
+		return x.CreatedAtUnixMs
+	}
+	return 0
+}
+
+func (x *Round) GetUpdatedAtUnixMs() int64 {
+	if x != nil {
+		return x.UpdatedAtUnixMs
+	}
+	return 0
+}
+
+func (x *Round) GetDeadlineUnixMs() int64 {
+	if x != nil {
+		return x.DeadlineUnixMs
+	}
+	return 0
+}
+
+func (x *Round) GetStartAtUnixMs() int64 {
+	if x != nil {
+		return x.StartAtUnixMs
+	}
+	return 0
+}
+
+func (x *Round) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *Round) GetHistory() []*RoundTransition {
+	if x != nil {
+		return x.History
+	}
+	return nil
+}
+
+type OpenRoundRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Room  string                 `protobuf:"bytes,1,opt,name=room,proto3" json:"room,omitempty"`
+	// Session subject of the participant joining; required.
+	Member        string `protobuf:"bytes,2,opt,name=member,proto3" json:"member,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *OpenRoundRequest) Reset() {
+	*x = OpenRoundRequest{}
+	mi := &file_gateway_api_v1_round_service_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *OpenRoundRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OpenRoundRequest) ProtoMessage() {}
+
+func (x *OpenRoundRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_gateway_api_v1_round_service_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OpenRoundRequest.ProtoReflect.Descriptor instead.
+func (*OpenRoundRequest) Descriptor() ([]byte, []int) {
+	return file_gateway_api_v1_round_service_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *OpenRoundRequest) GetRoom() string {
+	if x != nil {
+		return x.Room
+	}
+	return ""
+}
+
+func (x *OpenRoundRequest) GetMember() string {
+	if x != nil {
+		return x.Member
+	}
+	return ""
+}
+
+type OpenRoundResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Round         *Round                 `protobuf:"bytes,1,opt,name=round,proto3" json:"round,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *OpenRoundResponse) Reset() {
+	*x = OpenRoundResponse{}
+	mi := &file_gateway_api_v1_round_service_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *OpenRoundResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OpenRoundResponse) ProtoMessage() {}
+
+func (x *OpenRoundResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_gateway_api_v1_round_service_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OpenRoundResponse.ProtoReflect.Descriptor instead.
+func (*OpenRoundResponse) Descriptor() ([]byte, []int) {
+	return file_gateway_api_v1_round_service_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *OpenRoundResponse) GetRound() *Round {
+	if x != nil {
+		return x.Round
+	}
+	return nil
+}
+
+type GetRoundRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetRoundRequest) Reset() {
+	*x = GetRoundRequest{}
+	mi := &file_gateway_api_v1_round_service_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetRoundRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetRoundRequest) ProtoMessage() {}
+
+func (x *GetRoundRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_gateway_api_v1_round_service_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetRoundRequest.ProtoReflect.Descriptor instead.
+func (*GetRoundRequest) Descriptor() ([]byte, []int) {
+	return file_gateway_api_v1_round_service_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *GetRoundRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type GetRoundResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Round         *Round                 `protobuf:"bytes,1,opt,name=round,proto3" json:"round,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetRoundResponse) Reset() {
+	*x = GetRoundResponse{}
+	mi := &file_gateway_api_v1_round_service_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetRoundResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetRoundResponse) ProtoMessage() {}
+
+func (x *GetRoundResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_gateway_api_v1_round_service_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetRoundResponse.ProtoReflect.Descriptor instead.
+func (*GetRoundResponse) Descriptor() ([]byte, []int) {
+	return file_gateway_api_v1_round_service_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *GetRoundResponse) GetRound() *Round {
+	if x != nil {
+		return x.Round
+	}
+	return nil
+}
+
+type AdvanceRoundRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// Must be the round's room (INVALID_ARGUMENT otherwise); required.
+	Room  string     `protobuf:"bytes,2,opt,name=room,proto3" json:"room,omitempty"`
+	State RoundState `protobuf:"varint,3,opt,name=state,proto3,enum=gateway_api.v1.RoundState" json:"state,omitempty"`
+	// When chanting starts; read when moving to COUNTDOWN.
+	StartAtUnixMs int64  `protobuf:"varint,4,opt,name=start_at_unix_ms,json=startAtUnixMs,proto3" json:"start_at_unix_ms,omitempty"`
+	Reason        string `protobuf:"bytes,5,opt,name=reason,proto3" json:"reason,omitempty"`
+	// Session subject of the participant the move is made for, which must
+	// have joined the round through OpenRound; required.
+	Member        string `protobuf:"bytes,6,opt,name=member,proto3" json:"member,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AdvanceRoundRequest) Reset() {
+	*x = AdvanceRoundRequest{}
+	mi := &file_gateway_api_v1_round_service_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AdvanceRoundRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AdvanceRoundRequest) ProtoMessage() {}
+
+func (x *AdvanceRoundRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_gateway_api_v1_round_service_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AdvanceRoundRequest.ProtoReflect.Descriptor instead.
+func (*AdvanceRoundRequest) Descriptor() ([]byte, []int) {
+	return file_gateway_api_v1_round_service_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *AdvanceRoundRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *AdvanceRoundRequest) GetRoom() string {
+	if x != nil {
+		return x.Room
+	}
+	return ""
+}
+
+func (x *AdvanceRoundRequest) GetState() RoundState {
+	if x != nil {
+		return x.State
+	}
+	return RoundState_ROUND_STATE_UNSPECIFIED
+}
+
+func (x *AdvanceRoundRequest) GetStartAtUnixMs() int64 {
+	if x != nil {
+		return x.StartAtUnixMs
+	}
+	return 0
+}
+
+func (x *AdvanceRoundRequest) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *AdvanceRoundRequest) GetMember() string {
+	if x != nil {
+		return x.Member
+	}
+	return ""
+}
+
+type AdvanceRoundResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Round         *Round                 `protobuf:"bytes,1,opt,name=round,proto3" json:"round,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AdvanceRoundResponse) Reset() {
+	*x = AdvanceRoundResponse{}
+	mi := &file_gateway_api_v1_round_service_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AdvanceRoundResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AdvanceRoundResponse) ProtoMessage() {}
+
+func (x *AdvanceRoundResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_gateway_api_v1_round_service_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AdvanceRoundResponse.ProtoReflect.Descriptor instead.
+func (*AdvanceRoundResponse) Descriptor() ([]byte, []int) {
+	return file_gateway_api_v1_round_service_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *AdvanceRoundResponse) GetRound() *Round {
+	if x != nil {
+		return x.Round
+	}
+	return nil
+}
+
+var File_gateway_api_v1_round_service_proto protoreflect.FileDescriptor
+
+const file_gateway_api_v1_round_service_proto_rawDesc = "" +
+	"\n" +
+	"\"gateway_api/v1/round_service.proto\x12\x0egateway_api.v1\"y\n" +
+	"\x0fRoundTransition\x120\n" +
+	"\x05state\x18\x01 \x01(\x0e2\x1a.gateway_api.v1.RoundStateR\x05state\x12\x1c\n" +
+	"\n" +
+	"at_unix_ms\x18\x02 \x01(\x03R\batUnixMs\x12\x16\n" +
+	"\x06reason\x18\x03 \x01(\tR\x06reason\"\xdd\x02\n" +
+	"\x05Round\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04room\x18\x02 \x01(\tR\x04room\x120\n" +
+	"\x05state\x18\x03 \x01(\x0e2\x1a.gateway_api.v1.RoundStateR\x05state\x12+\n" +
+	"\x12created_at_unix_ms\x18\x04 \x01(\x03R\x0fcreatedAtUnixMs\x12+\n" +
+	"\x12updated_at_unix_ms\x18\x05 \x01(\x03R\x0fupdatedAtUnixMs\x12(\n" +
+	"\x10deadline_unix_ms\x18\x06 \x01(\x03R\x0edeadlineUnixMs\x12'\n" +
+	"\x10start_at_unix_ms\x18\a \x01(\x03R\rstartAtUnixMs\x12\x16\n" +
+	"\x06reason\x18\b \x01(\tR\x06reason\x129\n" +
+	"\ahistory\x18\t \x03(\v2\x1f.gateway_api.v1.RoundTransitionR\ahistory\">\n" +
+	"\x10OpenRoundRequest\x12\x12\n" +
+	"\x04room\x18\x01 \x01(\tR\x04room\x12\x16\n" +
+	"\x06member\x18\x02 \x01(\tR\x06member\"@\n" +
+	"\x11OpenRoundResponse\x12+\n" +
+	"\x05round\x18\x01 \x01(\v2\x15.gateway_api.v1.RoundR\x05round\"!\n" +
+	"\x0fGetRoundRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"?\n" +
+	"\x10GetRoundResponse\x12+\n" +
+	"\x05round\x18\x01 \x01(\v2\x15.gateway_api.v1.RoundR\x05round\"\xc4\x01\n" +
+	"\x13AdvanceRoundRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04room\x18\x02 \x01(\tR\x04room\x120\n" +
+	"\x05state\x18\x03 \x01(\x0e2\x1a.gateway_api.v1.RoundStateR\x05state\x12'\n" +
+	"\x10start_at_unix_ms\x18\x04 \x01(\x03R\rstartAtUnixMs\x12\x16\n" +
+	"\x06reason\x18\x05 \x01(\tR\x06reason\x12\x16\n" +
+	"\x06member\x18\x06 \x01(\tR\x06member\"C\n" +
+	"\x14AdvanceRoundResponse\x12+\n" +
+	"\x05round\x18\x01 \x01(\v2\x15.gateway_api.v1.RoundR\x05round*\xdb\x01\n" +
+	"\n" +
+	"RoundState\x12\x1b\n" +
+	"\x17ROUND_STATE_UNSPECIFIED\x10\x00\x12\x15\n" +
+	"\x11ROUND_STATE_LOBBY\x10\x01\x12\x19\n" +
+	"\x15ROUND_STATE_COUNTDOWN\x10\x02\x12\x18\n" +
+	"\x14ROUND_STATE_CHANTING\x10\x03\x12\x1a\n" +
+	"\x16ROUND_STATE_CONFIRMING\x10\x04\x12\x17\n" +
+	"\x13ROUND_STATE_ORDERED\x10\x05\x12\x16\n" +
+	"\x12ROUND_STATE_FAILED\x10\x06\x12\x17\n" +
+	"\x13ROUND_STATE_EXPIRED\x10\a2\x90\x02\n" +
+	"\fRoundService\x12R\n" +
+	"\tOpenRound\x12 .gateway_api.v1.OpenRoundRequest\x1a!.gateway_api.v1.OpenRoundResponse\"\x00\x12O\n" +
+	"\bGetRound\x12\x1f.gateway_api.v1.GetRoundRequest\x1a .gateway_api.v1.GetRoundResponse\"\x00\x12[\n" +
+	"\fAdvanceRound\x12#.gateway_api.v1.AdvanceRoundRequest\x1a$.gateway_api.v1.AdvanceRoundResponse\"\x00B5Z3chantingkakigori/gen/go/gateway_api/v1;gatewayapiv1b\x06proto3"
+
+var (
+	file_gateway_api_v1_round_service_proto_rawDescOnce sync.Once
+	file_gateway_api_v1_round_service_proto_rawDescData []byte
+)
+
+func file_gateway_api_v1_round_service_proto_rawDescGZIP() []byte {
+	file_gateway_api_v1_round_service_proto_rawDescOnce.Do(func() {
+		file_gateway_api_v1_round_service_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_gateway_api_v1_round_service_proto_rawDesc), len(file_gateway_api_v1_round_service_proto_rawDesc)))
+	})
+	return file_gateway_api_v1_round_service_proto_rawDescData
+}
+
+var file_gateway_api_v1_round_service_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_gateway_api_v1_round_service_proto_msgTypes = make([]protoimpl.MessageInfo, 8)
+var file_gateway_api_v1_round_service_proto_goTypes = []any{
+	(RoundState)(0),              // 0: gateway_api.v1.RoundState
+	(*RoundTransition)(nil),      // 1: gateway_api.v1.RoundTransition
+	(*Round)(nil),                // 2: gateway_api.v1.Round
+	(*OpenRoundRequest)(nil),     // 3: gateway_api.v1.OpenRoundRequest
+	(*OpenRoundResponse)(nil),    // 4: gateway_api.v1.OpenRoundResponse
+	(*GetRoundRequest)(nil),      // 5: gateway_api.v1.GetRoundRequest
+	(*GetRoundResponse)(nil),     // 6: gateway_api.v1.GetRoundResponse
+	(*AdvanceRoundRequest)(nil),  // 7: gateway_api.v1.AdvanceRoundRequest
+	(*AdvanceRoundResponse)(nil), // 8: gateway_api.v1.AdvanceRoundResponse
+}
+var file_gateway_api_v1_round_service_proto_depIdxs = []int32{
+	0,  // 0: gateway_api.v1.RoundTransition.state:type_name -> gateway_api.v1.RoundState
+	0,  // 1: gateway_api.v1.Round.state:type_name -> gateway_api.v1.RoundState
+	1,  // 2: gateway_api.v1.Round.history:type_name -> gateway_api.v1.RoundTransition
+	2,  // 3: gateway_api.v1.OpenRoundResponse.round:type_name -> gateway_api.v1.Round
+	2,  // 4: gateway_api.v1.GetRoundResponse.round:type_name -> gateway_api.v1.Round
+	0,  // 5: gateway_api.v1.AdvanceRoundRequest.state:type_name -> gateway_api.v1.RoundState
+	2,  // 6: gateway_api.v1.AdvanceRoundResponse.round:type_name -> gateway_api.v1.Round
+	3,  // 7: gateway_api.v1.RoundService.OpenRound:input_type -> gateway_api.v1.OpenRoundRequest
+	5,  // 8: gateway_api.v1.RoundService.GetRound:input_type -> gateway_api.v1.GetRoundRequest
+	7,  // 9: gateway_api.v1.RoundService.AdvanceRound:input_type -> gateway_api.v1.AdvanceRoundRequest
+	4,  // 10: gateway_api.v1.RoundService.OpenRound:output_type -> gateway_api.v1.OpenRoundResponse
+	6,  // 11: gateway_api.v1.RoundService.GetRound:output_type -> gateway_api.v1.GetRoundResponse
+	8,  // 12: gateway_api.v1.RoundService.AdvanceRound:output_type -> gateway_api.v1.AdvanceRoundResponse
+	10, // [10:13] is the sub-list for method output_type
+	7,  // [7:10] is the sub-list for method input_type
+	7,  // [7:7] is the sub-list for extension type_name
+	7,  // [7:7] is the sub-list for extension extendee
+	0,  // [0:7] is the sub-list for field type_name
+}
+
+func init() { file_gateway_api_v1_round_service_proto_init() }
+func file_gateway_api_v1_round_service_proto_init() {
+	if File_gateway_api_v1_round_service_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_gateway_api_v1_round_service_proto_rawDesc), len(file_gateway_api_v1_round_service_proto_rawDesc)),
+			NumEnums:      1,
+			NumMessages:   8,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_gateway_api_v1_round_service_proto_goTypes,
+		DependencyIndexes: file_gateway_api_v1_round_service_proto_depIdxs,
+		EnumInfos:         file_gateway_api_v1_round_service_proto_enumTypes,
+		MessageInfos:      file_gateway_api_v1_round_service_proto_msgTypes,
+	}.Build()
+	File_gateway_api_v1_round_service_proto = out.File
+	file_gateway_api_v1_round_service_proto_goTypes = nil
+	file_gateway_api_v1_round_service_proto_depIdxs = nil
+}
@@ -0,0 +1,225 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: gateway_api/v1/round_service.proto
+
+package gatewayapiv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	RoundService_OpenRound_FullMethodName    = "/gateway_api.v1.RoundService/OpenRound"
+	RoundService_GetRound_FullMethodName     = "/gateway_api.v1.RoundService/GetRound"
+	RoundService_AdvanceRound_FullMethodName = "/gateway_api.v1.RoundService/AdvanceRound"
+)
+
+// RoundServiceClient is the client API for RoundService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// RoundService owns a party's round from the lobby to its order: /ws/stay,
+// /ws and /ws/confirm carry the round ID and move the round through
+// lobby -> countdown -> chanting -> confirming -> ordered | failed. A failed
+// round may still become ordered, as each replica holding part of the party
+// reports its own outcome. A round that overstays its state's deadline is
+// expired.
+type RoundServiceClient interface {
+	// OpenRound returns the room's round in the lobby, opening one if there is
+	// none (or it expired), with the member joined to it.
+	OpenRound(ctx context.Context, in *OpenRoundRequest, opts ...grpc.CallOption) (*OpenRoundResponse, error)
+	// GetRound answers NOT_FOUND for unknown and forgotten rounds.
+	GetRound(ctx context.Context, in *GetRoundRequest, opts ...grpc.CallOption) (*GetRoundResponse, error)
+	// AdvanceRound moves a round to state for one of its members; moving it to
+	// the state it is in is a no-op. A caller that is not a member is
+	// PERMISSION_DENIED. A move the state machine does not allow, or of a round
+	// past its deadline (which expires it), is FAILED_PRECONDITION.
+	AdvanceRound(ctx context.Context, in *AdvanceRoundRequest, opts ...grpc.CallOption) (*AdvanceRoundResponse, error)
+}
+
+type roundServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewRoundServiceClient(cc grpc.ClientConnInterface) RoundServiceClient {
+	return &roundServiceClient{cc}
+}
+
+func (c *roundServiceClient) OpenRound(ctx context.Context, in *OpenRoundRequest, opts ...grpc.CallOption) (*OpenRoundResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(OpenRoundResponse)
+	err := c.cc.Invoke(ctx, RoundService_OpenRound_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *roundServiceClient) GetRound(ctx context.Context, in *GetRoundRequest, opts ...grpc.CallOption) (*GetRoundResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetRoundResponse)
+	err := c.cc.Invoke(ctx, RoundService_GetRound_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *roundServiceClient) AdvanceRound(ctx context.Context, in *AdvanceRoundRequest, opts ...grpc.CallOption) (*AdvanceRoundResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AdvanceRoundResponse)
+	err := c.cc.Invoke(ctx, RoundService_AdvanceRound_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RoundServiceServer is the server API for RoundService service.
+// All implementations must embed UnimplementedRoundServiceServer
+// for forward compatibility.
+//
+// RoundService owns a party's round from the lobby to its order: /ws/stay,
+// /ws and /ws/confirm carry the round ID and move the round through
+// lobby -> countdown -> chanting -> confirming -> ordered | failed. A failed
+// round may still become ordered, as each replica holding part of the party
+// reports its own outcome. A round that overstays its state's deadline is
+// expired.
+type RoundServiceServer interface {
+	// OpenRound returns the room's round in the lobby, opening one if there is
+	// none (or it expired), with the member joined to it.
+	OpenRound(context.Context, *OpenRoundRequest) (*OpenRoundResponse, error)
+	// GetRound answers NOT_FOUND for unknown and forgotten rounds.
+	GetRound(context.Context, *GetRoundRequest) (*GetRoundResponse, error)
+	// AdvanceRound moves a round to state for one of its members; moving it to
+	// the state it is in is a no-op. A caller that is not a member is
+	// PERMISSION_DENIED. A move the state machine does not allow, or of a round
+	// past its deadline (which expires it), is FAILED_PRECONDITION.
+	AdvanceRound(context.Context, *AdvanceRoundRequest) (*AdvanceRoundResponse, error)
+	mustEmbedUnimplementedRoundServiceServer()
+}
+
+// UnimplementedRoundServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedRoundServiceServer struct{}
+
+func (UnimplementedRoundServiceServer) OpenRound(context.Context, *OpenRoundRequest) (*OpenRoundResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method OpenRound not implemented")
+}
+func (UnimplementedRoundServiceServer) GetRound(context.Context, *GetRoundRequest) (*GetRoundResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRound not implemented")
+}
+func (UnimplementedRoundServiceServer) AdvanceRound(context.Context, *AdvanceRoundRequest) (*AdvanceRoundResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AdvanceRound not implemented")
+}
+func (UnimplementedRoundServiceServer) mustEmbedUnimplementedRoundServiceServer() {}
+func (UnimplementedRoundServiceServer) testEmbeddedByValue()                      {}
+
+// UnsafeRoundServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to RoundServiceServer will
+// result in compilation errors.
+type UnsafeRoundServiceServer interface {
+	mustEmbedUnimplementedRoundServiceServer()
+}
+
+func RegisterRoundServiceServer(s grpc.ServiceRegistrar, srv RoundServiceServer) {
+	// If the following call pancis, it indicates UnimplementedRoundServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&RoundService_ServiceDesc, srv)
+}
+
+func _RoundService_OpenRound_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(OpenRoundRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RoundServiceServer).OpenRound(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RoundService_OpenRound_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RoundServiceServer).OpenRound(ctx, req.(*OpenRoundRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RoundService_GetRound_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRoundRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RoundServiceServer).GetRound(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RoundService_GetRound_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RoundServiceServer).GetRound(ctx, req.(*GetRoundRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RoundService_AdvanceRound_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AdvanceRoundRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RoundServiceServer).AdvanceRound(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RoundService_AdvanceRound_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RoundServiceServer).AdvanceRound(ctx, req.(*AdvanceRoundRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// RoundService_ServiceDesc is the grpc.ServiceDesc for RoundService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var RoundService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "gateway_api.v1.RoundService",
+	HandlerType: (*RoundServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "OpenRound",
+			Handler:    _RoundService_OpenRound_Handler,
+		},
+		{
+			MethodName: "GetRound",
+			Handler:    _RoundService_GetRound_Handler,
+		},
+		{
+			MethodName: "AdvanceRound",
+			Handler:    _RoundService_AdvanceRound_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "gateway_api/v1/round_service.proto",
+}
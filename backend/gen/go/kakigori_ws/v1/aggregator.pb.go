@@ -21,10 +21,201 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+// AudioCodec is how an AudioFrame is encoded. Audio is always mono.
+type AudioCodec int32
+
+const (
+	AudioCodec_AUDIO_CODEC_UNSPECIFIED AudioCodec = 0
+	// Little-endian signed 16-bit PCM.
+	AudioCodec_AUDIO_CODEC_PCM_S16LE AudioCodec = 1
+	// Opus packets, each preceded by its little-endian uint16 length.
+	AudioCodec_AUDIO_CODEC_OPUS AudioCodec = 2
+)
+
+// Enum value maps for AudioCodec.
+var (
+	AudioCodec_name = map[int32]string{
+		0: "AUDIO_CODEC_UNSPECIFIED",
+		1: "AUDIO_CODEC_PCM_S16LE",
+		2: "AUDIO_CODEC_OPUS",
+	}
+	AudioCodec_value = map[string]int32{
+		"AUDIO_CODEC_UNSPECIFIED": 0,
+		"AUDIO_CODEC_PCM_S16LE":   1,
+		"AUDIO_CODEC_OPUS":        2,
+	}
+)
+
+func (x AudioCodec) Enum() *AudioCodec {
+	p := new(AudioCodec)
+	*p = x
+	return p
+}
+
+func (x AudioCodec) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (AudioCodec) Descriptor() protoreflect.EnumDescriptor {
+	return file_kakigori_ws_v1_aggregator_proto_enumTypes[0].Descriptor()
+}
+
+func (AudioCodec) Type() protoreflect.EnumType {
+	return &file_kakigori_ws_v1_aggregator_proto_enumTypes[0]
+}
+
+func (x AudioCodec) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use AudioCodec.Descriptor instead.
+func (AudioCodec) EnumDescriptor() ([]byte, []int) {
+	return file_kakigori_ws_v1_aggregator_proto_rawDescGZIP(), []int{0}
+}
+
+// SampleVerdict is what the aggregator did with the last request of a
+// stream: for a batch, the most severe verdict among its samples.
+type SampleVerdict int32
+
+const (
+	SampleVerdict_SAMPLE_VERDICT_UNSPECIFIED SampleVerdict = 0
+	SampleVerdict_SAMPLE_VERDICT_ACCEPTED    SampleVerdict = 1
+	// Outlier relative to the room; folded in at the room's bound instead.
+	SampleVerdict_SAMPLE_VERDICT_CLAMPED SampleVerdict = 2
+	// Out of range, sent too fast or too old for the window; not folded in.
+	SampleVerdict_SAMPLE_VERDICT_REJECTED SampleVerdict = 3
+)
+
+// Enum value maps for SampleVerdict.
+var (
+	SampleVerdict_name = map[int32]string{
+		0: "SAMPLE_VERDICT_UNSPECIFIED",
+		1: "SAMPLE_VERDICT_ACCEPTED",
+		2: "SAMPLE_VERDICT_CLAMPED",
+		3: "SAMPLE_VERDICT_REJECTED",
+	}
+	SampleVerdict_value = map[string]int32{
+		"SAMPLE_VERDICT_UNSPECIFIED": 0,
+		"SAMPLE_VERDICT_ACCEPTED":    1,
+		"SAMPLE_VERDICT_CLAMPED":     2,
+		"SAMPLE_VERDICT_REJECTED":    3,
+	}
+)
+
+func (x SampleVerdict) Enum() *SampleVerdict {
+	p := new(SampleVerdict)
+	*p = x
+	return p
+}
+
+func (x SampleVerdict) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (SampleVerdict) Descriptor() protoreflect.EnumDescriptor {
+	return file_kakigori_ws_v1_aggregator_proto_enumTypes[1].Descriptor()
+}
+
+func (SampleVerdict) Type() protoreflect.EnumType {
+	return &file_kakigori_ws_v1_aggregator_proto_enumTypes[1]
+}
+
+func (x SampleVerdict) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use SampleVerdict.Descriptor instead.
+func (SampleVerdict) EnumDescriptor() ([]byte, []int) {
+	return file_kakigori_ws_v1_aggregator_proto_rawDescGZIP(), []int{1}
+}
+
+type WatchEventType int32
+
+const (
+	WatchEventType_WATCH_EVENT_TYPE_UNSPECIFIED WatchEventType = 0
+	// The room's average or participants changed.
+	WatchEventType_WATCH_EVENT_TYPE_UPDATE WatchEventType = 1
+	// The room is gone: reason is "finished" (its last participant left) or
+	// "closed" (CloseRoom).
+	WatchEventType_WATCH_EVENT_TYPE_ENDED WatchEventType = 2
+	// The room was exported to another replica, which reports it from now on.
+	WatchEventType_WATCH_EVENT_TYPE_MOVED WatchEventType = 3
+)
+
+// Enum value maps for WatchEventType.
+var (
+	WatchEventType_name = map[int32]string{
+		0: "WATCH_EVENT_TYPE_UNSPECIFIED",
+		1: "WATCH_EVENT_TYPE_UPDATE",
+		2: "WATCH_EVENT_TYPE_ENDED",
+		3: "WATCH_EVENT_TYPE_MOVED",
+	}
+	WatchEventType_value = map[string]int32{
+		"WATCH_EVENT_TYPE_UNSPECIFIED": 0,
+		"WATCH_EVENT_TYPE_UPDATE":      1,
+		"WATCH_EVENT_TYPE_ENDED":       2,
+		"WATCH_EVENT_TYPE_MOVED":       3,
+	}
+)
+
+func (x WatchEventType) Enum() *WatchEventType {
+	p := new(WatchEventType)
+	*p = x
+	return p
+}
+
+func (x WatchEventType) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (WatchEventType) Descriptor() protoreflect.EnumDescriptor {
+	return file_kakigori_ws_v1_aggregator_proto_enumTypes[2].Descriptor()
+}
+
+func (WatchEventType) Type() protoreflect.EnumType {
+	return &file_kakigori_ws_v1_aggregator_proto_enumTypes[2]
+}
+
+func (x WatchEventType) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use WatchEventType.Descriptor instead.
+func (WatchEventType) EnumDescriptor() ([]byte, []int) {
+	return file_kakigori_ws_v1_aggregator_proto_rawDescGZIP(), []int{2}
+}
+
 type AggregateRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Room          string                 `protobuf:"bytes,1,opt,name=room,proto3" json:"room,omitempty"`
-	Value         float64                `protobuf:"fixed64,2,opt,name=value,proto3" json:"value,omitempty"`
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Room  string                 `protobuf:"bytes,1,opt,name=room,proto3" json:"room,omitempty"`
+	// One sample, placed at the time it arrives. Ignored when samples is set.
+	Value float64 `protobuf:"fixed64,2,opt,name=value,proto3" json:"value,omitempty"`
+	// Caller's participant ID, used in flags, logs and the breakdown. It should
+	// be stable for the participant (not per connection). The server assigns
+	// one when empty. Read from the first request of a stream only.
+	ClientId string `protobuf:"bytes,3,opt,name=client_id,json=clientId,proto3" json:"client_id,omitempty"`
+	// Name shown to the other participants. First request only.
+	DisplayName string `protobuf:"bytes,4,opt,name=display_name,json=displayName,proto3" json:"display_name,omitempty"`
+	// Ask for the per-participant breakdown on every response. First request
+	// only.
+	IncludeBreakdown bool `protobuf:"varint,5,opt,name=include_breakdown,json=includeBreakdown,proto3" json:"include_breakdown,omitempty"`
+	// Samples batched by the client, answered with one response. Each is
+	// placed at its client_ts_unix_ms corrected by the stream's clock offset,
+	// so network jitter does not move it within the window.
+	Samples []*ClientSample `protobuf:"bytes,6,rep,name=samples,proto3" json:"samples,omitempty"`
+	// Raw microphone audio the server turns into one sample, instead of a
+	// loudness the client computed. Ignored when samples is set.
+	Audio *AudioFrame `protobuf:"bytes,7,opt,name=audio,proto3" json:"audio,omitempty"`
+	// Device class of the microphone (e.g. "iPhone15,2" or a browser's
+	// platform string), matched against the loudness calibration table.
+	// First request only.
+	Device string `protobuf:"bytes,8,opt,name=device,proto3" json:"device,omitempty"`
+	// Party round (gateway-api's round ID) the stream chants in; the room's
+	// finished round is recorded under it. First request only.
+	RoundId string `protobuf:"bytes,9,opt,name=round_id,json=roundId,proto3" json:"round_id,omitempty"`
+	// Menu item chanted for, which leaderboards rank by; defaults to room.
+	// Set it when room is not the menu item ID. First request only.
+	MenuItemId    string `protobuf:"bytes,10,opt,name=menu_item_id,json=menuItemId,proto3" json:"menu_item_id,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -73,18 +264,288 @@ func (x *AggregateRequest) GetValue() float64 {
 	return 0
 }
 
+func (x *AggregateRequest) GetClientId() string {
+	if x != nil {
+		return x.ClientId
+	}
+	return ""
+}
+
+func (x *AggregateRequest) GetDisplayName() string {
+	if x != nil {
+		return x.DisplayName
+	}
+	return ""
+}
+
+func (x *AggregateRequest) GetIncludeBreakdown() bool {
+	if x != nil {
+		return x.IncludeBreakdown
+	}
+	return false
+}
+
+func (x *AggregateRequest) GetSamples() []*ClientSample {
+	if x != nil {
+		return x.Samples
+	}
+	return nil
+}
+
+func (x *AggregateRequest) GetAudio() *AudioFrame {
+	if x != nil {
+		return x.Audio
+	}
+	return nil
+}
+
+func (x *AggregateRequest) GetDevice() string {
+	if x != nil {
+		return x.Device
+	}
+	return ""
+}
+
+func (x *AggregateRequest) GetRoundId() string {
+	if x != nil {
+		return x.RoundId
+	}
+	return ""
+}
+
+func (x *AggregateRequest) GetMenuItemId() string {
+	if x != nil {
+		return x.MenuItemId
+	}
+	return ""
+}
+
+// AudioFrame is a stretch of audio measured as one loudness sample, at the
+// time its last sample was captured.
+type AudioFrame struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Codec AudioCodec             `protobuf:"varint,1,opt,name=codec,proto3,enum=kakigori_ws.v1.AudioCodec" json:"codec,omitempty"`
+	// 8000 to 48000 Hz; Opus takes 8000, 12000, 16000, 24000 or 48000. A
+	// stream keeps the codec and rate of its first frame.
+	SampleRate int32  `protobuf:"varint,2,opt,name=sample_rate,json=sampleRate,proto3" json:"sample_rate,omitempty"`
+	Data       []byte `protobuf:"bytes,3,opt,name=data,proto3" json:"data,omitempty"`
+	// When the first sample was captured, Unix milliseconds on the client's
+	// clock; 0 places the frame at the time it arrives.
+	ClientTsUnixMs int64 `protobuf:"varint,4,opt,name=client_ts_unix_ms,json=clientTsUnixMs,proto3" json:"client_ts_unix_ms,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *AudioFrame) Reset() {
+	*x = AudioFrame{}
+	mi := &file_kakigori_ws_v1_aggregator_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AudioFrame) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AudioFrame) ProtoMessage() {}
+
+func (x *AudioFrame) ProtoReflect() protoreflect.Message {
+	mi := &file_kakigori_ws_v1_aggregator_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AudioFrame.ProtoReflect.Descriptor instead.
+func (*AudioFrame) Descriptor() ([]byte, []int) {
+	return file_kakigori_ws_v1_aggregator_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *AudioFrame) GetCodec() AudioCodec {
+	if x != nil {
+		return x.Codec
+	}
+	return AudioCodec_AUDIO_CODEC_UNSPECIFIED
+}
+
+func (x *AudioFrame) GetSampleRate() int32 {
+	if x != nil {
+		return x.SampleRate
+	}
+	return 0
+}
+
+func (x *AudioFrame) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *AudioFrame) GetClientTsUnixMs() int64 {
+	if x != nil {
+		return x.ClientTsUnixMs
+	}
+	return 0
+}
+
+// ClientSample is a value stamped with the client's clock.
+type ClientSample struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Value float64                `protobuf:"fixed64,1,opt,name=value,proto3" json:"value,omitempty"`
+	// When the client measured it, Unix milliseconds on the client's clock;
+	// 0 places the sample at the time it arrives.
+	ClientTsUnixMs int64 `protobuf:"varint,2,opt,name=client_ts_unix_ms,json=clientTsUnixMs,proto3" json:"client_ts_unix_ms,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *ClientSample) Reset() {
+	*x = ClientSample{}
+	mi := &file_kakigori_ws_v1_aggregator_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ClientSample) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ClientSample) ProtoMessage() {}
+
+func (x *ClientSample) ProtoReflect() protoreflect.Message {
+	mi := &file_kakigori_ws_v1_aggregator_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ClientSample.ProtoReflect.Descriptor instead.
+func (*ClientSample) Descriptor() ([]byte, []int) {
+	return file_kakigori_ws_v1_aggregator_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ClientSample) GetValue() float64 {
+	if x != nil {
+		return x.Value
+	}
+	return 0
+}
+
+func (x *ClientSample) GetClientTsUnixMs() int64 {
+	if x != nil {
+		return x.ClientTsUnixMs
+	}
+	return 0
+}
+
+// Participant is one client's share of the room window.
+type Participant struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	ClientId    string                 `protobuf:"bytes,1,opt,name=client_id,json=clientId,proto3" json:"client_id,omitempty"`
+	DisplayName string                 `protobuf:"bytes,2,opt,name=display_name,json=displayName,proto3" json:"display_name,omitempty"`
+	// Mean of the client's samples in the window (0 when it has none).
+	Mean  float64 `protobuf:"fixed64,3,opt,name=mean,proto3" json:"mean,omitempty"`
+	Count int32   `protobuf:"varint,4,opt,name=count,proto3" json:"count,omitempty"`
+	// Time of the client's last accepted sample, Unix milliseconds (0 if none).
+	LastSeenUnixMs int64 `protobuf:"varint,5,opt,name=last_seen_unix_ms,json=lastSeenUnixMs,proto3" json:"last_seen_unix_ms,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *Participant) Reset() {
+	*x = Participant{}
+	mi := &file_kakigori_ws_v1_aggregator_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Participant) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Participant) ProtoMessage() {}
+
+func (x *Participant) ProtoReflect() protoreflect.Message {
+	mi := &file_kakigori_ws_v1_aggregator_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Participant.ProtoReflect.Descriptor instead.
+func (*Participant) Descriptor() ([]byte, []int) {
+	return file_kakigori_ws_v1_aggregator_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Participant) GetClientId() string {
+	if x != nil {
+		return x.ClientId
+	}
+	return ""
+}
+
+func (x *Participant) GetDisplayName() string {
+	if x != nil {
+		return x.DisplayName
+	}
+	return ""
+}
+
+func (x *Participant) GetMean() float64 {
+	if x != nil {
+		return x.Mean
+	}
+	return 0
+}
+
+func (x *Participant) GetCount() int32 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+func (x *Participant) GetLastSeenUnixMs() int64 {
+	if x != nil {
+		return x.LastSeenUnixMs
+	}
+	return 0
+}
+
 type AggregateResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Room          string                 `protobuf:"bytes,1,opt,name=room,proto3" json:"room,omitempty"`
-	Average       float64                `protobuf:"fixed64,2,opt,name=average,proto3" json:"average,omitempty"`
-	Count         int32                  `protobuf:"varint,3,opt,name=count,proto3" json:"count,omitempty"`
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Room    string                 `protobuf:"bytes,1,opt,name=room,proto3" json:"room,omitempty"`
+	Average float64                `protobuf:"fixed64,2,opt,name=average,proto3" json:"average,omitempty"`
+	Count   int32                  `protobuf:"varint,3,opt,name=count,proto3" json:"count,omitempty"`
+	Verdict SampleVerdict          `protobuf:"varint,4,opt,name=verdict,proto3,enum=kakigori_ws.v1.SampleVerdict" json:"verdict,omitempty"`
+	// Participants of the room currently flagged as suspicious.
+	FlaggedClients []string `protobuf:"bytes,5,rep,name=flagged_clients,json=flaggedClients,proto3" json:"flagged_clients,omitempty"`
+	// Every participant of the room, ordered by client_id; only when the
+	// stream asked for include_breakdown.
+	Participants  []*Participant `protobuf:"bytes,6,rep,name=participants,proto3" json:"participants,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *AggregateResponse) Reset() {
 	*x = AggregateResponse{}
-	mi := &file_kakigori_ws_v1_aggregator_proto_msgTypes[1]
+	mi := &file_kakigori_ws_v1_aggregator_proto_msgTypes[4]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -96,7 +557,7 @@ func (x *AggregateResponse) String() string {
 func (*AggregateResponse) ProtoMessage() {}
 
 func (x *AggregateResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_kakigori_ws_v1_aggregator_proto_msgTypes[1]
+	mi := &file_kakigori_ws_v1_aggregator_proto_msgTypes[4]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -109,7 +570,7 @@ func (x *AggregateResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use AggregateResponse.ProtoReflect.Descriptor instead.
 func (*AggregateResponse) Descriptor() ([]byte, []int) {
-	return file_kakigori_ws_v1_aggregator_proto_rawDescGZIP(), []int{1}
+	return file_kakigori_ws_v1_aggregator_proto_rawDescGZIP(), []int{4}
 }
 
 func (x *AggregateResponse) GetRoom() string {
@@ -133,20 +594,1358 @@ func (x *AggregateResponse) GetCount() int32 {
 	return 0
 }
 
-var File_kakigori_ws_v1_aggregator_proto protoreflect.FileDescriptor
+func (x *AggregateResponse) GetVerdict() SampleVerdict {
+	if x != nil {
+		return x.Verdict
+	}
+	return SampleVerdict_SAMPLE_VERDICT_UNSPECIFIED
+}
 
-const file_kakigori_ws_v1_aggregator_proto_rawDesc = "" +
-	"\n" +
-	"\x1fkakigori_ws/v1/aggregator.proto\x12\x0ekakigori_ws.v1\"<\n" +
-	"\x10AggregateRequest\x12\x12\n" +
-	"\x04room\x18\x01 \x01(\tR\x04room\x12\x14\n" +
-	"\x05value\x18\x02 \x01(\x01R\x05value\"W\n" +
-	"\x11AggregateResponse\x12\x12\n" +
-	"\x04room\x18\x01 \x01(\tR\x04room\x12\x18\n" +
-	"\aaverage\x18\x02 \x01(\x01R\aaverage\x12\x14\n" +
-	"\x05count\x18\x03 \x01(\x05R\x05count2s\n" +
-	"\x1bKakigoriWsAggregatorService\x12T\n" +
-	"\tAggregate\x12 .kakigori_ws.v1.AggregateRequest\x1a!.kakigori_ws.v1.AggregateResponse(\x010\x01B5Z3chantingkakigori/gen/go/kakigori_ws/v1;kakigoriwsv1b\x06proto3"
+func (x *AggregateResponse) GetFlaggedClients() []string {
+	if x != nil {
+		return x.FlaggedClients
+	}
+	return nil
+}
+
+func (x *AggregateResponse) GetParticipants() []*Participant {
+	if x != nil {
+		return x.Participants
+	}
+	return nil
+}
+
+// RoomInfo is an operator's view of one aggregator room.
+type RoomInfo struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Room  string                 `protobuf:"bytes,1,opt,name=room,proto3" json:"room,omitempty"`
+	// Every participant, ordered by client_id.
+	Participants []*Participant `protobuf:"bytes,2,rep,name=participants,proto3" json:"participants,omitempty"`
+	// Non-zero samples in the current 5-second window, and their mean.
+	Count   int32   `protobuf:"varint,3,opt,name=count,proto3" json:"count,omitempty"`
+	Average float64 `protobuf:"fixed64,4,opt,name=average,proto3" json:"average,omitempty"`
+	// Open Aggregate streams for the room on this instance.
+	Streams        int32    `protobuf:"varint,5,opt,name=streams,proto3" json:"streams,omitempty"`
+	FlaggedClients []string `protobuf:"bytes,6,rep,name=flagged_clients,json=flaggedClients,proto3" json:"flagged_clients,omitempty"`
+	// When the room was created and when it last accepted a sample, Unix
+	// milliseconds (last_sample is 0 if none yet).
+	CreatedAtUnixMs  int64 `protobuf:"varint,7,opt,name=created_at_unix_ms,json=createdAtUnixMs,proto3" json:"created_at_unix_ms,omitempty"`
+	LastSampleUnixMs int64 `protobuf:"varint,8,opt,name=last_sample_unix_ms,json=lastSampleUnixMs,proto3" json:"last_sample_unix_ms,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *RoomInfo) Reset() {
+	*x = RoomInfo{}
+	mi := &file_kakigori_ws_v1_aggregator_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RoomInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RoomInfo) ProtoMessage() {}
+
+func (x *RoomInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_kakigori_ws_v1_aggregator_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RoomInfo.ProtoReflect.Descriptor instead.
+func (*RoomInfo) Descriptor() ([]byte, []int) {
+	return file_kakigori_ws_v1_aggregator_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *RoomInfo) GetRoom() string {
+	if x != nil {
+		return x.Room
+	}
+	return ""
+}
+
+func (x *RoomInfo) GetParticipants() []*Participant {
+	if x != nil {
+		return x.Participants
+	}
+	return nil
+}
+
+func (x *RoomInfo) GetCount() int32 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+func (x *RoomInfo) GetAverage() float64 {
+	if x != nil {
+		return x.Average
+	}
+	return 0
+}
+
+func (x *RoomInfo) GetStreams() int32 {
+	if x != nil {
+		return x.Streams
+	}
+	return 0
+}
+
+func (x *RoomInfo) GetFlaggedClients() []string {
+	if x != nil {
+		return x.FlaggedClients
+	}
+	return nil
+}
+
+func (x *RoomInfo) GetCreatedAtUnixMs() int64 {
+	if x != nil {
+		return x.CreatedAtUnixMs
+	}
+	return 0
+}
+
+func (x *RoomInfo) GetLastSampleUnixMs() int64 {
+	if x != nil {
+		return x.LastSampleUnixMs
+	}
+	return 0
+}
+
+type ListRoomsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListRoomsRequest) Reset() {
+	*x = ListRoomsRequest{}
+	mi := &file_kakigori_ws_v1_aggregator_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListRoomsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListRoomsRequest) ProtoMessage() {}
+
+func (x *ListRoomsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_kakigori_ws_v1_aggregator_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListRoomsRequest.ProtoReflect.Descriptor instead.
+func (*ListRoomsRequest) Descriptor() ([]byte, []int) {
+	return file_kakigori_ws_v1_aggregator_proto_rawDescGZIP(), []int{6}
+}
+
+type ListRoomsResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Ordered by room.
+	Rooms         []*RoomInfo `protobuf:"bytes,1,rep,name=rooms,proto3" json:"rooms,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListRoomsResponse) Reset() {
+	*x = ListRoomsResponse{}
+	mi := &file_kakigori_ws_v1_aggregator_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListRoomsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListRoomsResponse) ProtoMessage() {}
+
+func (x *ListRoomsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_kakigori_ws_v1_aggregator_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListRoomsResponse.ProtoReflect.Descriptor instead.
+func (*ListRoomsResponse) Descriptor() ([]byte, []int) {
+	return file_kakigori_ws_v1_aggregator_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ListRoomsResponse) GetRooms() []*RoomInfo {
+	if x != nil {
+		return x.Rooms
+	}
+	return nil
+}
+
+type GetRoomRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Room          string                 `protobuf:"bytes,1,opt,name=room,proto3" json:"room,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetRoomRequest) Reset() {
+	*x = GetRoomRequest{}
+	mi := &file_kakigori_ws_v1_aggregator_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetRoomRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetRoomRequest) ProtoMessage() {}
+
+func (x *GetRoomRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_kakigori_ws_v1_aggregator_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetRoomRequest.ProtoReflect.Descriptor instead.
+func (*GetRoomRequest) Descriptor() ([]byte, []int) {
+	return file_kakigori_ws_v1_aggregator_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *GetRoomRequest) GetRoom() string {
+	if x != nil {
+		return x.Room
+	}
+	return ""
+}
+
+type GetRoomResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Room          *RoomInfo              `protobuf:"bytes,1,opt,name=room,proto3" json:"room,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetRoomResponse) Reset() {
+	*x = GetRoomResponse{}
+	mi := &file_kakigori_ws_v1_aggregator_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetRoomResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetRoomResponse) ProtoMessage() {}
+
+func (x *GetRoomResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_kakigori_ws_v1_aggregator_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetRoomResponse.ProtoReflect.Descriptor instead.
+func (*GetRoomResponse) Descriptor() ([]byte, []int) {
+	return file_kakigori_ws_v1_aggregator_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *GetRoomResponse) GetRoom() *RoomInfo {
+	if x != nil {
+		return x.Room
+	}
+	return nil
+}
+
+type CloseRoomRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Room  string                 `protobuf:"bytes,1,opt,name=room,proto3" json:"room,omitempty"`
+	// Logged with the closure; free text.
+	Reason        string `protobuf:"bytes,2,opt,name=reason,proto3" json:"reason,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CloseRoomRequest) Reset() {
+	*x = CloseRoomRequest{}
+	mi := &file_kakigori_ws_v1_aggregator_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CloseRoomRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CloseRoomRequest) ProtoMessage() {}
+
+func (x *CloseRoomRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_kakigori_ws_v1_aggregator_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CloseRoomRequest.ProtoReflect.Descriptor instead.
+func (*CloseRoomRequest) Descriptor() ([]byte, []int) {
+	return file_kakigori_ws_v1_aggregator_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *CloseRoomRequest) GetRoom() string {
+	if x != nil {
+		return x.Room
+	}
+	return ""
+}
+
+func (x *CloseRoomRequest) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+type CloseRoomResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Aggregate streams ended by the closure.
+	ClosedStreams int32 `protobuf:"varint,1,opt,name=closed_streams,json=closedStreams,proto3" json:"closed_streams,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CloseRoomResponse) Reset() {
+	*x = CloseRoomResponse{}
+	mi := &file_kakigori_ws_v1_aggregator_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CloseRoomResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CloseRoomResponse) ProtoMessage() {}
+
+func (x *CloseRoomResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_kakigori_ws_v1_aggregator_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CloseRoomResponse.ProtoReflect.Descriptor instead.
+func (*CloseRoomResponse) Descriptor() ([]byte, []int) {
+	return file_kakigori_ws_v1_aggregator_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *CloseRoomResponse) GetClosedStreams() int32 {
+	if x != nil {
+		return x.ClosedStreams
+	}
+	return 0
+}
+
+// Sample is one value in a room window.
+type Sample struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	AtUnixMs      int64                  `protobuf:"varint,1,opt,name=at_unix_ms,json=atUnixMs,proto3" json:"at_unix_ms,omitempty"`
+	Value         float64                `protobuf:"fixed64,2,opt,name=value,proto3" json:"value,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Sample) Reset() {
+	*x = Sample{}
+	mi := &file_kakigori_ws_v1_aggregator_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Sample) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Sample) ProtoMessage() {}
+
+func (x *Sample) ProtoReflect() protoreflect.Message {
+	mi := &file_kakigori_ws_v1_aggregator_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Sample.ProtoReflect.Descriptor instead.
+func (*Sample) Descriptor() ([]byte, []int) {
+	return file_kakigori_ws_v1_aggregator_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *Sample) GetAtUnixMs() int64 {
+	if x != nil {
+		return x.AtUnixMs
+	}
+	return 0
+}
+
+func (x *Sample) GetValue() float64 {
+	if x != nil {
+		return x.Value
+	}
+	return 0
+}
+
+// ParticipantState is one participant's part of a RoomSnapshot.
+type ParticipantState struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	ClientId       string                 `protobuf:"bytes,1,opt,name=client_id,json=clientId,proto3" json:"client_id,omitempty"`
+	DisplayName    string                 `protobuf:"bytes,2,opt,name=display_name,json=displayName,proto3" json:"display_name,omitempty"`
+	LastSeenUnixMs int64                  `protobuf:"varint,3,opt,name=last_seen_unix_ms,json=lastSeenUnixMs,proto3" json:"last_seen_unix_ms,omitempty"`
+	// Clamped or rejected samples so far, and whether that flagged it.
+	Strikes       int32     `protobuf:"varint,4,opt,name=strikes,proto3" json:"strikes,omitempty"`
+	Flagged       bool      `protobuf:"varint,5,opt,name=flagged,proto3" json:"flagged,omitempty"`
+	Samples       []*Sample `protobuf:"bytes,6,rep,name=samples,proto3" json:"samples,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ParticipantState) Reset() {
+	*x = ParticipantState{}
+	mi := &file_kakigori_ws_v1_aggregator_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ParticipantState) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ParticipantState) ProtoMessage() {}
+
+func (x *ParticipantState) ProtoReflect() protoreflect.Message {
+	mi := &file_kakigori_ws_v1_aggregator_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ParticipantState.ProtoReflect.Descriptor instead.
+func (*ParticipantState) Descriptor() ([]byte, []int) {
+	return file_kakigori_ws_v1_aggregator_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *ParticipantState) GetClientId() string {
+	if x != nil {
+		return x.ClientId
+	}
+	return ""
+}
+
+func (x *ParticipantState) GetDisplayName() string {
+	if x != nil {
+		return x.DisplayName
+	}
+	return ""
+}
+
+func (x *ParticipantState) GetLastSeenUnixMs() int64 {
+	if x != nil {
+		return x.LastSeenUnixMs
+	}
+	return 0
+}
+
+func (x *ParticipantState) GetStrikes() int32 {
+	if x != nil {
+		return x.Strikes
+	}
+	return 0
+}
+
+func (x *ParticipantState) GetFlagged() bool {
+	if x != nil {
+		return x.Flagged
+	}
+	return false
+}
+
+func (x *ParticipantState) GetSamples() []*Sample {
+	if x != nil {
+		return x.Samples
+	}
+	return nil
+}
+
+// RoomSnapshot is the portable state of a room, moved between replicas when
+// the room's owner changes.
+type RoomSnapshot struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	Room             string                 `protobuf:"bytes,1,opt,name=room,proto3" json:"room,omitempty"`
+	CreatedAtUnixMs  int64                  `protobuf:"varint,2,opt,name=created_at_unix_ms,json=createdAtUnixMs,proto3" json:"created_at_unix_ms,omitempty"`
+	LastSampleUnixMs int64                  `protobuf:"varint,3,opt,name=last_sample_unix_ms,json=lastSampleUnixMs,proto3" json:"last_sample_unix_ms,omitempty"`
+	Participants     []*ParticipantState    `protobuf:"bytes,4,rep,name=participants,proto3" json:"participants,omitempty"`
+	// The room's round so far, carried so its stats survive a handoff.
+	Round         *RoundState `protobuf:"bytes,5,opt,name=round,proto3" json:"round,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RoomSnapshot) Reset() {
+	*x = RoomSnapshot{}
+	mi := &file_kakigori_ws_v1_aggregator_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RoomSnapshot) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RoomSnapshot) ProtoMessage() {}
+
+func (x *RoomSnapshot) ProtoReflect() protoreflect.Message {
+	mi := &file_kakigori_ws_v1_aggregator_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RoomSnapshot.ProtoReflect.Descriptor instead.
+func (*RoomSnapshot) Descriptor() ([]byte, []int) {
+	return file_kakigori_ws_v1_aggregator_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *RoomSnapshot) GetRoom() string {
+	if x != nil {
+		return x.Room
+	}
+	return ""
+}
+
+func (x *RoomSnapshot) GetCreatedAtUnixMs() int64 {
+	if x != nil {
+		return x.CreatedAtUnixMs
+	}
+	return 0
+}
+
+func (x *RoomSnapshot) GetLastSampleUnixMs() int64 {
+	if x != nil {
+		return x.LastSampleUnixMs
+	}
+	return 0
+}
+
+func (x *RoomSnapshot) GetParticipants() []*ParticipantState {
+	if x != nil {
+		return x.Participants
+	}
+	return nil
+}
+
+func (x *RoomSnapshot) GetRound() *RoundState {
+	if x != nil {
+		return x.Round
+	}
+	return nil
+}
+
+type RoundState struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	StartedAtUnixMs  int64                  `protobuf:"varint,1,opt,name=started_at_unix_ms,json=startedAtUnixMs,proto3" json:"started_at_unix_ms,omitempty"`
+	Peak             float64                `protobuf:"fixed64,2,opt,name=peak,proto3" json:"peak,omitempty"`
+	AboveThresholdMs int64                  `protobuf:"varint,3,opt,name=above_threshold_ms,json=aboveThresholdMs,proto3" json:"above_threshold_ms,omitempty"`
+	// Clients that sent a sample during the round.
+	Clients       []string `protobuf:"bytes,4,rep,name=clients,proto3" json:"clients,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RoundState) Reset() {
+	*x = RoundState{}
+	mi := &file_kakigori_ws_v1_aggregator_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RoundState) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RoundState) ProtoMessage() {}
+
+func (x *RoundState) ProtoReflect() protoreflect.Message {
+	mi := &file_kakigori_ws_v1_aggregator_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RoundState.ProtoReflect.Descriptor instead.
+func (*RoundState) Descriptor() ([]byte, []int) {
+	return file_kakigori_ws_v1_aggregator_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *RoundState) GetStartedAtUnixMs() int64 {
+	if x != nil {
+		return x.StartedAtUnixMs
+	}
+	return 0
+}
+
+func (x *RoundState) GetPeak() float64 {
+	if x != nil {
+		return x.Peak
+	}
+	return 0
+}
+
+func (x *RoundState) GetAboveThresholdMs() int64 {
+	if x != nil {
+		return x.AboveThresholdMs
+	}
+	return 0
+}
+
+func (x *RoundState) GetClients() []string {
+	if x != nil {
+		return x.Clients
+	}
+	return nil
+}
+
+type ExportRoomRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Room          string                 `protobuf:"bytes,1,opt,name=room,proto3" json:"room,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExportRoomRequest) Reset() {
+	*x = ExportRoomRequest{}
+	mi := &file_kakigori_ws_v1_aggregator_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExportRoomRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExportRoomRequest) ProtoMessage() {}
+
+func (x *ExportRoomRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_kakigori_ws_v1_aggregator_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExportRoomRequest.ProtoReflect.Descriptor instead.
+func (*ExportRoomRequest) Descriptor() ([]byte, []int) {
+	return file_kakigori_ws_v1_aggregator_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *ExportRoomRequest) GetRoom() string {
+	if x != nil {
+		return x.Room
+	}
+	return ""
+}
+
+type ExportRoomResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Snapshot      *RoomSnapshot          `protobuf:"bytes,1,opt,name=snapshot,proto3" json:"snapshot,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExportRoomResponse) Reset() {
+	*x = ExportRoomResponse{}
+	mi := &file_kakigori_ws_v1_aggregator_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExportRoomResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExportRoomResponse) ProtoMessage() {}
+
+func (x *ExportRoomResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_kakigori_ws_v1_aggregator_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExportRoomResponse.ProtoReflect.Descriptor instead.
+func (*ExportRoomResponse) Descriptor() ([]byte, []int) {
+	return file_kakigori_ws_v1_aggregator_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *ExportRoomResponse) GetSnapshot() *RoomSnapshot {
+	if x != nil {
+		return x.Snapshot
+	}
+	return nil
+}
+
+type ImportRoomRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Snapshot      *RoomSnapshot          `protobuf:"bytes,1,opt,name=snapshot,proto3" json:"snapshot,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ImportRoomRequest) Reset() {
+	*x = ImportRoomRequest{}
+	mi := &file_kakigori_ws_v1_aggregator_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ImportRoomRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ImportRoomRequest) ProtoMessage() {}
+
+func (x *ImportRoomRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_kakigori_ws_v1_aggregator_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ImportRoomRequest.ProtoReflect.Descriptor instead.
+func (*ImportRoomRequest) Descriptor() ([]byte, []int) {
+	return file_kakigori_ws_v1_aggregator_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *ImportRoomRequest) GetSnapshot() *RoomSnapshot {
+	if x != nil {
+		return x.Snapshot
+	}
+	return nil
+}
+
+type ImportRoomResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ImportRoomResponse) Reset() {
+	*x = ImportRoomResponse{}
+	mi := &file_kakigori_ws_v1_aggregator_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ImportRoomResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ImportRoomResponse) ProtoMessage() {}
+
+func (x *ImportRoomResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_kakigori_ws_v1_aggregator_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ImportRoomResponse.ProtoReflect.Descriptor instead.
+func (*ImportRoomResponse) Descriptor() ([]byte, []int) {
+	return file_kakigori_ws_v1_aggregator_proto_rawDescGZIP(), []int{19}
+}
+
+type LeaderboardRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Menu item (room) to rank; empty ranks every item together.
+	MenuItemId string `protobuf:"bytes,1,opt,name=menu_item_id,json=menuItemId,proto3" json:"menu_item_id,omitempty"`
+	// Day (YYYY-MM-DD, Japan time) the rounds ended on; empty for all time.
+	Day string `protobuf:"bytes,2,opt,name=day,proto3" json:"day,omitempty"`
+	// Rounds to return, 1..100; 0 means 10.
+	Limit         int32 `protobuf:"varint,3,opt,name=limit,proto3" json:"limit,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LeaderboardRequest) Reset() {
+	*x = LeaderboardRequest{}
+	mi := &file_kakigori_ws_v1_aggregator_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LeaderboardRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LeaderboardRequest) ProtoMessage() {}
+
+func (x *LeaderboardRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_kakigori_ws_v1_aggregator_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LeaderboardRequest.ProtoReflect.Descriptor instead.
+func (*LeaderboardRequest) Descriptor() ([]byte, []int) {
+	return file_kakigori_ws_v1_aggregator_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *LeaderboardRequest) GetMenuItemId() string {
+	if x != nil {
+		return x.MenuItemId
+	}
+	return ""
+}
+
+func (x *LeaderboardRequest) GetDay() string {
+	if x != nil {
+		return x.Day
+	}
+	return ""
+}
+
+func (x *LeaderboardRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+// RoundStats is one finished round: a room from its first sample until its
+// last participant left.
+type RoundStats struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// The menu item ID.
+	Room            string `protobuf:"bytes,1,opt,name=room,proto3" json:"room,omitempty"`
+	StartedAtUnixMs int64  `protobuf:"varint,2,opt,name=started_at_unix_ms,json=startedAtUnixMs,proto3" json:"started_at_unix_ms,omitempty"`
+	EndedAtUnixMs   int64  `protobuf:"varint,3,opt,name=ended_at_unix_ms,json=endedAtUnixMs,proto3" json:"ended_at_unix_ms,omitempty"`
+	// Highest room average during the round.
+	Peak float64 `protobuf:"fixed64,4,opt,name=peak,proto3" json:"peak,omitempty"`
+	// Time the room average stayed at or above the threshold.
+	AboveThresholdMs int64 `protobuf:"varint,5,opt,name=above_threshold_ms,json=aboveThresholdMs,proto3" json:"above_threshold_ms,omitempty"`
+	Participants     int32 `protobuf:"varint,6,opt,name=participants,proto3" json:"participants,omitempty"`
+	// gateway-api round the chant was for; empty if the clients passed none.
+	RoundId       string `protobuf:"bytes,7,opt,name=round_id,json=roundId,proto3" json:"round_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RoundStats) Reset() {
+	*x = RoundStats{}
+	mi := &file_kakigori_ws_v1_aggregator_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RoundStats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RoundStats) ProtoMessage() {}
+
+func (x *RoundStats) ProtoReflect() protoreflect.Message {
+	mi := &file_kakigori_ws_v1_aggregator_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RoundStats.ProtoReflect.Descriptor instead.
+func (*RoundStats) Descriptor() ([]byte, []int) {
+	return file_kakigori_ws_v1_aggregator_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *RoundStats) GetRoom() string {
+	if x != nil {
+		return x.Room
+	}
+	return ""
+}
+
+func (x *RoundStats) GetStartedAtUnixMs() int64 {
+	if x != nil {
+		return x.StartedAtUnixMs
+	}
+	return 0
+}
+
+func (x *RoundStats) GetEndedAtUnixMs() int64 {
+	if x != nil {
+		return x.EndedAtUnixMs
+	}
+	return 0
+}
+
+func (x *RoundStats) GetPeak() float64 {
+	if x != nil {
+		return x.Peak
+	}
+	return 0
+}
+
+func (x *RoundStats) GetAboveThresholdMs() int64 {
+	if x != nil {
+		return x.AboveThresholdMs
+	}
+	return 0
+}
+
+func (x *RoundStats) GetParticipants() int32 {
+	if x != nil {
+		return x.Participants
+	}
+	return 0
+}
+
+func (x *RoundStats) GetRoundId() string {
+	if x != nil {
+		return x.RoundId
+	}
+	return ""
+}
+
+type LeaderboardResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Best first: by peak (to three decimals), then time above threshold.
+	Rounds        []*RoundStats `protobuf:"bytes,1,rep,name=rounds,proto3" json:"rounds,omitempty"`
+	Threshold     float64       `protobuf:"fixed64,2,opt,name=threshold,proto3" json:"threshold,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LeaderboardResponse) Reset() {
+	*x = LeaderboardResponse{}
+	mi := &file_kakigori_ws_v1_aggregator_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LeaderboardResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LeaderboardResponse) ProtoMessage() {}
+
+func (x *LeaderboardResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_kakigori_ws_v1_aggregator_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LeaderboardResponse.ProtoReflect.Descriptor instead.
+func (*LeaderboardResponse) Descriptor() ([]byte, []int) {
+	return file_kakigori_ws_v1_aggregator_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *LeaderboardResponse) GetRounds() []*RoundStats {
+	if x != nil {
+		return x.Rounds
+	}
+	return nil
+}
+
+func (x *LeaderboardResponse) GetThreshold() float64 {
+	if x != nil {
+		return x.Threshold
+	}
+	return 0
+}
+
+type WatchRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Rooms to watch; empty watches every room on the instance, including
+	// rooms created later.
+	Rooms         []string `protobuf:"bytes,1,rep,name=rooms,proto3" json:"rooms,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchRequest) Reset() {
+	*x = WatchRequest{}
+	mi := &file_kakigori_ws_v1_aggregator_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchRequest) ProtoMessage() {}
+
+func (x *WatchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_kakigori_ws_v1_aggregator_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchRequest.ProtoReflect.Descriptor instead.
+func (*WatchRequest) Descriptor() ([]byte, []int) {
+	return file_kakigori_ws_v1_aggregator_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *WatchRequest) GetRooms() []string {
+	if x != nil {
+		return x.Rooms
+	}
+	return nil
+}
+
+// WatchEvent is the state of one room. A watcher that falls behind gets
+// only the latest event of each room.
+type WatchEvent struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Room  string                 `protobuf:"bytes,1,opt,name=room,proto3" json:"room,omitempty"`
+	Type  WatchEventType         `protobuf:"varint,2,opt,name=type,proto3,enum=kakigori_ws.v1.WatchEventType" json:"type,omitempty"`
+	// Average and non-zero samples in the 5-second window.
+	Average float64 `protobuf:"fixed64,3,opt,name=average,proto3" json:"average,omitempty"`
+	Count   int32   `protobuf:"varint,4,opt,name=count,proto3" json:"count,omitempty"`
+	// Participants connected to the room.
+	Participants  int32  `protobuf:"varint,5,opt,name=participants,proto3" json:"participants,omitempty"`
+	Reason        string `protobuf:"bytes,6,opt,name=reason,proto3" json:"reason,omitempty"`
+	AtUnixMs      int64  `protobuf:"varint,7,opt,name=at_unix_ms,json=atUnixMs,proto3" json:"at_unix_ms,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchEvent) Reset() {
+	*x = WatchEvent{}
+	mi := &file_kakigori_ws_v1_aggregator_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchEvent) ProtoMessage() {}
+
+func (x *WatchEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_kakigori_ws_v1_aggregator_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchEvent.ProtoReflect.Descriptor instead.
+func (*WatchEvent) Descriptor() ([]byte, []int) {
+	return file_kakigori_ws_v1_aggregator_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *WatchEvent) GetRoom() string {
+	if x != nil {
+		return x.Room
+	}
+	return ""
+}
+
+func (x *WatchEvent) GetType() WatchEventType {
+	if x != nil {
+		return x.Type
+	}
+	return WatchEventType_WATCH_EVENT_TYPE_UNSPECIFIED
+}
+
+func (x *WatchEvent) GetAverage() float64 {
+	if x != nil {
+		return x.Average
+	}
+	return 0
+}
+
+func (x *WatchEvent) GetCount() int32 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+func (x *WatchEvent) GetParticipants() int32 {
+	if x != nil {
+		return x.Participants
+	}
+	return 0
+}
+
+func (x *WatchEvent) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *WatchEvent) GetAtUnixMs() int64 {
+	if x != nil {
+		return x.AtUnixMs
+	}
+	return 0
+}
+
+var File_kakigori_ws_v1_aggregator_proto protoreflect.FileDescriptor
+
+const file_kakigori_ws_v1_aggregator_proto_rawDesc = "" +
+	"\n" +
+	"\x1fkakigori_ws/v1/aggregator.proto\x12\x0ekakigori_ws.v1\"\xe8\x02\n" +
+	"\x10AggregateRequest\x12\x12\n" +
+	"\x04room\x18\x01 \x01(\tR\x04room\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x01R\x05value\x12\x1b\n" +
+	"\tclient_id\x18\x03 \x01(\tR\bclientId\x12!\n" +
+	"\fdisplay_name\x18\x04 \x01(\tR\vdisplayName\x12+\n" +
+	"\x11include_breakdown\x18\x05 \x01(\bR\x10includeBreakdown\x126\n" +
+	"\asamples\x18\x06 \x03(\v2\x1c.kakigori_ws.v1.ClientSampleR\asamples\x120\n" +
+	"\x05audio\x18\a \x01(\v2\x1a.kakigori_ws.v1.AudioFrameR\x05audio\x12\x16\n" +
+	"\x06device\x18\b \x01(\tR\x06device\x12\x19\n" +
+	"\bround_id\x18\t \x01(\tR\aroundId\x12 \n" +
+	"\fmenu_item_id\x18\n" +
+	" \x01(\tR\n" +
+	"menuItemId\"\x9e\x01\n" +
+	"\n" +
+	"AudioFrame\x120\n" +
+	"\x05codec\x18\x01 \x01(\x0e2\x1a.kakigori_ws.v1.AudioCodecR\x05codec\x12\x1f\n" +
+	"\vsample_rate\x18\x02 \x01(\x05R\n" +
+	"sampleRate\x12\x12\n" +
+	"\x04data\x18\x03 \x01(\fR\x04data\x12)\n" +
+	"\x11client_ts_unix_ms\x18\x04 \x01(\x03R\x0eclientTsUnixMs\"O\n" +
+	"\fClientSample\x12\x14\n" +
+	"\x05value\x18\x01 \x01(\x01R\x05value\x12)\n" +
+	"\x11client_ts_unix_ms\x18\x02 \x01(\x03R\x0eclientTsUnixMs\"\xa2\x01\n" +
+	"\vParticipant\x12\x1b\n" +
+	"\tclient_id\x18\x01 \x01(\tR\bclientId\x12!\n" +
+	"\fdisplay_name\x18\x02 \x01(\tR\vdisplayName\x12\x12\n" +
+	"\x04mean\x18\x03 \x01(\x01R\x04mean\x12\x14\n" +
+	"\x05count\x18\x04 \x01(\x05R\x05count\x12)\n" +
+	"\x11last_seen_unix_ms\x18\x05 \x01(\x03R\x0elastSeenUnixMs\"\xfa\x01\n" +
+	"\x11AggregateResponse\x12\x12\n" +
+	"\x04room\x18\x01 \x01(\tR\x04room\x12\x18\n" +
+	"\aaverage\x18\x02 \x01(\x01R\aaverage\x12\x14\n" +
+	"\x05count\x18\x03 \x01(\x05R\x05count\x127\n" +
+	"\averdict\x18\x04 \x01(\x0e2\x1d.kakigori_ws.v1.SampleVerdictR\averdict\x12'\n" +
+	"\x0fflagged_clients\x18\x05 \x03(\tR\x0eflaggedClients\x12?\n" +
+	"\fparticipants\x18\x06 \x03(\v2\x1b.kakigori_ws.v1.ParticipantR\fparticipants\"\xae\x02\n" +
+	"\bRoomInfo\x12\x12\n" +
+	"\x04room\x18\x01 \x01(\tR\x04room\x12?\n" +
+	"\fparticipants\x18\x02 \x03(\v2\x1b.kakigori_ws.v1.ParticipantR\fparticipants\x12\x14\n" +
+	"\x05count\x18\x03 \x01(\x05R\x05count\x12\x18\n" +
+	"\aaverage\x18\x04 \x01(\x01R\aaverage\x12\x18\n" +
+	"\astreams\x18\x05 \x01(\x05R\astreams\x12'\n" +
+	"\x0fflagged_clients\x18\x06 \x03(\tR\x0eflaggedClients\x12+\n" +
+	"\x12created_at_unix_ms\x18\a \x01(\x03R\x0fcreatedAtUnixMs\x12-\n" +
+	"\x13last_sample_unix_ms\x18\b \x01(\x03R\x10lastSampleUnixMs\"\x12\n" +
+	"\x10ListRoomsRequest\"C\n" +
+	"\x11ListRoomsResponse\x12.\n" +
+	"\x05rooms\x18\x01 \x03(\v2\x18.kakigori_ws.v1.RoomInfoR\x05rooms\"$\n" +
+	"\x0eGetRoomRequest\x12\x12\n" +
+	"\x04room\x18\x01 \x01(\tR\x04room\"?\n" +
+	"\x0fGetRoomResponse\x12,\n" +
+	"\x04room\x18\x01 \x01(\v2\x18.kakigori_ws.v1.RoomInfoR\x04room\">\n" +
+	"\x10CloseRoomRequest\x12\x12\n" +
+	"\x04room\x18\x01 \x01(\tR\x04room\x12\x16\n" +
+	"\x06reason\x18\x02 \x01(\tR\x06reason\":\n" +
+	"\x11CloseRoomResponse\x12%\n" +
+	"\x0eclosed_streams\x18\x01 \x01(\x05R\rclosedStreams\"<\n" +
+	"\x06Sample\x12\x1c\n" +
+	"\n" +
+	"at_unix_ms\x18\x01 \x01(\x03R\batUnixMs\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x01R\x05value\"\xe3\x01\n" +
+	"\x10ParticipantState\x12\x1b\n" +
+	"\tclient_id\x18\x01 \x01(\tR\bclientId\x12!\n" +
+	"\fdisplay_name\x18\x02 \x01(\tR\vdisplayName\x12)\n" +
+	"\x11last_seen_unix_ms\x18\x03 \x01(\x03R\x0elastSeenUnixMs\x12\x18\n" +
+	"\astrikes\x18\x04 \x01(\x05R\astrikes\x12\x18\n" +
+	"\aflagged\x18\x05 \x01(\bR\aflagged\x120\n" +
+	"\asamples\x18\x06 \x03(\v2\x16.kakigori_ws.v1.SampleR\asamples\"\xf6\x01\n" +
+	"\fRoomSnapshot\x12\x12\n" +
+	"\x04room\x18\x01 \x01(\tR\x04room\x12+\n" +
+	"\x12created_at_unix_ms\x18\x02 \x01(\x03R\x0fcreatedAtUnixMs\x12-\n" +
+	"\x13last_sample_unix_ms\x18\x03 \x01(\x03R\x10lastSampleUnixMs\x12D\n" +
+	"\fparticipants\x18\x04 \x03(\v2 .kakigori_ws.v1.ParticipantStateR\fparticipants\x120\n" +
+	"\x05round\x18\x05 \x01(\v2\x1a.kakigori_ws.v1.RoundStateR\x05round\"\x95\x01\n" +
+	"\n" +
+	"RoundState\x12+\n" +
+	"\x12started_at_unix_ms\x18\x01 \x01(\x03R\x0fstartedAtUnixMs\x12\x12\n" +
+	"\x04peak\x18\x02 \x01(\x01R\x04peak\x12,\n" +
+	"\x12above_threshold_ms\x18\x03 \x01(\x03R\x10aboveThresholdMs\x12\x18\n" +
+	"\aclients\x18\x04 \x03(\tR\aclients\"'\n" +
+	"\x11ExportRoomRequest\x12\x12\n" +
+	"\x04room\x18\x01 \x01(\tR\x04room\"N\n" +
+	"\x12ExportRoomResponse\x128\n" +
+	"\bsnapshot\x18\x01 \x01(\v2\x1c.kakigori_ws.v1.RoomSnapshotR\bsnapshot\"M\n" +
+	"\x11ImportRoomRequest\x128\n" +
+	"\bsnapshot\x18\x01 \x01(\v2\x1c.kakigori_ws.v1.RoomSnapshotR\bsnapshot\"\x14\n" +
+	"\x12ImportRoomResponse\"^\n" +
+	"\x12LeaderboardRequest\x12 \n" +
+	"\fmenu_item_id\x18\x01 \x01(\tR\n" +
+	"menuItemId\x12\x10\n" +
+	"\x03day\x18\x02 \x01(\tR\x03day\x12\x14\n" +
+	"\x05limit\x18\x03 \x01(\x05R\x05limit\"\xf7\x01\n" +
+	"\n" +
+	"RoundStats\x12\x12\n" +
+	"\x04room\x18\x01 \x01(\tR\x04room\x12+\n" +
+	"\x12started_at_unix_ms\x18\x02 \x01(\x03R\x0fstartedAtUnixMs\x12'\n" +
+	"\x10ended_at_unix_ms\x18\x03 \x01(\x03R\rendedAtUnixMs\x12\x12\n" +
+	"\x04peak\x18\x04 \x01(\x01R\x04peak\x12,\n" +
+	"\x12above_threshold_ms\x18\x05 \x01(\x03R\x10aboveThresholdMs\x12\"\n" +
+	"\fparticipants\x18\x06 \x01(\x05R\fparticipants\x12\x19\n" +
+	"\bround_id\x18\a \x01(\tR\aroundId\"g\n" +
+	"\x13LeaderboardResponse\x122\n" +
+	"\x06rounds\x18\x01 \x03(\v2\x1a.kakigori_ws.v1.RoundStatsR\x06rounds\x12\x1c\n" +
+	"\tthreshold\x18\x02 \x01(\x01R\tthreshold\"$\n" +
+	"\fWatchRequest\x12\x14\n" +
+	"\x05rooms\x18\x01 \x03(\tR\x05rooms\"\xde\x01\n" +
+	"\n" +
+	"WatchEvent\x12\x12\n" +
+	"\x04room\x18\x01 \x01(\tR\x04room\x122\n" +
+	"\x04type\x18\x02 \x01(\x0e2\x1e.kakigori_ws.v1.WatchEventTypeR\x04type\x12\x18\n" +
+	"\aaverage\x18\x03 \x01(\x01R\aaverage\x12\x14\n" +
+	"\x05count\x18\x04 \x01(\x05R\x05count\x12\"\n" +
+	"\fparticipants\x18\x05 \x01(\x05R\fparticipants\x12\x16\n" +
+	"\x06reason\x18\x06 \x01(\tR\x06reason\x12\x1c\n" +
+	"\n" +
+	"at_unix_ms\x18\a \x01(\x03R\batUnixMs*Z\n" +
+	"\n" +
+	"AudioCodec\x12\x1b\n" +
+	"\x17AUDIO_CODEC_UNSPECIFIED\x10\x00\x12\x19\n" +
+	"\x15AUDIO_CODEC_PCM_S16LE\x10\x01\x12\x14\n" +
+	"\x10AUDIO_CODEC_OPUS\x10\x02*\x85\x01\n" +
+	"\rSampleVerdict\x12\x1e\n" +
+	"\x1aSAMPLE_VERDICT_UNSPECIFIED\x10\x00\x12\x1b\n" +
+	"\x17SAMPLE_VERDICT_ACCEPTED\x10\x01\x12\x1a\n" +
+	"\x16SAMPLE_VERDICT_CLAMPED\x10\x02\x12\x1b\n" +
+	"\x17SAMPLE_VERDICT_REJECTED\x10\x03*\x87\x01\n" +
+	"\x0eWatchEventType\x12 \n" +
+	"\x1cWATCH_EVENT_TYPE_UNSPECIFIED\x10\x00\x12\x1b\n" +
+	"\x17WATCH_EVENT_TYPE_UPDATE\x10\x01\x12\x1a\n" +
+	"\x16WATCH_EVENT_TYPE_ENDED\x10\x02\x12\x1a\n" +
+	"\x16WATCH_EVENT_TYPE_MOVED\x10\x032\xaa\x05\n" +
+	"\x1bKakigoriWsAggregatorService\x12T\n" +
+	"\tAggregate\x12 .kakigori_ws.v1.AggregateRequest\x1a!.kakigori_ws.v1.AggregateResponse(\x010\x01\x12P\n" +
+	"\tListRooms\x12 .kakigori_ws.v1.ListRoomsRequest\x1a!.kakigori_ws.v1.ListRoomsResponse\x12J\n" +
+	"\aGetRoom\x12\x1e.kakigori_ws.v1.GetRoomRequest\x1a\x1f.kakigori_ws.v1.GetRoomResponse\x12P\n" +
+	"\tCloseRoom\x12 .kakigori_ws.v1.CloseRoomRequest\x1a!.kakigori_ws.v1.CloseRoomResponse\x12S\n" +
+	"\n" +
+	"ExportRoom\x12!.kakigori_ws.v1.ExportRoomRequest\x1a\".kakigori_ws.v1.ExportRoomResponse\x12S\n" +
+	"\n" +
+	"ImportRoom\x12!.kakigori_ws.v1.ImportRoomRequest\x1a\".kakigori_ws.v1.ImportRoomResponse\x12V\n" +
+	"\vLeaderboard\x12\".kakigori_ws.v1.LeaderboardRequest\x1a#.kakigori_ws.v1.LeaderboardResponse\x12C\n" +
+	"\x05Watch\x12\x1c.kakigori_ws.v1.WatchRequest\x1a\x1a.kakigori_ws.v1.WatchEvent0\x01B5Z3chantingkakigori/gen/go/kakigori_ws/v1;kakigoriwsv1b\x06proto3"
 
 var (
 	file_kakigori_ws_v1_aggregator_proto_rawDescOnce sync.Once
@@ -160,19 +1959,75 @@ func file_kakigori_ws_v1_aggregator_proto_rawDescGZIP() []byte {
 	return file_kakigori_ws_v1_aggregator_proto_rawDescData
 }
 
-var file_kakigori_ws_v1_aggregator_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_kakigori_ws_v1_aggregator_proto_enumTypes = make([]protoimpl.EnumInfo, 3)
+var file_kakigori_ws_v1_aggregator_proto_msgTypes = make([]protoimpl.MessageInfo, 25)
 var file_kakigori_ws_v1_aggregator_proto_goTypes = []any{
-	(*AggregateRequest)(nil),  // 0: kakigori_ws.v1.AggregateRequest
-	(*AggregateResponse)(nil), // 1: kakigori_ws.v1.AggregateResponse
+	(AudioCodec)(0),             // 0: kakigori_ws.v1.AudioCodec
+	(SampleVerdict)(0),          // 1: kakigori_ws.v1.SampleVerdict
+	(WatchEventType)(0),         // 2: kakigori_ws.v1.WatchEventType
+	(*AggregateRequest)(nil),    // 3: kakigori_ws.v1.AggregateRequest
+	(*AudioFrame)(nil),          // 4: kakigori_ws.v1.AudioFrame
+	(*ClientSample)(nil),        // 5: kakigori_ws.v1.ClientSample
+	(*Participant)(nil),         // 6: kakigori_ws.v1.Participant
+	(*AggregateResponse)(nil),   // 7: kakigori_ws.v1.AggregateResponse
+	(*RoomInfo)(nil),            // 8: kakigori_ws.v1.RoomInfo
+	(*ListRoomsRequest)(nil),    // 9: kakigori_ws.v1.ListRoomsRequest
+	(*ListRoomsResponse)(nil),   // 10: kakigori_ws.v1.ListRoomsResponse
+	(*GetRoomRequest)(nil),      // 11: kakigori_ws.v1.GetRoomRequest
+	(*GetRoomResponse)(nil),     // 12: kakigori_ws.v1.GetRoomResponse
+	(*CloseRoomRequest)(nil),    // 13: kakigori_ws.v1.CloseRoomRequest
+	(*CloseRoomResponse)(nil),   // 14: kakigori_ws.v1.CloseRoomResponse
+	(*Sample)(nil),              // 15: kakigori_ws.v1.Sample
+	(*ParticipantState)(nil),    // 16: kakigori_ws.v1.ParticipantState
+	(*RoomSnapshot)(nil),        // 17: kakigori_ws.v1.RoomSnapshot
+	(*RoundState)(nil),          // 18: kakigori_ws.v1.RoundState
+	(*ExportRoomRequest)(nil),   // 19: kakigori_ws.v1.ExportRoomRequest
+	(*ExportRoomResponse)(nil),  // 20: kakigori_ws.v1.ExportRoomResponse
+	(*ImportRoomRequest)(nil),   // 21: kakigori_ws.v1.ImportRoomRequest
+	(*ImportRoomResponse)(nil),  // 22: kakigori_ws.v1.ImportRoomResponse
+	(*LeaderboardRequest)(nil),  // 23: kakigori_ws.v1.LeaderboardRequest
+	(*RoundStats)(nil),          // 24: kakigori_ws.v1.RoundStats
+	(*LeaderboardResponse)(nil), // 25: kakigori_ws.v1.LeaderboardResponse
+	(*WatchRequest)(nil),        // 26: kakigori_ws.v1.WatchRequest
+	(*WatchEvent)(nil),          // 27: kakigori_ws.v1.WatchEvent
 }
 var file_kakigori_ws_v1_aggregator_proto_depIdxs = []int32{
-	0, // 0: kakigori_ws.v1.KakigoriWsAggregatorService.Aggregate:input_type -> kakigori_ws.v1.AggregateRequest
-	1, // 1: kakigori_ws.v1.KakigoriWsAggregatorService.Aggregate:output_type -> kakigori_ws.v1.AggregateResponse
-	1, // [1:2] is the sub-list for method output_type
-	0, // [0:1] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	5,  // 0: kakigori_ws.v1.AggregateRequest.samples:type_name -> kakigori_ws.v1.ClientSample
+	4,  // 1: kakigori_ws.v1.AggregateRequest.audio:type_name -> kakigori_ws.v1.AudioFrame
+	0,  // 2: kakigori_ws.v1.AudioFrame.codec:type_name -> kakigori_ws.v1.AudioCodec
+	1,  // 3: kakigori_ws.v1.AggregateResponse.verdict:type_name -> kakigori_ws.v1.SampleVerdict
+	6,  // 4: kakigori_ws.v1.AggregateResponse.participants:type_name -> kakigori_ws.v1.Participant
+	6,  // 5: kakigori_ws.v1.RoomInfo.participants:type_name -> kakigori_ws.v1.Participant
+	8,  // 6: kakigori_ws.v1.ListRoomsResponse.rooms:type_name -> kakigori_ws.v1.RoomInfo
+	8,  // 7: kakigori_ws.v1.GetRoomResponse.room:type_name -> kakigori_ws.v1.RoomInfo
+	15, // 8: kakigori_ws.v1.ParticipantState.samples:type_name -> kakigori_ws.v1.Sample
+	16, // 9: kakigori_ws.v1.RoomSnapshot.participants:type_name -> kakigori_ws.v1.ParticipantState
+	18, // 10: kakigori_ws.v1.RoomSnapshot.round:type_name -> kakigori_ws.v1.RoundState
+	17, // 11: kakigori_ws.v1.ExportRoomResponse.snapshot:type_name -> kakigori_ws.v1.RoomSnapshot
+	17, // 12: kakigori_ws.v1.ImportRoomRequest.snapshot:type_name -> kakigori_ws.v1.RoomSnapshot
+	24, // 13: kakigori_ws.v1.LeaderboardResponse.rounds:type_name -> kakigori_ws.v1.RoundStats
+	2,  // 14: kakigori_ws.v1.WatchEvent.type:type_name -> kakigori_ws.v1.WatchEventType
+	3,  // 15: kakigori_ws.v1.KakigoriWsAggregatorService.Aggregate:input_type -> kakigori_ws.v1.AggregateRequest
+	9,  // 16: kakigori_ws.v1.KakigoriWsAggregatorService.ListRooms:input_type -> kakigori_ws.v1.ListRoomsRequest
+	11, // 17: kakigori_ws.v1.KakigoriWsAggregatorService.GetRoom:input_type -> kakigori_ws.v1.GetRoomRequest
+	13, // 18: kakigori_ws.v1.KakigoriWsAggregatorService.CloseRoom:input_type -> kakigori_ws.v1.CloseRoomRequest
+	19, // 19: kakigori_ws.v1.KakigoriWsAggregatorService.ExportRoom:input_type -> kakigori_ws.v1.ExportRoomRequest
+	21, // 20: kakigori_ws.v1.KakigoriWsAggregatorService.ImportRoom:input_type -> kakigori_ws.v1.ImportRoomRequest
+	23, // 21: kakigori_ws.v1.KakigoriWsAggregatorService.Leaderboard:input_type -> kakigori_ws.v1.LeaderboardRequest
+	26, // 22: kakigori_ws.v1.KakigoriWsAggregatorService.Watch:input_type -> kakigori_ws.v1.WatchRequest
+	7,  // 23: kakigori_ws.v1.KakigoriWsAggregatorService.Aggregate:output_type -> kakigori_ws.v1.AggregateResponse
+	10, // 24: kakigori_ws.v1.KakigoriWsAggregatorService.ListRooms:output_type -> kakigori_ws.v1.ListRoomsResponse
+	12, // 25: kakigori_ws.v1.KakigoriWsAggregatorService.GetRoom:output_type -> kakigori_ws.v1.GetRoomResponse
+	14, // 26: kakigori_ws.v1.KakigoriWsAggregatorService.CloseRoom:output_type -> kakigori_ws.v1.CloseRoomResponse
+	20, // 27: kakigori_ws.v1.KakigoriWsAggregatorService.ExportRoom:output_type -> kakigori_ws.v1.ExportRoomResponse
+	22, // 28: kakigori_ws.v1.KakigoriWsAggregatorService.ImportRoom:output_type -> kakigori_ws.v1.ImportRoomResponse
+	25, // 29: kakigori_ws.v1.KakigoriWsAggregatorService.Leaderboard:output_type -> kakigori_ws.v1.LeaderboardResponse
+	27, // 30: kakigori_ws.v1.KakigoriWsAggregatorService.Watch:output_type -> kakigori_ws.v1.WatchEvent
+	23, // [23:31] is the sub-list for method output_type
+	15, // [15:23] is the sub-list for method input_type
+	15, // [15:15] is the sub-list for extension type_name
+	15, // [15:15] is the sub-list for extension extendee
+	0,  // [0:15] is the sub-list for field type_name
 }
 
 func init() { file_kakigori_ws_v1_aggregator_proto_init() }
@@ -185,13 +2040,14 @@ func file_kakigori_ws_v1_aggregator_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_kakigori_ws_v1_aggregator_proto_rawDesc), len(file_kakigori_ws_v1_aggregator_proto_rawDesc)),
-			NumEnums:      0,
-			NumMessages:   2,
+			NumEnums:      3,
+			NumMessages:   25,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
 		GoTypes:           file_kakigori_ws_v1_aggregator_proto_goTypes,
 		DependencyIndexes: file_kakigori_ws_v1_aggregator_proto_depIdxs,
+		EnumInfos:         file_kakigori_ws_v1_aggregator_proto_enumTypes,
 		MessageInfos:      file_kakigori_ws_v1_aggregator_proto_msgTypes,
 	}.Build()
 	File_kakigori_ws_v1_aggregator_proto = out.File
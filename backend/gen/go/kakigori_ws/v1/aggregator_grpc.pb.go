@@ -19,7 +19,14 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	KakigoriWsAggregatorService_Aggregate_FullMethodName = "/kakigori_ws.v1.KakigoriWsAggregatorService/Aggregate"
+	KakigoriWsAggregatorService_Aggregate_FullMethodName   = "/kakigori_ws.v1.KakigoriWsAggregatorService/Aggregate"
+	KakigoriWsAggregatorService_ListRooms_FullMethodName   = "/kakigori_ws.v1.KakigoriWsAggregatorService/ListRooms"
+	KakigoriWsAggregatorService_GetRoom_FullMethodName     = "/kakigori_ws.v1.KakigoriWsAggregatorService/GetRoom"
+	KakigoriWsAggregatorService_CloseRoom_FullMethodName   = "/kakigori_ws.v1.KakigoriWsAggregatorService/CloseRoom"
+	KakigoriWsAggregatorService_ExportRoom_FullMethodName  = "/kakigori_ws.v1.KakigoriWsAggregatorService/ExportRoom"
+	KakigoriWsAggregatorService_ImportRoom_FullMethodName  = "/kakigori_ws.v1.KakigoriWsAggregatorService/ImportRoom"
+	KakigoriWsAggregatorService_Leaderboard_FullMethodName = "/kakigori_ws.v1.KakigoriWsAggregatorService/Leaderboard"
+	KakigoriWsAggregatorService_Watch_FullMethodName       = "/kakigori_ws.v1.KakigoriWsAggregatorService/Watch"
 )
 
 // KakigoriWsAggregatorServiceClient is the client API for KakigoriWsAggregatorService service.
@@ -27,6 +34,26 @@ const (
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type KakigoriWsAggregatorServiceClient interface {
 	Aggregate(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[AggregateRequest, AggregateResponse], error)
+	// ListRooms reports every room on this instance.
+	ListRooms(ctx context.Context, in *ListRoomsRequest, opts ...grpc.CallOption) (*ListRoomsResponse, error)
+	// GetRoom reports one room; NOT_FOUND if the instance does not have it.
+	GetRoom(ctx context.Context, in *GetRoomRequest, opts ...grpc.CallOption) (*GetRoomResponse, error)
+	// CloseRoom drops the room's window and ends its Aggregate streams with
+	// FAILED_PRECONDITION (apperror code "conflict"); NOT_FOUND if unknown.
+	CloseRoom(ctx context.Context, in *CloseRoomRequest, opts ...grpc.CallOption) (*CloseRoomResponse, error)
+	// ExportRoom removes the room from this instance and returns its state;
+	// NOT_FOUND if the instance does not have it. Its streams keep running and
+	// are expected to move to the new owner.
+	ExportRoom(ctx context.Context, in *ExportRoomRequest, opts ...grpc.CallOption) (*ExportRoomResponse, error)
+	// ImportRoom merges a snapshot into the room on this instance.
+	ImportRoom(ctx context.Context, in *ImportRoomRequest, opts ...grpc.CallOption) (*ImportRoomResponse, error)
+	// Leaderboard ranks the finished rounds in the shared round repository,
+	// so any replica answers for all of them.
+	Leaderboard(ctx context.Context, in *LeaderboardRequest, opts ...grpc.CallOption) (*LeaderboardResponse, error)
+	// Watch reports rooms without joining them: first an UPDATE for every
+	// watched room the instance has, then every change until the caller
+	// cancels.
+	Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[WatchEvent], error)
 }
 
 type kakigoriWsAggregatorServiceClient struct {
@@ -50,11 +77,110 @@ func (c *kakigoriWsAggregatorServiceClient) Aggregate(ctx context.Context, opts
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
 type KakigoriWsAggregatorService_AggregateClient = grpc.BidiStreamingClient[AggregateRequest, AggregateResponse]
 
+func (c *kakigoriWsAggregatorServiceClient) ListRooms(ctx context.Context, in *ListRoomsRequest, opts ...grpc.CallOption) (*ListRoomsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListRoomsResponse)
+	err := c.cc.Invoke(ctx, KakigoriWsAggregatorService_ListRooms_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kakigoriWsAggregatorServiceClient) GetRoom(ctx context.Context, in *GetRoomRequest, opts ...grpc.CallOption) (*GetRoomResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetRoomResponse)
+	err := c.cc.Invoke(ctx, KakigoriWsAggregatorService_GetRoom_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kakigoriWsAggregatorServiceClient) CloseRoom(ctx context.Context, in *CloseRoomRequest, opts ...grpc.CallOption) (*CloseRoomResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CloseRoomResponse)
+	err := c.cc.Invoke(ctx, KakigoriWsAggregatorService_CloseRoom_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kakigoriWsAggregatorServiceClient) ExportRoom(ctx context.Context, in *ExportRoomRequest, opts ...grpc.CallOption) (*ExportRoomResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ExportRoomResponse)
+	err := c.cc.Invoke(ctx, KakigoriWsAggregatorService_ExportRoom_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kakigoriWsAggregatorServiceClient) ImportRoom(ctx context.Context, in *ImportRoomRequest, opts ...grpc.CallOption) (*ImportRoomResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ImportRoomResponse)
+	err := c.cc.Invoke(ctx, KakigoriWsAggregatorService_ImportRoom_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kakigoriWsAggregatorServiceClient) Leaderboard(ctx context.Context, in *LeaderboardRequest, opts ...grpc.CallOption) (*LeaderboardResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(LeaderboardResponse)
+	err := c.cc.Invoke(ctx, KakigoriWsAggregatorService_Leaderboard_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kakigoriWsAggregatorServiceClient) Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[WatchEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &KakigoriWsAggregatorService_ServiceDesc.Streams[1], KakigoriWsAggregatorService_Watch_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchRequest, WatchEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type KakigoriWsAggregatorService_WatchClient = grpc.ServerStreamingClient[WatchEvent]
+
 // KakigoriWsAggregatorServiceServer is the server API for KakigoriWsAggregatorService service.
 // All implementations must embed UnimplementedKakigoriWsAggregatorServiceServer
 // for forward compatibility.
 type KakigoriWsAggregatorServiceServer interface {
 	Aggregate(grpc.BidiStreamingServer[AggregateRequest, AggregateResponse]) error
+	// ListRooms reports every room on this instance.
+	ListRooms(context.Context, *ListRoomsRequest) (*ListRoomsResponse, error)
+	// GetRoom reports one room; NOT_FOUND if the instance does not have it.
+	GetRoom(context.Context, *GetRoomRequest) (*GetRoomResponse, error)
+	// CloseRoom drops the room's window and ends its Aggregate streams with
+	// FAILED_PRECONDITION (apperror code "conflict"); NOT_FOUND if unknown.
+	CloseRoom(context.Context, *CloseRoomRequest) (*CloseRoomResponse, error)
+	// ExportRoom removes the room from this instance and returns its state;
+	// NOT_FOUND if the instance does not have it. Its streams keep running and
+	// are expected to move to the new owner.
+	ExportRoom(context.Context, *ExportRoomRequest) (*ExportRoomResponse, error)
+	// ImportRoom merges a snapshot into the room on this instance.
+	ImportRoom(context.Context, *ImportRoomRequest) (*ImportRoomResponse, error)
+	// Leaderboard ranks the finished rounds in the shared round repository,
+	// so any replica answers for all of them.
+	Leaderboard(context.Context, *LeaderboardRequest) (*LeaderboardResponse, error)
+	// Watch reports rooms without joining them: first an UPDATE for every
+	// watched room the instance has, then every change until the caller
+	// cancels.
+	Watch(*WatchRequest, grpc.ServerStreamingServer[WatchEvent]) error
 	mustEmbedUnimplementedKakigoriWsAggregatorServiceServer()
 }
 
@@ -68,6 +194,27 @@ type UnimplementedKakigoriWsAggregatorServiceServer struct{}
 func (UnimplementedKakigoriWsAggregatorServiceServer) Aggregate(grpc.BidiStreamingServer[AggregateRequest, AggregateResponse]) error {
 	return status.Errorf(codes.Unimplemented, "method Aggregate not implemented")
 }
+func (UnimplementedKakigoriWsAggregatorServiceServer) ListRooms(context.Context, *ListRoomsRequest) (*ListRoomsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListRooms not implemented")
+}
+func (UnimplementedKakigoriWsAggregatorServiceServer) GetRoom(context.Context, *GetRoomRequest) (*GetRoomResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRoom not implemented")
+}
+func (UnimplementedKakigoriWsAggregatorServiceServer) CloseRoom(context.Context, *CloseRoomRequest) (*CloseRoomResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CloseRoom not implemented")
+}
+func (UnimplementedKakigoriWsAggregatorServiceServer) ExportRoom(context.Context, *ExportRoomRequest) (*ExportRoomResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ExportRoom not implemented")
+}
+func (UnimplementedKakigoriWsAggregatorServiceServer) ImportRoom(context.Context, *ImportRoomRequest) (*ImportRoomResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ImportRoom not implemented")
+}
+func (UnimplementedKakigoriWsAggregatorServiceServer) Leaderboard(context.Context, *LeaderboardRequest) (*LeaderboardResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Leaderboard not implemented")
+}
+func (UnimplementedKakigoriWsAggregatorServiceServer) Watch(*WatchRequest, grpc.ServerStreamingServer[WatchEvent]) error {
+	return status.Errorf(codes.Unimplemented, "method Watch not implemented")
+}
 func (UnimplementedKakigoriWsAggregatorServiceServer) mustEmbedUnimplementedKakigoriWsAggregatorServiceServer() {
 }
 func (UnimplementedKakigoriWsAggregatorServiceServer) testEmbeddedByValue() {}
@@ -97,13 +244,157 @@ func _KakigoriWsAggregatorService_Aggregate_Handler(srv interface{}, stream grpc
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
 type KakigoriWsAggregatorService_AggregateServer = grpc.BidiStreamingServer[AggregateRequest, AggregateResponse]
 
+func _KakigoriWsAggregatorService_ListRooms_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListRoomsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KakigoriWsAggregatorServiceServer).ListRooms(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: KakigoriWsAggregatorService_ListRooms_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KakigoriWsAggregatorServiceServer).ListRooms(ctx, req.(*ListRoomsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _KakigoriWsAggregatorService_GetRoom_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRoomRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KakigoriWsAggregatorServiceServer).GetRoom(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: KakigoriWsAggregatorService_GetRoom_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KakigoriWsAggregatorServiceServer).GetRoom(ctx, req.(*GetRoomRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _KakigoriWsAggregatorService_CloseRoom_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CloseRoomRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KakigoriWsAggregatorServiceServer).CloseRoom(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: KakigoriWsAggregatorService_CloseRoom_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KakigoriWsAggregatorServiceServer).CloseRoom(ctx, req.(*CloseRoomRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _KakigoriWsAggregatorService_ExportRoom_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExportRoomRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KakigoriWsAggregatorServiceServer).ExportRoom(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: KakigoriWsAggregatorService_ExportRoom_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KakigoriWsAggregatorServiceServer).ExportRoom(ctx, req.(*ExportRoomRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _KakigoriWsAggregatorService_ImportRoom_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ImportRoomRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KakigoriWsAggregatorServiceServer).ImportRoom(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: KakigoriWsAggregatorService_ImportRoom_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KakigoriWsAggregatorServiceServer).ImportRoom(ctx, req.(*ImportRoomRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _KakigoriWsAggregatorService_Leaderboard_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LeaderboardRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KakigoriWsAggregatorServiceServer).Leaderboard(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: KakigoriWsAggregatorService_Leaderboard_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KakigoriWsAggregatorServiceServer).Leaderboard(ctx, req.(*LeaderboardRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _KakigoriWsAggregatorService_Watch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(KakigoriWsAggregatorServiceServer).Watch(m, &grpc.GenericServerStream[WatchRequest, WatchEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type KakigoriWsAggregatorService_WatchServer = grpc.ServerStreamingServer[WatchEvent]
+
 // KakigoriWsAggregatorService_ServiceDesc is the grpc.ServiceDesc for KakigoriWsAggregatorService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
 var KakigoriWsAggregatorService_ServiceDesc = grpc.ServiceDesc{
 	ServiceName: "kakigori_ws.v1.KakigoriWsAggregatorService",
 	HandlerType: (*KakigoriWsAggregatorServiceServer)(nil),
-	Methods:     []grpc.MethodDesc{},
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListRooms",
+			Handler:    _KakigoriWsAggregatorService_ListRooms_Handler,
+		},
+		{
+			MethodName: "GetRoom",
+			Handler:    _KakigoriWsAggregatorService_GetRoom_Handler,
+		},
+		{
+			MethodName: "CloseRoom",
+			Handler:    _KakigoriWsAggregatorService_CloseRoom_Handler,
+		},
+		{
+			MethodName: "ExportRoom",
+			Handler:    _KakigoriWsAggregatorService_ExportRoom_Handler,
+		},
+		{
+			MethodName: "ImportRoom",
+			Handler:    _KakigoriWsAggregatorService_ImportRoom_Handler,
+		},
+		{
+			MethodName: "Leaderboard",
+			Handler:    _KakigoriWsAggregatorService_Leaderboard_Handler,
+		},
+	},
 	Streams: []grpc.StreamDesc{
 		{
 			StreamName:    "Aggregate",
@@ -111,6 +402,11 @@ var KakigoriWsAggregatorService_ServiceDesc = grpc.ServiceDesc{
 			ServerStreams: true,
 			ClientStreams: true,
 		},
+		{
+			StreamName:    "Watch",
+			Handler:       _KakigoriWsAggregatorService_Watch_Handler,
+			ServerStreams: true,
+		},
 	},
 	Metadata: "kakigori_ws/v1/aggregator.proto",
 }
@@ -0,0 +1,143 @@
+// Package apperror provides the error model shared by every service: typed
+// domain errors with stable codes, and renderers for HTTP (RFC 7807
+// problem+json), gRPC status and WebSocket error frames.
+package apperror
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Code is a stable, machine readable error code. Clients may branch on it, so
+// existing values must never change.
+type Code string
+
+const (
+	CodeInvalidArgument  Code = "invalid_argument"
+	CodeUnauthenticated  Code = "unauthenticated"
+	CodePermissionDenied Code = "permission_denied"
+	CodeNotFound         Code = "not_found"
+	CodeConflict         Code = "conflict"
+	CodeRateLimited      Code = "rate_limited"
+	CodeInternal         Code = "internal"
+	CodeUpstream         Code = "upstream_error"
+	CodeUnavailable      Code = "unavailable"
+	CodeTimeout          Code = "timeout"
+)
+
+// HTTPStatus returns the HTTP status code used when rendering c.
+func (c Code) HTTPStatus() int {
+	switch c {
+	case CodeInvalidArgument:
+		return http.StatusBadRequest
+	case CodeUnauthenticated:
+		return http.StatusUnauthorized
+	case CodePermissionDenied:
+		return http.StatusForbidden
+	case CodeNotFound:
+		return http.StatusNotFound
+	case CodeConflict:
+		return http.StatusConflict
+	case CodeRateLimited:
+		return http.StatusTooManyRequests
+	case CodeUpstream:
+		return http.StatusBadGateway
+	case CodeUnavailable:
+		return http.StatusServiceUnavailable
+	case CodeTimeout:
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// DefaultMessage is the client facing message for c when the error behind it
+// says nothing safe to show.
+func (c Code) DefaultMessage() string {
+	switch c {
+	case CodeInvalidArgument:
+		return "invalid request"
+	case CodeUnauthenticated:
+		return "authentication required"
+	case CodePermissionDenied:
+		return "permission denied"
+	case CodeNotFound:
+		return "not found"
+	case CodeConflict:
+		return "conflict"
+	case CodeRateLimited:
+		return "rate limited"
+	case CodeUpstream:
+		return "upstream error"
+	case CodeUnavailable:
+		return "service unavailable"
+	case CodeTimeout:
+		return "request timed out"
+	default:
+		return "internal error"
+	}
+}
+
+// Error is a domain error carrying a stable Code and a message that is safe to
+// show to clients. The wrapped Err is kept for logging and errors.Is/As only.
+type Error struct {
+	Code    Code
+	Message string
+	Err     error
+}
+
+func New(code Code, msg string) *Error { return &Error{Code: code, Message: msg} }
+
+func Newf(code Code, format string, args ...any) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// Wrap attaches code and a client facing message to err.
+func Wrap(code Code, err error, msg string) *Error { return &Error{Code: code, Message: msg, Err: err} }
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %s: %v", e.Code, e.Message, e.Err)
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// Coder is implemented by errors that know how to describe themselves as an
+// *Error without depending on it directly (e.g. usecase.UpstreamError).
+type Coder interface {
+	AppError() *Error
+}
+
+// From converts any error into an *Error. Errors that are neither *Error nor
+// Coder are classified as fallback, except context cancellation/deadline
+// errors which always map to CodeTimeout; their text stays in Err, and the
+// client sees the code's DefaultMessage.
+func From(err error, fallback Code) *Error {
+	if err == nil {
+		return nil
+	}
+	var ae *Error
+	if errors.As(err, &ae) {
+		return ae
+	}
+	var c Coder
+	if errors.As(err, &c) {
+		return c.AppError()
+	}
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return Wrap(CodeTimeout, err, CodeTimeout.DefaultMessage())
+	}
+	return Wrap(fallback, err, fallback.DefaultMessage())
+}
+
+// CodeOf returns the Code of err, or CodeInternal if it has none.
+func CodeOf(err error) Code {
+	if err == nil {
+		return ""
+	}
+	return From(err, CodeInternal).Code
+}
@@ -0,0 +1,92 @@
+package apperror
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type coderErr struct{}
+
+func (coderErr) Error() string    { return "coder" }
+func (coderErr) AppError() *Error { return New(CodeNotFound, "from coder") }
+
+func TestFrom(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		want Code
+	}{
+		{"app error", New(CodeInvalidArgument, "bad"), CodeInvalidArgument},
+		{"wrapped app error", fmt.Errorf("ctx: %w", New(CodeRateLimited, "slow down")), CodeRateLimited},
+		{"coder", coderErr{}, CodeNotFound},
+		{"deadline", fmt.Errorf("upstream: %w", context.DeadlineExceeded), CodeTimeout},
+		{"plain", errors.New("boom"), CodeUpstream},
+	}
+	for _, tc := range cases {
+		if got := From(tc.err, CodeUpstream).Code; got != tc.want {
+			t.Fatalf("%s: expected %s got %s", tc.name, tc.want, got)
+		}
+	}
+
+	// raw error text is kept for logs but never shown to clients
+	ae := From(errors.New("dial tcp 10.0.0.7:6379: connection refused"), CodeUnavailable)
+	if ae.Message != "service unavailable" || !errors.Is(ae, ae.Err) || ae.Err.Error() != "dial tcp 10.0.0.7:6379: connection refused" {
+		t.Fatalf("unexpected fallback: %#v", ae)
+	}
+}
+
+func TestWriteProblem(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/stores/orders/x", nil)
+	rec := httptest.NewRecorder()
+	WriteProblem(rec, req, New(CodeNotFound, "order not found"))
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != ProblemContentType {
+		t.Fatalf("unexpected content type: %s", ct)
+	}
+	var p Problem
+	if err := json.Unmarshal(rec.Body.Bytes(), &p); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if p.Type != "urn:chantingkakigori:problem:not_found" || p.Title != "Not Found" || p.Status != 404 ||
+		p.Detail != "order not found" || p.Instance != "/api/v1/stores/orders/x" || p.Code != CodeNotFound {
+		t.Fatalf("unexpected problem: %#v", p)
+	}
+}
+
+func TestGRPCRoundTrip(t *testing.T) {
+	err := ToGRPC(New(CodeUpstream, "upstream down"))
+	st, _ := status.FromError(err)
+	if st.Code() != codes.Unavailable {
+		t.Fatalf("expected Unavailable, got %s", st.Code())
+	}
+	ae := FromGRPC(err)
+	if ae.Code != CodeUpstream || ae.Message != "upstream down" {
+		t.Fatalf("unexpected round trip: %#v", ae)
+	}
+
+	// Statuses without our ErrorInfo fall back to the gRPC code mapping.
+	if got := FromGRPC(status.Error(codes.NotFound, "x")); got.Code != CodeNotFound || got.Message != "not found" {
+		t.Fatalf("expected not_found without the foreign message, got %#v", got)
+	}
+}
+
+func TestWSFrame(t *testing.T) {
+	var f Frame
+	if err := json.Unmarshal(WSFrame(New(CodeUpstream, "order failed")), &f); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if f.Type != FrameTypeError || f.Code != CodeUpstream || f.Message != "order failed" {
+		t.Fatalf("unexpected frame: %#v", f)
+	}
+}
@@ -0,0 +1,102 @@
+package apperror
+
+import (
+	"errors"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errorDomain identifies our error codes inside google.rpc.ErrorInfo.
+const errorDomain = "chantingkakigori"
+
+// GRPCCode returns the gRPC status code used when rendering c.
+func (c Code) GRPCCode() codes.Code {
+	switch c {
+	case CodeInvalidArgument:
+		return codes.InvalidArgument
+	case CodeUnauthenticated:
+		return codes.Unauthenticated
+	case CodePermissionDenied:
+		return codes.PermissionDenied
+	case CodeNotFound:
+		return codes.NotFound
+	case CodeConflict:
+		return codes.FailedPrecondition
+	case CodeRateLimited:
+		return codes.ResourceExhausted
+	case CodeUpstream, CodeUnavailable:
+		return codes.Unavailable
+	case CodeTimeout:
+		return codes.DeadlineExceeded
+	default:
+		return codes.Internal
+	}
+}
+
+// ToGRPC converts err into a gRPC status error. The stable Code travels in an
+// ErrorInfo detail so FromGRPC can restore it on the client side.
+func ToGRPC(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok && !isAppError(err) {
+		return err
+	}
+	ae := From(err, CodeInternal)
+	st := status.New(ae.Code.GRPCCode(), ae.Message)
+	if withInfo, derr := st.WithDetails(&errdetails.ErrorInfo{Reason: string(ae.Code), Domain: errorDomain}); derr == nil {
+		st = withInfo
+	}
+	return st.Err()
+}
+
+// FromGRPC converts a gRPC status error back into an *Error. Only statuses
+// from our own servers (carrying their ErrorInfo) keep their message; others,
+// such as transport failures, get the code's DefaultMessage.
+func FromGRPC(err error) *Error {
+	if err == nil {
+		return nil
+	}
+	st, ok := status.FromError(err)
+	if !ok {
+		return From(err, CodeInternal)
+	}
+	for _, d := range st.Details() {
+		if info, ok := d.(*errdetails.ErrorInfo); ok && info.GetDomain() == errorDomain {
+			return Wrap(Code(info.GetReason()), err, st.Message())
+		}
+	}
+	code := codeFromGRPC(st.Code())
+	return Wrap(code, err, code.DefaultMessage())
+}
+
+func codeFromGRPC(c codes.Code) Code {
+	switch c {
+	case codes.InvalidArgument, codes.OutOfRange:
+		return CodeInvalidArgument
+	case codes.Unauthenticated:
+		return CodeUnauthenticated
+	case codes.PermissionDenied:
+		return CodePermissionDenied
+	case codes.NotFound:
+		return CodeNotFound
+	case codes.AlreadyExists, codes.FailedPrecondition, codes.Aborted:
+		return CodeConflict
+	case codes.ResourceExhausted:
+		return CodeRateLimited
+	case codes.Unavailable:
+		return CodeUnavailable
+	case codes.DeadlineExceeded, codes.Canceled:
+		return CodeTimeout
+	default:
+		return CodeInternal
+	}
+}
+
+func isAppError(err error) bool {
+	var ae *Error
+	var c Coder
+	return errors.As(err, &ae) || errors.As(err, &c)
+}
@@ -0,0 +1,44 @@
+package apperror
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// ProblemContentType is the media type defined by RFC 7807.
+const ProblemContentType = "application/problem+json"
+
+// Problem is an RFC 7807 problem details object. Code is an extension member
+// carrying the stable error code.
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	Code     Code   `json:"code"`
+}
+
+// TypeURI returns the problem type URI for code.
+func TypeURI(code Code) string { return "urn:chantingkakigori:problem:" + string(code) }
+
+// NewProblem builds the problem details for e. instance is usually the request path.
+func NewProblem(e *Error, instance string) Problem {
+	status := e.Code.HTTPStatus()
+	return Problem{
+		Type:     TypeURI(e.Code),
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   e.Message,
+		Instance: instance,
+		Code:     e.Code,
+	}
+}
+
+// WriteProblem renders err as application/problem+json.
+func WriteProblem(w http.ResponseWriter, r *http.Request, err *Error) {
+	p := NewProblem(err, r.URL.Path)
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.WriteHeader(p.Status)
+	_ = json.NewEncoder(w).Encode(p)
+}
@@ -0,0 +1,20 @@
+package apperror
+
+import "encoding/json"
+
+// FrameTypeError is the "type" of WebSocket error frames.
+const FrameTypeError = "error"
+
+// Frame is the JSON text frame sent to WebSocket clients when something goes
+// wrong, e.g. {"type":"error","code":"upstream_error","message":"order failed"}.
+type Frame struct {
+	Type    string `json:"type"`
+	Code    Code   `json:"code"`
+	Message string `json:"message"`
+}
+
+// WSFrame encodes e as a WebSocket error frame payload.
+func WSFrame(e *Error) []byte {
+	b, _ := json.Marshal(Frame{Type: FrameTypeError, Code: e.Code, Message: e.Message})
+	return b
+}
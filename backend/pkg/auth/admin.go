@@ -0,0 +1,39 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"os"
+	"strings"
+
+	"chantingkakigori/pkg/apperror"
+)
+
+// AdminTokenFromEnv returns ADMIN_TOKEN. Operator endpoints are meant to stay
+// unregistered when it is empty.
+func AdminTokenFromEnv() string { return strings.TrimSpace(os.Getenv("ADMIN_TOKEN")) }
+
+// AdminGuard lets a request through when it carries token, either as a bearer
+// token or as the Basic auth password (so a browser can open the page).
+// Because browsers replay Basic credentials on cross-site requests, anything
+// but GET/HEAD from another origin is refused.
+func AdminGuard(token string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		got := ""
+		if h := r.Header.Get("Authorization"); len(h) > 7 && strings.EqualFold(h[:7], "bearer ") {
+			got = strings.TrimSpace(h[7:])
+		} else if _, pass, ok := r.BasicAuth(); ok {
+			got = pass
+		}
+		if got == "" || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Basic realm="admin"`)
+			apperror.WriteProblem(w, r, apperror.New(apperror.CodeUnauthenticated, "admin token required"))
+			return
+		}
+		if r.Method != http.MethodGet && r.Method != http.MethodHead && !sameOrigin(r) {
+			apperror.WriteProblem(w, r, apperror.New(apperror.CodePermissionDenied, "cross-origin request refused"))
+			return
+		}
+		next(w, r)
+	}
+}
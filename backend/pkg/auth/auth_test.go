@@ -0,0 +1,183 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"chantingkakigori/pkg/apperror"
+)
+
+var testSecret = []byte("0123456789abcdef0123456789abcdef")
+
+func TestIssueVerify(t *testing.T) {
+	s := NewSigner(testSecret, time.Minute)
+	tok, issued, err := s.Issue("giiku-sai", "giiku-sai")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := s.Verify(tok)
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if c.Room != "giiku-sai" || c.MenuItemID != "giiku-sai" || c.Subject != issued.Subject || c.Issuer != Issuer {
+		t.Fatalf("unexpected claims: %+v", c)
+	}
+}
+
+func TestVerifyRejects(t *testing.T) {
+	s := NewSigner(testSecret, time.Minute)
+	tok, _, _ := s.Issue("giiku-sai", "giiku-sai")
+	parts := strings.Split(tok, ".")
+
+	expired := NewSigner(testSecret, time.Minute)
+	expired.now = func() time.Time { return time.Now().Add(-time.Hour) }
+	old, _, _ := expired.Issue("giiku-sai", "giiku-sai")
+
+	other, _, _ := NewSigner([]byte(strings.Repeat("x", 32)), time.Minute).Issue("giiku-sai", "giiku-sai")
+
+	for name, tok := range map[string]string{
+		"malformed":  "abc",
+		"tampered":   parts[0] + "." + parts[1] + "x." + parts[2],
+		"alg none":   "eyJhbGciOiJub25lIn0." + parts[1] + ".",
+		"expired":    old,
+		"wrong key":  other,
+		"bad base64": parts[0] + ".!!!." + parts[2],
+	} {
+		_, err := s.Verify(tok)
+		var ae *apperror.Error
+		if !errors.As(err, &ae) || ae.Code != apperror.CodeUnauthenticated {
+			t.Errorf("%s: expected unauthenticated, got %v", name, err)
+		}
+	}
+}
+
+func TestSignerFromEnv(t *testing.T) {
+	t.Setenv("AUTH_DISABLED", "")
+	t.Setenv("AUTH_HMAC_SECRET", "short")
+	if _, err := SignerFromEnv(); err == nil {
+		t.Fatal("expected error for short secret")
+	}
+	t.Setenv("AUTH_HMAC_SECRET", string(testSecret))
+	t.Setenv("AUTH_TOKEN_TTL", "2m")
+	s, err := SignerFromEnv()
+	if err != nil || s == nil || s.ttl != 2*time.Minute {
+		t.Fatalf("unexpected signer %+v, %v", s, err)
+	}
+	t.Setenv("AUTH_DISABLED", "true")
+	if s, err := SignerFromEnv(); s != nil || err != nil {
+		t.Fatalf("expected disabled, got %+v, %v", s, err)
+	}
+}
+
+func TestRoomGuard(t *testing.T) {
+	s := NewSigner(testSecret, time.Minute)
+	tok, _, _ := s.Issue("giiku-sai", "giiku-sai")
+	var got *Claims
+	h := s.RoomGuard(func(w http.ResponseWriter, r *http.Request) { got = FromContext(r.Context()) })
+
+	for _, tc := range []struct {
+		name, url, header string
+		want              int
+	}{
+		{"missing", "/ws?room=giiku-sai", "", http.StatusUnauthorized},
+		{"query token", "/ws?room=giiku-sai&token=" + tok, "", http.StatusOK},
+		{"bearer", "/ws?room=giiku-sai", "Bearer " + tok, http.StatusOK},
+		{"other room", "/ws?room=giiku-ten&token=" + tok, "", http.StatusForbidden},
+	} {
+		got = nil
+		req := httptest.NewRequest(http.MethodGet, tc.url, nil)
+		if tc.header != "" {
+			req.Header.Set("Authorization", tc.header)
+		}
+		rec := httptest.NewRecorder()
+		h(rec, req)
+		if rec.Code != tc.want {
+			t.Errorf("%s: expected %d, got %d", tc.name, tc.want, rec.Code)
+		}
+		if tc.want == http.StatusOK && (got == nil || got.Room != "giiku-sai") {
+			t.Errorf("%s: claims not in context", tc.name)
+		}
+	}
+
+	// A nil Signer (AUTH_DISABLED) lets everything through.
+	var disabled *Signer
+	rec := httptest.NewRecorder()
+	disabled.RoomGuard(func(w http.ResponseWriter, r *http.Request) {})(rec, httptest.NewRequest(http.MethodGet, "/ws?room=x", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("disabled: expected 200, got %d", rec.Code)
+	}
+}
+
+func TestRequireMenuItem(t *testing.T) {
+	ctx := NewContext(context.Background(), &Claims{MenuItemID: "giiku-sai"})
+	if err := RequireMenuItem(ctx, "giiku-sai"); err != nil {
+		t.Fatal(err)
+	}
+	if err := RequireMenuItem(ctx, "giiku-ten"); err == nil {
+		t.Fatal("expected permission denied")
+	}
+	if err := RequireMenuItem(context.Background(), "giiku-ten"); err != nil {
+		t.Fatal("no claims (auth disabled) should pass")
+	}
+}
+
+func TestOrigins(t *testing.T) {
+	o := ParseOrigins(" https://kakigori.example/ , http://localhost:3000")
+	if !o.Allowed("https://kakigori.example") || !o.Allowed("http://localhost:3000") || o.Allowed("https://evil.example") {
+		t.Fatal("allowlist mismatch")
+	}
+	req := httptest.NewRequest(http.MethodGet, "http://gw.internal/ws", nil)
+	if !o.CheckOrigin(req) {
+		t.Fatal("no Origin header should pass")
+	}
+	req.Header.Set("Origin", "http://gw.internal")
+	if !o.CheckOrigin(req) {
+		t.Fatal("same host should pass")
+	}
+	req.Header.Set("Origin", "https://evil.example")
+	if o.CheckOrigin(req) {
+		t.Fatal("foreign origin should be rejected")
+	}
+	if !ParseOrigins("*").Allowed("https://evil.example") {
+		t.Fatal("* should allow all")
+	}
+}
+
+func TestAdminGuard(t *testing.T) {
+	h := AdminGuard("s3cret", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) })
+	cases := []struct {
+		name   string
+		method string
+		setup  func(r *http.Request)
+		want   int
+	}{
+		{"missing", http.MethodGet, func(*http.Request) {}, http.StatusUnauthorized},
+		{"wrong bearer", http.MethodGet, func(r *http.Request) { r.Header.Set("Authorization", "Bearer nope") }, http.StatusUnauthorized},
+		{"bearer", http.MethodGet, func(r *http.Request) { r.Header.Set("Authorization", "Bearer s3cret") }, http.StatusNoContent},
+		{"basic", http.MethodGet, func(r *http.Request) { r.SetBasicAuth("ops", "s3cret") }, http.StatusNoContent},
+		{"same-origin post", http.MethodPost, func(r *http.Request) {
+			r.SetBasicAuth("ops", "s3cret")
+			r.Header.Set("Origin", "http://example.com")
+		}, http.StatusNoContent},
+		{"cross-origin post", http.MethodPost, func(r *http.Request) {
+			r.SetBasicAuth("ops", "s3cret")
+			r.Header.Set("Origin", "http://evil.test")
+		}, http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(tc.method, "http://example.com/admin/rooms", nil)
+			tc.setup(r)
+			rec := httptest.NewRecorder()
+			h(rec, r)
+			if rec.Code != tc.want {
+				t.Fatalf("status=%d, want %d", rec.Code, tc.want)
+			}
+		})
+	}
+}
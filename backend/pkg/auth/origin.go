@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// Origins is the browser origin allowlist for CORS and WebSocket upgrades.
+type Origins struct {
+	any  bool
+	list map[string]struct{}
+}
+
+// ParseOrigins parses a comma-separated list such as
+// "https://kakigori.example,http://localhost:3000". "*" allows every origin.
+func ParseOrigins(s string) Origins {
+	o := Origins{list: make(map[string]struct{})}
+	for _, v := range strings.Split(s, ",") {
+		v = strings.TrimRight(strings.TrimSpace(v), "/")
+		switch v {
+		case "":
+		case "*":
+			o.any = true
+		default:
+			o.list[strings.ToLower(v)] = struct{}{}
+		}
+	}
+	return o
+}
+
+// OriginsFromEnv reads ALLOWED_ORIGINS.
+func OriginsFromEnv() Origins { return ParseOrigins(os.Getenv("ALLOWED_ORIGINS")) }
+
+// Allowed reports whether origin is on the list.
+func (o Origins) Allowed(origin string) bool {
+	if o.any {
+		return true
+	}
+	_, ok := o.list[strings.ToLower(strings.TrimRight(origin, "/"))]
+	return ok
+}
+
+// CheckOrigin is a websocket.Upgrader CheckOrigin: requests without an Origin
+// header (non-browser clients) and same-host origins pass, anything else must
+// be on the list.
+func (o Origins) CheckOrigin(r *http.Request) bool {
+	return sameOrigin(r) || o.Allowed(r.Header.Get("Origin"))
+}
+
+// CORS sets CORS headers for allowed origins and answers preflight requests.
+func (o Origins) CORS(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if origin := r.Header.Get("Origin"); origin != "" && o.Allowed(origin) {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Add("Vary", "Origin")
+			w.Header().Set("Access-Control-Allow-Methods", "GET,POST,PUT,PATCH,DELETE,OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Authorization,Content-Type,X-Request-ID")
+		}
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// sameOrigin reports whether r has no Origin header or one naming r.Host.
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
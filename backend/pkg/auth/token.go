@@ -0,0 +1,139 @@
+// Package auth issues and verifies the short-lived session tokens that bind a
+// participant to a room and menu item. Tokens are HS256 JWTs signed with a
+// secret shared by gateway-api (issuer) and the gateways (verifiers), so every
+// service checks them locally without a round trip.
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"time"
+
+	"chantingkakigori/pkg/apperror"
+	"chantingkakigori/pkg/requestid"
+)
+
+// Issuer is the iss claim of every token.
+const Issuer = "chantingkakigori"
+
+// DefaultTTL is the token lifetime used when AUTH_TOKEN_TTL is unset.
+const DefaultTTL = 15 * time.Minute
+
+// leeway tolerates clock skew between services when checking exp.
+const leeway = 30 * time.Second
+
+// Claims is the token payload.
+type Claims struct {
+	Issuer     string `json:"iss"`
+	Subject    string `json:"sub"`
+	Room       string `json:"room"`
+	MenuItemID string `json:"menu_item_id"`
+	IssuedAt   int64  `json:"iat"`
+	ExpiresAt  int64  `json:"exp"`
+}
+
+// Expiry returns exp as a time.
+func (c *Claims) Expiry() time.Time { return time.Unix(c.ExpiresAt, 0) }
+
+// Signer issues and verifies tokens with one HMAC secret.
+type Signer struct {
+	key []byte
+	ttl time.Duration
+	now func() time.Time
+}
+
+// NewSigner returns a Signer; ttl <= 0 uses DefaultTTL.
+func NewSigner(secret []byte, ttl time.Duration) *Signer {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	return &Signer{key: secret, ttl: ttl, now: time.Now}
+}
+
+// SignerFromEnv builds the Signer from AUTH_HMAC_SECRET (at least 32 bytes)
+// and AUTH_TOKEN_TTL. With AUTH_DISABLED=true it returns nil, which every
+// helper in this package treats as "authentication off" (local development).
+func SignerFromEnv() (*Signer, error) {
+	if strings.EqualFold(os.Getenv("AUTH_DISABLED"), "true") {
+		return nil, nil
+	}
+	secret := os.Getenv("AUTH_HMAC_SECRET")
+	if len(secret) < 32 {
+		return nil, errors.New("AUTH_HMAC_SECRET must be set to at least 32 bytes (or AUTH_DISABLED=true)")
+	}
+	var ttl time.Duration
+	if v := os.Getenv("AUTH_TOKEN_TTL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, err
+		}
+		ttl = d
+	}
+	return NewSigner([]byte(secret), ttl), nil
+}
+
+var jwtHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+// Issue returns a token for a new participant in room ordering menuItemID.
+func (s *Signer) Issue(room, menuItemID string) (string, *Claims, error) {
+	now := s.now()
+	c := &Claims{
+		Issuer:     Issuer,
+		Subject:    requestid.New(),
+		Room:       room,
+		MenuItemID: menuItemID,
+		IssuedAt:   now.Unix(),
+		ExpiresAt:  now.Add(s.ttl).Unix(),
+	}
+	payload, err := json.Marshal(c)
+	if err != nil {
+		return "", nil, err
+	}
+	signing := jwtHeader + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return signing + "." + s.sign(signing), c, nil
+}
+
+// Verify checks the signature, algorithm, issuer and expiry of token. Errors
+// carry apperror.CodeUnauthenticated.
+func (s *Signer) Verify(token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, apperror.New(apperror.CodeUnauthenticated, "malformed token")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if b, err := base64.RawURLEncoding.DecodeString(parts[0]); err != nil || json.Unmarshal(b, &header) != nil || header.Alg != "HS256" {
+		return nil, apperror.New(apperror.CodeUnauthenticated, "unsupported token header")
+	}
+	want := s.sign(parts[0] + "." + parts[1])
+	if !hmac.Equal([]byte(want), []byte(parts[2])) {
+		return nil, apperror.New(apperror.CodeUnauthenticated, "invalid token signature")
+	}
+	b, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, apperror.New(apperror.CodeUnauthenticated, "malformed token")
+	}
+	var c Claims
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, apperror.New(apperror.CodeUnauthenticated, "malformed token")
+	}
+	if c.Issuer != Issuer {
+		return nil, apperror.New(apperror.CodeUnauthenticated, "unknown token issuer")
+	}
+	if s.now().After(c.Expiry().Add(leeway)) {
+		return nil, apperror.New(apperror.CodeUnauthenticated, "token expired")
+	}
+	return &c, nil
+}
+
+func (s *Signer) sign(signing string) string {
+	m := hmac.New(sha256.New, s.key)
+	m.Write([]byte(signing))
+	return base64.RawURLEncoding.EncodeToString(m.Sum(nil))
+}
@@ -1,20 +1,96 @@
+// Package grpcjson adds JSON codecs to gRPC next to its default protobuf
+// one. A server that calls Register answers each call in the codec the
+// client picked (the content-subtype of application/grpc+<name>), so
+// clients of every codec can share it; protobuf stays the default.
 package grpcjson
 
 import (
 	"encoding/json"
+	"fmt"
+	"os"
 
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/encoding"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// Codec names, as sent in the content-subtype.
+const (
+	// Proto is gRPC's built-in protobuf codec.
+	Proto = "proto"
+	// JSON is Codec. Clients predating codec negotiation force it.
+	JSON = "json"
+	// ProtoJSON is ProtoJSONCodec, for reading payloads while debugging.
+	ProtoJSON = "protojson"
 )
 
 // Codec implements gRPC's Codec for JSON payloads.
 // This lets us use plain Go structs over gRPC without protobuf codegen.
+// On generated messages it follows their Go struct tags rather than the
+// proto definitions (enums as numbers, no oneofs); prefer ProtoJSONCodec.
 type Codec struct{}
 
-func (Codec) Name() string { return "json" }
+func (Codec) Name() string { return JSON }
 
 func (Codec) Marshal(v any) ([]byte, error) { return json.Marshal(v) }
 
 func (Codec) Unmarshal(data []byte, v any) error { return json.Unmarshal(data, v) }
 
-// Register makes the codec discoverable by name.
-func Register() { encoding.RegisterCodec(Codec{}) }
+// ProtoJSONCodec encodes generated messages with protojson, using the field
+// names of the .proto files and enum value names. Unknown fields are
+// ignored, so either end may be newer.
+type ProtoJSONCodec struct{}
+
+var (
+	protoJSONMarshal   = protojson.MarshalOptions{UseProtoNames: true}
+	protoJSONUnmarshal = protojson.UnmarshalOptions{DiscardUnknown: true}
+)
+
+func (ProtoJSONCodec) Name() string { return ProtoJSON }
+
+func (ProtoJSONCodec) Marshal(v any) ([]byte, error) {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("grpcjson: %T is not a proto.Message", v)
+	}
+	return protoJSONMarshal.Marshal(m)
+}
+
+func (ProtoJSONCodec) Unmarshal(data []byte, v any) error {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("grpcjson: %T is not a proto.Message", v)
+	}
+	return protoJSONUnmarshal.Unmarshal(data, m)
+}
+
+// Register makes the JSON codecs discoverable by name.
+func Register() {
+	encoding.RegisterCodec(Codec{})
+	encoding.RegisterCodec(ProtoJSONCodec{})
+}
+
+// ParseCodec checks that name is Proto, JSON or ProtoJSON.
+func ParseCodec(name string) (string, error) {
+	switch name {
+	case Proto, JSON, ProtoJSON:
+		return name, nil
+	default:
+		return "", fmt.Errorf("grpcjson: unknown codec %q", name)
+	}
+}
+
+// CodecFromEnv reads a codec name from the environment variable key,
+// falling back to def when it is unset or invalid.
+func CodecFromEnv(key, def string) string {
+	name, err := ParseCodec(os.Getenv(key))
+	if err != nil {
+		return def
+	}
+	return name
+}
+
+// CallOption makes calls use the codec name. The server needs it
+// registered; Proto always is.
+func CallOption(name string) grpc.CallOption { return grpc.CallContentSubtype(name) }
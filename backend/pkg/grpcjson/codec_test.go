@@ -0,0 +1,184 @@
+package grpcjson
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+
+	kakigoriwsv1 "chantingkakigori/gen/go/kakigori_ws/v1"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+)
+
+// response is a busy room's AggregateResponse, the message sent per sample.
+func response(participants int) *kakigoriwsv1.AggregateResponse {
+	resp := &kakigoriwsv1.AggregateResponse{
+		Room:           "giiku-sai",
+		Average:        0.4321,
+		Count:          int32(participants * 25),
+		Verdict:        kakigoriwsv1.SampleVerdict_SAMPLE_VERDICT_CLAMPED,
+		FlaggedClients: []string{"p-3"},
+	}
+	for i := range participants {
+		resp.Participants = append(resp.Participants, &kakigoriwsv1.Participant{
+			ClientId:       fmt.Sprintf("p-%d", i),
+			DisplayName:    "かき氷",
+			Mean:           0.4 + float64(i)/100,
+			Count:          25,
+			LastSeenUnixMs: 1754000000000 + int64(i),
+		})
+	}
+	return resp
+}
+
+func TestCodecs_RoundTrip(t *testing.T) {
+	for _, c := range []encoding.Codec{Codec{}, ProtoJSONCodec{}} {
+		want := response(3)
+		b, err := c.Marshal(want)
+		if err != nil {
+			t.Fatalf("%s: %v", c.Name(), err)
+		}
+		got := &kakigoriwsv1.AggregateResponse{}
+		if err := c.Unmarshal(b, got); err != nil {
+			t.Fatalf("%s: %v", c.Name(), err)
+		}
+		if !proto.Equal(got, want) {
+			t.Fatalf("%s: got %v, want %v", c.Name(), got, want)
+		}
+	}
+}
+
+func TestProtoJSONCodec_ProtoNames(t *testing.T) {
+	b, err := ProtoJSONCodec{}.Marshal(response(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"flagged_clients"`, `"last_seen_unix_ms"`, `"SAMPLE_VERDICT_CLAMPED"`} {
+		if !strings.Contains(string(b), want) {
+			t.Errorf("%s lacks %s", b, want)
+		}
+	}
+	// a newer peer's fields are skipped
+	var req kakigoriwsv1.AggregateRequest
+	if err := (ProtoJSONCodec{}).Unmarshal([]byte(`{"room":"r","value":0.5,"added_later":1}`), &req); err != nil || req.GetValue() != 0.5 {
+		t.Fatalf("req=%v err=%v", &req, err)
+	}
+	if _, err := (ProtoJSONCodec{}).Marshal(struct{}{}); err == nil {
+		t.Fatal("marshalled a non-message")
+	}
+}
+
+func TestParseCodec(t *testing.T) {
+	for _, name := range []string{Proto, JSON, ProtoJSON} {
+		if got, err := ParseCodec(name); err != nil || got != name {
+			t.Errorf("ParseCodec(%q)=%q, %v", name, got, err)
+		}
+	}
+	if _, err := ParseCodec("xml"); err == nil {
+		t.Error("xml accepted")
+	}
+	t.Setenv("TEST_CODEC", "bogus")
+	if got := CodecFromEnv("TEST_CODEC", Proto); got != Proto {
+		t.Errorf("invalid env gave %q", got)
+	}
+}
+
+type roomServer struct {
+	kakigoriwsv1.UnimplementedKakigoriWsAggregatorServiceServer
+	contentType chan string
+}
+
+func (s *roomServer) GetRoom(ctx context.Context, req *kakigoriwsv1.GetRoomRequest) (*kakigoriwsv1.GetRoomResponse, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	s.contentType <- strings.Join(md.Get("content-type"), ",")
+	return &kakigoriwsv1.GetRoomResponse{Room: &kakigoriwsv1.RoomInfo{Room: req.GetRoom(), Count: 7}}, nil
+}
+
+// One server answers clients of every codec in their own.
+func TestNegotiation(t *testing.T) {
+	Register()
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	rs := &roomServer{contentType: make(chan string, 1)}
+	kakigoriwsv1.RegisterKakigoriWsAggregatorServiceServer(srv, rs)
+	go func() { _ = srv.Serve(lis) }()
+	defer srv.Stop()
+
+	for _, tc := range []struct {
+		opt  grpc.CallOption
+		want string
+	}{
+		{CallOption(Proto), "application/grpc+proto"},
+		{CallOption(JSON), "application/grpc+json"},
+		{CallOption(ProtoJSON), "application/grpc+protojson"},
+		// gateway-ws before negotiation
+		{grpc.ForceCodec(Codec{}), "application/grpc+json"},
+	} {
+		conn, err := grpc.NewClient("passthrough:///bufnet",
+			grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+			grpc.WithDefaultCallOptions(tc.opt),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := kakigoriwsv1.NewKakigoriWsAggregatorServiceClient(conn).GetRoom(context.Background(), &kakigoriwsv1.GetRoomRequest{Room: "r"})
+		ct := <-rs.contentType
+		_ = conn.Close()
+		if err != nil || resp.GetRoom().GetCount() != 7 || ct != tc.want {
+			t.Fatalf("%s: content-type=%s resp=%v err=%v", tc.want, ct, resp, err)
+		}
+	}
+}
+
+// protoCodec is what gRPC's built-in codec does, minus its buffer pooling.
+type protoCodec struct{}
+
+func (protoCodec) Name() string                  { return Proto }
+func (protoCodec) Marshal(v any) ([]byte, error) { return proto.Marshal(v.(proto.Message)) }
+func (protoCodec) Unmarshal(data []byte, v any) error {
+	return proto.Unmarshal(data, v.(proto.Message))
+}
+
+// BenchmarkCodecs compares the codecs on the aggregator's per-sample
+// messages:
+//
+//	go test ./pkg/grpcjson -run '^$' -bench Codecs
+func BenchmarkCodecs(b *testing.B) {
+	req := &kakigoriwsv1.AggregateRequest{Room: "giiku-sai", Value: 0.42, ClientId: "p-1"}
+	codecs := []encoding.Codec{protoCodec{}, Codec{}, ProtoJSONCodec{}}
+	for _, msg := range []struct {
+		name string
+		m    proto.Message
+		new  func() proto.Message
+	}{
+		{"request", req, func() proto.Message { return &kakigoriwsv1.AggregateRequest{} }},
+		{"response", response(3), func() proto.Message { return &kakigoriwsv1.AggregateResponse{} }},
+		{"response-breakdown-30", response(30), func() proto.Message { return &kakigoriwsv1.AggregateResponse{} }},
+	} {
+		for _, c := range codecs {
+			b.Run(msg.name+"/"+c.Name(), func(b *testing.B) {
+				data, err := c.Marshal(msg.m)
+				if err != nil {
+					b.Fatal(err)
+				}
+				b.ReportMetric(float64(len(data)), "bytes/msg")
+				b.ReportAllocs()
+				b.ResetTimer()
+				for range b.N {
+					data, _ := c.Marshal(msg.m)
+					if err := c.Unmarshal(data, msg.new()); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
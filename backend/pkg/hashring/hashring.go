@@ -0,0 +1,93 @@
+// Package hashring maps keys (room IDs) to nodes (aggregator replicas) with
+// consistent hashing, so a membership change moves only about 1/n of the
+// keys.
+package hashring
+
+import (
+	"hash/fnv"
+	"slices"
+	"sort"
+	"strconv"
+)
+
+// DefaultReplicas is the number of virtual points per node. More points
+// spread keys more evenly at the cost of a larger ring.
+const DefaultReplicas = 128
+
+// Ring is an immutable consistent-hash ring. Build a new one when the node
+// set changes.
+type Ring struct {
+	nodes  []string
+	points []uint64
+	owner  map[uint64]string
+}
+
+// New builds a ring of the distinct nodes with replicas virtual points each
+// (DefaultReplicas when replicas <= 0).
+func New(nodes []string, replicas int) *Ring {
+	if replicas <= 0 {
+		replicas = DefaultReplicas
+	}
+	r := &Ring{owner: make(map[uint64]string)}
+	for _, n := range nodes {
+		if n == "" || slices.Contains(r.nodes, n) {
+			continue
+		}
+		r.nodes = append(r.nodes, n)
+		for i := 0; i < replicas; i++ {
+			h := hash(n + "#" + strconv.Itoa(i))
+			// On a (vanishingly rare) collision the smaller node wins, so
+			// the result does not depend on the input order.
+			if prev, ok := r.owner[h]; ok {
+				if n < prev {
+					r.owner[h] = n
+				}
+				continue
+			}
+			r.points = append(r.points, h)
+			r.owner[h] = n
+		}
+	}
+	sort.Strings(r.nodes)
+	slices.Sort(r.points)
+	return r
+}
+
+// Get returns the node owning key, or "" for an empty ring.
+func (r *Ring) Get(key string) string {
+	if len(r.points) == 0 {
+		return ""
+	}
+	h := hash(key)
+	i := sort.Search(len(r.points), func(i int) bool { return r.points[i] >= h })
+	if i == len(r.points) {
+		i = 0
+	}
+	return r.owner[r.points[i]]
+}
+
+// Nodes returns the ring's nodes, sorted.
+func (r *Ring) Nodes() []string { return slices.Clone(r.nodes) }
+
+// Has reports whether node is on the ring.
+func (r *Ring) Has(node string) bool {
+	_, ok := slices.BinarySearch(r.nodes, node)
+	return ok
+}
+
+// Equal reports whether r and o have the same nodes.
+func (r *Ring) Equal(o *Ring) bool { return slices.Equal(r.nodes, o.nodes) }
+
+// hash is FNV-1a followed by the splitmix64 finalizer; plain FNV clusters
+// the points of short, similar names ("k1#0", "k1#1", ...).
+func hash(s string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(s))
+	x := h.Sum64()
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
@@ -0,0 +1,46 @@
+package hashring
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestGetIsStableAndOrderIndependent(t *testing.T) {
+	a := New([]string{"k1:50051", "k2:50051", "k3:50051"}, 0)
+	b := New([]string{"k3:50051", "k1:50051", "k2:50051", "k1:50051"}, 0)
+	if !a.Equal(b) {
+		t.Fatalf("nodes differ: %v vs %v", a.Nodes(), b.Nodes())
+	}
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("room-%d", i)
+		if a.Get(key) != b.Get(key) || a.Get(key) != a.Get(key) {
+			t.Fatalf("owner of %s differs", key)
+		}
+	}
+}
+
+func TestAddingNodeMovesAboutOneNth(t *testing.T) {
+	before := New([]string{"a", "b", "c"}, 0)
+	after := New([]string{"a", "b", "c", "d"}, 0)
+	const keys = 10000
+	moved := 0
+	for i := 0; i < keys; i++ {
+		key := fmt.Sprintf("room-%d", i)
+		if o := after.Get(key); o != before.Get(key) {
+			if o != "d" {
+				t.Fatalf("%s moved between old nodes: %s -> %s", key, before.Get(key), o)
+			}
+			moved++
+		}
+	}
+	// expect ~1/4; allow generous slack for hash variance
+	if moved < keys/8 || moved > keys*3/8 {
+		t.Fatalf("moved %d of %d keys", moved, keys)
+	}
+}
+
+func TestEmptyRing(t *testing.T) {
+	if got := New(nil, 0).Get("x"); got != "" {
+		t.Fatalf("got %q", got)
+	}
+}
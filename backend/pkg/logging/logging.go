@@ -0,0 +1,90 @@
+// Package logging configures log/slog for every service: JSON output on
+// stdout, level from LOG_LEVEL, and a fixed field schema so log lines can be
+// joined across services (service, request_id, room, client, order_id,
+// round_id).
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strings"
+
+	"chantingkakigori/pkg/requestid"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Field names shared by all services.
+const (
+	KeyService   = "service"
+	KeyRequestID = "request_id"
+	KeyTraceID   = "trace_id"
+	KeyRoom      = "room"
+	KeyClient    = "client"
+	KeyOrderID   = "order_id"
+	KeyRoundID   = "round_id"
+)
+
+// Init installs a JSON slog logger for service as the process default and
+// returns it. The standard library log package is redirected to it as well.
+func Init(service string) *slog.Logger {
+	h := slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: levelFromEnv()})
+	logger := slog.New(contextHandler{h}).With(slog.String(KeyService, service))
+	slog.SetDefault(logger)
+	return logger
+}
+
+func levelFromEnv() slog.Level {
+	switch strings.ToLower(os.Getenv("LOG_LEVEL")) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// Room, Client, OrderID and RoundID build the schema attributes.
+func Room(id string) slog.Attr    { return slog.String(KeyRoom, id) }
+func Client(id string) slog.Attr  { return slog.String(KeyClient, id) }
+func OrderID(id string) slog.Attr { return slog.String(KeyOrderID, id) }
+func RoundID(id string) slog.Attr { return slog.String(KeyRoundID, id) }
+
+// Err is the conventional attribute for errors.
+func Err(err error) slog.Attr { return slog.Any("error", err) }
+
+// contextHandler adds request_id and trace_id from the record's context, so
+// callers only need to use the *Context logging variants.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if ctx != nil {
+		if id := requestid.FromContext(ctx); id != "" {
+			r.AddAttrs(slog.String(KeyRequestID, id))
+		}
+		if sc := trace.SpanContextFromContext(ctx); sc.HasTraceID() {
+			r.AddAttrs(slog.String(KeyTraceID, sc.TraceID().String()))
+		}
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+// Fatal logs msg at error level and exits, replacing log.Fatalf in mains.
+func Fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
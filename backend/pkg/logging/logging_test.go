@@ -0,0 +1,42 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"chantingkakigori/pkg/requestid"
+)
+
+func TestContextHandler_AddsRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(contextHandler{slog.NewJSONHandler(&buf, nil)}).With(slog.String(KeyService, "svc"))
+
+	ctx := requestid.NewContext(context.Background(), "req-1")
+	logger.InfoContext(ctx, "hello", Room("r1"), Client("c1"))
+
+	var line map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("invalid json: %v", err)
+	}
+	for k, want := range map[string]string{KeyService: "svc", KeyRequestID: "req-1", KeyRoom: "r1", KeyClient: "c1"} {
+		if line[k] != want {
+			t.Errorf("%s = %v, want %s", k, line[k], want)
+		}
+	}
+}
+
+func TestSampler(t *testing.T) {
+	s := NewSampler(3)
+	var allowed int
+	for i := 0; i < 9; i++ {
+		if s.Allow() {
+			allowed++
+		}
+	}
+	if allowed != 3 {
+		t.Fatalf("allowed = %d, want 3", allowed)
+	}
+}
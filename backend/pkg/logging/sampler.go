@@ -0,0 +1,23 @@
+package logging
+
+import "sync/atomic"
+
+// Sampler thins out hot-path logs (per-message lines) by letting through the
+// first of every N calls.
+type Sampler struct {
+	every uint64
+	n     atomic.Uint64
+}
+
+// NewSampler returns a Sampler allowing one in every calls. every <= 1 allows all.
+func NewSampler(every uint64) *Sampler {
+	if every == 0 {
+		every = 1
+	}
+	return &Sampler{every: every}
+}
+
+// Allow reports whether this call should be logged.
+func (s *Sampler) Allow() bool {
+	return (s.n.Add(1)-1)%s.every == 0
+}
@@ -0,0 +1,30 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryClientInterceptor records outcome (gRPC status code) and latency of
+// unary calls made to target.
+func UnaryClientInterceptor(target string) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		start := time.Now()
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		UpstreamDuration.WithLabelValues(target).Observe(time.Since(start).Seconds())
+		UpstreamRequests.WithLabelValues(target, status.Code(err).String()).Inc()
+		return err
+	}
+}
+
+// StreamClientInterceptor counts stream opens to target by outcome.
+func StreamClientInterceptor(target string) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		cs, err := streamer(ctx, desc, cc, method, opts...)
+		UpstreamRequests.WithLabelValues(target, status.Code(err).String()).Inc()
+		return cs, err
+	}
+}
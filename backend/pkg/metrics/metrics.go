@@ -0,0 +1,195 @@
+// Package metrics defines the Prometheus collectors shared by all services and
+// the helpers that feed them. Collectors are created unregistered so packages
+// can record unconditionally; each main calls Register once with its service
+// name, which is attached to every series as the "service" label.
+package metrics
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	// HTTPRequestDuration tracks inbound HTTP latency by route pattern.
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Latency of inbound HTTP requests by route.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// UpstreamRequests counts outbound calls by target and outcome.
+	UpstreamRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "upstream_requests_total",
+		Help: "Outbound calls to upstream HTTP/gRPC services by outcome.",
+	}, []string{"target", "outcome"})
+
+	// UpstreamDuration tracks outbound call latency by target.
+	UpstreamDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "upstream_request_duration_seconds",
+		Help:    "Latency of outbound calls to upstream services.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"target"})
+
+	// GeminiDuration tracks Gemini GenerateContent latency.
+	GeminiDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "gemini_request_duration_seconds",
+		Help:    "Latency of Gemini chant generation calls.",
+		Buckets: []float64{0.25, 0.5, 1, 2, 4, 8, 15},
+	}, []string{"model"})
+
+	// GeminiErrors counts failed Gemini calls.
+	GeminiErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "gemini_errors_total",
+		Help: "Failed Gemini chant generation calls.",
+	}, []string{"model"})
+
+	// WSConnections is the number of open WebSocket connections per endpoint.
+	WSConnections = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "websocket_connections_active",
+		Help: "Currently open WebSocket connections.",
+	}, []string{"endpoint"})
+
+	// WSDroppedFrames counts outbound WebSocket frames that were never written,
+	// by reason: "coalesced" (superseded by a newer frame), "overflow" (queue
+	// full) or "evicted" (client disconnected as a slow consumer).
+	WSDroppedFrames = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "websocket_dropped_frames_total",
+		Help: "Outbound WebSocket frames dropped before being written.",
+	}, []string{"endpoint", "reason"})
+
+	// WSEvictions counts clients disconnected for not keeping up.
+	WSEvictions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "websocket_slow_consumer_evictions_total",
+		Help: "WebSocket clients disconnected as slow consumers.",
+	}, []string{"endpoint"})
+
+	// WSBytes counts WebSocket data frame payload bytes by direction ("in",
+	// "out") and negotiated subprotocol ("none" without one), to compare the
+	// encodings' bandwidth.
+	WSBytes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "websocket_payload_bytes_total",
+		Help: "WebSocket data frame payload bytes.",
+	}, []string{"endpoint", "direction", "subprotocol"})
+
+	// RateLimited counts requests and messages rejected or shed by a rate
+	// limiter, by kind ("http", "ws", "grpc") and route.
+	RateLimited = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "rate_limited_total",
+		Help: "Requests and messages rejected by rate limiters.",
+	}, []string{"kind", "route"})
+
+	// Rooms is the number of live rooms per kind (chant, stay, confirm, aggregate).
+	Rooms = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "rooms_active",
+		Help: "Currently live rooms.",
+	}, []string{"kind"})
+
+	// AggregateUpdates counts samples folded into a room average; use rate() for updates/sec.
+	AggregateUpdates = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "aggregate_updates_total",
+		Help: "Samples applied to room aggregates.",
+	})
+
+	// AggregateFiltered counts samples the aggregator clamped or rejected, by
+	// verdict and reason ("range", "rate", "outlier", "stale").
+	AggregateFiltered = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "aggregate_filtered_samples_total",
+		Help: "Samples clamped or rejected by the aggregator's anti-cheat filter.",
+	}, []string{"verdict", "reason"})
+
+	// AudioFrames counts raw audio frames kakigori-ws measured into samples,
+	// by codec ("pcm_s16le", "opus") and outcome ("measured", "silent",
+	// "malformed", "unsupported").
+	AudioFrames = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "aggregate_audio_frames_total",
+		Help: "Raw audio frames measured into loudness samples.",
+	}, []string{"codec", "outcome"})
+
+	// AggregateWatchStreams is the number of open Watch streams (spectator
+	// screens, through gateway-ws).
+	AggregateWatchStreams = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "aggregate_watch_streams",
+		Help: "Open Watch streams reporting rooms to spectators.",
+	})
+
+	// AggregateRounds counts finished rounds kakigori-ws wrote to its round
+	// repository, by outcome ("saved", "failed"); a failed round is retried
+	// and counted again.
+	AggregateRounds = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "aggregate_rounds_total",
+		Help: "Finished rounds written to the leaderboard's round repository.",
+	}, []string{"outcome"})
+
+	// AggregatorPeers is the number of kakigori-ws replicas a gateway routes
+	// rooms to.
+	AggregatorPeers = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "aggregator_peers",
+		Help: "kakigori-ws replicas currently on the gateway's hash ring.",
+	})
+
+	// AggregatorHandoffs counts room moves between kakigori-ws replicas after
+	// a membership change, by outcome ("moved", "empty", "failed").
+	AggregatorHandoffs = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "aggregator_room_handoffs_total",
+		Help: "Rooms moved between aggregator replicas on membership change.",
+	}, []string{"outcome"})
+
+	// AggregatorReconnects counts Aggregate streams reopened after a
+	// transient error, by outcome ("reopened", "gave_up").
+	AggregatorReconnects = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "aggregator_stream_reconnects_total",
+		Help: "Aggregate streams reopened after the aggregator replica failed.",
+	}, []string{"outcome"})
+
+	// RoundTransitions counts states gateway-api's rounds entered, by state
+	// ("lobby" for each round opened, "expired" for each timed out).
+	RoundTransitions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "round_transitions_total",
+		Help: "Party rounds entering each lifecycle state.",
+	}, []string{"state"})
+
+	// OrdersPlaced counts successfully placed orders per menu item.
+	OrdersPlaced = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "orders_placed_total",
+		Help: "Orders accepted by the upstream store API per menu item.",
+	}, []string{"menu_item_id"})
+)
+
+var registerOnce sync.Once
+
+// Register exposes the shared collectors on the default registry (which
+// already carries the Go and process collectors), labelled with service.
+func Register(service string) {
+	registerOnce.Do(func() {
+		reg := prometheus.WrapRegistererWith(prometheus.Labels{"service": service}, prometheus.DefaultRegisterer)
+		reg.MustRegister(
+			HTTPRequestDuration,
+			UpstreamRequests,
+			UpstreamDuration,
+			GeminiDuration,
+			GeminiErrors,
+			WSConnections,
+			WSDroppedFrames,
+			WSEvictions,
+			WSBytes,
+			RateLimited,
+			Rooms,
+			AggregateUpdates,
+			AggregateFiltered,
+			AudioFrames,
+			AggregateWatchStreams,
+			AggregateRounds,
+			AggregatorPeers,
+			AggregatorHandoffs,
+			AggregatorReconnects,
+			RoundTransitions,
+			OrdersPlaced,
+		)
+	})
+}
+
+// Handler serves the /metrics endpoint.
+func Handler() http.Handler { return promhttp.Handler() }
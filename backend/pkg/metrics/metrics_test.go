@@ -0,0 +1,63 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestInstrumentRoundTripper_Outcomes(t *testing.T) {
+	statuses := []int{http.StatusOK, http.StatusNotFound, http.StatusBadGateway}
+	i := 0
+	rt := InstrumentRoundTripper("test-target", roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		if i >= len(statuses) {
+			return nil, errors.New("dial failed")
+		}
+		code := statuses[i]
+		i++
+		return &http.Response{StatusCode: code, Body: http.NoBody}, nil
+	}))
+	for range 4 {
+		req := httptest.NewRequest(http.MethodGet, "http://upstream/x", nil)
+		_, _ = rt.RoundTrip(req)
+	}
+
+	for outcome, want := range map[string]float64{"success": 1, "client_error": 1, "server_error": 1, "error": 1} {
+		if got := testutil.ToFloat64(UpstreamRequests.WithLabelValues("test-target", outcome)); got != want {
+			t.Fatalf("outcome %s: expected %v got %v", outcome, want, got)
+		}
+	}
+}
+
+func TestInstrumentHandler_RecordsStatus(t *testing.T) {
+	h := InstrumentHandler("/test", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	h(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/test", nil))
+
+	reg := prometheus.NewPedanticRegistry()
+	reg.MustRegister(HTTPRequestDuration)
+	mfs, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("gather: %v", err)
+	}
+	var found bool
+	for _, mf := range mfs {
+		for _, m := range mf.GetMetric() {
+			labels := map[string]string{}
+			for _, lp := range m.GetLabel() {
+				labels[lp.GetName()] = lp.GetValue()
+			}
+			if labels["route"] == "/test" && labels["status"] == "418" && m.GetHistogram().GetSampleCount() == 1 {
+				found = true
+			}
+		}
+	}
+	if !found {
+		t.Fatalf("expected a /test 418 observation")
+	}
+}
@@ -0,0 +1,39 @@
+package ratelimit
+
+import (
+	"chantingkakigori/pkg/metrics"
+
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc"
+)
+
+// StreamServerInterceptor limits inbound messages per stream. Messages over
+// the limit are dropped (the next one is read in its place) rather than
+// failing the stream: a chatty client loses samples, not its session.
+func StreamServerInterceptor(l Limit) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		lim := l.NewLimiter()
+		if lim == nil || !info.IsClientStream {
+			return handler(srv, ss)
+		}
+		return handler(srv, &limitedStream{ServerStream: ss, lim: lim, method: info.FullMethod})
+	}
+}
+
+type limitedStream struct {
+	grpc.ServerStream
+	lim    *rate.Limiter
+	method string
+}
+
+func (s *limitedStream) RecvMsg(m any) error {
+	for {
+		if err := s.ServerStream.RecvMsg(m); err != nil {
+			return err
+		}
+		if s.lim.Allow() {
+			return nil
+		}
+		metrics.RateLimited.WithLabelValues("grpc", s.method).Inc()
+	}
+}
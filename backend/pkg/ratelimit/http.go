@@ -0,0 +1,59 @@
+package ratelimit
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+
+	"chantingkakigori/pkg/apperror"
+	"chantingkakigori/pkg/auth"
+	"chantingkakigori/pkg/metrics"
+)
+
+// KeyFunc picks the bucket a request is charged to.
+type KeyFunc func(r *http.Request) string
+
+// ClientIP returns the caller's address. The edge (nginx) overwrites
+// X-Real-IP with the peer address, so it is trusted when present.
+func ClientIP(r *http.Request) string {
+	if ip := r.Header.Get("X-Real-IP"); ip != "" {
+		return ip
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// ByIP charges requests to the caller's IP address.
+func ByIP(r *http.Request) string { return "ip:" + ClientIP(r) }
+
+// ByClient charges requests to the session token's subject, falling back to
+// the IP address when the request carries no claims (auth disabled).
+func ByClient(r *http.Request) string {
+	if c := auth.FromContext(r.Context()); c != nil {
+		return "sub:" + c.Subject
+	}
+	return ByIP(r)
+}
+
+// Middleware rejects requests over the limit with a problem+json 429 and a
+// Retry-After header. route labels the rate_limited_total metric.
+func (l *Limiter) Middleware(route string, key KeyFunc) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if l == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ok, wait := l.Allow(key(r))
+			if !ok {
+				metrics.RateLimited.WithLabelValues("http", route).Inc()
+				w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds(wait)))
+				apperror.WriteProblem(w, r, apperror.New(apperror.CodeRateLimited, "too many requests, retry later"))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
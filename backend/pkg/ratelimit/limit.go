@@ -0,0 +1,100 @@
+// Package ratelimit provides the token-bucket limiters used by the gateways
+// and kakigori-ws: keyed limiters for HTTP routes (per client or per IP), a
+// per-connection limit for inbound WebSocket messages, and a gRPC stream
+// interceptor that sheds excess messages.
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Limit is a token-bucket configuration. The zero Limit means unlimited.
+type Limit struct {
+	Rate  rate.Limit // tokens per second
+	Burst int
+}
+
+// Unlimited reports whether l disables limiting.
+func (l Limit) Unlimited() bool { return l.Rate <= 0 || l.Burst <= 0 }
+
+// NewLimiter returns a single bucket for l, or nil when l is unlimited.
+func (l Limit) NewLimiter() *rate.Limiter {
+	if l.Unlimited() {
+		return nil
+	}
+	return rate.NewLimiter(l.Rate, l.Burst)
+}
+
+func (l Limit) String() string {
+	if l.Unlimited() {
+		return "off"
+	}
+	return fmt.Sprintf("%g/s:%d", float64(l.Rate), l.Burst)
+}
+
+// ParseLimit parses "<n>/<s|m|h>[:<burst>]", e.g. "6/m:3" (six per minute,
+// bursts of three) or "30/s". Without a burst it defaults to n (at least 1).
+// "off" and "" mean unlimited.
+func ParseLimit(s string) (Limit, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || strings.EqualFold(s, "off") {
+		return Limit{}, nil
+	}
+	spec, burstStr, hasBurst := strings.Cut(s, ":")
+	nStr, unit, ok := strings.Cut(spec, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("ratelimit: %q: want <n>/<s|m|h>[:<burst>]", s)
+	}
+	n, err := strconv.ParseFloat(nStr, 64)
+	if err != nil || n <= 0 {
+		return Limit{}, fmt.Errorf("ratelimit: %q: bad count", s)
+	}
+	var per time.Duration
+	switch unit {
+	case "s":
+		per = time.Second
+	case "m":
+		per = time.Minute
+	case "h":
+		per = time.Hour
+	default:
+		return Limit{}, fmt.Errorf("ratelimit: %q: unit must be s, m or h", s)
+	}
+	burst := int(math.Max(1, math.Ceil(n)))
+	if hasBurst {
+		if burst, err = strconv.Atoi(burstStr); err != nil || burst <= 0 {
+			return Limit{}, fmt.Errorf("ratelimit: %q: bad burst", s)
+		}
+	}
+	return Limit{Rate: rate.Limit(n / per.Seconds()), Burst: burst}, nil
+}
+
+// MustParse is ParseLimit for compile-time defaults.
+func MustParse(s string) Limit {
+	l, err := ParseLimit(s)
+	if err != nil {
+		panic(err)
+	}
+	return l
+}
+
+// FromEnv reads the limit from the environment variable name, falling back
+// to def when it is unset or invalid.
+func FromEnv(name string, def Limit) Limit {
+	v, ok := os.LookupEnv(name)
+	if !ok {
+		return def
+	}
+	l, err := ParseLimit(v)
+	if err != nil {
+		return def
+	}
+	return l
+}
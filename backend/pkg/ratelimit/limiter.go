@@ -0,0 +1,74 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// idleTTL is how long an unused key keeps its bucket. A bucket idle this long
+// has refilled anyway, so dropping it loses nothing.
+const idleTTL = 10 * time.Minute
+
+type entry struct {
+	lim  *rate.Limiter
+	seen time.Time
+}
+
+// Limiter holds one token bucket per key (client, IP, ...).
+type Limiter struct {
+	limit Limit
+
+	mu        sync.Mutex
+	buckets   map[string]*entry
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// New returns a keyed Limiter; a nil *Limiter (from an unlimited l) allows
+// everything.
+func New(l Limit) *Limiter {
+	if l.Unlimited() {
+		return nil
+	}
+	return &Limiter{limit: l, buckets: make(map[string]*entry), now: time.Now}
+}
+
+// Allow takes a token from key's bucket. When the bucket is empty it returns
+// false and how long until the next token.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	if l == nil {
+		return true, 0
+	}
+	now := l.now()
+	l.mu.Lock()
+	if now.Sub(l.lastSweep) > idleTTL {
+		for k, e := range l.buckets {
+			if now.Sub(e.seen) > idleTTL {
+				delete(l.buckets, k)
+			}
+		}
+		l.lastSweep = now
+	}
+	e, ok := l.buckets[key]
+	if !ok {
+		e = &entry{lim: rate.NewLimiter(l.limit.Rate, l.limit.Burst)}
+		l.buckets[key] = e
+	}
+	e.seen = now
+	l.mu.Unlock()
+
+	r := e.lim.ReserveN(now, 1)
+	if d := r.DelayFrom(now); d > 0 {
+		r.CancelAt(now)
+		return false, d
+	}
+	return true, 0
+}
+
+// retryAfterSeconds rounds d up to whole seconds for the Retry-After header.
+func retryAfterSeconds(d time.Duration) int {
+	return int(math.Max(1, math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,129 @@
+package ratelimit
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc"
+)
+
+func TestParseLimit(t *testing.T) {
+	for in, want := range map[string]Limit{
+		"6/m:3": {Rate: rate.Limit(0.1), Burst: 3},
+		"30/s":  {Rate: 30, Burst: 30},
+		"0.5/s": {Rate: 0.5, Burst: 1},
+		"off":   {},
+		"":      {},
+	} {
+		got, err := ParseLimit(in)
+		if err != nil || got != want {
+			t.Errorf("ParseLimit(%q) = %+v, %v; want %+v", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"6", "6/d", "x/s", "6/s:0", "-1/s"} {
+		if _, err := ParseLimit(in); err == nil {
+			t.Errorf("ParseLimit(%q): expected error", in)
+		}
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	def := MustParse("1/s")
+	t.Setenv("RL_TEST", "bogus")
+	if got := FromEnv("RL_TEST", def); got != def {
+		t.Fatalf("invalid value should keep default, got %v", got)
+	}
+	t.Setenv("RL_TEST", "off")
+	if got := FromEnv("RL_TEST", def); !got.Unlimited() {
+		t.Fatalf("off should disable, got %v", got)
+	}
+}
+
+func TestLimiterPerKey(t *testing.T) {
+	l := New(Limit{Rate: 1, Burst: 2})
+	now := time.Unix(1000, 0)
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if ok, _ := l.Allow("a"); !ok {
+			t.Fatalf("burst request %d rejected", i)
+		}
+	}
+	ok, wait := l.Allow("a")
+	if ok || wait <= 0 || wait > time.Second {
+		t.Fatalf("third request: ok=%v wait=%v", ok, wait)
+	}
+	if ok, _ := l.Allow("b"); !ok {
+		t.Fatal("other key should have its own bucket")
+	}
+	now = now.Add(time.Second)
+	if ok, _ := l.Allow("a"); !ok {
+		t.Fatal("token should have refilled")
+	}
+
+	var unlimited *Limiter
+	if ok, _ := unlimited.Allow("a"); !ok {
+		t.Fatal("nil limiter must allow")
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	h := New(Limit{Rate: 0.001, Burst: 1}).Middleware("/x", ByIP)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	do := func(ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/x", nil)
+		req.Header.Set("X-Real-IP", ip)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+	if rec := do("10.0.0.1"); rec.Code != http.StatusOK {
+		t.Fatalf("first: %d", rec.Code)
+	}
+	rec := do("10.0.0.1")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Fatalf("second: %d, Retry-After=%q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	if rec := do("10.0.0.2"); rec.Code != http.StatusOK {
+		t.Fatalf("other ip: %d", rec.Code)
+	}
+}
+
+type fakeStream struct {
+	grpc.ServerStream
+	msgs []int
+}
+
+func (f *fakeStream) Context() context.Context { return context.Background() }
+
+func (f *fakeStream) RecvMsg(m any) error {
+	if len(f.msgs) == 0 {
+		return io.EOF
+	}
+	*m.(*int) = f.msgs[0]
+	f.msgs = f.msgs[1:]
+	return nil
+}
+
+func TestStreamInterceptorShedsExcess(t *testing.T) {
+	in := StreamServerInterceptor(Limit{Rate: 0.001, Burst: 2})
+	var got []int
+	err := in(nil, &fakeStream{msgs: []int{1, 2, 3, 4}}, &grpc.StreamServerInfo{IsClientStream: true},
+		func(_ any, ss grpc.ServerStream) error {
+			for {
+				var v int
+				if err := ss.RecvMsg(&v); err != nil {
+					return err
+				}
+				got = append(got, v)
+			}
+		})
+	if err != io.EOF || len(got) != 2 || got[0] != 1 || got[1] != 2 {
+		t.Fatalf("got %v, %v", got, err)
+	}
+}
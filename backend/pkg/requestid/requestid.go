@@ -0,0 +1,52 @@
+// Package requestid generates and propagates the request/connection ID that
+// ties log lines together across services. The edge (nginx) sets X-Request-ID;
+// services accept it, generate one if missing, and forward it over gRPC
+// metadata.
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+const (
+	// Header is the HTTP header carrying the ID.
+	Header = "X-Request-ID"
+	// MetadataKey is the gRPC metadata key carrying the ID.
+	MetadataKey = "x-request-id"
+)
+
+type ctxKey struct{}
+
+// New returns a random 128-bit hex ID.
+func New() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// NewContext returns ctx carrying id.
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, id)
+}
+
+// FromContext returns the ID stored in ctx, or "" if none.
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(ctxKey{}).(string)
+	return id
+}
+
+// Middleware reads X-Request-ID (generating one when absent), echoes it on the
+// response and stores it in the request context.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(Header)
+		if id == "" || len(id) > 128 {
+			id = New()
+		}
+		w.Header().Set(Header, id)
+		next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), id)))
+	})
+}
@@ -0,0 +1,48 @@
+package requestid
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"google.golang.org/grpc/metadata"
+)
+
+func TestMiddleware_KeepsIncomingID(t *testing.T) {
+	var got string
+	h := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = FromContext(r.Context())
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/ws", nil)
+	req.Header.Set(Header, "edge-id")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if got != "edge-id" {
+		t.Fatalf("context id = %q, want edge-id", got)
+	}
+	if rec.Header().Get(Header) != "edge-id" {
+		t.Fatalf("response header = %q, want edge-id", rec.Header().Get(Header))
+	}
+}
+
+func TestMiddleware_GeneratesID(t *testing.T) {
+	var got string
+	h := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = FromContext(r.Context())
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if len(got) != 32 {
+		t.Fatalf("generated id = %q, want 32 hex chars", got)
+	}
+}
+
+func TestMetadataRoundTrip(t *testing.T) {
+	out := outgoing(NewContext(context.Background(), "abc"))
+	md, _ := metadata.FromOutgoingContext(out)
+	in := incoming(metadata.NewIncomingContext(context.Background(), md))
+	if id := FromContext(in); id != "abc" {
+		t.Fatalf("incoming id = %q, want abc", id)
+	}
+}
@@ -0,0 +1,107 @@
+// Package shutdown coordinates SIGTERM handling for the services: flip the
+// readiness probe first, give load balancers a moment to stop routing, then
+// close listeners, drain WebSocket sessions and stop gRPC servers within a
+// bounded timeout.
+package shutdown
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// RestartReason is the close reason (with code 1012 Service Restart) sent to
+// WebSocket clients when the server goes away; clients should reconnect.
+const RestartReason = "server restarting, reconnect"
+
+// NotifyContext returns a context cancelled on SIGINT or SIGTERM.
+func NotifyContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+}
+
+// DrainDelay is how long a service keeps serving after failing readiness
+// (SHUTDOWN_DRAIN_DELAY, default 5s).
+func DrainDelay() time.Duration { return durationEnv("SHUTDOWN_DRAIN_DELAY", 5*time.Second) }
+
+// Timeout bounds the rest of the shutdown (SHUTDOWN_TIMEOUT, default 20s).
+// Keep it below terminationGracePeriodSeconds minus DrainDelay.
+func Timeout() time.Duration { return durationEnv("SHUTDOWN_TIMEOUT", 20*time.Second) }
+
+func durationEnv(key string, def time.Duration) time.Duration {
+	if v := os.Getenv(key); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+			return d
+		}
+	}
+	return def
+}
+
+// Readiness backs the /readyz probe. It reports ready until Drain is called.
+type Readiness struct {
+	draining atomic.Bool
+}
+
+// Draining reports whether Drain has been called.
+func (r *Readiness) Draining() bool { return r.draining.Load() }
+
+// Drain makes the probe fail and then waits DrainDelay (or ctx) so that the
+// endpoint is removed from Services before listeners close.
+func (r *Readiness) Drain(ctx context.Context) {
+	r.draining.Store(true)
+	t := time.NewTimer(DrainDelay())
+	defer t.Stop()
+	select {
+	case <-t.C:
+	case <-ctx.Done():
+	}
+}
+
+// ServeHTTP answers 200 {"status":"ok"} or 503 {"status":"draining"}.
+func (r *Readiness) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Draining() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_ = json.NewEncoder(w).Encode(map[string]string{"status": "draining"})
+		return
+	}
+	_ = json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
+
+// StopGRPC calls GracefulStop and falls back to Stop when ctx expires first,
+// so long-lived streams cannot hold the process past its grace period.
+func StopGRPC(ctx context.Context, s *grpc.Server) {
+	done := make(chan struct{})
+	go func() {
+		s.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		s.Stop()
+		<-done
+	}
+}
+
+// Wait blocks until wg is done or ctx expires, returning ctx.Err() in the latter case.
+func Wait(ctx context.Context, wg *sync.WaitGroup) error {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,42 @@
+package shutdown
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestReadiness_FlipsOnDrain(t *testing.T) {
+	t.Setenv("SHUTDOWN_DRAIN_DELAY", "0s")
+	r := &Readiness{}
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("before drain: status = %d, want 200", rec.Code)
+	}
+
+	r.Drain(context.Background())
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("after drain: status = %d, want 503", rec.Code)
+	}
+}
+
+func TestWait_TimesOut(t *testing.T) {
+	var wg sync.WaitGroup
+	wg.Add(1)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := Wait(ctx, &wg); err == nil {
+		t.Fatal("expected timeout error")
+	}
+	wg.Done()
+	if err := Wait(context.Background(), &wg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
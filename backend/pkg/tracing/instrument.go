@@ -0,0 +1,34 @@
+package tracing
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/labstack/echo/otelecho"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"google.golang.org/grpc"
+)
+
+// ServerOption instruments every RPC served by a gRPC server.
+func ServerOption() grpc.ServerOption { return grpc.StatsHandler(otelgrpc.NewServerHandler()) }
+
+// DialOption instruments every RPC made through a gRPC client connection and
+// propagates the caller's trace context in the request metadata.
+func DialOption() grpc.DialOption { return grpc.WithStatsHandler(otelgrpc.NewClientHandler()) }
+
+// EchoMiddleware starts a server span per echo request.
+func EchoMiddleware(service string) echo.MiddlewareFunc { return otelecho.Middleware(service) }
+
+// Handler starts a server span per request served by h. WebSocket upgrades are
+// skipped: they are modelled as session spans by StartSession instead of one
+// HTTP span lasting the whole connection.
+func Handler(h http.Handler, operation string) http.Handler {
+	return otelhttp.NewHandler(h, operation, otelhttp.WithFilter(func(r *http.Request) bool {
+		return !isWebSocketUpgrade(r)
+	}))
+}
+
+// Transport propagates trace context on outbound HTTP calls and records a
+// client span per request.
+func Transport(next http.RoundTripper) http.RoundTripper { return otelhttp.NewTransport(next) }
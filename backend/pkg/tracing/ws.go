@@ -0,0 +1,60 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Session is a span covering the lifetime of one WebSocket connection.
+// Individual frames are recorded as span events rather than child spans; the
+// SDK caps events per span, so very chatty sessions keep only the first ones.
+type Session struct {
+	span trace.Span
+}
+
+// StartSession starts the session span for a WebSocket upgrade on endpoint,
+// continuing any trace context carried in the upgrade request headers.
+func StartSession(r *http.Request, endpoint string, attrs ...attribute.KeyValue) (context.Context, *Session) {
+	ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+	attrs = append(attrs, attribute.String("ws.endpoint", endpoint))
+	ctx, span := Tracer().Start(ctx, "WS "+endpoint,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(attrs...),
+	)
+	return ctx, &Session{span: span}
+}
+
+// Received records an inbound frame.
+func (s *Session) Received(size int, attrs ...attribute.KeyValue) {
+	s.span.AddEvent("ws.message.received", trace.WithAttributes(append(attrs, attribute.Int("ws.message.size", size))...))
+}
+
+// Sent records an outbound frame.
+func (s *Session) Sent(size int, attrs ...attribute.KeyValue) {
+	s.span.AddEvent("ws.message.sent", trace.WithAttributes(append(attrs, attribute.Int("ws.message.size", size))...))
+}
+
+// Event records a named lifecycle event (ready, order placed, ...).
+func (s *Session) Event(name string, attrs ...attribute.KeyValue) {
+	s.span.AddEvent(name, trace.WithAttributes(attrs...))
+}
+
+// End closes the session span. A non-nil err marks the session as failed.
+func (s *Session) End(err error) {
+	if err != nil {
+		s.span.RecordError(err)
+		s.span.SetStatus(codes.Error, err.Error())
+	}
+	s.span.End()
+}
+
+func isWebSocketUpgrade(r *http.Request) bool {
+	return strings.EqualFold(r.Header.Get("Upgrade"), "websocket")
+}
@@ -0,0 +1,44 @@
+package tracing
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestStartSession_ContinuesTraceAndRecordsEvents(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	req := httptest.NewRequest("GET", "/ws?room=r", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	req.Header.Set("Upgrade", "websocket")
+
+	_, s := StartSession(req, "/ws")
+	s.Received(12)
+	s.Sent(30)
+	s.End(nil)
+
+	spans := rec.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	sp := spans[0]
+	if sp.Name() != "WS /ws" {
+		t.Fatalf("unexpected span name: %s", sp.Name())
+	}
+	if got := sp.SpanContext().TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Fatalf("expected trace to continue from traceparent, got %s", got)
+	}
+	if len(sp.Events()) != 2 || sp.Events()[0].Name != "ws.message.received" || sp.Events()[1].Name != "ws.message.sent" {
+		t.Fatalf("unexpected events: %#v", sp.Events())
+	}
+	if !isWebSocketUpgrade(req) {
+		t.Fatalf("expected upgrade request to be detected")
+	}
+}
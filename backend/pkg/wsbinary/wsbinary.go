@@ -0,0 +1,359 @@
+// Package wsbinary is the compact binary encoding of the /ws chant, for
+// clients that negotiate Subprotocol (Sec-WebSocket-Protocol). Phones batch
+// their loudness samples into binary frames, or send raw audio for the
+// server to measure, and get the room average back as binary frames;
+// everything else (participant, session and error frames) stays JSON text,
+// and a text {"value":...} is still accepted.
+//
+// All integers and floats are little-endian, so a browser can read the
+// samples and the average header with typed arrays. A samples frame is
+//
+//	offset 0  uint8    KindSamples
+//	       1  uint8    n, 1..MaxBatch
+//	       2  uint16   reserved, 0
+//	       4  uint32   seq of the first sample; each sample takes one
+//	       8  float32  × n values
+//
+// which the server places at the time it arrives. A timed samples frame
+// carries when each sample was measured, so network jitter does not move it:
+//
+//	offset 0  uint8    KindTimedSamples
+//	       1  uint8    n, 1..MaxBatch
+//	       2  uint16   reserved, 0
+//	       4  uint32   seq of the first sample
+//	       8  float64  t0, Unix milliseconds on the client's clock
+//	      16  float32  × n values
+//	   16+4n  uint16   × n milliseconds after t0 of each value
+//
+// An audio frame carries raw microphone audio for the server to measure,
+// instead of a loudness the phone computed:
+//
+//	offset 0  uint8    KindAudio
+//	       1  uint8    codec, AudioPCM16 or AudioOpus
+//	       2  uint16   reserved, 0
+//	       4  uint32   seq; each audio frame takes one
+//	       8  float64  t0, Unix milliseconds on the client's clock when the
+//	                   first sample was captured, 0 if unknown
+//	      16  uint32   sample rate in Hz
+//	      20  mono int16 PCM samples, or Opus packets each preceded by its
+//	          uint16 length; at most MaxAudio bytes
+//
+// An average frame is
+//
+//	offset 0  uint8    KindAverage
+//	       1  uint8    participants in the breakdown, at most 255
+//	       2  uint16   reserved, 0
+//	       4  uint32   count
+//	       8  float32  average
+//	      12  participants, each:
+//	            float32 mean, uint32 count,
+//	            uint8 id length, id, uint8 name length, name (UTF-8)
+//
+// The breakdown leaves out last_seen, which the JSON frame carries.
+package wsbinary
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+)
+
+// Subprotocol selects this encoding.
+const Subprotocol = "kakigori.bin.v1"
+
+// Frame kinds, the first byte of every frame.
+const (
+	KindSamples      byte = 0x01
+	KindAverage      byte = 0x02
+	KindTimedSamples byte = 0x03
+	KindAudio        byte = 0x04
+)
+
+// Audio codecs.
+const (
+	AudioPCM16 byte = 0x01
+	AudioOpus  byte = 0x02
+)
+
+// MaxAudio caps an audio frame's payload: a second of 48kHz PCM fits.
+const MaxAudio = 1 << 17
+
+// Sample rates an audio frame may declare.
+const (
+	MinSampleRate = 8000
+	MaxSampleRate = 48000
+)
+
+// MaxBatch is the most samples one frame may carry. The inbound message
+// rate limit counts frames, so it also caps the samples a client can send.
+const MaxBatch = 16
+
+const headerSize = 8
+
+// ErrMalformed is returned, wrapped, for frames that do not parse.
+var ErrMalformed = errors.New("wsbinary: malformed frame")
+
+// Samples is one batch: Values[i] has sequence number Seq+i and, in a timed
+// batch, was measured at ClientTimes[i] (Unix milliseconds on the client's
+// clock).
+type Samples struct {
+	Seq         uint32
+	Values      []float32
+	ClientTimes []int64
+}
+
+// AppendSamples appends a samples frame to b, a timed one when ClientTimes
+// is set. It panics if Values is empty or longer than MaxBatch, or if
+// ClientTimes does not match Values or spans more than a minute.
+func AppendSamples(b []byte, s Samples) []byte {
+	n := len(s.Values)
+	if n == 0 || n > MaxBatch {
+		panic(fmt.Sprintf("wsbinary: batch of %d samples", n))
+	}
+	kind := KindSamples
+	if s.ClientTimes != nil {
+		kind = KindTimedSamples
+	}
+	b = append(b, kind, byte(n), 0, 0)
+	b = binary.LittleEndian.AppendUint32(b, s.Seq)
+	var t0 int64
+	if kind == KindTimedSamples {
+		if len(s.ClientTimes) != n {
+			panic(fmt.Sprintf("wsbinary: %d times for %d samples", len(s.ClientTimes), n))
+		}
+		t0 = slices.Min(s.ClientTimes)
+		if slices.Max(s.ClientTimes)-t0 > math.MaxUint16 {
+			panic("wsbinary: batch spans more than a minute")
+		}
+		b = binary.LittleEndian.AppendUint64(b, math.Float64bits(float64(t0)))
+	}
+	for _, v := range s.Values {
+		b = binary.LittleEndian.AppendUint32(b, math.Float32bits(v))
+	}
+	for _, t := range s.ClientTimes {
+		b = binary.LittleEndian.AppendUint16(b, uint16(t-t0))
+	}
+	return b
+}
+
+// ParseSamples decodes a samples or timed samples frame.
+func ParseSamples(data []byte) (Samples, error) {
+	if len(data) < headerSize || (data[0] != KindSamples && data[0] != KindTimedSamples) {
+		return Samples{}, fmt.Errorf("%w: not a samples frame", ErrMalformed)
+	}
+	n := int(data[1])
+	if n == 0 || n > MaxBatch {
+		return Samples{}, fmt.Errorf("%w: batch of %d samples", ErrMalformed, n)
+	}
+	timed := data[0] == KindTimedSamples
+	size, values := headerSize+4*n, headerSize
+	if timed {
+		size, values = headerSize+8+6*n, headerSize+8
+	}
+	if len(data) != size {
+		return Samples{}, fmt.Errorf("%w: %d bytes for %d samples", ErrMalformed, len(data), n)
+	}
+	s := Samples{Seq: binary.LittleEndian.Uint32(data[4:]), Values: make([]float32, n)}
+	for i := range s.Values {
+		s.Values[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[values+4*i:]))
+	}
+	if timed {
+		t0 := math.Float64frombits(binary.LittleEndian.Uint64(data[headerSize:]))
+		if math.IsNaN(t0) || t0 < 0 || t0 > maxUnixMs {
+			return Samples{}, fmt.Errorf("%w: t0 %v", ErrMalformed, t0)
+		}
+		s.ClientTimes = make([]int64, n)
+		for i := range s.ClientTimes {
+			s.ClientTimes[i] = int64(t0) + int64(binary.LittleEndian.Uint16(data[values+4*n+2*i:]))
+		}
+	}
+	return s, nil
+}
+
+// maxUnixMs bounds t0 to the integers a float64 holds exactly.
+const maxUnixMs = 1 << 53
+
+// Audio is one audio frame.
+type Audio struct {
+	Seq        uint32
+	Codec      byte
+	SampleRate int
+	ClientTime int64 // Unix milliseconds, 0 if unknown
+	Data       []byte
+}
+
+const audioHeaderSize = 20
+
+// AppendAudio appends an audio frame to b. It panics if Data is empty or
+// longer than MaxAudio.
+func AppendAudio(b []byte, a Audio) []byte {
+	if len(a.Data) == 0 || len(a.Data) > MaxAudio {
+		panic(fmt.Sprintf("wsbinary: %d bytes of audio", len(a.Data)))
+	}
+	b = append(b, KindAudio, a.Codec, 0, 0)
+	b = binary.LittleEndian.AppendUint32(b, a.Seq)
+	b = binary.LittleEndian.AppendUint64(b, math.Float64bits(float64(a.ClientTime)))
+	b = binary.LittleEndian.AppendUint32(b, uint32(a.SampleRate))
+	return append(b, a.Data...)
+}
+
+// ParseAudio decodes an audio frame. The payload is checked for size only;
+// Data aliases data.
+func ParseAudio(data []byte) (Audio, error) {
+	if len(data) < audioHeaderSize || data[0] != KindAudio {
+		return Audio{}, fmt.Errorf("%w: not an audio frame", ErrMalformed)
+	}
+	a := Audio{
+		Seq:        binary.LittleEndian.Uint32(data[4:]),
+		Codec:      data[1],
+		SampleRate: int(binary.LittleEndian.Uint32(data[16:])),
+		Data:       data[audioHeaderSize:],
+	}
+	if a.Codec != AudioPCM16 && a.Codec != AudioOpus {
+		return Audio{}, fmt.Errorf("%w: codec %#x", ErrMalformed, a.Codec)
+	}
+	if a.SampleRate < MinSampleRate || a.SampleRate > MaxSampleRate {
+		return Audio{}, fmt.Errorf("%w: sample rate %d", ErrMalformed, a.SampleRate)
+	}
+	if len(a.Data) == 0 || len(a.Data) > MaxAudio || (a.Codec == AudioPCM16 && len(a.Data)%2 != 0) {
+		return Audio{}, fmt.Errorf("%w: %d bytes of audio", ErrMalformed, len(a.Data))
+	}
+	t0 := math.Float64frombits(binary.LittleEndian.Uint64(data[8:]))
+	if math.IsNaN(t0) || t0 < 0 || t0 > maxUnixMs {
+		return Audio{}, fmt.Errorf("%w: t0 %v", ErrMalformed, t0)
+	}
+	a.ClientTime = int64(t0)
+	return a, nil
+}
+
+// Average is a room average with its per-participant breakdown.
+type Average struct {
+	Average      float64
+	Count        int
+	Participants []Participant
+}
+
+// Participant is one breakdown entry.
+type Participant struct {
+	ID    string
+	Name  string
+	Mean  float64
+	Count int
+}
+
+// AppendAverage appends an average frame to b. Participants past the 255th
+// are left out and IDs and names longer than 255 bytes are cut.
+func AppendAverage(b []byte, a Average) []byte {
+	ps := a.Participants[:min(len(a.Participants), math.MaxUint8)]
+	b = append(b, KindAverage, byte(len(ps)), 0, 0)
+	b = binary.LittleEndian.AppendUint32(b, clampUint32(a.Count))
+	b = binary.LittleEndian.AppendUint32(b, math.Float32bits(float32(a.Average)))
+	for _, p := range ps {
+		b = binary.LittleEndian.AppendUint32(b, math.Float32bits(float32(p.Mean)))
+		b = binary.LittleEndian.AppendUint32(b, clampUint32(p.Count))
+		b = appendString(b, p.ID)
+		b = appendString(b, p.Name)
+	}
+	return b
+}
+
+// ParseAverage decodes an average frame.
+func ParseAverage(data []byte) (Average, error) {
+	if len(data) < headerSize+4 || data[0] != KindAverage {
+		return Average{}, fmt.Errorf("%w: not an average frame", ErrMalformed)
+	}
+	a := Average{
+		Count:   int(binary.LittleEndian.Uint32(data[4:])),
+		Average: float64(math.Float32frombits(binary.LittleEndian.Uint32(data[8:]))),
+	}
+	n := int(data[1])
+	rest := data[headerSize+4:]
+	for range n {
+		if len(rest) < 8 {
+			return Average{}, fmt.Errorf("%w: truncated participant", ErrMalformed)
+		}
+		p := Participant{
+			Mean:  float64(math.Float32frombits(binary.LittleEndian.Uint32(rest))),
+			Count: int(binary.LittleEndian.Uint32(rest[4:])),
+		}
+		var ok bool
+		if p.ID, rest, ok = readString(rest[8:]); !ok {
+			return Average{}, fmt.Errorf("%w: truncated participant", ErrMalformed)
+		}
+		if p.Name, rest, ok = readString(rest); !ok {
+			return Average{}, fmt.Errorf("%w: truncated participant", ErrMalformed)
+		}
+		a.Participants = append(a.Participants, p)
+	}
+	if len(rest) != 0 {
+		return Average{}, fmt.Errorf("%w: %d trailing bytes", ErrMalformed, len(rest))
+	}
+	return a, nil
+}
+
+func appendString(b []byte, s string) []byte {
+	s = s[:min(len(s), math.MaxUint8)]
+	return append(append(b, byte(len(s))), s...)
+}
+
+func readString(b []byte) (string, []byte, bool) {
+	if len(b) < 1 || len(b) < 1+int(b[0]) {
+		return "", nil, false
+	}
+	n := int(b[0])
+	return string(b[1 : 1+n]), b[1+n:], true
+}
+
+func clampUint32(n int) uint32 {
+	return uint32(min(max(n, 0), math.MaxUint32))
+}
+
+// Sequencer drops samples a client sent twice, e.g. a batch it resent after
+// resuming its session. The zero value expects any first batch.
+type Sequencer struct {
+	next    uint32
+	started bool
+}
+
+// Fresh returns the part of s not seen before, and how many samples were
+// skipped since the previous batch (lost on the way, or never sent).
+// Sequence numbers are expected to grow for the session's lifetime; at the
+// default rate limit uint32 lasts years.
+func (q *Sequencer) Fresh(s Samples) (fresh Samples, lost uint32) {
+	seen, lost := q.advance(s.Seq, len(s.Values))
+	if seen == 0 {
+		return s, lost
+	}
+	fresh = Samples{Seq: s.Seq + seen, Values: s.Values[seen:]}
+	if s.ClientTimes != nil {
+		fresh.ClientTimes = s.ClientTimes[seen:]
+	}
+	return fresh, lost
+}
+
+// FreshAudio is Fresh for an audio frame, which takes one sequence number.
+func (q *Sequencer) FreshAudio(a Audio) (fresh bool, lost uint32) {
+	seen, lost := q.advance(a.Seq, 1)
+	return seen == 0, lost
+}
+
+// advance takes the n sequence numbers from seq on and returns how many of
+// them were seen before and how many were skipped since the previous call.
+func (q *Sequencer) advance(seq uint32, n int) (seen, lost uint32) {
+	if !q.started {
+		q.started, q.next = true, seq
+	}
+	end := seq + uint32(n)
+	switch {
+	case end <= q.next:
+		return uint32(n), 0
+	case seq < q.next:
+		seen = q.next - seq
+	default:
+		lost = seq - q.next
+	}
+	q.next = end
+	return seen, lost
+}
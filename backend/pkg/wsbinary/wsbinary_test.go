@@ -0,0 +1,153 @@
+package wsbinary
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestSamples_RoundTrip(t *testing.T) {
+	want := Samples{Seq: 41, Values: []float32{0.25, 0.5, 0.875}}
+	b := AppendSamples(nil, want)
+	if len(b) != headerSize+4*3 {
+		t.Fatalf("len = %d", len(b))
+	}
+	got, err := ParseSamples(b)
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v, %v", got, err)
+	}
+}
+
+func TestTimedSamples_RoundTrip(t *testing.T) {
+	want := Samples{Seq: 7, Values: []float32{0.25, 0.5}, ClientTimes: []int64{1760000000250, 1760000000050}}
+	b := AppendSamples(nil, want)
+	if b[0] != KindTimedSamples || len(b) != headerSize+8+6*2 {
+		t.Fatalf("frame % x", b)
+	}
+	got, err := ParseSamples(b)
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v, %v", got, err)
+	}
+	if _, err := ParseSamples(b[:len(b)-2]); !errors.Is(err, ErrMalformed) {
+		t.Fatalf("truncated times: %v", err)
+	}
+}
+
+func TestParseSamples_Malformed(t *testing.T) {
+	ok := AppendSamples(nil, Samples{Values: []float32{0.5, 0.5}})
+	tooMany := append([]byte{KindSamples, MaxBatch + 1, 0, 0, 0, 0, 0, 0}, make([]byte, 4*(MaxBatch+1))...)
+	for name, data := range map[string][]byte{
+		"empty":     nil,
+		"average":   AppendAverage(nil, Average{}),
+		"no values": ok[:headerSize],
+		"truncated": ok[:len(ok)-1],
+		"trailing":  append(slices.Clone(ok), 0),
+		"too many":  tooMany,
+	} {
+		if _, err := ParseSamples(data); !errors.Is(err, ErrMalformed) {
+			t.Errorf("%s: err = %v", name, err)
+		}
+	}
+}
+
+func TestAudio_RoundTrip(t *testing.T) {
+	want := Audio{Seq: 3, Codec: AudioPCM16, SampleRate: 16000, ClientTime: 1760000000000, Data: []byte{1, 0, 0xff, 0x7f}}
+	b := AppendAudio(nil, want)
+	got, err := ParseAudio(b)
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v, %v", got, err)
+	}
+	for name, frame := range map[string][]byte{
+		"odd pcm":  b[:len(b)-1],
+		"no audio": b[:audioHeaderSize],
+		"codec":    append([]byte{KindAudio, 9}, b[2:]...),
+		"rate":     AppendAudio(nil, Audio{Codec: AudioOpus, SampleRate: 96000, Data: []byte{0}}),
+		"samples":  AppendSamples(nil, Samples{Values: []float32{1}}),
+	} {
+		if _, err := ParseAudio(frame); !errors.Is(err, ErrMalformed) {
+			t.Errorf("%s: err = %v", name, err)
+		}
+	}
+}
+
+func TestAverage_RoundTrip(t *testing.T) {
+	want := Average{Average: 0.5, Count: 75, Participants: []Participant{
+		{ID: "p-1", Name: "かき氷", Mean: 0.25, Count: 25},
+		{ID: "p-2", Mean: 0.75, Count: 50},
+	}}
+	b := AppendAverage(nil, want)
+	got, err := ParseAverage(b)
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v, %v", got, err)
+	}
+	for i := range len(b) {
+		if _, err := ParseAverage(b[:i]); !errors.Is(err, ErrMalformed) {
+			t.Fatalf("parsed %d of %d bytes: %v", i, len(b), err)
+		}
+	}
+}
+
+// The point of the encoding: a busy room's frame is a fraction of the JSON.
+func TestAverage_SmallerThanJSON(t *testing.T) {
+	type participant struct {
+		ID       string  `json:"id"`
+		Name     string  `json:"name,omitempty"`
+		Mean     float64 `json:"mean"`
+		Count    int     `json:"count"`
+		LastSeen string  `json:"last_seen,omitempty"`
+	}
+	a := Average{Average: 0.43219876, Count: 250}
+	var ps []participant
+	for range 10 {
+		p := Participant{ID: "3f9c2a7be1d04c55", Name: "かき氷", Mean: 0.41234567, Count: 25}
+		a.Participants = append(a.Participants, p)
+		ps = append(ps, participant{p.ID, p.Name, p.Mean, p.Count, "2025-10-18T12:34:56.789+09:00"})
+	}
+	js, _ := json.Marshal(map[string]any{"average": a.Average, "count": a.Count, "participants": ps})
+	if bin := AppendAverage(nil, a); 2*len(bin) > len(js) {
+		t.Fatalf("binary %d bytes, JSON %d", len(bin), len(js))
+	}
+}
+
+func TestSequencer(t *testing.T) {
+	var q Sequencer
+	batch := func(seq uint32, n int) Samples {
+		s := Samples{Seq: seq}
+		for i := range n {
+			s.Values = append(s.Values, float32(seq)+float32(i))
+		}
+		return s
+	}
+	for _, tc := range []struct {
+		in       Samples
+		want     []float32
+		wantLost uint32
+	}{
+		{batch(10, 3), []float32{10, 11, 12}, 0}, // any start
+		{batch(13, 2), []float32{13, 14}, 0},
+		{batch(13, 2), nil, 0},               // resent
+		{batch(14, 3), []float32{15, 16}, 0}, // overlapping
+		{batch(20, 1), []float32{20}, 3},     // 17..19 lost
+		{batch(5, 1), nil, 0},                // stale
+		{batch(21, 1), []float32{21}, 0},
+	} {
+		got, lost := q.Fresh(tc.in)
+		if !slices.Equal(got.Values, tc.want) || lost != tc.wantLost {
+			t.Fatalf("Fresh(%d+%d) = %v, %d; want %v, %d", tc.in.Seq, len(tc.in.Values), got.Values, lost, tc.want, tc.wantLost)
+		}
+	}
+	// times stay with their values
+	timed := Samples{Seq: 20, Values: []float32{1, 2, 3}, ClientTimes: []int64{100, 200, 300}}
+	if got, _ := q.Fresh(timed); got.Seq != 22 || !slices.Equal(got.ClientTimes, []int64{300}) {
+		t.Fatalf("Fresh(timed) = %+v", got)
+	}
+	// audio frames take one number each, from the same sequence
+	if fresh, lost := q.FreshAudio(Audio{Seq: 23}); !fresh || lost != 0 {
+		t.Fatalf("FreshAudio(23) = %v, %d", fresh, lost)
+	}
+	if fresh, _ := q.FreshAudio(Audio{Seq: 23}); fresh {
+		t.Fatal("FreshAudio(23) again is fresh")
+	}
+}
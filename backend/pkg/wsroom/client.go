@@ -0,0 +1,431 @@
+package wsroom
+
+import (
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"chantingkakigori/pkg/apperror"
+	"chantingkakigori/pkg/metrics"
+
+	"github.com/gorilla/websocket"
+	"golang.org/x/time/rate"
+)
+
+// SlowConsumerReason is the close reason sent to evicted clients.
+const SlowConsumerReason = "slow consumer"
+
+// RateLimitReason is the close reason sent to clients that keep flooding.
+const RateLimitReason = "rate limit exceeded"
+
+// Client is one participant. It is backed by a WebSocket connection that may
+// be replaced when the participant resumes its session after a drop. All
+// writes go through its queue and a single writer goroutine per connection,
+// so it is safe to use from any goroutine.
+type Client struct {
+	// ID identifies the participant in logs (the request ID of the first
+	// connection when available).
+	ID string
+	// Token resumes the session on reconnect; empty when resumption is off.
+	Token string
+	// Protocol is the subprotocol negotiated on upgrade, empty when the
+	// client offered none of Options.Subprotocols. A resume must negotiate
+	// the same one.
+	Protocol string
+
+	hub  *Hub
+	room atomic.Pointer[Room]
+
+	connMu sync.Mutex
+	conn   *websocket.Conn
+	stop   chan struct{} // stops the current writer
+	done   chan struct{} // closed when the current writer has exited
+
+	resume chan *websocket.Conn
+
+	limiter *rate.Limiter // inbound messages; nil when unlimited
+	limited int           // messages dropped since the last allowed one
+
+	send chan message
+
+	latestMu sync.Mutex
+	latest   map[string]message // coalesced frames by key, newest wins
+	wake     chan struct{}
+
+	closeOnce  sync.Once
+	closing    chan struct{} // closed by Close; writer flushes then closes
+	closeFrame []byte
+
+	quitOnce sync.Once
+	quit     chan struct{} // closed when the session ends
+}
+
+// message is a queued data frame.
+type message struct {
+	typ  int // websocket.TextMessage or websocket.BinaryMessage
+	data []byte
+}
+
+func newClient(h *Hub, conn *websocket.Conn, id string) *Client {
+	return &Client{
+		ID:      id,
+		hub:     h,
+		conn:    conn,
+		resume:  make(chan *websocket.Conn, 1),
+		limiter: h.opts.MessageRate.NewLimiter(),
+		send:    make(chan message, h.opts.SendBuffer),
+		latest:  make(map[string]message),
+		wake:    make(chan struct{}, 1),
+		closing: make(chan struct{}),
+		quit:    make(chan struct{}),
+	}
+}
+
+// Room returns the room c joined, or nil.
+func (c *Client) Room() *Room { return c.room.Load() }
+
+// Done is closed once the session has ended.
+func (c *Client) Done() <-chan struct{} { return c.quit }
+
+// Send queues a text message without blocking. It returns false if the
+// client is closing or the frame was dropped under the hub's overflow policy.
+// Messages sent while the participant is disconnected wait for a resume.
+func (c *Client) Send(data []byte) bool {
+	return c.SendMessage(websocket.TextMessage, data)
+}
+
+// SendMessage is Send for a message of type msgType (websocket.TextMessage
+// or websocket.BinaryMessage).
+func (c *Client) SendMessage(msgType int, data []byte) bool {
+	if c.closed() {
+		return false
+	}
+	m := message{typ: msgType, data: data}
+	select {
+	case c.send <- m:
+		return true
+	default:
+	}
+	switch c.hub.opts.Overflow {
+	case PolicyDropNewest:
+		c.dropped("overflow", 1)
+		return false
+	case PolicyDropOldest:
+		select {
+		case <-c.send:
+			c.dropped("overflow", 1)
+		default:
+		}
+		select {
+		case c.send <- m:
+			return true
+		default:
+			c.dropped("overflow", 1)
+			return false
+		}
+	default:
+		c.evict()
+		return false
+	}
+}
+
+// SendLatest queues a text message that supersedes any not-yet-written
+// message with the same key, for frames where only the newest value matters
+// (room averages). It never overflows the queue.
+func (c *Client) SendLatest(key string, data []byte) bool {
+	return c.SendLatestMessage(key, websocket.TextMessage, data)
+}
+
+// SendLatestMessage is SendLatest for a message of type msgType.
+func (c *Client) SendLatestMessage(key string, msgType int, data []byte) bool {
+	if c.closed() {
+		return false
+	}
+	c.latestMu.Lock()
+	if _, ok := c.latest[key]; ok {
+		c.dropped("coalesced", 1)
+	}
+	c.latest[key] = message{typ: msgType, data: data}
+	c.latestMu.Unlock()
+	select {
+	case c.wake <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+func (c *Client) closed() bool {
+	select {
+	case <-c.closing:
+		return true
+	case <-c.quit:
+		return true
+	default:
+		return false
+	}
+}
+
+func (c *Client) evict() {
+	metrics.WSEvictions.WithLabelValues(c.hub.opts.Endpoint).Inc()
+	c.latestMu.Lock()
+	pending := len(c.send) + len(c.latest) + 1
+	c.latestMu.Unlock()
+	c.dropped("evicted", pending)
+	c.Close(websocket.CloseTryAgainLater, SlowConsumerReason)
+}
+
+func (c *Client) dropped(reason string, n int) {
+	metrics.WSDroppedFrames.WithLabelValues(c.hub.opts.Endpoint, reason).Add(float64(n))
+}
+
+// Close writes whatever is already queued, sends a close frame with code and
+// reason, and closes the connection. The session is not resumable afterwards.
+// Later calls are no-ops.
+func (c *Client) Close(code int, reason string) {
+	c.closeOnce.Do(func() {
+		c.closeFrame = websocket.FormatCloseMessage(code, reason)
+		close(c.closing)
+	})
+}
+
+// sessionFrame tells the client its resume token.
+type sessionFrame struct {
+	Type    string `json:"type"`
+	Token   string `json:"token"`
+	Resumed bool   `json:"resumed"`
+}
+
+func (c *Client) sendSession(resumed bool) {
+	if c.Token == "" {
+		return
+	}
+	b, _ := json.Marshal(sessionFrame{Type: "session", Token: c.Token, Resumed: resumed})
+	c.Send(b)
+}
+
+// Run reads messages until the session ends, passing each to onMessage
+// (which may be nil), then leaves the room and releases the client. If the
+// connection drops without a close handshake and resumption is enabled, Run
+// keeps the participant in its room for Options.ResumeGrace and continues on
+// the new connection when it resumes.
+//
+// Every upgraded client must be Run exactly once; to reject one after
+// upgrading, Close it and then Run it.
+func (c *Client) Run(onMessage func(msgType int, data []byte)) error {
+	defer c.release()
+	for {
+		err := c.read(onMessage)
+		c.detach()
+		if !c.resumable(err) {
+			return err
+		}
+		timer := time.NewTimer(c.hub.opts.ResumeGrace)
+		select {
+		case conn := <-c.resume:
+			timer.Stop()
+			c.attach(conn)
+			c.sendSession(true)
+			if c.hub.opts.OnResume != nil {
+				c.hub.opts.OnResume(c)
+			}
+		case <-timer.C:
+			return err
+		case <-c.closing:
+			timer.Stop()
+			return err
+		}
+	}
+}
+
+// resumable reports whether the participant may come back after err ended
+// its connection. Deliberate closes (ours, or 1000/1001 from the client) end
+// the session.
+func (c *Client) resumable(err error) bool {
+	if c.Token == "" || c.closed() {
+		return false
+	}
+	return !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway)
+}
+
+func (c *Client) read(onMessage func(msgType int, data []byte)) error {
+	c.connMu.Lock()
+	conn := c.conn
+	c.connMu.Unlock()
+	pongWait := c.hub.opts.PongWait
+	_ = conn.SetReadDeadline(time.Now().Add(pongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+	for {
+		msgType, data, err := conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+		_ = conn.SetReadDeadline(time.Now().Add(pongWait))
+		c.counted("in", len(data))
+		if !c.allowMessage() {
+			continue
+		}
+		if onMessage != nil {
+			onMessage(msgType, data)
+		}
+	}
+}
+
+// allowMessage applies Options.MessageRate to one inbound message. The first
+// dropped message of a run gets a rate_limited error frame; a run longer than
+// the burst closes the session with 1008 Policy Violation.
+func (c *Client) allowMessage() bool {
+	if c.limiter == nil || c.limiter.Allow() {
+		c.limited = 0
+		return true
+	}
+	metrics.RateLimited.WithLabelValues("ws", c.hub.opts.Endpoint).Inc()
+	c.limited++
+	switch {
+	case c.limited == 1:
+		c.Send(apperror.WSFrame(apperror.New(apperror.CodeRateLimited, "message rate exceeded, slow down")))
+	case c.limited > c.hub.opts.MessageRate.Burst:
+		c.Close(websocket.ClosePolicyViolation, RateLimitReason)
+	}
+	return false
+}
+
+// attach makes conn the client's connection and starts its writer.
+func (c *Client) attach(conn *websocket.Conn) {
+	c.connMu.Lock()
+	defer c.connMu.Unlock()
+	c.conn = conn
+	c.stop = make(chan struct{})
+	c.done = make(chan struct{})
+	go c.writeLoop(conn, c.stop, c.done)
+}
+
+// detach stops the current writer and closes the current connection.
+func (c *Client) detach() {
+	c.connMu.Lock()
+	conn, stop, done := c.conn, c.stop, c.done
+	c.stop = nil
+	c.connMu.Unlock()
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+	_ = conn.Close()
+}
+
+// takeover hands a resuming connection to the session, dropping the old
+// connection if the server had not noticed it was gone yet.
+func (c *Client) takeover(conn *websocket.Conn) bool {
+	select {
+	case c.resume <- conn:
+	default:
+		return false
+	}
+	c.connMu.Lock()
+	old := c.conn
+	c.connMu.Unlock()
+	if old != conn {
+		_ = old.Close()
+	}
+	return true
+}
+
+func (c *Client) release() {
+	c.hub.leave(c)
+	c.quitOnce.Do(func() { close(c.quit) })
+	// a resume that raced with the end of the session
+	select {
+	case conn := <-c.resume:
+		_ = conn.Close()
+	default:
+	}
+	c.hub.active.Done()
+}
+
+func (c *Client) writeLoop(conn *websocket.Conn, stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(c.hub.opts.PingPeriod)
+	defer ticker.Stop()
+	// frames coalesced while detached
+	if err := c.writeLatest(conn); err != nil {
+		_ = conn.Close()
+		return
+	}
+	for {
+		select {
+		case m := <-c.send:
+			if err := c.write(conn, m); err != nil {
+				_ = conn.Close()
+				return
+			}
+		case <-c.wake:
+			if err := c.writeLatest(conn); err != nil {
+				_ = conn.Close()
+				return
+			}
+		case <-ticker.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(c.hub.opts.WriteWait)); err != nil {
+				_ = conn.Close()
+				return
+			}
+		case <-c.closing:
+			c.flush(conn)
+			_ = conn.WriteControl(websocket.CloseMessage, c.closeFrame, time.Now().Add(c.hub.opts.WriteWait))
+			_ = conn.Close()
+			return
+		case <-stop:
+			return
+		}
+	}
+}
+
+// writeLatest writes and clears the coalesced frames.
+func (c *Client) writeLatest(conn *websocket.Conn) error {
+	c.latestMu.Lock()
+	frames := c.latest
+	c.latest = make(map[string]message, len(frames))
+	c.latestMu.Unlock()
+	for _, m := range frames {
+		if err := c.write(conn, m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// flush writes messages queued before Close, without blocking for new ones.
+func (c *Client) flush(conn *websocket.Conn) {
+	if err := c.writeLatest(conn); err != nil {
+		return
+	}
+	for {
+		select {
+		case m := <-c.send:
+			if err := c.write(conn, m); err != nil {
+				return
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (c *Client) write(conn *websocket.Conn, m message) error {
+	_ = conn.SetWriteDeadline(time.Now().Add(c.hub.opts.WriteWait))
+	if err := conn.WriteMessage(m.typ, m.data); err != nil {
+		return err
+	}
+	c.counted("out", len(m.data))
+	return nil
+}
+
+// counted adds a data frame's payload to the traffic metric.
+func (c *Client) counted(direction string, n int) {
+	protocol := c.Protocol
+	if protocol == "" {
+		protocol = "none"
+	}
+	metrics.WSBytes.WithLabelValues(c.hub.opts.Endpoint, direction, protocol).Add(float64(n))
+}
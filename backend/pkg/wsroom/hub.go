@@ -0,0 +1,236 @@
+// Package wsroom is the WebSocket session layer shared by the gateway
+// services: an upgrader, a hub of rooms keyed by ID, and clients with a
+// buffered send queue drained by a single writer goroutine that also sends
+// heartbeats. A full queue never blocks a broadcast: depending on the Policy
+// the frame is dropped or the client is evicted, and frames sent with
+// Client.SendLatest are coalesced so only the newest one per key is written.
+//
+// A handler upgrades with Hub.Upgrade, joins a room with Hub.Join and then
+// blocks in Client.Run, which reads until the session ends and cleans up
+// (leaves the room, removes it when empty, stops the writer).
+//
+// With Options.ResumeGrace set, every new client first receives
+// {"type":"session","token":"..."}; a client whose connection drops may
+// reconnect with ?resume=<token> within the grace period and continue the same
+// session: it keeps its room slot and per-room state, the original handler's
+// Run carries on with the new connection, and Options.OnResume replays state.
+package wsroom
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"chantingkakigori/pkg/ratelimit"
+	"chantingkakigori/pkg/requestid"
+	"chantingkakigori/pkg/shutdown"
+
+	"github.com/gorilla/websocket"
+)
+
+// Defaults used for zero Options fields.
+const (
+	DefaultPongWait   = 60 * time.Second
+	DefaultPingPeriod = 30 * time.Second
+	DefaultWriteWait  = 5 * time.Second
+	DefaultSendBuffer = 32
+)
+
+// Options configures a Hub. Zero values fall back to the defaults above.
+type Options struct {
+	// Endpoint labels the hub's metrics (e.g. "/ws").
+	Endpoint string
+
+	// PongWait is how long a connection may stay silent (no pong or message).
+	PongWait time.Duration
+	// PingPeriod must be shorter than PongWait.
+	PingPeriod time.Duration
+	// WriteWait bounds each write, including control frames.
+	WriteWait time.Duration
+	// SendBuffer is the per-client queue length.
+	SendBuffer int
+	// Overflow is applied when a client's queue is full.
+	Overflow Policy
+	// CheckOrigin is passed to the upgrader; nil accepts every origin.
+	CheckOrigin func(r *http.Request) bool
+	// Subprotocols are the Sec-WebSocket-Protocol values the server
+	// accepts, in order of preference. A client offering none of them
+	// still connects, with an empty Client.Protocol.
+	Subprotocols []string
+	// ResumeGrace is how long a dropped participant is held for a resume;
+	// zero disables session tokens.
+	ResumeGrace time.Duration
+	// MessageRate limits inbound messages per participant; the zero Limit
+	// is unlimited. Excess messages are dropped, and a client that keeps
+	// sending a whole burst's worth while limited is closed with 1008.
+	MessageRate ratelimit.Limit
+
+	// NewState creates the per-room value returned by Room.State.
+	NewState func(roomID string) any
+	// OnRoomCreate runs after a room is created by the first Join.
+	OnRoomCreate func(rm *Room)
+	// OnRoomClose runs after the last client left and the room was removed.
+	OnRoomClose func(rm *Room)
+	// OnResume runs after a client resumed, to replay the latest room state.
+	OnResume func(c *Client)
+}
+
+// Hub owns the rooms of one endpoint.
+type Hub struct {
+	opts     Options
+	upgrader websocket.Upgrader
+
+	mu       sync.Mutex
+	rooms    map[string]*Room
+	sessions map[string]*Client // by resume token
+
+	active sync.WaitGroup
+}
+
+// NewHub returns a Hub configured by opts.
+func NewHub(opts Options) *Hub {
+	if opts.PongWait <= 0 {
+		opts.PongWait = DefaultPongWait
+	}
+	if opts.PingPeriod <= 0 {
+		opts.PingPeriod = DefaultPingPeriod
+	}
+	if opts.WriteWait <= 0 {
+		opts.WriteWait = DefaultWriteWait
+	}
+	if opts.SendBuffer <= 0 {
+		opts.SendBuffer = DefaultSendBuffer
+	}
+	checkOrigin := opts.CheckOrigin
+	if checkOrigin == nil {
+		checkOrigin = func(*http.Request) bool { return true }
+	}
+	return &Hub{
+		opts:     opts,
+		upgrader: websocket.Upgrader{CheckOrigin: checkOrigin, Subprotocols: opts.Subprotocols},
+		rooms:    make(map[string]*Room),
+		sessions: make(map[string]*Client),
+	}
+}
+
+// Upgrade upgrades the request and starts the client's writer. On error the
+// upgrader has already replied to the client. The client ID is the request ID
+// when one is present.
+//
+// When the request carries ?resume=<token> of a live session, the connection
+// is handed to that session and resumed is true: the returned client is
+// already joined and Run by the original handler, so the caller must simply
+// return. An unknown or expired token, or a resume negotiating another
+// subprotocol than the session's, starts a new session.
+func (h *Hub) Upgrade(w http.ResponseWriter, r *http.Request) (c *Client, resumed bool, err error) {
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	if token := r.URL.Query().Get("resume"); token != "" && h.opts.ResumeGrace > 0 {
+		h.mu.Lock()
+		c, ok := h.sessions[token]
+		if ok && c.Protocol == conn.Subprotocol() && c.takeover(conn) {
+			h.mu.Unlock()
+			return c, true, nil
+		}
+		h.mu.Unlock()
+	}
+
+	id := requestid.FromContext(r.Context())
+	if id == "" {
+		id = requestid.New()
+	}
+	h.active.Add(1)
+	c = newClient(h, conn, id)
+	c.Protocol = conn.Subprotocol()
+	if h.opts.ResumeGrace > 0 {
+		c.Token = requestid.New()
+		h.mu.Lock()
+		h.sessions[c.Token] = c
+		h.mu.Unlock()
+	}
+	c.attach(conn)
+	c.sendSession(false)
+	return c, false, nil
+}
+
+// Join adds c to the room roomID, creating it if needed, and returns the room
+// and its size including c.
+func (h *Hub) Join(roomID string, c *Client) (*Room, int) {
+	h.mu.Lock()
+	rm, ok := h.rooms[roomID]
+	if !ok {
+		rm = &Room{ID: roomID, clients: make(map[*Client]struct{})}
+		if h.opts.NewState != nil {
+			rm.state = h.opts.NewState(roomID)
+		}
+		h.rooms[roomID] = rm
+	}
+	rm.mu.Lock()
+	rm.clients[c] = struct{}{}
+	n := len(rm.clients)
+	rm.mu.Unlock()
+	h.mu.Unlock()
+
+	c.room.Store(rm)
+	if !ok && h.opts.OnRoomCreate != nil {
+		h.opts.OnRoomCreate(rm)
+	}
+	return rm, n
+}
+
+// leave forgets c's session, removes it from its room and drops the room once
+// empty.
+func (h *Hub) leave(c *Client) {
+	h.mu.Lock()
+	if c.Token != "" {
+		delete(h.sessions, c.Token)
+	}
+	rm := c.room.Load()
+	if rm == nil {
+		h.mu.Unlock()
+		return
+	}
+	rm.mu.Lock()
+	delete(rm.clients, c)
+	empty := len(rm.clients) == 0
+	rm.mu.Unlock()
+	removed := empty && h.rooms[rm.ID] == rm
+	if removed {
+		delete(h.rooms, rm.ID)
+	}
+	h.mu.Unlock()
+
+	if removed && h.opts.OnRoomClose != nil {
+		h.opts.OnRoomClose(rm)
+	}
+}
+
+// Room returns the room with id, or nil.
+func (h *Hub) Room(id string) *Room {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.rooms[id]
+}
+
+// Rooms returns a snapshot of the current rooms.
+func (h *Hub) Rooms() []*Room {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	out := make([]*Room, 0, len(h.rooms))
+	for _, rm := range h.rooms {
+		out = append(out, rm)
+	}
+	return out
+}
+
+// Shutdown closes every client with 1012 Service Restart and waits for their
+// sessions to end or ctx to expire.
+func (h *Hub) Shutdown(ctx context.Context) error {
+	for _, rm := range h.Rooms() {
+		rm.CloseAll(websocket.CloseServiceRestart, shutdown.RestartReason)
+	}
+	return shutdown.Wait(ctx, &h.active)
+}
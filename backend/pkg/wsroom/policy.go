@@ -0,0 +1,76 @@
+package wsroom
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"chantingkakigori/pkg/ratelimit"
+)
+
+// Policy decides what happens when a client's send queue is full.
+type Policy int
+
+const (
+	// PolicyDisconnect evicts the client with close 1013 "slow consumer".
+	PolicyDisconnect Policy = iota
+	// PolicyDropNewest discards the frame being sent.
+	PolicyDropNewest
+	// PolicyDropOldest discards the oldest queued frame to make room.
+	PolicyDropOldest
+)
+
+func (p Policy) String() string {
+	switch p {
+	case PolicyDropNewest:
+		return "drop-newest"
+	case PolicyDropOldest:
+		return "drop-oldest"
+	default:
+		return "disconnect"
+	}
+}
+
+// ParsePolicy parses "disconnect", "drop-newest" or "drop-oldest".
+func ParsePolicy(s string) (Policy, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "disconnect":
+		return PolicyDisconnect, nil
+	case "drop-newest":
+		return PolicyDropNewest, nil
+	case "drop-oldest":
+		return PolicyDropOldest, nil
+	default:
+		return PolicyDisconnect, fmt.Errorf("wsroom: unknown overflow policy %q", s)
+	}
+}
+
+// DefaultResumeGrace is the resume window used by EnvOptions.
+const DefaultResumeGrace = 15 * time.Second
+
+// DefaultMessageRate is the inbound message limit used by EnvOptions. Chant
+// clients send a sample every 200ms; this leaves room for jitter.
+var DefaultMessageRate = ratelimit.MustParse("20/s:40")
+
+// EnvOptions returns Options with SendBuffer, Overflow, ResumeGrace and
+// MessageRate taken from WS_SEND_BUFFER, WS_OVERFLOW_POLICY, WS_RESUME_GRACE
+// ("0s" disables resumption) and WS_MESSAGE_RATE ("off" disables limiting);
+// unset or invalid values keep the defaults.
+func EnvOptions() Options {
+	o := Options{
+		ResumeGrace: DefaultResumeGrace,
+		MessageRate: ratelimit.FromEnv("WS_MESSAGE_RATE", DefaultMessageRate),
+	}
+	if d, err := time.ParseDuration(os.Getenv("WS_RESUME_GRACE")); err == nil && d >= 0 {
+		o.ResumeGrace = d
+	}
+	if n, err := strconv.Atoi(os.Getenv("WS_SEND_BUFFER")); err == nil && n > 0 {
+		o.SendBuffer = n
+	}
+	if p, err := ParsePolicy(os.Getenv("WS_OVERFLOW_POLICY")); err == nil {
+		o.Overflow = p
+	}
+	return o
+}
@@ -0,0 +1,70 @@
+package wsroom
+
+import "testing"
+
+// queued drains c's queue without a writer running.
+func queued(c *Client) []string {
+	var out []string
+	for {
+		select {
+		case b := <-c.send:
+			out = append(out, string(b.data))
+		default:
+			return out
+		}
+	}
+}
+
+func TestOverflowPolicies(t *testing.T) {
+	tests := []struct {
+		policy  Policy
+		want    []string
+		closing bool
+	}{
+		{PolicyDropNewest, []string{"a", "b"}, false},
+		{PolicyDropOldest, []string{"b", "c"}, false},
+		{PolicyDisconnect, []string{"a", "b"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy.String(), func(t *testing.T) {
+			h := NewHub(Options{SendBuffer: 2, Overflow: tt.policy})
+			c := newClient(h, nil, "c1")
+			c.Send([]byte("a"))
+			c.Send([]byte("b"))
+			c.Send([]byte("c"))
+
+			got := queued(c)
+			if len(got) != len(tt.want) || got[0] != tt.want[0] || got[1] != tt.want[1] {
+				t.Fatalf("queue = %v, want %v", got, tt.want)
+			}
+			if c.closed() != tt.closing {
+				t.Fatalf("closing = %v, want %v", c.closed(), tt.closing)
+			}
+		})
+	}
+}
+
+func TestSendLatestCoalesces(t *testing.T) {
+	h := NewHub(Options{})
+	c := newClient(h, nil, "c1")
+	for _, v := range []string{"1", "2", "3"} {
+		c.SendLatest("average", []byte(v))
+	}
+	c.SendLatest("other", []byte("x"))
+
+	if len(c.latest) != 2 || string(c.latest["average"].data) != "3" {
+		t.Fatalf("latest = %v, want average=3 and other=x", c.latest)
+	}
+}
+
+func TestParsePolicy(t *testing.T) {
+	for in, want := range map[string]Policy{"": PolicyDisconnect, "drop-oldest": PolicyDropOldest, "DROP-NEWEST": PolicyDropNewest} {
+		got, err := ParsePolicy(in)
+		if err != nil || got != want {
+			t.Errorf("ParsePolicy(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	if _, err := ParsePolicy("block"); err == nil {
+		t.Error("expected error for unknown policy")
+	}
+}
@@ -0,0 +1,103 @@
+package wsroom
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func readSession(t *testing.T, conn *websocket.Conn) sessionFrame {
+	t.Helper()
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("read session frame: %v", err)
+	}
+	var f sessionFrame
+	if err := json.Unmarshal(data, &f); err != nil || f.Type != "session" || f.Token == "" {
+		t.Fatalf("unexpected first frame %s", data)
+	}
+	return f
+}
+
+func TestResumeKeepsSlotAndReplays(t *testing.T) {
+	resumed := make(chan *Client, 1)
+	h := NewHub(Options{ResumeGrace: 2 * time.Second, OnResume: func(c *Client) { resumed <- c }})
+	srv := newTestServer(t, h)
+
+	conn := dial(t, srv, "r")
+	token := readSession(t, conn).Token
+	waitFor(t, func() bool { return h.Room("r") != nil })
+	first := h.Room("r").Clients()[0]
+
+	// drop without a close handshake, as a phone losing signal would
+	_ = conn.UnderlyingConn().Close()
+	time.Sleep(50 * time.Millisecond)
+	if rm := h.Room("r"); rm == nil || rm.Len() != 1 {
+		t.Fatal("participant slot was not held")
+	}
+	h.Room("r").Broadcast([]byte("missed"))
+
+	conn2 := dial(t, srv, "r&resume="+token)
+	_, data, err := conn2.ReadMessage()
+	if err != nil || string(data) != "missed" {
+		t.Fatalf("read = %q, %v; want queued frame", data, err)
+	}
+	if f := readSession(t, conn2); !f.Resumed || f.Token != token {
+		t.Fatalf("session frame = %+v, want resumed with same token", f)
+	}
+	select {
+	case c := <-resumed:
+		if c != first {
+			t.Fatal("resumed a different client")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("OnResume not called")
+	}
+	if n := h.Room("r").Len(); n != 1 {
+		t.Fatalf("room size = %d, want 1", n)
+	}
+}
+
+func TestResumeGraceExpires(t *testing.T) {
+	h := NewHub(Options{ResumeGrace: 50 * time.Millisecond})
+	srv := newTestServer(t, h)
+	conn := dial(t, srv, "r")
+	readSession(t, conn)
+	waitFor(t, func() bool { return h.Room("r") != nil })
+
+	_ = conn.UnderlyingConn().Close()
+	waitFor(t, func() bool { return h.Room("r") == nil })
+}
+
+func TestNormalCloseIsNotResumable(t *testing.T) {
+	h := NewHub(Options{ResumeGrace: time.Minute})
+	srv := newTestServer(t, h)
+	conn := dial(t, srv, "r")
+	readSession(t, conn)
+	waitFor(t, func() bool { return h.Room("r") != nil })
+
+	_ = conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "bye"))
+	waitFor(t, func() bool { return h.Room("r") == nil })
+}
+
+func TestResumeNeedsSameSubprotocol(t *testing.T) {
+	h := NewHub(Options{ResumeGrace: time.Minute, Subprotocols: []string{"bin"}})
+	srv := newTestServer(t, h)
+	conn := dial(t, srv, "r")
+	token := readSession(t, conn).Token
+	waitFor(t, func() bool { return h.Room("r") != nil })
+	_ = conn.UnderlyingConn().Close()
+
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/?room=r&resume=" + token
+	conn2, _, err := (&websocket.Dialer{Subprotocols: []string{"bin"}}).Dial(url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn2.Close()
+	if f := readSession(t, conn2); f.Resumed || f.Token == token {
+		t.Fatalf("session frame = %+v, want a new session", f)
+	}
+}
@@ -0,0 +1,96 @@
+package wsroom
+
+import "sync"
+
+// Room is a set of clients sharing broadcasts, plus optional per-room state.
+type Room struct {
+	ID string
+
+	mu      sync.Mutex
+	clients map[*Client]struct{}
+	state   any
+}
+
+// State returns the value created by Options.NewState. Guard mutable state
+// with Locked so it stays consistent with membership.
+func (r *Room) State() any { return r.state }
+
+// Len returns the number of clients in the room.
+func (r *Room) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.clients)
+}
+
+// Locked runs fn with the room locked, passing the current size. Join and
+// leave take the same lock, so fn sees a stable membership. fn must not call
+// other Room methods.
+func (r *Room) Locked(fn func(n int)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	fn(len(r.clients))
+}
+
+// Clients returns a snapshot of the room's clients.
+func (r *Room) Clients() []*Client {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]*Client, 0, len(r.clients))
+	for c := range r.clients {
+		out = append(out, c)
+	}
+	return out
+}
+
+// Broadcast queues a text message to every client and returns how many
+// accepted it. It never waits on a slow client.
+func (r *Room) Broadcast(data []byte) int {
+	n := 0
+	for _, c := range r.Clients() {
+		if c.Send(data) {
+			n++
+		}
+	}
+	return n
+}
+
+// BroadcastLatest is Broadcast with Client.SendLatest semantics.
+func (r *Room) BroadcastLatest(key string, data []byte) int {
+	n := 0
+	for _, c := range r.Clients() {
+		if c.SendLatest(key, data) {
+			n++
+		}
+	}
+	return n
+}
+
+// BroadcastLatestBy is BroadcastLatest for rooms whose clients negotiated
+// different subprotocols: encode is called once per subprotocol present and
+// returns the message type and payload for its clients.
+func (r *Room) BroadcastLatestBy(key string, encode func(protocol string) (msgType int, data []byte)) int {
+	type encoded struct {
+		typ  int
+		data []byte
+	}
+	byProtocol := map[string]encoded{}
+	n := 0
+	for _, c := range r.Clients() {
+		e, ok := byProtocol[c.Protocol]
+		if !ok {
+			e.typ, e.data = encode(c.Protocol)
+			byProtocol[c.Protocol] = e
+		}
+		if c.SendLatestMessage(key, e.typ, e.data) {
+			n++
+		}
+	}
+	return n
+}
+
+// CloseAll closes every client after its queued messages are written.
+func (r *Room) CloseAll(code int, reason string) {
+	for _, c := range r.Clients() {
+		c.Close(code, reason)
+	}
+}
@@ -0,0 +1,193 @@
+package wsroom
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"chantingkakigori/pkg/ratelimit"
+
+	"github.com/gorilla/websocket"
+)
+
+func newTestServer(t *testing.T, h *Hub) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, resumed, err := h.Upgrade(w, r)
+		if err != nil || resumed {
+			return
+		}
+		h.Join(r.URL.Query().Get("room"), c)
+		_ = c.Run(nil)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func dial(t *testing.T, srv *httptest.Server, room string) *websocket.Conn {
+	t.Helper()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/?room=" + room
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestBroadcastAndRoomLifecycle(t *testing.T) {
+	var created, closed atomic.Int32
+	h := NewHub(Options{
+		OnRoomCreate: func(*Room) { created.Add(1) },
+		OnRoomClose:  func(*Room) { closed.Add(1) },
+	})
+	srv := newTestServer(t, h)
+
+	a := dial(t, srv, "r1")
+	b := dial(t, srv, "r1")
+	waitFor(t, func() bool { rm := h.Room("r1"); return rm != nil && rm.Len() == 2 })
+
+	if n := h.Room("r1").Broadcast([]byte("hi")); n != 2 {
+		t.Fatalf("recipients = %d, want 2", n)
+	}
+	for _, conn := range []*websocket.Conn{a, b} {
+		_, data, err := conn.ReadMessage()
+		if err != nil || string(data) != "hi" {
+			t.Fatalf("read = %q, %v", data, err)
+		}
+	}
+
+	_ = a.Close()
+	_ = b.Close()
+	waitFor(t, func() bool { return h.Room("r1") == nil })
+	waitFor(t, func() bool { return closed.Load() == 1 })
+	if created.Load() != 1 {
+		t.Fatalf("created = %d, want 1", created.Load())
+	}
+}
+
+func TestCloseFlushesQueuedMessages(t *testing.T) {
+	h := NewHub(Options{})
+	srv := newTestServer(t, h)
+	conn := dial(t, srv, "r")
+	waitFor(t, func() bool { return h.Room("r") != nil })
+
+	rm := h.Room("r")
+	rm.Broadcast([]byte("result"))
+	rm.CloseAll(websocket.CloseNormalClosure, "done")
+
+	_, data, err := conn.ReadMessage()
+	if err != nil || string(data) != "result" {
+		t.Fatalf("read = %q, %v", data, err)
+	}
+	_, _, err = conn.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+		t.Fatalf("expected normal close, got %v", err)
+	}
+}
+
+func TestSlowConsumerIsEvicted(t *testing.T) {
+	h := NewHub(Options{SendBuffer: 1})
+	srv := newTestServer(t, h)
+	_ = dial(t, srv, "r")
+	waitFor(t, func() bool { return h.Room("r") != nil })
+
+	c := h.Room("r").Clients()[0]
+	// Never read on the client side; the queue fills once the socket buffers do.
+	payload := make([]byte, 64<<10)
+	evicted := false
+	for i := 0; i < 10000 && !evicted; i++ {
+		evicted = !c.Send(payload)
+	}
+	if !evicted {
+		t.Fatal("client was not evicted")
+	}
+	waitFor(t, func() bool { return h.Room("r") == nil })
+}
+
+func TestShutdownClosesWithRestart(t *testing.T) {
+	h := NewHub(Options{})
+	srv := newTestServer(t, h)
+	conn := dial(t, srv, "r")
+	waitFor(t, func() bool { return h.Room("r") != nil })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := h.Shutdown(ctx); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+	_, _, err := conn.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.CloseServiceRestart) {
+		t.Fatalf("expected 1012 close, got %v", err)
+	}
+}
+
+func TestMessageFloodIsLimitedThenClosed(t *testing.T) {
+	h := NewHub(Options{MessageRate: ratelimit.Limit{Rate: 1, Burst: 2}})
+	srv := newTestServer(t, h)
+	conn := dial(t, srv, "r")
+
+	for i := 0; i < 10; i++ {
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(`{"value":1}`)); err != nil {
+			break
+		}
+	}
+	_, data, err := conn.ReadMessage()
+	if err != nil || !strings.Contains(string(data), `"code":"rate_limited"`) {
+		t.Fatalf("expected rate_limited error frame, got %q, %v", data, err)
+	}
+	_, _, err = conn.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.ClosePolicyViolation) {
+		t.Fatalf("expected 1008 close, got %v", err)
+	}
+}
+
+func TestSubprotocolsAndBroadcastLatestBy(t *testing.T) {
+	h := NewHub(Options{Subprotocols: []string{"bin"}})
+	srv := newTestServer(t, h)
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/?room=r"
+	bin, _, err := (&websocket.Dialer{Subprotocols: []string{"bin"}}).Dial(url, nil)
+	if err != nil || bin.Subprotocol() != "bin" {
+		t.Fatalf("dial: %v, subprotocol %q", err, bin.Subprotocol())
+	}
+	defer bin.Close()
+	text := dial(t, srv, "r")
+	waitFor(t, func() bool { rm := h.Room("r"); return rm != nil && rm.Len() == 2 })
+
+	var encoded []string
+	n := h.Room("r").BroadcastLatestBy("average", func(protocol string) (int, []byte) {
+		encoded = append(encoded, protocol)
+		if protocol == "bin" {
+			return websocket.BinaryMessage, []byte{1}
+		}
+		return websocket.TextMessage, []byte("1")
+	})
+	if n != 2 || len(encoded) != 2 {
+		t.Fatalf("recipients = %d, encoded for %q", n, encoded)
+	}
+	for _, tc := range []struct {
+		conn *websocket.Conn
+		typ  int
+		data string
+	}{{bin, websocket.BinaryMessage, "\x01"}, {text, websocket.TextMessage, "1"}} {
+		typ, data, err := tc.conn.ReadMessage()
+		if err != nil || typ != tc.typ || string(data) != tc.data {
+			t.Fatalf("read = %d %q, %v; want %d %q", typ, data, err, tc.typ, tc.data)
+		}
+	}
+}
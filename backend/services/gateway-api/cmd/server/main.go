@@ -1,13 +1,27 @@
 package main
 
 import (
-	"log"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
 	"net"
 	"net/http"
 	"os"
 	"time"
 
 	gatewayapiv1 "chantingkakigori/gen/go/gateway_api/v1"
+	kakigoriwsv1 "chantingkakigori/gen/go/kakigori_ws/v1"
+	"chantingkakigori/pkg/apperror"
+	"chantingkakigori/pkg/auth"
+	"chantingkakigori/pkg/grpcjson"
+	"chantingkakigori/pkg/logging"
+	"chantingkakigori/pkg/metrics"
+	"chantingkakigori/pkg/ratelimit"
+	"chantingkakigori/pkg/requestid"
+	"chantingkakigori/pkg/shutdown"
+	"chantingkakigori/pkg/tracing"
+	"chantingkakigori/services/gateway-api/internal/infrastructure/roundstore"
 	"chantingkakigori/services/gateway-api/internal/interface/handler"
 	"chantingkakigori/services/gateway-api/internal/usecase"
 
@@ -15,11 +29,12 @@ import (
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
 )
 
 const (
-	baseURL = "https://kakigori-api.fly.dev"
-	storeID = "HKWZRTNL"
+	defaultStoreAPIURL = "https://kakigori-api.fly.dev"
+	storeID            = "HKWZRTNL"
 )
 
 func init() {
@@ -27,52 +42,135 @@ func init() {
 }
 
 func main() {
+	logging.Init("gateway-api")
+	ctx, stop := shutdown.NotifyContext()
+	defer stop()
 	httpPort := os.Getenv("PORT")
 	if httpPort == "" {
 		httpPort = "8080"
 	}
+	metrics.Register("gateway-api")
+	shutdownTracing, err := tracing.Init(context.Background(), "gateway-api")
+	if err != nil {
+		logging.Fatal("failed to init tracing", logging.Err(err))
+	}
+	defer func() { _ = shutdownTracing(context.Background()) }()
+
+	signer, err := auth.SignerFromEnv()
+	if err != nil {
+		logging.Fatal("failed to init auth", logging.Err(err))
+	}
+	if signer == nil {
+		slog.Warn("authentication disabled (AUTH_DISABLED=true)")
+	}
+	origins := auth.OriginsFromEnv()
+
+	// STORE_API_URL points at a fake store for load tests (cmd/loadbot fake-store)
+	baseURL := os.Getenv("STORE_API_URL")
+	if baseURL == "" {
+		baseURL = defaultStoreAPIURL
+	}
 
 	// DI(Usecase)
 	menuUsecase := usecase.NewMenuUsecase(baseURL)
 	orderUsecase := usecase.NewOrderUsecase(baseURL)
 	chantUsecase, err := usecase.NewChantUsecase()
 	if err != nil {
-		log.Fatalf("failed to init chant usecase: %v", err)
+		logging.Fatal("failed to init chant usecase", logging.Err(err))
+	}
+	// the leaderboard comes from kakigori-ws, whose replicas share the rounds
+	kakigoriAddr := os.Getenv("KAKIGORI_GRPC_ADDR")
+	if kakigoriAddr == "" {
+		kakigoriAddr = "localhost:50051"
 	}
+	kakigoriConn, err := grpc.NewClient(kakigoriAddr,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithChainUnaryInterceptor(metrics.UnaryClientInterceptor("kakigori-ws"), requestid.UnaryClientInterceptor()),
+		tracing.DialOption(),
+	)
+	if err != nil {
+		logging.Fatal("failed to dial kakigori-ws", logging.Err(err))
+	}
+	defer kakigoriConn.Close()
+	leaderboardUsecase := usecase.NewLeaderboardUsecase(kakigoriwsv1.NewKakigoriWsAggregatorServiceClient(kakigoriConn))
+	// rounds are shared between replicas through REDIS_URL
+	roundStore, closeRounds, err := roundstore.FromEnv(ctx)
+	if err != nil {
+		logging.Fatal("failed to open round store", logging.Err(err))
+	}
+	defer func() { _ = closeRounds() }()
+	roundUsecase := usecase.NewRoundUsecase(roundStore)
 
 	// DI(Handler)
 	menuHandler := handler.NewMenuHandler(menuUsecase)
 	orderHandler := handler.NewOrderHandler(orderUsecase)
 	chantHandler := handler.NewChantHandler(chantUsecase)
+	sessionHandler := handler.NewSessionHandler(menuUsecase, signer)
+	leaderboardHandler := handler.NewLeaderboardHandler(leaderboardUsecase)
+	roundHandler := handler.NewRoundHandler(roundUsecase)
 
-	// gRPC server for OrderService
+	// gRPC server for OrderService and RoundService
 	grpcAddr := os.Getenv("GRPC_ADDR")
 	if grpcAddr == "" {
 		grpcAddr = ":9090"
 	}
+	lis, err := net.Listen("tcp", grpcAddr)
+	if err != nil {
+		logging.Fatal("failed to listen gRPC", logging.Err(err))
+	}
+	// protobuf by default; the JSON codecs are there for debugging clients
+	grpcjson.Register()
+	grpcServer := grpc.NewServer(
+		tracing.ServerOption(),
+		grpc.ChainUnaryInterceptor(requestid.UnaryServerInterceptor()),
+	)
+	gatewayapiv1.RegisterOrderServiceServer(grpcServer, handler.NewOrderGRPCServer(orderUsecase, storeID))
+	gatewayapiv1.RegisterRoundServiceServer(grpcServer, handler.NewRoundGRPCServer(roundUsecase))
 	go func() {
-		lis, err := net.Listen("tcp", grpcAddr)
-		if err != nil {
-			log.Fatalf("failed to listen gRPC: %v", err)
-		}
-		s := grpc.NewServer()
-		gatewayapiv1.RegisterOrderServiceServer(s, handler.NewOrderGRPCServer(orderUsecase, storeID))
-		log.Printf("gateway-api gRPC listening on %s", grpcAddr)
-		if err := s.Serve(lis); err != nil {
-			log.Fatalf("gRPC server error: %v", err)
+		slog.Info("gRPC listening", slog.String("addr", grpcAddr))
+		if err := grpcServer.Serve(lis); err != nil {
+			logging.Fatal("gRPC server error", logging.Err(err))
 		}
 	}()
 
+	// Rate limits (RATE_LIMIT_* = "<n>/<s|m|h>[:<burst>]" or "off"). Chant
+	// costs a Gemini call per request; the per-IP limits stay loose since a
+	// venue's attendees share one address.
+	sessionIPLimit := ratelimit.New(ratelimit.FromEnv("RATE_LIMIT_SESSIONS_IP", ratelimit.MustParse("120/m:60")))
+	orderLimit := ratelimit.New(ratelimit.FromEnv("RATE_LIMIT_ORDERS", ratelimit.MustParse("6/m:3")))
+	chantLimit := ratelimit.New(ratelimit.FromEnv("RATE_LIMIT_CHANT", ratelimit.MustParse("6/m:4")))
+	chantIPLimit := ratelimit.New(ratelimit.FromEnv("RATE_LIMIT_CHANT_IP", ratelimit.MustParse("120/m:30")))
+	leaderboardIPLimit := ratelimit.New(ratelimit.FromEnv("RATE_LIMIT_LEADERBOARD_IP", ratelimit.MustParse("120/m:60")))
+	roundsIPLimit := ratelimit.New(ratelimit.FromEnv("RATE_LIMIT_ROUNDS_IP", ratelimit.MustParse("600/m:120")))
+
 	// Routing
 	e := echo.New()
+	e.HTTPErrorHandler = func(err error, c echo.Context) {
+		if c.Response().Committed {
+			return
+		}
+		var he *echo.HTTPError
+		if errors.As(err, &he) {
+			apperror.WriteProblem(c.Response().Writer, c.Request(), apperror.New(httpStatusCode(he.Code), fmt.Sprint(he.Message)))
+			return
+		}
+		slog.ErrorContext(c.Request().Context(), "unhandled error", logging.Err(err))
+		apperror.WriteProblem(c.Response().Writer, c.Request(), apperror.From(err, apperror.CodeInternal))
+	}
+	e.Use(echo.WrapMiddleware(requestid.Middleware))
+	e.Use(tracing.EchoMiddleware("gateway-api"))
+	e.Use(metrics.EchoMiddleware())
 	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
-		AllowOrigins: []string{"*"},
-		AllowMethods: []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodOptions},
-		AllowHeaders: []string{"*"},
+		AllowOriginFunc: func(origin string) (bool, error) { return origins.Allowed(origin), nil },
+		AllowMethods:    []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodOptions},
+		AllowHeaders:    []string{echo.HeaderAuthorization, echo.HeaderContentType, requestid.Header},
 	}))
 	e.GET("/api/v1/healthz", func(c echo.Context) error {
 		return c.JSON(http.StatusOK, map[string]string{"status": "ok"})
 	})
+	e.GET("/metrics", echo.WrapHandler(metrics.Handler()))
+	ready := &shutdown.Readiness{}
+	e.GET("/readyz", echo.WrapHandler(ready))
 	e.GET("/api/v1/swagger.yaml", func(c echo.Context) error {
 		return c.File("/v1/swagger/gateway-api.yml")
 	})
@@ -80,18 +178,38 @@ func main() {
 		menuHandler.GetMenu(c.Response().Writer, c.Request(), storeID)
 		return nil
 	})
+	e.POST("/api/v1/sessions", func(c echo.Context) error {
+		sessionHandler.PostSession(c.Response().Writer, c.Request(), storeID)
+		return nil
+	}, echo.WrapMiddleware(sessionIPLimit.Middleware("/api/v1/sessions", ratelimit.ByIP)))
+	e.GET("/api/v1/leaderboard", func(c echo.Context) error {
+		leaderboardHandler.GetLeaderboard(c.Response().Writer, c.Request())
+		return nil
+	}, echo.WrapMiddleware(leaderboardIPLimit.Middleware("/api/v1/leaderboard", ratelimit.ByIP)))
+	e.GET("/api/v1/rounds/:round_id", func(c echo.Context) error {
+		roundHandler.GetRound(c.Response().Writer, c.Request(), c.Param("round_id"))
+		return nil
+	}, echo.WrapMiddleware(roundsIPLimit.Middleware("/api/v1/rounds", ratelimit.ByIP)))
+
+	// The routes below require a session token. Per-route rather than a
+	// Group: a group's middleware also guards its catch-all 404 routes.
+	requireAuth := echo.WrapMiddleware(signer.Middleware)
 	e.POST("/api/v1/stores/orders", func(c echo.Context) error {
 		orderHandler.PostOrders(c.Response().Writer, c.Request(), storeID)
 		return nil
-	})
+	}, requireAuth, echo.WrapMiddleware(orderLimit.Middleware("/api/v1/stores/orders", ratelimit.ByClient)))
 	e.GET("/api/v1/stores/orders/:order_id", func(c echo.Context) error {
 		orderHandler.GetOrderByID(c.Response().Writer, c.Request(), storeID, c.Param("order_id"))
 		return nil
-	})
+	}, requireAuth)
 	e.POST("/api/v1/chant", func(c echo.Context) error {
 		chantHandler.PostChant(c.Response().Writer, c.Request())
 		return nil
-	})
+	},
+		echo.WrapMiddleware(chantIPLimit.Middleware("/api/v1/chant", ratelimit.ByIP)),
+		requireAuth,
+		echo.WrapMiddleware(chantLimit.Middleware("/api/v1/chant", ratelimit.ByClient)),
+	)
 
 	srv := &http.Server{
 		Addr:              ":" + httpPort,
@@ -101,8 +219,44 @@ func main() {
 		WriteTimeout:      15 * time.Second,
 		IdleTimeout:       60 * time.Second,
 	}
-	log.Printf("gateway-api HTTP listening on :%s", httpPort)
-	if err := e.StartServer(srv); err != nil && err != http.ErrServerClosed {
-		log.Fatalf("http server error: %v", err)
+	go func() {
+		slog.Info("HTTP listening", slog.String("addr", ":"+httpPort))
+		if err := e.StartServer(srv); err != nil && err != http.ErrServerClosed {
+			logging.Fatal("http server error", logging.Err(err))
+		}
+	}()
+
+	<-ctx.Done()
+	slog.Info("shutting down")
+	ready.Drain(context.Background())
+	sctx, cancel := context.WithTimeout(context.Background(), shutdown.Timeout())
+	defer cancel()
+	if err := e.Shutdown(sctx); err != nil {
+		slog.Warn("http shutdown", logging.Err(err))
+	}
+	// In-flight PostOrder and round calls from the WebSocket gateways finish
+	// before we exit.
+	shutdown.StopGRPC(sctx, grpcServer)
+	slog.Info("shutdown complete")
+}
+
+// httpStatusCode maps statuses produced by echo itself (404 route, 405, ...)
+// onto the shared error codes.
+func httpStatusCode(status int) apperror.Code {
+	switch status {
+	case http.StatusBadRequest:
+		return apperror.CodeInvalidArgument
+	case http.StatusUnauthorized:
+		return apperror.CodeUnauthenticated
+	case http.StatusForbidden:
+		return apperror.CodePermissionDenied
+	case http.StatusNotFound, http.StatusMethodNotAllowed:
+		return apperror.CodeNotFound
+	case http.StatusTooManyRequests:
+		return apperror.CodeRateLimited
+	case http.StatusServiceUnavailable:
+		return apperror.CodeUnavailable
+	default:
+		return apperror.CodeInternal
 	}
 }
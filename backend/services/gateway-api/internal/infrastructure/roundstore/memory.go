@@ -0,0 +1,88 @@
+package roundstore
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"chantingkakigori/services/gateway-api/internal/usecase"
+)
+
+// Memory keeps rounds in process.
+type Memory struct {
+	mu      sync.Mutex
+	rounds  map[string]usecase.Round
+	lobbies map[string]string // room -> round ID
+	pruned  time.Time
+	now     func() time.Time
+}
+
+// NewMemory returns an empty store.
+func NewMemory() *Memory {
+	return &Memory{rounds: map[string]usecase.Round{}, lobbies: map[string]string{}, now: time.Now}
+}
+
+// clone keeps callers and fns from sharing a stored round's history.
+func clone(r usecase.Round) usecase.Round {
+	r.History = slices.Clone(r.History)
+	return r
+}
+
+func (m *Memory) Get(_ context.Context, id string) (usecase.Round, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	r, ok := m.rounds[id]
+	return clone(r), ok, nil
+}
+
+func (m *Memory) Update(_ context.Context, id string, fn func(*usecase.Round) bool) (usecase.Round, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored, ok := m.rounds[id]
+	if !ok {
+		return usecase.Round{}, false, nil
+	}
+	r := clone(stored)
+	if !fn(&r) {
+		return r, true, nil
+	}
+	m.rounds[id] = clone(r)
+	if stored.State == usecase.RoundLobby && r.State != usecase.RoundLobby && m.lobbies[r.Room] == id {
+		delete(m.lobbies, r.Room)
+	}
+	m.pruneLocked()
+	return r, true, nil
+}
+
+func (m *Memory) Lobby(_ context.Context, room string, keep func(usecase.Round) bool, open func() usecase.Round) (usecase.Round, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if id, ok := m.lobbies[room]; ok {
+		if r, ok := m.rounds[id]; ok && keep(clone(r)) {
+			return clone(r), nil
+		}
+	}
+	r := open()
+	m.rounds[r.ID] = clone(r)
+	m.lobbies[room] = r.ID
+	m.pruneLocked()
+	return r, nil
+}
+
+// pruneLocked forgets rounds past Retention, at most once a minute.
+func (m *Memory) pruneLocked() {
+	now := m.now()
+	if now.Sub(m.pruned) < time.Minute {
+		return
+	}
+	m.pruned = now
+	for id, r := range m.rounds {
+		if now.Sub(r.UpdatedAt) > Retention {
+			delete(m.rounds, id)
+			if m.lobbies[r.Room] == id {
+				delete(m.lobbies, r.Room)
+			}
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package roundstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"chantingkakigori/services/gateway-api/internal/usecase"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// maxAttempts bounds the optimistic retries of one contended write; a round
+// only sees a few writers (one per replica holding its sockets).
+const maxAttempts = 8
+
+var errContended = errors.New("round store: too much contention")
+
+// Redis keeps each round as JSON under round:<id> and each room's lobby round
+// ID under round:lobby:<room>, both expiring Retention after their last
+// write. Writes are compare-and-set on WATCHed keys.
+type Redis struct {
+	rdb redis.UniversalClient
+}
+
+// NewRedis returns the store on rdb, which the caller closes.
+func NewRedis(rdb redis.UniversalClient) *Redis { return &Redis{rdb: rdb} }
+
+func roundKey(id string) string   { return "round:" + id }
+func lobbyKey(room string) string { return "round:lobby:" + room }
+
+func get(ctx context.Context, c redis.Cmdable, id string) (usecase.Round, bool, error) {
+	b, err := c.Get(ctx, roundKey(id)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return usecase.Round{}, false, nil
+	}
+	if err != nil {
+		return usecase.Round{}, false, err
+	}
+	var r usecase.Round
+	if err := json.Unmarshal(b, &r); err != nil {
+		return usecase.Round{}, false, err
+	}
+	return r, true, nil
+}
+
+// retry runs fn in a WATCH transaction on keys until it commits.
+func (s *Redis) retry(ctx context.Context, fn func(*redis.Tx) error, keys ...string) error {
+	for range maxAttempts {
+		err := s.rdb.Watch(ctx, fn, keys...)
+		if !errors.Is(err, redis.TxFailedErr) {
+			return err
+		}
+	}
+	return errContended
+}
+
+func (s *Redis) Get(ctx context.Context, id string) (usecase.Round, bool, error) {
+	return get(ctx, s.rdb, id)
+}
+
+func (s *Redis) Update(ctx context.Context, id string, fn func(*usecase.Round) bool) (usecase.Round, bool, error) {
+	var out usecase.Round
+	var found bool
+	err := s.retry(ctx, func(tx *redis.Tx) error {
+		r, ok, err := get(ctx, tx, id)
+		if err != nil || !ok {
+			found = false
+			return err
+		}
+		found = true
+		wasLobby := r.State == usecase.RoundLobby
+		if !fn(&r) {
+			out = r
+			return nil
+		}
+		b, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+		clearLobby := false
+		if wasLobby && r.State != usecase.RoundLobby {
+			if err := tx.Watch(ctx, lobbyKey(r.Room)).Err(); err != nil {
+				return err
+			}
+			cur, err := tx.Get(ctx, lobbyKey(r.Room)).Result()
+			if err != nil && !errors.Is(err, redis.Nil) {
+				return err
+			}
+			clearLobby = cur == id
+		}
+		_, err = tx.TxPipelined(ctx, func(p redis.Pipeliner) error {
+			p.Set(ctx, roundKey(id), b, Retention)
+			if clearLobby {
+				p.Del(ctx, lobbyKey(r.Room))
+			}
+			return nil
+		})
+		out = r
+		return err
+	}, roundKey(id))
+	return out, found, err
+}
+
+func (s *Redis) Lobby(ctx context.Context, room string, keep func(usecase.Round) bool, open func() usecase.Round) (usecase.Round, error) {
+	var out usecase.Round
+	err := s.retry(ctx, func(tx *redis.Tx) error {
+		id, err := tx.Get(ctx, lobbyKey(room)).Result()
+		if err != nil && !errors.Is(err, redis.Nil) {
+			return err
+		}
+		if id != "" {
+			if err := tx.Watch(ctx, roundKey(id)).Err(); err != nil {
+				return err
+			}
+			r, ok, err := get(ctx, tx, id)
+			if err != nil {
+				return err
+			}
+			if ok && keep(r) {
+				out = r
+				return nil
+			}
+		}
+		r := open()
+		b, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+		_, err = tx.TxPipelined(ctx, func(p redis.Pipeliner) error {
+			p.Set(ctx, roundKey(r.ID), b, Retention)
+			p.Set(ctx, lobbyKey(room), r.ID, Retention)
+			return nil
+		})
+		out = r
+		return err
+	}, lobbyKey(room))
+	return out, err
+}
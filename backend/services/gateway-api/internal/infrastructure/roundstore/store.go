@@ -0,0 +1,37 @@
+// Package roundstore keeps gateway-api's party rounds. Redis shares them
+// between gateway-api replicas; Memory keeps them until the process exits.
+package roundstore
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"chantingkakigori/services/gateway-api/internal/usecase"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Retention is how long a round is kept after its last change, so GET
+// /api/v1/rounds/{id} still answers for the rest of the day.
+const Retention = 24 * time.Hour
+
+// FromEnv returns the store: Redis when REDIS_URL is set (e.g.
+// redis://redis:6379/0), Memory otherwise. closeFn releases it.
+func FromEnv(ctx context.Context) (store usecase.RoundStore, closeFn func() error, err error) {
+	url := os.Getenv("REDIS_URL")
+	if url == "" {
+		return NewMemory(), func() error { return nil }, nil
+	}
+	opt, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, nil, fmt.Errorf("parse REDIS_URL: %w", err)
+	}
+	rdb := redis.NewClient(opt)
+	if err := rdb.Ping(ctx).Err(); err != nil {
+		_ = rdb.Close()
+		return nil, nil, fmt.Errorf("ping redis %s: %w", opt.Addr, err)
+	}
+	return NewRedis(rdb), rdb.Close, nil
+}
@@ -0,0 +1,120 @@
+package roundstore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"chantingkakigori/services/gateway-api/internal/usecase"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// testStore runs the behaviour every implementation shares.
+func testStore(t *testing.T, store usecase.RoundStore) {
+	ctx := context.Background()
+	// Memory forgets rounds by the wall clock
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	n := 0
+	open := func() usecase.Round {
+		n++
+		id := "r" + string(rune('0'+n))
+		return usecase.Round{ID: id, Room: "ichigo", State: usecase.RoundLobby, CreatedAt: now, UpdatedAt: now,
+			History: []usecase.RoundTransition{{State: usecase.RoundLobby, At: now}}}
+	}
+	always := func(usecase.Round) bool { return true }
+
+	first, err := store.Lobby(ctx, "ichigo", always, open)
+	if err != nil || first.ID != "r1" {
+		t.Fatalf("first lobby = %+v, %v", first, err)
+	}
+	again, err := store.Lobby(ctx, "ichigo", always, open)
+	if err != nil || again.ID != "r1" || n != 1 {
+		t.Fatalf("lobby was not kept: %+v, %v (opened %d)", again, err, n)
+	}
+	other, err := store.Lobby(ctx, "matcha", always, func() usecase.Round { r := open(); r.Room = "matcha"; return r })
+	if err != nil || other.ID != "r2" {
+		t.Fatalf("other room's lobby = %+v, %v", other, err)
+	}
+	replaced, err := store.Lobby(ctx, "ichigo", func(usecase.Round) bool { return false }, open)
+	if err != nil || replaced.ID != "r3" {
+		t.Fatalf("rejected lobby was not replaced: %+v, %v", replaced, err)
+	}
+
+	// an unchanged round is not written
+	if _, ok, err := store.Update(ctx, "r3", func(r *usecase.Round) bool { r.Reason = "dropped"; return false }); err != nil || !ok {
+		t.Fatalf("update: ok=%v err=%v", ok, err)
+	}
+	if r, _, _ := store.Get(ctx, "r3"); r.Reason != "" {
+		t.Fatalf("unchanged update was stored: %+v", r)
+	}
+
+	got, ok, err := store.Update(ctx, "r3", func(r *usecase.Round) bool {
+		r.State = usecase.RoundCountdown
+		r.History = append(r.History, usecase.RoundTransition{State: usecase.RoundCountdown, At: now})
+		return true
+	})
+	if err != nil || !ok || got.State != usecase.RoundCountdown {
+		t.Fatalf("update = %+v, %v, %v", got, ok, err)
+	}
+	stored, ok, err := store.Get(ctx, "r3")
+	if err != nil || !ok || stored.State != usecase.RoundCountdown || len(stored.History) != 2 || !stored.CreatedAt.Equal(now) {
+		t.Fatalf("stored = %+v, %v, %v", stored, ok, err)
+	}
+
+	// the round left the lobby, so the room gets a new one
+	next, err := store.Lobby(ctx, "ichigo", always, open)
+	if err != nil || next.ID != "r4" {
+		t.Fatalf("lobby after countdown = %+v, %v", next, err)
+	}
+	// moving an old round on leaves the new lobby alone
+	if _, _, err := store.Update(ctx, "r1", func(r *usecase.Round) bool { r.State = usecase.RoundExpired; return true }); err != nil {
+		t.Fatal(err)
+	}
+	if r, _ := store.Lobby(ctx, "ichigo", always, open); r.ID != "r4" {
+		t.Fatalf("lobby = %s, want r4", r.ID)
+	}
+
+	if _, ok, err := store.Get(ctx, "nope"); ok || err != nil {
+		t.Fatalf("unknown round: ok=%v err=%v", ok, err)
+	}
+	if _, ok, err := store.Update(ctx, "nope", func(*usecase.Round) bool { t.Fatal("fn ran"); return false }); ok || err != nil {
+		t.Fatalf("unknown update: ok=%v err=%v", ok, err)
+	}
+}
+
+func TestMemory(t *testing.T) { testStore(t, NewMemory()) }
+
+func TestRedis(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer rdb.Close()
+	testStore(t, NewRedis(rdb))
+
+	if ttl := mr.TTL(roundKey("r3")); ttl != Retention {
+		t.Fatalf("round ttl = %v, want %v", ttl, Retention)
+	}
+}
+
+func TestMemoryForgetsOldRounds(t *testing.T) {
+	m := NewMemory()
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	m.now = func() time.Time { return now }
+	ctx := context.Background()
+	old := usecase.Round{ID: "old", Room: "ichigo", State: usecase.RoundLobby, UpdatedAt: now}
+	if _, err := m.Lobby(ctx, "ichigo", nil, func() usecase.Round { return old }); err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(Retention + time.Hour)
+	fresh := usecase.Round{ID: "fresh", Room: "matcha", State: usecase.RoundLobby, UpdatedAt: now}
+	if _, err := m.Lobby(ctx, "matcha", nil, func() usecase.Round { return fresh }); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ := m.Get(ctx, "old"); ok {
+		t.Fatal("round past retention was kept")
+	}
+	if _, ok, _ := m.Get(ctx, "fresh"); !ok {
+		t.Fatal("fresh round was forgotten")
+	}
+}
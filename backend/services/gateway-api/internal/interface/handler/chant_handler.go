@@ -6,6 +6,8 @@ import (
 	"net/http"
 	"time"
 
+	"chantingkakigori/pkg/apperror"
+	"chantingkakigori/pkg/auth"
 	openapi "chantingkakigori/services/gateway-api/internal/swagger"
 	"chantingkakigori/services/gateway-api/internal/usecase"
 )
@@ -26,23 +28,17 @@ func (h *ChantHandler) PostChant(w http.ResponseWriter, r *http.Request) {
 
 	var body openapi.PostApiV1ChantJSONRequestBody
 	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.MenuItemId == nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		_ = json.NewEncoder(w).Encode(map[string]string{
-			"error":   "Bad Request",
-			"message": "Invalid request body",
-		})
+		apperror.WriteProblem(w, r, apperror.New(apperror.CodeInvalidArgument, "Invalid request body"))
+		return
+	}
+	if err := auth.RequireMenuItem(ctx, string(*body.MenuItemId)); err != nil {
+		apperror.WriteProblem(w, r, apperror.From(err, apperror.CodePermissionDenied))
 		return
 	}
 
 	res, err := h.Usecase.GenerateChant(ctx, body)
 	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		_ = json.NewEncoder(w).Encode(map[string]string{
-			"error":   "Bad Request",
-			"message": err.Error(),
-		})
+		apperror.WriteProblem(w, r, apperror.From(err, apperror.CodeUpstream))
 		return
 	}
 
@@ -3,12 +3,12 @@ package handler
 import (
 	"context"
 	"encoding/json"
-	"errors"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
 
+	"chantingkakigori/pkg/apperror"
 	openapi "chantingkakigori/services/gateway-api/internal/swagger"
 	"chantingkakigori/services/gateway-api/internal/usecase"
 )
@@ -32,15 +32,15 @@ func TestChantHandler_BadRequest_InvalidBody(t *testing.T) {
 	if rec.Code != http.StatusBadRequest {
 		t.Fatalf("expected 400, got %d", rec.Code)
 	}
-	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
-		t.Fatalf("expected application/json, got %s", ct)
+	if ct := rec.Header().Get("Content-Type"); ct != apperror.ProblemContentType {
+		t.Fatalf("expected %s, got %s", apperror.ProblemContentType, ct)
 	}
 
-	var body map[string]string
+	var body apperror.Problem
 	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
 		t.Fatalf("decode: %v", err)
 	}
-	if body["error"] != "Bad Request" || body["message"] != "Invalid request body" {
+	if body.Title != "Bad Request" || body.Detail != "Invalid request body" || body.Code != apperror.CodeInvalidArgument {
 		t.Fatalf("unexpected body: %s", rec.Body.String())
 	}
 }
@@ -69,7 +69,7 @@ func TestChantHandler_Success(t *testing.T) {
 }
 
 func TestChantHandler_UsecaseError(t *testing.T) {
-	h := NewChantHandler(fakeChantUsecase{err: errors.New("nope")})
+	h := NewChantHandler(fakeChantUsecase{err: apperror.New(apperror.CodeInvalidArgument, "nope")})
 
 	req := httptest.NewRequest(http.MethodPost, "/api/v1/chant", strings.NewReader(`{"menu_item_id":"giiku-sai"}`))
 	rec := httptest.NewRecorder()
@@ -78,15 +78,15 @@ func TestChantHandler_UsecaseError(t *testing.T) {
 	if rec.Code != http.StatusBadRequest {
 		t.Fatalf("expected 400, got %d", rec.Code)
 	}
-	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
-		t.Fatalf("expected application/json, got %s", ct)
+	if ct := rec.Header().Get("Content-Type"); ct != apperror.ProblemContentType {
+		t.Fatalf("expected %s, got %s", apperror.ProblemContentType, ct)
 	}
 
-	var body map[string]string
+	var body apperror.Problem
 	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
 		t.Fatalf("decode: %v", err)
 	}
-	if body["error"] != "Bad Request" || body["message"] != "nope" {
+	if body.Title != "Bad Request" || body.Detail != "nope" || body.Code != apperror.CodeInvalidArgument {
 		t.Fatalf("unexpected body: %s", rec.Body.String())
 	}
 }
@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"chantingkakigori/pkg/apperror"
+	openapi "chantingkakigori/services/gateway-api/internal/swagger"
+	"chantingkakigori/services/gateway-api/internal/usecase"
+)
+
+// Leaderboard limits; kakigori-ws keeps the best 100 rounds of each board.
+const (
+	defaultLeaderboardLimit = 10
+	maxLeaderboardLimit     = 100
+)
+
+// LeaderboardHandler serves the chant leaderboard.
+type LeaderboardHandler struct {
+	Fetcher usecase.LeaderboardFetcher
+	// Now is overridable for testing; today's board follows it.
+	Now func() time.Time
+}
+
+func NewLeaderboardHandler(f usecase.LeaderboardFetcher) *LeaderboardHandler {
+	return &LeaderboardHandler{Fetcher: f, Now: time.Now}
+}
+
+// GetLeaderboard processes GET /api/v1/leaderboard requests: the strongest
+// rounds of a day (today in Japan time unless date is given) or of all time,
+// for one menu item or all of them.
+func (h *LeaderboardHandler) GetLeaderboard(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if _, hasDeadline := ctx.Deadline(); !hasDeadline {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+	}
+
+	params, perr := leaderboardParams(r)
+	if perr != nil {
+		apperror.WriteProblem(w, r, perr)
+		return
+	}
+	q := usecase.LeaderboardQuery{Limit: defaultLeaderboardLimit}
+	if params.MenuItemId != nil {
+		q.MenuItemID = *params.MenuItemId
+	}
+	if params.Limit != nil {
+		if *params.Limit < 1 || *params.Limit > maxLeaderboardLimit {
+			apperror.WriteProblem(w, r, apperror.New(apperror.CodeInvalidArgument, "limit must be between 1 and 100"))
+			return
+		}
+		q.Limit = *params.Limit
+	}
+	period := openapi.GetApiV1LeaderboardParamsPeriodDaily
+	if params.Period != nil {
+		period = *params.Period
+	}
+	switch period {
+	case openapi.GetApiV1LeaderboardParamsPeriodDaily:
+		q.Date = h.Now().In(usecase.Japan).Format(time.DateOnly)
+		if params.Date != nil {
+			if _, err := time.Parse(time.DateOnly, *params.Date); err != nil {
+				apperror.WriteProblem(w, r, apperror.New(apperror.CodeInvalidArgument, "date must be YYYY-MM-DD"))
+				return
+			}
+			q.Date = *params.Date
+		}
+	case openapi.GetApiV1LeaderboardParamsPeriodAll:
+		if params.Date != nil {
+			apperror.WriteProblem(w, r, apperror.New(apperror.CodeInvalidArgument, "date is only allowed with period=daily"))
+			return
+		}
+	default:
+		apperror.WriteProblem(w, r, apperror.New(apperror.CodeInvalidArgument, "period must be daily or all"))
+		return
+	}
+
+	board, err := h.Fetcher.FetchLeaderboard(ctx, q)
+	if err != nil {
+		apperror.WriteProblem(w, r, apperror.From(err, apperror.CodeUpstream))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(board)
+}
+
+func leaderboardParams(r *http.Request) (openapi.GetApiV1LeaderboardParams, *apperror.Error) {
+	var p openapi.GetApiV1LeaderboardParams
+	v := r.URL.Query()
+	if s := v.Get("period"); s != "" {
+		period := openapi.GetApiV1LeaderboardParamsPeriod(s)
+		p.Period = &period
+	}
+	if s := v.Get("date"); s != "" {
+		p.Date = &s
+	}
+	if s := v.Get("menu_item_id"); s != "" {
+		p.MenuItemId = &s
+	}
+	if s := v.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return p, apperror.New(apperror.CodeInvalidArgument, "limit must be an integer")
+		}
+		p.Limit = &n
+	}
+	return p, nil
+}
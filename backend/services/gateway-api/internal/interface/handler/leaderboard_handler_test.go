@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"chantingkakigori/pkg/apperror"
+	openapi "chantingkakigori/services/gateway-api/internal/swagger"
+	"chantingkakigori/services/gateway-api/internal/usecase"
+)
+
+type fakeLeaderboardFetcher struct {
+	got *usecase.LeaderboardQuery
+	err error
+}
+
+func (f fakeLeaderboardFetcher) FetchLeaderboard(_ context.Context, q usecase.LeaderboardQuery) (*openapi.Leaderboard, error) {
+	*f.got = q
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &openapi.Leaderboard{Period: openapi.LeaderboardPeriodDaily, Date: &q.Date, Entries: []openapi.LeaderboardEntry{{Rank: 1, MenuItemId: "giiku-sai", Peak: 0.9}}}, nil
+}
+
+func TestLeaderboardHandler_DefaultsToToday(t *testing.T) {
+	var got usecase.LeaderboardQuery
+	h := NewLeaderboardHandler(fakeLeaderboardFetcher{got: &got})
+	// already the 2nd in Japan
+	h.Now = func() time.Time { return time.Date(2026, 10, 1, 15, 30, 0, 0, time.UTC) }
+
+	rec := httptest.NewRecorder()
+	h.GetLeaderboard(rec, httptest.NewRequest(http.MethodGet, "/api/v1/leaderboard?menu_item_id=giiku-sai", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	if got != (usecase.LeaderboardQuery{Date: "2026-10-02", MenuItemID: "giiku-sai", Limit: 10}) {
+		t.Fatalf("query=%+v", got)
+	}
+	var board openapi.Leaderboard
+	if err := json.Unmarshal(rec.Body.Bytes(), &board); err != nil || len(board.Entries) != 1 || board.Entries[0].Rank != 1 {
+		t.Fatalf("board=%+v err=%v", board, err)
+	}
+}
+
+func TestLeaderboardHandler_AllTime(t *testing.T) {
+	var got usecase.LeaderboardQuery
+	h := NewLeaderboardHandler(fakeLeaderboardFetcher{got: &got})
+
+	rec := httptest.NewRecorder()
+	h.GetLeaderboard(rec, httptest.NewRequest(http.MethodGet, "/api/v1/leaderboard?period=all&limit=3", nil))
+
+	if rec.Code != http.StatusOK || got != (usecase.LeaderboardQuery{Limit: 3}) {
+		t.Fatalf("status=%d query=%+v", rec.Code, got)
+	}
+}
+
+func TestLeaderboardHandler_BadRequest(t *testing.T) {
+	for _, q := range []string{"period=weekly", "date=10/01", "period=all&date=2026-10-01", "limit=0", "limit=101", "limit=ten"} {
+		var got usecase.LeaderboardQuery
+		h := NewLeaderboardHandler(fakeLeaderboardFetcher{got: &got})
+		rec := httptest.NewRecorder()
+		h.GetLeaderboard(rec, httptest.NewRequest(http.MethodGet, "/api/v1/leaderboard?"+q, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d", q, rec.Code)
+		}
+	}
+}
+
+func TestLeaderboardHandler_Unavailable(t *testing.T) {
+	var got usecase.LeaderboardQuery
+	h := NewLeaderboardHandler(fakeLeaderboardFetcher{got: &got, err: apperror.New(apperror.CodeUnavailable, "rounds are not recorded")})
+
+	rec := httptest.NewRecorder()
+	h.GetLeaderboard(rec, httptest.NewRequest(http.MethodGet, "/api/v1/leaderboard", nil))
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %d", rec.Code)
+	}
+}
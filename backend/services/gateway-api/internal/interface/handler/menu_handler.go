@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"time"
 
+	"chantingkakigori/pkg/apperror"
 	"chantingkakigori/services/gateway-api/internal/usecase"
 )
 
@@ -29,23 +30,13 @@ func (h *MenuHandler) GetMenu(w http.ResponseWriter, r *http.Request, storeID st
 	}
 
 	if storeID == "" {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		_ = json.NewEncoder(w).Encode(map[string]string{
-			"error":   "Bad Request",
-			"message": "missing store id",
-		})
+		apperror.WriteProblem(w, r, apperror.New(apperror.CodeInvalidArgument, "missing store id"))
 		return
 	}
 
 	items, err := h.Fetcher.FetchMenu(ctx, storeID)
 	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadGateway)
-		_ = json.NewEncoder(w).Encode(map[string]string{
-			"error":   "Bad Gateway",
-			"message": err.Error(),
-		})
+		apperror.WriteProblem(w, r, apperror.From(err, apperror.CodeUpstream))
 		return
 	}
 
@@ -7,6 +7,8 @@ import (
 	"time"
 
 	gatewayapiv1 "chantingkakigori/gen/go/gateway_api/v1"
+	"chantingkakigori/pkg/apperror"
+	"chantingkakigori/pkg/auth"
 	"chantingkakigori/services/gateway-api/internal/usecase"
 )
 
@@ -30,49 +32,17 @@ func (h *OrderHandler) PostOrders(w http.ResponseWriter, r *http.Request, storeI
 		MenuItemID string `json:"menu_item_id"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.MenuItemID == "" {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		_ = json.NewEncoder(w).Encode(map[string]string{
-			"error":   "Bad Request",
-			"message": "Invalid request body",
-		})
+		apperror.WriteProblem(w, r, apperror.New(apperror.CodeInvalidArgument, "Invalid request body"))
+		return
+	}
+	if err := auth.RequireMenuItem(ctx, body.MenuItemID); err != nil {
+		apperror.WriteProblem(w, r, apperror.From(err, apperror.CodePermissionDenied))
 		return
 	}
 
 	order, err := h.Usecase.PostOrder(ctx, storeID, body.MenuItemID)
 	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		// Map upstream status codes when possible
-		if ue, ok := err.(*usecase.UpstreamError); ok {
-			switch ue.StatusCode {
-			case http.StatusBadRequest:
-				w.WriteHeader(http.StatusBadRequest)
-				_ = json.NewEncoder(w).Encode(map[string]string{
-					"error":   "Bad Request",
-					"message": ue.Body,
-				})
-				return
-			case http.StatusNotFound:
-				w.WriteHeader(http.StatusNotFound)
-				_ = json.NewEncoder(w).Encode(map[string]string{
-					"error":   "Not Found",
-					"message": ue.Body,
-				})
-				return
-			default:
-				w.WriteHeader(http.StatusBadGateway)
-				_ = json.NewEncoder(w).Encode(map[string]string{
-					"error":   "Bad Gateway",
-					"message": ue.Body,
-				})
-				return
-			}
-		}
-		w.WriteHeader(http.StatusBadGateway)
-		_ = json.NewEncoder(w).Encode(map[string]string{
-			"error":   "Bad Gateway",
-			"message": err.Error(),
-		})
+		apperror.WriteProblem(w, r, apperror.From(err, apperror.CodeUpstream))
 		return
 	}
 
@@ -91,47 +61,12 @@ func (h *OrderHandler) GetOrderByID(w http.ResponseWriter, r *http.Request, stor
 
 	order, err := h.Usecase.GetOrderByID(ctx, storeID, orderID)
 	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		if ue, ok := err.(*usecase.UpstreamError); ok {
-			switch ue.StatusCode {
-			case http.StatusBadRequest:
-				w.WriteHeader(http.StatusBadRequest)
-				_ = json.NewEncoder(w).Encode(map[string]string{
-					"error":   "Bad Request",
-					"message": ue.Body,
-				})
-				return
-			case http.StatusNotFound:
-				w.WriteHeader(http.StatusNotFound)
-				_ = json.NewEncoder(w).Encode(map[string]string{
-					"error":   "Not Found",
-					"message": ue.Body,
-				})
-				return
-			default:
-				w.WriteHeader(http.StatusBadGateway)
-				_ = json.NewEncoder(w).Encode(map[string]string{
-					"error":   "Bad Gateway",
-					"message": ue.Body,
-				})
-				return
-			}
-		}
-		w.WriteHeader(http.StatusBadGateway)
-		_ = json.NewEncoder(w).Encode(map[string]string{
-			"error":   "Bad Gateway",
-			"message": err.Error(),
-		})
+		apperror.WriteProblem(w, r, apperror.From(err, apperror.CodeUpstream))
 		return
 	}
 
 	if order == nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusNotFound)
-		_ = json.NewEncoder(w).Encode(map[string]string{
-			"error":   "Not Found",
-			"message": "order not found",
-		})
+		apperror.WriteProblem(w, r, apperror.New(apperror.CodeNotFound, "order not found"))
 		return
 	}
 
@@ -154,7 +89,7 @@ func NewOrderGRPCServer(uc usecase.OrderUsecase, storeID string) *OrderGRPCServe
 func (s *OrderGRPCServer) PostOrder(ctx context.Context, req *gatewayapiv1.PostOrderRequest) (*gatewayapiv1.PostOrderResponse, error) {
 	order, err := s.UC.PostOrder(ctx, s.StoreID, req.GetMenuItemId())
 	if err != nil {
-		return nil, err
+		return nil, apperror.ToGRPC(apperror.From(err, apperror.CodeUpstream))
 	}
 	// Map to proto response
 	resp := &gatewayapiv1.PostOrderResponse{}
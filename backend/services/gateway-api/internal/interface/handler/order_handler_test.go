@@ -9,7 +9,14 @@ import (
 	"strings"
 	"testing"
 
+	gatewayapiv1 "chantingkakigori/gen/go/gateway_api/v1"
+	"chantingkakigori/pkg/apperror"
+	"chantingkakigori/pkg/auth"
 	openapi "chantingkakigori/services/gateway-api/internal/swagger"
+	"chantingkakigori/services/gateway-api/internal/usecase"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type fakeOrderUsecase struct {
@@ -36,15 +43,15 @@ func TestOrderHandler_BadRequest_InvalidBody(t *testing.T) {
 	if rec.Code != http.StatusBadRequest {
 		t.Fatalf("expected 400, got %d", rec.Code)
 	}
-	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
-		t.Fatalf("expected application/json, got %s", ct)
+	if ct := rec.Header().Get("Content-Type"); ct != apperror.ProblemContentType {
+		t.Fatalf("expected %s, got %s", apperror.ProblemContentType, ct)
 	}
 
-	var body map[string]string
+	var body apperror.Problem
 	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
 		t.Fatalf("decode: %v", err)
 	}
-	if body["error"] != "Bad Request" || body["message"] != "Invalid request body" {
+	if body.Title != "Bad Request" || body.Detail != "Invalid request body" || body.Code != apperror.CodeInvalidArgument {
 		t.Fatalf("unexpected body: %s", rec.Body.String())
 	}
 }
@@ -97,15 +104,15 @@ func TestOrderHandler_PostOrders_UpstreamError(t *testing.T) {
 	if rec.Code != http.StatusBadGateway {
 		t.Fatalf("expected 502, got %d", rec.Code)
 	}
-	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
-		t.Fatalf("expected application/json, got %s", ct)
+	if ct := rec.Header().Get("Content-Type"); ct != apperror.ProblemContentType {
+		t.Fatalf("expected %s, got %s", apperror.ProblemContentType, ct)
 	}
 
-	var body map[string]string
+	var body apperror.Problem
 	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
 		t.Fatalf("decode: %v", err)
 	}
-	if body["error"] != "Bad Gateway" || body["message"] != "oops" {
+	if body.Title != "Bad Gateway" || body.Detail != "upstream error" || body.Code != apperror.CodeUpstream {
 		t.Fatalf("unexpected body: %s", rec.Body.String())
 	}
 }
@@ -144,15 +151,60 @@ func TestOrderHandler_GetOrderByID_UpstreamError(t *testing.T) {
 	if rec.Code != http.StatusBadGateway {
 		t.Fatalf("expected 502, got %d", rec.Code)
 	}
-	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
-		t.Fatalf("expected application/json, got %s", ct)
+	if ct := rec.Header().Get("Content-Type"); ct != apperror.ProblemContentType {
+		t.Fatalf("expected %s, got %s", apperror.ProblemContentType, ct)
+	}
+
+	var body apperror.Problem
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if body.Title != "Bad Gateway" || body.Detail != "upstream error" || body.Code != apperror.CodeUpstream {
+		t.Fatalf("unexpected body: %s", rec.Body.String())
+	}
+}
+
+func TestOrderHandler_GetOrderByID_UpstreamNotFound(t *testing.T) {
+	h := NewOrderHandler(fakeOrderUsecase{err: &usecase.UpstreamError{StatusCode: http.StatusNotFound, Body: "no such order"}})
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/stores/HKWZRTNL/orders/o-404", nil)
+	rec := httptest.NewRecorder()
+	h.GetOrderByID(rec, req, "HKWZRTNL", "o-404")
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rec.Code)
 	}
 
-	var body map[string]string
+	var body apperror.Problem
 	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
 		t.Fatalf("decode: %v", err)
 	}
-	if body["error"] != "Bad Gateway" || body["message"] != "oops" {
+	if body.Code != apperror.CodeNotFound || body.Detail != "no such order" || body.Instance != "/v1/stores/HKWZRTNL/orders/o-404" {
 		t.Fatalf("unexpected body: %s", rec.Body.String())
 	}
 }
+
+func TestOrderGRPCServer_PostOrder_UpstreamErrorStatus(t *testing.T) {
+	s := NewOrderGRPCServer(fakeOrderUsecase{err: &usecase.UpstreamError{StatusCode: http.StatusBadRequest, Body: "unknown menu"}}, "HKWZRTNL")
+
+	_, err := s.PostOrder(context.Background(), &gatewayapiv1.PostOrderRequest{MenuItemId: "giiku-unknown"})
+	if st, ok := status.FromError(err); !ok || st.Code() != codes.InvalidArgument {
+		t.Fatalf("expected InvalidArgument status, got %v", err)
+	}
+	if ae := apperror.FromGRPC(err); ae.Code != apperror.CodeInvalidArgument || ae.Message != "unknown menu" {
+		t.Fatalf("unexpected app error: %#v", ae)
+	}
+}
+
+func TestOrderHandler_PostOrders_TokenForOtherMenuItem(t *testing.T) {
+	h := NewOrderHandler(fakeOrderUsecase{})
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/stores/HKWZRTNL/orders", strings.NewReader(`{"menu_item_id":"giiku-ten"}`))
+	req = req.WithContext(auth.NewContext(req.Context(), &auth.Claims{Room: "giiku-sai", MenuItemID: "giiku-sai"}))
+	rec := httptest.NewRecorder()
+	h.PostOrders(rec, req, "HKWZRTNL")
+
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,152 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	gatewayapiv1 "chantingkakigori/gen/go/gateway_api/v1"
+	"chantingkakigori/pkg/apperror"
+	openapi "chantingkakigori/services/gateway-api/internal/swagger"
+	"chantingkakigori/services/gateway-api/internal/usecase"
+)
+
+// RoundHandler serves party rounds to clients.
+type RoundHandler struct {
+	Usecase usecase.RoundUsecase
+}
+
+func NewRoundHandler(u usecase.RoundUsecase) *RoundHandler { return &RoundHandler{Usecase: u} }
+
+// GetRound processes GET /api/v1/rounds/{round_id} requests.
+func (h *RoundHandler) GetRound(w http.ResponseWriter, r *http.Request, roundID string) {
+	ctx := r.Context()
+	if _, hasDeadline := ctx.Deadline(); !hasDeadline {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+	}
+
+	round, err := h.Usecase.GetRound(ctx, roundID)
+	if err != nil {
+		apperror.WriteProblem(w, r, apperror.From(err, apperror.CodeUnavailable))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(toOpenAPIRound(round))
+}
+
+func toOpenAPIRound(r usecase.Round) openapi.Round {
+	out := openapi.Round{
+		Id:         r.ID,
+		MenuItemId: r.Room,
+		State:      openapi.RoundState(r.State),
+		CreatedAt:  r.CreatedAt,
+		UpdatedAt:  r.UpdatedAt,
+		History:    make([]openapi.RoundTransition, 0, len(r.History)),
+	}
+	if !r.Deadline.IsZero() {
+		out.Deadline = &r.Deadline
+	}
+	if !r.StartAt.IsZero() {
+		out.StartAt = &r.StartAt
+	}
+	if r.Reason != "" {
+		out.Reason = &r.Reason
+	}
+	for _, t := range r.History {
+		tr := openapi.RoundTransition{State: openapi.RoundState(t.State), At: t.At}
+		if t.Reason != "" {
+			tr.Reason = &t.Reason
+		}
+		out.History = append(out.History, tr)
+	}
+	return out
+}
+
+// RoundGRPCServer implements gateway_api.v1.RoundService for the WebSocket
+// gateways, which move rounds along as parties connect.
+type RoundGRPCServer struct {
+	gatewayapiv1.UnimplementedRoundServiceServer
+	UC usecase.RoundUsecase
+}
+
+func NewRoundGRPCServer(uc usecase.RoundUsecase) *RoundGRPCServer {
+	return &RoundGRPCServer{UC: uc}
+}
+
+func (s *RoundGRPCServer) OpenRound(ctx context.Context, req *gatewayapiv1.OpenRoundRequest) (*gatewayapiv1.OpenRoundResponse, error) {
+	r, err := s.UC.OpenRound(ctx, req.GetRoom(), req.GetMember())
+	if err != nil {
+		return nil, apperror.ToGRPC(apperror.From(err, apperror.CodeUnavailable))
+	}
+	return &gatewayapiv1.OpenRoundResponse{Round: roundToProto(r)}, nil
+}
+
+func (s *RoundGRPCServer) GetRound(ctx context.Context, req *gatewayapiv1.GetRoundRequest) (*gatewayapiv1.GetRoundResponse, error) {
+	r, err := s.UC.GetRound(ctx, req.GetId())
+	if err != nil {
+		return nil, apperror.ToGRPC(apperror.From(err, apperror.CodeUnavailable))
+	}
+	return &gatewayapiv1.GetRoundResponse{Round: roundToProto(r)}, nil
+}
+
+func (s *RoundGRPCServer) AdvanceRound(ctx context.Context, req *gatewayapiv1.AdvanceRoundRequest) (*gatewayapiv1.AdvanceRoundResponse, error) {
+	var startAt time.Time
+	if ms := req.GetStartAtUnixMs(); ms > 0 {
+		startAt = time.UnixMilli(ms)
+	}
+	r, err := s.UC.AdvanceRound(ctx, req.GetId(), req.GetRoom(), req.GetMember(), roundStateFromProto(req.GetState()), startAt, req.GetReason())
+	if err != nil {
+		return nil, apperror.ToGRPC(apperror.From(err, apperror.CodeUnavailable))
+	}
+	return &gatewayapiv1.AdvanceRoundResponse{Round: roundToProto(r)}, nil
+}
+
+var roundStates = map[usecase.RoundState]gatewayapiv1.RoundState{
+	usecase.RoundLobby:      gatewayapiv1.RoundState_ROUND_STATE_LOBBY,
+	usecase.RoundCountdown:  gatewayapiv1.RoundState_ROUND_STATE_COUNTDOWN,
+	usecase.RoundChanting:   gatewayapiv1.RoundState_ROUND_STATE_CHANTING,
+	usecase.RoundConfirming: gatewayapiv1.RoundState_ROUND_STATE_CONFIRMING,
+	usecase.RoundOrdered:    gatewayapiv1.RoundState_ROUND_STATE_ORDERED,
+	usecase.RoundFailed:     gatewayapiv1.RoundState_ROUND_STATE_FAILED,
+	usecase.RoundExpired:    gatewayapiv1.RoundState_ROUND_STATE_EXPIRED,
+}
+
+// roundStateFromProto maps UNSPECIFIED and unknown values to "", which
+// AdvanceRound rejects.
+func roundStateFromProto(s gatewayapiv1.RoundState) usecase.RoundState {
+	for state, p := range roundStates {
+		if p == s {
+			return state
+		}
+	}
+	return ""
+}
+
+func unixMilli(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixMilli()
+}
+
+func roundToProto(r usecase.Round) *gatewayapiv1.Round {
+	out := &gatewayapiv1.Round{
+		Id:              r.ID,
+		Room:            r.Room,
+		State:           roundStates[r.State],
+		CreatedAtUnixMs: unixMilli(r.CreatedAt),
+		UpdatedAtUnixMs: unixMilli(r.UpdatedAt),
+		DeadlineUnixMs:  unixMilli(r.Deadline),
+		StartAtUnixMs:   unixMilli(r.StartAt),
+		Reason:          r.Reason,
+	}
+	for _, t := range r.History {
+		out.History = append(out.History, &gatewayapiv1.RoundTransition{State: roundStates[t.State], AtUnixMs: unixMilli(t.At), Reason: t.Reason})
+	}
+	return out
+}
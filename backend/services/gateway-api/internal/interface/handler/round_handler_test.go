@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	gatewayapiv1 "chantingkakigori/gen/go/gateway_api/v1"
+	"chantingkakigori/pkg/apperror"
+	"chantingkakigori/services/gateway-api/internal/infrastructure/roundstore"
+	openapi "chantingkakigori/services/gateway-api/internal/swagger"
+	"chantingkakigori/services/gateway-api/internal/usecase"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestRoundGRPCServer_Lifecycle(t *testing.T) {
+	uc := usecase.NewRoundUsecase(roundstore.NewMemory())
+	s := NewRoundGRPCServer(uc)
+	ctx := context.Background()
+
+	opened, err := s.OpenRound(ctx, &gatewayapiv1.OpenRoundRequest{Room: "giiku-sai", Member: "alice"})
+	if err != nil || opened.GetRound().GetState() != gatewayapiv1.RoundState_ROUND_STATE_LOBBY {
+		t.Fatalf("open = %v, %v", opened, err)
+	}
+	id := opened.GetRound().GetId()
+
+	startAt := time.Now().Add(5 * time.Second).UnixMilli()
+	adv, err := s.AdvanceRound(ctx, &gatewayapiv1.AdvanceRoundRequest{Id: id, Room: "giiku-sai", Member: "alice", State: gatewayapiv1.RoundState_ROUND_STATE_COUNTDOWN, StartAtUnixMs: startAt})
+	if err != nil || adv.GetRound().GetState() != gatewayapiv1.RoundState_ROUND_STATE_COUNTDOWN || adv.GetRound().GetStartAtUnixMs() != startAt {
+		t.Fatalf("countdown = %v, %v", adv, err)
+	}
+
+	_, err = s.AdvanceRound(ctx, &gatewayapiv1.AdvanceRoundRequest{Id: id, Room: "giiku-sai", Member: "mallory", State: gatewayapiv1.RoundState_ROUND_STATE_CHANTING})
+	if st, _ := status.FromError(err); st.Code() != codes.PermissionDenied {
+		t.Fatalf("advance by a non-member: %v", err)
+	}
+	_, err = s.AdvanceRound(ctx, &gatewayapiv1.AdvanceRoundRequest{Id: id, Room: "giiku-sai", Member: "alice", State: gatewayapiv1.RoundState_ROUND_STATE_ORDERED})
+	if st, _ := status.FromError(err); st.Code() != codes.FailedPrecondition {
+		t.Fatalf("skipping to ordered: %v", err)
+	}
+	_, err = s.AdvanceRound(ctx, &gatewayapiv1.AdvanceRoundRequest{Id: id, Room: "giiku-sai", Member: "alice"})
+	if st, _ := status.FromError(err); st.Code() != codes.InvalidArgument {
+		t.Fatalf("unspecified state: %v", err)
+	}
+	_, err = s.GetRound(ctx, &gatewayapiv1.GetRoundRequest{Id: "nope"})
+	if st, _ := status.FromError(err); st.Code() != codes.NotFound {
+		t.Fatalf("unknown round: %v", err)
+	}
+
+	got, err := s.GetRound(ctx, &gatewayapiv1.GetRoundRequest{Id: id})
+	if err != nil || len(got.GetRound().GetHistory()) != 2 || got.GetRound().GetDeadlineUnixMs() == 0 {
+		t.Fatalf("get = %v, %v", got, err)
+	}
+}
+
+func TestRoundHandler_GetRound(t *testing.T) {
+	uc := usecase.NewRoundUsecase(roundstore.NewMemory())
+	ctx := context.Background()
+	r, _ := uc.OpenRound(ctx, "giiku-sai", "alice")
+	for _, s := range []usecase.RoundState{usecase.RoundCountdown, usecase.RoundChanting, usecase.RoundConfirming} {
+		r, _ = uc.AdvanceRound(ctx, r.ID, "giiku-sai", "alice", s, time.Now(), "")
+	}
+	if _, err := uc.AdvanceRound(ctx, r.ID, "giiku-sai", "alice", usecase.RoundFailed, time.Time{}, "store rejected the order"); err != nil {
+		t.Fatal(err)
+	}
+	h := NewRoundHandler(uc)
+
+	rec := httptest.NewRecorder()
+	h.GetRound(rec, httptest.NewRequest(http.MethodGet, "/api/v1/rounds/"+r.ID, nil), r.ID)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var body openapi.Round
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if body.Id != r.ID || body.MenuItemId != "giiku-sai" || body.State != openapi.Failed || body.Reason == nil || *body.Reason != "store rejected the order" {
+		t.Fatalf("unexpected body: %s", rec.Body.String())
+	}
+	if body.Deadline != nil || body.StartAt == nil || len(body.History) != 5 || body.History[4].State != openapi.Failed {
+		t.Fatalf("unexpected body: %s", rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	h.GetRound(rec, httptest.NewRequest(http.MethodGet, "/api/v1/rounds/nope", nil), "nope")
+	if rec.Code != http.StatusNotFound || rec.Header().Get("Content-Type") != apperror.ProblemContentType {
+		t.Fatalf("expected 404 problem, got %d %s", rec.Code, rec.Header().Get("Content-Type"))
+	}
+}
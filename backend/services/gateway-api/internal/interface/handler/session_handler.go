@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"chantingkakigori/pkg/apperror"
+	"chantingkakigori/pkg/auth"
+	"chantingkakigori/pkg/logging"
+	openapi "chantingkakigori/services/gateway-api/internal/swagger"
+	"chantingkakigori/services/gateway-api/internal/usecase"
+)
+
+// SessionHandler issues session tokens once a participant has picked a menu item.
+type SessionHandler struct {
+	Fetcher usecase.MenuFetcher
+	Signer  *auth.Signer
+}
+
+func NewSessionHandler(f usecase.MenuFetcher, s *auth.Signer) *SessionHandler {
+	return &SessionHandler{Fetcher: f, Signer: s}
+}
+
+// PostSession processes POST /v1/sessions requests. The token binds the
+// caller to the room of the chosen menu item (room ID == menu item ID).
+func (h *SessionHandler) PostSession(w http.ResponseWriter, r *http.Request, storeID string) {
+	ctx := r.Context()
+	if _, hasDeadline := ctx.Deadline(); !hasDeadline {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, 10*time.Second)
+		defer cancel()
+	}
+
+	if h.Signer == nil {
+		apperror.WriteProblem(w, r, apperror.New(apperror.CodeUnavailable, "authentication is disabled"))
+		return
+	}
+
+	var body openapi.PostApiV1SessionsJSONRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.MenuItemId == "" {
+		apperror.WriteProblem(w, r, apperror.New(apperror.CodeInvalidArgument, "Invalid request body"))
+		return
+	}
+
+	items, err := h.Fetcher.FetchMenu(ctx, storeID)
+	if err != nil {
+		apperror.WriteProblem(w, r, apperror.From(err, apperror.CodeUpstream))
+		return
+	}
+	found := false
+	for _, it := range *items {
+		if it.Id != nil && *it.Id == body.MenuItemId {
+			found = true
+			break
+		}
+	}
+	if !found {
+		apperror.WriteProblem(w, r, apperror.New(apperror.CodeNotFound, "menu item not found"))
+		return
+	}
+
+	token, claims, err := h.Signer.Issue(body.MenuItemId, body.MenuItemId)
+	if err != nil {
+		apperror.WriteProblem(w, r, apperror.From(err, apperror.CodeInternal))
+		return
+	}
+	slog.InfoContext(ctx, "session issued", logging.Room(claims.Room), logging.Client(claims.Subject))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(openapi.Session{
+		Token:      token,
+		Room:       claims.Room,
+		MenuItemId: claims.MenuItemID,
+		ExpiresAt:  claims.Expiry().UTC(),
+	})
+}
@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"chantingkakigori/pkg/auth"
+	openapi "chantingkakigori/services/gateway-api/internal/swagger"
+)
+
+func TestSessionHandler_IssuesTokenForMenuItem(t *testing.T) {
+	id := "giiku-sai"
+	signer := auth.NewSigner([]byte("0123456789abcdef0123456789abcdef"), time.Minute)
+	h := NewSessionHandler(fakeMenuFetcher{items: []openapi.MenuItem{{Id: &id}}}, signer)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/sessions", strings.NewReader(`{"menu_item_id":"giiku-sai"}`))
+	rec := httptest.NewRecorder()
+	h.PostSession(rec, req, "HKWZRTNL")
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp openapi.Session
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	c, err := signer.Verify(resp.Token)
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if c.Room != id || c.MenuItemID != id || resp.Room != id {
+		t.Fatalf("unexpected claims %+v / response %+v", c, resp)
+	}
+}
+
+func TestSessionHandler_UnknownMenuItem(t *testing.T) {
+	id := "giiku-sai"
+	h := NewSessionHandler(fakeMenuFetcher{items: []openapi.MenuItem{{Id: &id}}},
+		auth.NewSigner([]byte("0123456789abcdef0123456789abcdef"), time.Minute))
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/sessions", strings.NewReader(`{"menu_item_id":"nope"}`))
+	rec := httptest.NewRecorder()
+	h.PostSession(rec, req, "HKWZRTNL")
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rec.Code)
+	}
+}
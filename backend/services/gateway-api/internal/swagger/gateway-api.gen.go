@@ -3,6 +3,20 @@
 // Code generated by github.com/oapi-codegen/oapi-codegen/v2 version v2.5.0 DO NOT EDIT.
 package openapi
 
+import (
+	"time"
+)
+
+const (
+	SessionTokenScopes = "sessionToken.Scopes"
+)
+
+// Defines values for LeaderboardPeriod.
+const (
+	LeaderboardPeriodAll   LeaderboardPeriod = "all"
+	LeaderboardPeriodDaily LeaderboardPeriod = "daily"
+)
+
 // Defines values for OrderResponseStatus.
 const (
 	Completed     OrderResponseStatus = "completed"
@@ -10,6 +24,37 @@ const (
 	WaitingPickup OrderResponseStatus = "waitingPickup"
 )
 
+// Defines values for ProblemCode.
+const (
+	ProblemCodeConflict         ProblemCode = "conflict"
+	ProblemCodeInternal         ProblemCode = "internal"
+	ProblemCodeInvalidArgument  ProblemCode = "invalid_argument"
+	ProblemCodeNotFound         ProblemCode = "not_found"
+	ProblemCodePermissionDenied ProblemCode = "permission_denied"
+	ProblemCodeRateLimited      ProblemCode = "rate_limited"
+	ProblemCodeTimeout          ProblemCode = "timeout"
+	ProblemCodeUnauthenticated  ProblemCode = "unauthenticated"
+	ProblemCodeUnavailable      ProblemCode = "unavailable"
+	ProblemCodeUpstreamError    ProblemCode = "upstream_error"
+)
+
+// Defines values for RoundState.
+const (
+	Chanting   RoundState = "chanting"
+	Confirming RoundState = "confirming"
+	Countdown  RoundState = "countdown"
+	Expired    RoundState = "expired"
+	Failed     RoundState = "failed"
+	Lobby      RoundState = "lobby"
+	Ordered    RoundState = "ordered"
+)
+
+// Defines values for GetApiV1LeaderboardParamsPeriod.
+const (
+	GetApiV1LeaderboardParamsPeriodAll   GetApiV1LeaderboardParamsPeriod = "all"
+	GetApiV1LeaderboardParamsPeriodDaily GetApiV1LeaderboardParamsPeriod = "daily"
+)
+
 // Defines values for PostApiV1ChantJSONBodyMenuItemId.
 const (
 	GiikuCamp PostApiV1ChantJSONBodyMenuItemId = "giiku-camp"
@@ -18,10 +63,38 @@ const (
 	GiikuTen  PostApiV1ChantJSONBodyMenuItemId = "giiku-ten"
 )
 
-// ErrorResponse defines model for ErrorResponse.
-type ErrorResponse struct {
-	Error   *string `json:"error,omitempty"`
-	Message *string `json:"message,omitempty"`
+// Leaderboard defines model for Leaderboard.
+type Leaderboard struct {
+	// Date Day ranked (YYYY-MM-DD, Japan time); only for the daily board.
+	Date *string `json:"date,omitempty"`
+
+	// Entries Best first: by peak (to three decimals), then time above threshold.
+	Entries    []LeaderboardEntry `json:"entries"`
+	MenuItemId *string            `json:"menu_item_id,omitempty"`
+	Period     LeaderboardPeriod  `json:"period"`
+
+	// Threshold Room average that counts towards above_threshold_seconds.
+	Threshold float64 `json:"threshold"`
+}
+
+// LeaderboardPeriod defines model for Leaderboard.Period.
+type LeaderboardPeriod string
+
+// LeaderboardEntry One finished round, from its first sample until its last participant left.
+type LeaderboardEntry struct {
+	// AboveThresholdSeconds Time the room average stayed at or above the threshold.
+	AboveThresholdSeconds float64   `json:"above_threshold_seconds"`
+	EndedAt               time.Time `json:"ended_at"`
+	MenuItemId            string    `json:"menu_item_id"`
+	Participants          int       `json:"participants"`
+
+	// Peak Highest room average during the round (0..1).
+	Peak float64 `json:"peak"`
+	Rank int     `json:"rank"`
+
+	// RoundId The party's round (GET /api/v1/rounds/{round_id}); omitted for chants without one.
+	RoundId   *string   `json:"round_id,omitempty"`
+	StartedAt time.Time `json:"started_at"`
 }
 
 // MenuItem defines model for MenuItem.
@@ -43,6 +116,88 @@ type OrderResponse struct {
 // OrderResponseStatus defines model for OrderResponse.Status.
 type OrderResponseStatus string
 
+// Problem RFC 7807 problem details. `code` is a stable error code clients may branch on.
+type Problem struct {
+	Code     ProblemCode `json:"code"`
+	Detail   *string     `json:"detail,omitempty"`
+	Instance *string     `json:"instance,omitempty"`
+	Status   int         `json:"status"`
+	Title    string      `json:"title"`
+	Type     string      `json:"type"`
+}
+
+// ProblemCode defines model for Problem.Code.
+type ProblemCode string
+
+// Round A party's round from the lobby (/ws/stay) to its order (/ws/confirm).
+type Round struct {
+	CreatedAt time.Time `json:"created_at"`
+
+	// Deadline When the current state expires; absent once the round has ended.
+	Deadline *time.Time `json:"deadline,omitempty"`
+
+	// History Every state the round entered, oldest first.
+	History    []RoundTransition `json:"history"`
+	Id         string            `json:"id"`
+	MenuItemId string            `json:"menu_item_id"`
+
+	// Reason Why the round failed or expired.
+	Reason *string `json:"reason,omitempty"`
+
+	// StartAt When chanting starts; absent until the countdown.
+	StartAt   *time.Time `json:"start_at,omitempty"`
+	State     RoundState `json:"state"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// RoundState defines model for RoundState.
+type RoundState string
+
+// RoundTransition defines model for RoundTransition.
+type RoundTransition struct {
+	At     time.Time  `json:"at"`
+	Reason *string    `json:"reason,omitempty"`
+	State  RoundState `json:"state"`
+}
+
+// Session defines model for Session.
+type Session struct {
+	ExpiresAt  time.Time `json:"expires_at"`
+	MenuItemId string    `json:"menu_item_id"`
+	Room       string    `json:"room"`
+	Token      string    `json:"token"`
+}
+
+// PermissionDenied RFC 7807 problem details. `code` is a stable error code clients may branch on.
+type PermissionDenied = Problem
+
+// RateLimited RFC 7807 problem details. `code` is a stable error code clients may branch on.
+type RateLimited = Problem
+
+// Unauthenticated RFC 7807 problem details. `code` is a stable error code clients may branch on.
+type Unauthenticated = Problem
+
+// UpstreamError RFC 7807 problem details. `code` is a stable error code clients may branch on.
+type UpstreamError = Problem
+
+// GetApiV1LeaderboardParams defines parameters for GetApiV1Leaderboard.
+type GetApiV1LeaderboardParams struct {
+	// Period daily ranks one day's rounds, all every round.
+	Period *GetApiV1LeaderboardParamsPeriod `form:"period,omitempty" json:"period,omitempty"`
+
+	// Date Day to rank (YYYY-MM-DD, Japan time) for period=daily; today by default.
+	Date *string `form:"date,omitempty" json:"date,omitempty"`
+
+	// MenuItemId Rank one menu item's rounds only.
+	MenuItemId *string `form:"menu_item_id,omitempty" json:"menu_item_id,omitempty"`
+
+	// Limit Rounds to return.
+	Limit *int `form:"limit,omitempty" json:"limit,omitempty"`
+}
+
+// GetApiV1LeaderboardParamsPeriod defines parameters for GetApiV1Leaderboard.
+type GetApiV1LeaderboardParamsPeriod string
+
 // PostApiV1ChantJSONBody defines parameters for PostApiV1Chant.
 type PostApiV1ChantJSONBody struct {
 	MenuItemId *PostApiV1ChantJSONBodyMenuItemId `json:"menu_item_id,omitempty"`
@@ -51,6 +206,11 @@ type PostApiV1ChantJSONBody struct {
 // PostApiV1ChantJSONBodyMenuItemId defines parameters for PostApiV1Chant.
 type PostApiV1ChantJSONBodyMenuItemId string
 
+// PostApiV1SessionsJSONBody defines parameters for PostApiV1Sessions.
+type PostApiV1SessionsJSONBody struct {
+	MenuItemId string `json:"menu_item_id"`
+}
+
 // PostApiV1StoresOrdersJSONBody defines parameters for PostApiV1StoresOrders.
 type PostApiV1StoresOrdersJSONBody struct {
 	MenuItemId *string `json:"menu_item_id,omitempty"`
@@ -59,5 +219,8 @@ type PostApiV1StoresOrdersJSONBody struct {
 // PostApiV1ChantJSONRequestBody defines body for PostApiV1Chant for application/json ContentType.
 type PostApiV1ChantJSONRequestBody PostApiV1ChantJSONBody
 
+// PostApiV1SessionsJSONRequestBody defines body for PostApiV1Sessions for application/json ContentType.
+type PostApiV1SessionsJSONRequestBody PostApiV1SessionsJSONBody
+
 // PostApiV1StoresOrdersJSONRequestBody defines body for PostApiV1StoresOrders for application/json ContentType.
 type PostApiV1StoresOrdersJSONRequestBody PostApiV1StoresOrdersJSONBody
@@ -4,9 +4,15 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"time"
 
+	"chantingkakigori/pkg/apperror"
+	"chantingkakigori/pkg/metrics"
+	"chantingkakigori/pkg/tracing"
 	openapi "chantingkakigori/services/gateway-api/internal/swagger"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/genai"
 )
 
@@ -33,15 +39,19 @@ func NewChantUsecase() (*ChantClient, error) {
 		Model:  Model,
 		APIKey: apiKey,
 		Generate: func(ctx context.Context, model string, prompt string) (string, error) {
+			start := time.Now()
+			defer func() { metrics.GeminiDuration.WithLabelValues(model).Observe(time.Since(start).Seconds()) }()
 			client, err := genai.NewClient(ctx, &genai.ClientConfig{
 				APIKey:  apiKey,
 				Backend: genai.BackendGeminiAPI,
 			})
 			if err != nil {
+				metrics.GeminiErrors.WithLabelValues(model).Inc()
 				return "", fmt.Errorf("genai client: %w", err)
 			}
 			result, err := client.Models.GenerateContent(ctx, model, genai.Text(prompt), nil)
 			if err != nil {
+				metrics.GeminiErrors.WithLabelValues(model).Inc()
 				return "", fmt.Errorf("generate content: %w", err)
 			}
 			return result.Text(), nil
@@ -57,12 +67,18 @@ type ChantUsecase interface {
 	GenerateChant(ctx context.Context, body openapi.PostApiV1ChantJSONRequestBody) (*ChantResponse, error)
 }
 
-func (c *ChantClient) GenerateChant(ctx context.Context, body openapi.PostApiV1ChantJSONRequestBody) (*ChantResponse, error) {
+func (c *ChantClient) GenerateChant(ctx context.Context, body openapi.PostApiV1ChantJSONRequestBody) (_ *ChantResponse, err error) {
+	ctx, span := tracing.Start(ctx, "ChantClient.GenerateChant", trace.WithAttributes(attribute.String("gemini.model", c.Model)))
+	defer func() {
+		tracing.RecordError(span, err)
+		span.End()
+	}()
+
 	if body.MenuItemId == nil {
-		return nil, fmt.Errorf("invalid request")
+		return nil, apperror.New(apperror.CodeInvalidArgument, "invalid request")
 	}
 	if c.APIKey == "" {
-		return nil, fmt.Errorf("missing GEMINI_API_KEY")
+		return nil, apperror.New(apperror.CodeInternal, "missing GEMINI_API_KEY")
 	}
 
 	event := ""
@@ -81,7 +97,7 @@ func (c *ChantClient) GenerateChant(ctx context.Context, body openapi.PostApiV1C
 		event = "技育キャンプ"
 		flavor = "オレンジ"
 	default:
-		return nil, fmt.Errorf("invalid menu_item_id")
+		return nil, apperror.Newf(apperror.CodeInvalidArgument, "invalid menu_item_id: %s", *body.MenuItemId)
 	}
 
 	prompt := fmt.Sprintf(
@@ -94,7 +110,7 @@ func (c *ChantClient) GenerateChant(ctx context.Context, body openapi.PostApiV1C
 		var err error
 		text, err = c.Generate(ctx, c.Model, prompt)
 		if err != nil {
-			return nil, err
+			return nil, apperror.Wrap(apperror.CodeUpstream, err, "chant generation failed")
 		}
 	} else {
 		// Fallback path (should not normally happen because NewChantUsecase sets Generate)
@@ -103,16 +119,16 @@ func (c *ChantClient) GenerateChant(ctx context.Context, body openapi.PostApiV1C
 			Backend: genai.BackendGeminiAPI,
 		})
 		if err != nil {
-			return nil, fmt.Errorf("genai client: %w", err)
+			return nil, apperror.Wrap(apperror.CodeUpstream, err, "chant generation failed")
 		}
 		result, err := client.Models.GenerateContent(ctx, c.Model, genai.Text(prompt), nil)
 		if err != nil {
-			return nil, fmt.Errorf("generate content: %w", err)
+			return nil, apperror.Wrap(apperror.CodeUpstream, err, "chant generation failed")
 		}
 		text = result.Text()
 	}
 	if text == "" {
-		return nil, fmt.Errorf("empty gemini response")
+		return nil, apperror.New(apperror.CodeUpstream, "empty gemini response")
 	}
 	return &ChantResponse{Chant: text}, nil
 }
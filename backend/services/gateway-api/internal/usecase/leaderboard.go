@@ -0,0 +1,80 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	kakigoriwsv1 "chantingkakigori/gen/go/kakigori_ws/v1"
+	"chantingkakigori/pkg/apperror"
+	"chantingkakigori/pkg/tracing"
+	openapi "chantingkakigori/services/gateway-api/internal/swagger"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Japan is the booth's time zone; a daily leaderboard's day runs midnight to
+// midnight in it.
+var Japan = time.FixedZone("JST", 9*60*60)
+
+// LeaderboardQuery is a validated leaderboard request.
+type LeaderboardQuery struct {
+	// Date is the day (YYYY-MM-DD) ranked, "" for all time.
+	Date       string
+	MenuItemID string
+	Limit      int
+}
+
+// LeaderboardFetcher is the interface used by handlers to rank rounds.
+type LeaderboardFetcher interface {
+	FetchLeaderboard(ctx context.Context, q LeaderboardQuery) (*openapi.Leaderboard, error)
+}
+
+// LeaderboardClient asks kakigori-ws, which records every finished round.
+type LeaderboardClient struct {
+	Client kakigoriwsv1.KakigoriWsAggregatorServiceClient
+}
+
+// NewLeaderboardUsecase returns a LeaderboardClient on c.
+func NewLeaderboardUsecase(c kakigoriwsv1.KakigoriWsAggregatorServiceClient) *LeaderboardClient {
+	return &LeaderboardClient{Client: c}
+}
+
+// FetchLeaderboard ranks the rounds q selects, best first.
+func (u *LeaderboardClient) FetchLeaderboard(ctx context.Context, q LeaderboardQuery) (_ *openapi.Leaderboard, err error) {
+	ctx, span := tracing.Start(ctx, "LeaderboardClient.FetchLeaderboard", trace.WithAttributes(
+		attribute.String("leaderboard.date", q.Date), attribute.String("menu.item_id", q.MenuItemID)))
+	defer func() {
+		tracing.RecordError(span, err)
+		span.End()
+	}()
+
+	res, err := u.Client.Leaderboard(ctx, &kakigoriwsv1.LeaderboardRequest{MenuItemId: q.MenuItemID, Day: q.Date, Limit: int32(q.Limit)})
+	if err != nil {
+		return nil, apperror.FromGRPC(err)
+	}
+	out := &openapi.Leaderboard{Period: openapi.LeaderboardPeriodAll, Threshold: res.GetThreshold(), Entries: []openapi.LeaderboardEntry{}}
+	if q.Date != "" {
+		out.Period = openapi.LeaderboardPeriodDaily
+		out.Date = &q.Date
+	}
+	if q.MenuItemID != "" {
+		out.MenuItemId = &q.MenuItemID
+	}
+	for i, r := range res.GetRounds() {
+		e := openapi.LeaderboardEntry{
+			Rank:                  i + 1,
+			MenuItemId:            r.GetRoom(),
+			Peak:                  r.GetPeak(),
+			AboveThresholdSeconds: float64(r.GetAboveThresholdMs()) / 1000,
+			Participants:          int(r.GetParticipants()),
+			StartedAt:             time.UnixMilli(r.GetStartedAtUnixMs()).In(Japan),
+			EndedAt:               time.UnixMilli(r.GetEndedAtUnixMs()).In(Japan),
+		}
+		if id := r.GetRoundId(); id != "" {
+			e.RoundId = &id
+		}
+		out.Entries = append(out.Entries, e)
+	}
+	return out, nil
+}
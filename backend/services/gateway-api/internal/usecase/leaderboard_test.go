@@ -0,0 +1,56 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+
+	kakigoriwsv1 "chantingkakigori/gen/go/kakigori_ws/v1"
+	"chantingkakigori/pkg/apperror"
+	openapi "chantingkakigori/services/gateway-api/internal/swagger"
+
+	"google.golang.org/grpc"
+)
+
+type fakeAggregatorClient struct {
+	kakigoriwsv1.KakigoriWsAggregatorServiceClient
+	got *kakigoriwsv1.LeaderboardRequest
+	res *kakigoriwsv1.LeaderboardResponse
+	err error
+}
+
+func (f *fakeAggregatorClient) Leaderboard(_ context.Context, req *kakigoriwsv1.LeaderboardRequest, _ ...grpc.CallOption) (*kakigoriwsv1.LeaderboardResponse, error) {
+	f.got = req
+	return f.res, f.err
+}
+
+func TestFetchLeaderboard_Success(t *testing.T) {
+	c := &fakeAggregatorClient{res: &kakigoriwsv1.LeaderboardResponse{Threshold: 0.5, Rounds: []*kakigoriwsv1.RoundStats{
+		{Room: "giiku-sai", StartedAtUnixMs: 1_790_000_000_000, EndedAtUnixMs: 1_790_000_030_000, Peak: 0.9, AboveThresholdMs: 12_500, Participants: 3, RoundId: "r-1"},
+		{Room: "giiku-sai", Peak: 0.7, Participants: 2},
+	}}}
+	uc := NewLeaderboardUsecase(c)
+
+	board, err := uc.FetchLeaderboard(context.Background(), LeaderboardQuery{Date: "2026-10-01", MenuItemID: "giiku-sai", Limit: 5})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.got.GetDay() != "2026-10-01" || c.got.GetMenuItemId() != "giiku-sai" || c.got.GetLimit() != 5 {
+		t.Fatalf("request=%v", c.got)
+	}
+	if board.Period != openapi.LeaderboardPeriodDaily || board.Date == nil || *board.Date != "2026-10-01" || board.Threshold != 0.5 {
+		t.Fatalf("board=%+v", board)
+	}
+	e := board.Entries[0]
+	if len(board.Entries) != 2 || e.Rank != 1 || board.Entries[1].Rank != 2 || e.AboveThresholdSeconds != 12.5 || e.Participants != 3 ||
+		e.EndedAt.Sub(e.StartedAt).Seconds() != 30 || e.RoundId == nil || *e.RoundId != "r-1" || board.Entries[1].RoundId != nil {
+		t.Fatalf("entries=%+v", board.Entries)
+	}
+}
+
+func TestFetchLeaderboard_Error(t *testing.T) {
+	c := &fakeAggregatorClient{err: apperror.ToGRPC(apperror.New(apperror.CodeUnavailable, "rounds are not recorded"))}
+	_, err := NewLeaderboardUsecase(c).FetchLeaderboard(context.Background(), LeaderboardQuery{Limit: 10})
+	if ae := apperror.From(err, apperror.CodeInternal); ae.Code != apperror.CodeUnavailable {
+		t.Fatalf("err=%v", err)
+	}
+}
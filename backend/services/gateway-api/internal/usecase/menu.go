@@ -10,7 +10,12 @@ import (
 	"time"
 
 	httpclient "chantingkakigori/pkg/httpclient"
+	"chantingkakigori/pkg/metrics"
+	"chantingkakigori/pkg/tracing"
 	openapi "chantingkakigori/services/gateway-api/internal/swagger"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // MenuUsecase fetches store menus from the upstream API.
@@ -24,7 +29,8 @@ func NewMenuUsecase(baseURL string) *MenuClient {
 	return &MenuClient{
 		BaseURL: baseURL,
 		Client: &http.Client{
-			Timeout: 10 * time.Second,
+			Timeout:   10 * time.Second,
+			Transport: tracing.Transport(metrics.InstrumentRoundTripper("store-api", http.DefaultTransport)),
 		},
 	}
 }
@@ -35,7 +41,13 @@ type MenuFetcher interface {
 }
 
 // FetchMenu retrieves menu items for the given storeID from upstream and converts them into the swagger-generated types.
-func (u *MenuClient) FetchMenu(ctx context.Context, storeID string) (*[]openapi.MenuItem, error) {
+func (u *MenuClient) FetchMenu(ctx context.Context, storeID string) (_ *[]openapi.MenuItem, err error) {
+	ctx, span := tracing.Start(ctx, "MenuClient.FetchMenu", trace.WithAttributes(attribute.String("store.id", storeID)))
+	defer func() {
+		tracing.RecordError(span, err)
+		span.End()
+	}()
+
 	base, err := url.Parse(u.BaseURL)
 	if err != nil {
 		return nil, fmt.Errorf("invalid base url: %w", err)
@@ -57,7 +69,7 @@ func (u *MenuClient) FetchMenu(ctx context.Context, storeID string) (*[]openapi.
 		// Read a small portion of the body for diagnostics
 		limited := io.LimitReader(resp.Body, 1024)
 		b, _ := io.ReadAll(limited)
-		return nil, fmt.Errorf("upstream returned status %d: %s", resp.StatusCode, string(b))
+		return nil, &UpstreamError{StatusCode: resp.StatusCode, Body: string(b)}
 	}
 
 	var upstream struct {
@@ -6,12 +6,20 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"time"
 
+	"chantingkakigori/pkg/apperror"
 	httpclient "chantingkakigori/pkg/httpclient"
+	"chantingkakigori/pkg/logging"
+	"chantingkakigori/pkg/metrics"
+	"chantingkakigori/pkg/tracing"
 	openapi "chantingkakigori/services/gateway-api/internal/swagger"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // OrderClient fetches store orders from the upstream API.
@@ -25,7 +33,8 @@ func NewOrderUsecase(baseURL string) *OrderClient {
 	return &OrderClient{
 		BaseURL: baseURL,
 		Client: &http.Client{
-			Timeout: 10 * time.Second,
+			Timeout:   10 * time.Second,
+			Transport: tracing.Transport(metrics.InstrumentRoundTripper("store-api", http.DefaultTransport)),
 		},
 	}
 }
@@ -45,7 +54,30 @@ func (e *UpstreamError) Error() string {
 	return fmt.Sprintf("upstream returned status %d: %s", e.StatusCode, e.Body)
 }
 
-func (u *OrderClient) PostOrder(ctx context.Context, storeID string, menuItemID string) (*openapi.OrderResponse, error) {
+// AppError maps the upstream status onto the shared error model: client errors
+// are passed through, anything else is reported as an upstream failure whose
+// body is kept for logs only.
+func (e *UpstreamError) AppError() *apperror.Error {
+	switch e.StatusCode {
+	case http.StatusBadRequest:
+		return apperror.Wrap(apperror.CodeInvalidArgument, e, e.Body)
+	case http.StatusNotFound:
+		return apperror.Wrap(apperror.CodeNotFound, e, e.Body)
+	default:
+		return apperror.Wrap(apperror.CodeUpstream, e, apperror.CodeUpstream.DefaultMessage())
+	}
+}
+
+func (u *OrderClient) PostOrder(ctx context.Context, storeID string, menuItemID string) (_ *openapi.OrderResponse, err error) {
+	ctx, span := tracing.Start(ctx, "OrderClient.PostOrder", trace.WithAttributes(
+		attribute.String("store.id", storeID),
+		attribute.String("menu_item.id", menuItemID),
+	))
+	defer func() {
+		tracing.RecordError(span, err)
+		span.End()
+	}()
+
 	base, err := url.Parse(u.BaseURL)
 	if err != nil {
 		return nil, fmt.Errorf("invalid base url: %w", err)
@@ -100,6 +132,8 @@ func (u *OrderClient) PostOrder(ctx context.Context, storeID string, menuItemID
 		menuName := first.MenuName
 		orderNumber := first.OrderNumber
 		status := first.Status
+		metrics.OrdersPlaced.WithLabelValues(menuItemId).Inc()
+		slog.InfoContext(ctx, "order placed", logging.OrderID(id), slog.String("menu_item_id", menuItemId))
 		return &openapi.OrderResponse{
 			Id:          &id,
 			MenuItemId:  &menuItemId,
@@ -123,6 +157,8 @@ func (u *OrderClient) PostOrder(ctx context.Context, storeID string, menuItemID
 		menuName := single.MenuName
 		orderNumber := single.OrderNumber
 		status := single.Status
+		metrics.OrdersPlaced.WithLabelValues(menuItemId).Inc()
+		slog.InfoContext(ctx, "order placed", logging.OrderID(id), slog.String("menu_item_id", menuItemId))
 		return &openapi.OrderResponse{
 			Id:          &id,
 			MenuItemId:  &menuItemId,
@@ -132,10 +168,19 @@ func (u *OrderClient) PostOrder(ctx context.Context, storeID string, menuItemID
 		}, nil
 	}
 
-	return nil, fmt.Errorf("empty or unrecognized upstream response")
+	return nil, apperror.New(apperror.CodeUpstream, "empty or unrecognized upstream response")
 }
 
-func (u *OrderClient) GetOrderByID(ctx context.Context, storeID string, orderID string) (*openapi.OrderResponse, error) {
+func (u *OrderClient) GetOrderByID(ctx context.Context, storeID string, orderID string) (_ *openapi.OrderResponse, err error) {
+	ctx, span := tracing.Start(ctx, "OrderClient.GetOrderByID", trace.WithAttributes(
+		attribute.String("store.id", storeID),
+		attribute.String("order.id", orderID),
+	))
+	defer func() {
+		tracing.RecordError(span, err)
+		span.End()
+	}()
+
 	base, err := url.Parse(u.BaseURL)
 	if err != nil {
 		return nil, fmt.Errorf("invalid base url: %w", err)
@@ -177,5 +222,5 @@ func (u *OrderClient) GetOrderByID(ctx context.Context, storeID string, orderID
 		return &single, nil
 	}
 
-	return nil, fmt.Errorf("empty or unrecognized upstream response")
+	return nil, apperror.New(apperror.CodeUpstream, "empty or unrecognized upstream response")
 }
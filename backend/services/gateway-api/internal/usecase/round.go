@@ -0,0 +1,330 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"chantingkakigori/pkg/apperror"
+	"chantingkakigori/pkg/metrics"
+)
+
+// RoundState is where a party's round is between gathering in the lobby and
+// getting its order.
+type RoundState string
+
+const (
+	RoundLobby      RoundState = "lobby"
+	RoundCountdown  RoundState = "countdown"
+	RoundChanting   RoundState = "chanting"
+	RoundConfirming RoundState = "confirming"
+	RoundOrdered    RoundState = "ordered"
+	RoundFailed     RoundState = "failed"
+	RoundExpired    RoundState = "expired"
+)
+
+// roundMoves lists where each state may go. Expired is only reached by
+// overstaying a deadline. A party split over /ws/confirm replicas reports
+// once per replica, so a replica whose orders all failed may report before
+// one whose orders went through: failed gives way to ordered, never the
+// other way round.
+var roundMoves = map[RoundState][]RoundState{
+	RoundLobby:      {RoundCountdown},
+	RoundCountdown:  {RoundChanting},
+	RoundChanting:   {RoundConfirming},
+	RoundConfirming: {RoundOrdered, RoundFailed},
+	RoundFailed:     {RoundOrdered},
+}
+
+// Ended reports whether s has no deadline left. Only failed can still move,
+// to ordered.
+func (s RoundState) Ended() bool {
+	return s == RoundOrdered || s == RoundFailed || s == RoundExpired
+}
+
+// CanMoveTo reports whether the state machine allows s -> to.
+func (s RoundState) CanMoveTo(to RoundState) bool {
+	for _, next := range roundMoves[s] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// RoundTransition is one state a round entered.
+type RoundTransition struct {
+	State  RoundState `json:"state"`
+	At     time.Time  `json:"at"`
+	Reason string     `json:"reason,omitempty"`
+}
+
+// Round is one party's visit: it gathers in the lobby (/ws/stay), counts
+// down, chants (/ws), confirms (/ws/confirm) and ends ordered or failed, or
+// expired when a state overstays its deadline.
+type Round struct {
+	ID string `json:"id"`
+	// Room is the menu item ID.
+	Room      string     `json:"room"`
+	State     RoundState `json:"state"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	// Deadline is when State expires; zero once the round has ended.
+	Deadline time.Time `json:"deadline"`
+	// StartAt is when chanting starts; zero until the countdown.
+	StartAt time.Time `json:"start_at"`
+	// Reason says why the round failed or expired.
+	Reason  string            `json:"reason,omitempty"`
+	History []RoundTransition `json:"history"`
+	// Members are the session subjects that joined in the lobby (or during
+	// the countdown); only they may move the round.
+	Members []string `json:"members,omitempty"`
+}
+
+// HasMember reports whether subject joined r.
+func (r Round) HasMember(subject string) bool {
+	for _, m := range r.Members {
+		if m == subject {
+			return true
+		}
+	}
+	return false
+}
+
+// RoundTimeouts bound how long a round may stay in each state.
+type RoundTimeouts struct {
+	// Lobby is how long a party has to gather.
+	Lobby time.Duration
+	// Countdown runs from the chant's start time, for the chant sockets to
+	// open.
+	Countdown time.Duration
+	// Chanting is how long the chant may run before confirming.
+	Chanting time.Duration
+	// Confirming covers /ws/confirm's wait for everyone and the orders.
+	Confirming time.Duration
+}
+
+// DefaultRoundTimeouts are generous next to the clients' own timers, so only
+// abandoned rounds expire.
+var DefaultRoundTimeouts = RoundTimeouts{
+	Lobby:      10 * time.Minute,
+	Countdown:  30 * time.Second,
+	Chanting:   3 * time.Minute,
+	Confirming: 5 * time.Minute,
+}
+
+// RoundStore keeps rounds and each room's lobby round. fn and keep may run
+// more than once when a store retries a contended write.
+type RoundStore interface {
+	// Get returns the round with id; ok is false if there is none.
+	Get(ctx context.Context, id string) (r Round, ok bool, err error)
+	// Update applies fn to the round with id atomically, storing the result
+	// when fn reports a change; ok is false if there is no such round. A
+	// round leaving the lobby stops being its room's lobby round.
+	Update(ctx context.Context, id string, fn func(r *Round) (changed bool)) (r Round, ok bool, err error)
+	// Lobby returns the room's lobby round if keep accepts it, otherwise
+	// stores open() as the room's lobby round and returns that.
+	Lobby(ctx context.Context, room string, keep func(Round) bool, open func() Round) (Round, error)
+}
+
+// RoundUsecase is the interface used by handlers to drive rounds. member is
+// the session subject of the participant a gateway calls for.
+type RoundUsecase interface {
+	OpenRound(ctx context.Context, room, member string) (Round, error)
+	GetRound(ctx context.Context, id string) (Round, error)
+	AdvanceRound(ctx context.Context, id, room, member string, to RoundState, startAt time.Time, reason string) (Round, error)
+}
+
+// RoundManager enforces the round state machine on a RoundStore. Deadlines
+// are checked whenever a round is read or moved, so an abandoned round reads
+// as expired without anything watching it.
+type RoundManager struct {
+	Store    RoundStore
+	Timeouts RoundTimeouts
+	// Now is overridable for testing.
+	Now func() time.Time
+}
+
+// NewRoundUsecase returns a RoundManager on store with the default timeouts.
+func NewRoundUsecase(store RoundStore) *RoundManager {
+	return &RoundManager{Store: store, Timeouts: DefaultRoundTimeouts, Now: time.Now}
+}
+
+// maxJoinAttempts bounds how often OpenRound looks for the lobby round again
+// when the one it found started before member could join it.
+const maxJoinAttempts = 3
+
+// OpenRound returns room's round in the lobby, opening one if there is none,
+// with member joined to it. A member may still join during the countdown,
+// as the party's last arrivals race the one that fills it.
+func (m *RoundManager) OpenRound(ctx context.Context, room, member string) (Round, error) {
+	if room == "" {
+		return Round{}, apperror.New(apperror.CodeInvalidArgument, "room is required")
+	}
+	if member == "" {
+		return Round{}, apperror.New(apperror.CodeInvalidArgument, "member is required")
+	}
+	for range maxJoinAttempts {
+		now := m.Now()
+		var opened string
+		r, err := m.Store.Lobby(ctx, room,
+			func(r Round) bool { return r.State == RoundLobby && !m.due(r, now) },
+			func() Round {
+				r := Round{ID: newRoundID(), Room: room, CreatedAt: now, Members: []string{member}}
+				m.enter(&r, RoundLobby, now, "")
+				opened = r.ID
+				return r
+			})
+		if err != nil {
+			return Round{}, apperror.Wrap(apperror.CodeUnavailable, err, "round store unavailable")
+		}
+		if r.ID == opened {
+			metrics.RoundTransitions.WithLabelValues(string(RoundLobby)).Inc()
+			return r, nil
+		}
+		joined := false
+		r, ok, err := m.Store.Update(ctx, r.ID, func(r *Round) bool {
+			joined = (r.State == RoundLobby || r.State == RoundCountdown) && !m.due(*r, now)
+			if !joined || r.HasMember(member) {
+				return false
+			}
+			r.Members = append(r.Members, member)
+			return true
+		})
+		if err != nil {
+			return Round{}, apperror.Wrap(apperror.CodeUnavailable, err, "round store unavailable")
+		}
+		if ok && joined {
+			return r, nil
+		}
+	}
+	return Round{}, apperror.New(apperror.CodeUnavailable, "lobby kept moving, try again")
+}
+
+// GetRound returns the round with id, expiring it first if it is overdue.
+func (m *RoundManager) GetRound(ctx context.Context, id string) (Round, error) {
+	now := m.Now()
+	expired := false
+	r, ok, err := m.Store.Update(ctx, id, func(r *Round) bool {
+		expired = m.expire(r, now)
+		return expired
+	})
+	if err != nil {
+		return Round{}, apperror.Wrap(apperror.CodeUnavailable, err, "round store unavailable")
+	}
+	if !ok {
+		return Round{}, apperror.New(apperror.CodeNotFound, "round not found")
+	}
+	if expired {
+		metrics.RoundTransitions.WithLabelValues(string(RoundExpired)).Inc()
+	}
+	return r, nil
+}
+
+// AdvanceRound moves the round with id to to on behalf of member, who must
+// have joined it in room. Moving a round to the state it is in returns it
+// unchanged, so callers on several replicas may all report the same step.
+// startAt is read when moving to the countdown.
+func (m *RoundManager) AdvanceRound(ctx context.Context, id, room, member string, to RoundState, startAt time.Time, reason string) (Round, error) {
+	switch to {
+	case RoundCountdown, RoundChanting, RoundConfirming, RoundOrdered, RoundFailed:
+	default:
+		return Round{}, apperror.Newf(apperror.CodeInvalidArgument, "cannot move a round to %q", to)
+	}
+	if room == "" {
+		return Round{}, apperror.New(apperror.CodeInvalidArgument, "room is required")
+	}
+	if member == "" {
+		return Round{}, apperror.New(apperror.CodeInvalidArgument, "member is required")
+	}
+	now := m.Now()
+	var moveErr *apperror.Error
+	var entered RoundState
+	r, ok, err := m.Store.Update(ctx, id, func(r *Round) bool {
+		moveErr, entered = nil, ""
+		switch {
+		case r.Room != room:
+			moveErr = apperror.Newf(apperror.CodeInvalidArgument, "round is not in room %s", room)
+			return false
+		case !r.HasMember(member):
+			moveErr = apperror.New(apperror.CodePermissionDenied, "not a member of this round")
+			return false
+		case m.expire(r, now):
+			entered = RoundExpired
+			moveErr = apperror.Newf(apperror.CodeConflict, "round %s", r.Reason)
+			return true
+		case r.State == to:
+			return false
+		case !r.State.CanMoveTo(to):
+			moveErr = apperror.Newf(apperror.CodeConflict, "round is %s and cannot move to %s", r.State, to)
+			return false
+		}
+		if to == RoundCountdown {
+			r.StartAt = startAt
+		}
+		m.enter(r, to, now, reason)
+		entered = to
+		return true
+	})
+	if err != nil {
+		return Round{}, apperror.Wrap(apperror.CodeUnavailable, err, "round store unavailable")
+	}
+	if !ok {
+		return Round{}, apperror.New(apperror.CodeNotFound, "round not found")
+	}
+	if entered != "" {
+		metrics.RoundTransitions.WithLabelValues(string(entered)).Inc()
+	}
+	if moveErr != nil {
+		return r, moveErr
+	}
+	return r, nil
+}
+
+// enter moves r to s at now and sets the new state's deadline.
+func (m *RoundManager) enter(r *Round, s RoundState, now time.Time, reason string) {
+	r.State = s
+	r.UpdatedAt = now
+	r.Reason = ""
+	if s == RoundFailed || s == RoundExpired {
+		r.Reason = reason
+	}
+	r.History = append(r.History, RoundTransition{State: s, At: now, Reason: reason})
+	switch s {
+	case RoundLobby:
+		r.Deadline = now.Add(m.Timeouts.Lobby)
+	case RoundCountdown:
+		from := r.StartAt
+		if from.Before(now) {
+			from = now
+		}
+		r.Deadline = from.Add(m.Timeouts.Countdown)
+	case RoundChanting:
+		r.Deadline = now.Add(m.Timeouts.Chanting)
+	case RoundConfirming:
+		r.Deadline = now.Add(m.Timeouts.Confirming)
+	default:
+		r.Deadline = time.Time{}
+	}
+}
+
+func (m *RoundManager) due(r Round, now time.Time) bool {
+	return !r.State.Ended() && !r.Deadline.IsZero() && !now.Before(r.Deadline)
+}
+
+// expire moves r to expired if its state's deadline has passed.
+func (m *RoundManager) expire(r *Round, now time.Time) bool {
+	if !m.due(*r, now) {
+		return false
+	}
+	m.enter(r, RoundExpired, now, fmt.Sprintf("expired in %s", r.State))
+	return true
+}
+
+func newRoundID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
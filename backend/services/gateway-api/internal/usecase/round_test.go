@@ -0,0 +1,209 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"chantingkakigori/pkg/apperror"
+)
+
+// fakeRoundStore is a minimal RoundStore; roundstore tests the real ones.
+type fakeRoundStore struct {
+	rounds  map[string]Round
+	lobbies map[string]string
+}
+
+func newFakeRoundStore() *fakeRoundStore {
+	return &fakeRoundStore{rounds: map[string]Round{}, lobbies: map[string]string{}}
+}
+
+func (s *fakeRoundStore) Get(_ context.Context, id string) (Round, bool, error) {
+	r, ok := s.rounds[id]
+	return r, ok, nil
+}
+
+func (s *fakeRoundStore) Update(_ context.Context, id string, fn func(*Round) bool) (Round, bool, error) {
+	r, ok := s.rounds[id]
+	if !ok {
+		return Round{}, false, nil
+	}
+	r.History = append([]RoundTransition(nil), r.History...)
+	if fn(&r) {
+		s.rounds[id] = r
+		if r.State != RoundLobby && s.lobbies[r.Room] == id {
+			delete(s.lobbies, r.Room)
+		}
+	}
+	return r, true, nil
+}
+
+func (s *fakeRoundStore) Lobby(_ context.Context, room string, keep func(Round) bool, open func() Round) (Round, error) {
+	if r, ok := s.rounds[s.lobbies[room]]; ok && keep(r) {
+		return r, nil
+	}
+	r := open()
+	s.rounds[r.ID] = r
+	s.lobbies[room] = r.ID
+	return r, nil
+}
+
+func newTestRounds() (*RoundManager, *time.Time) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	m := NewRoundUsecase(newFakeRoundStore())
+	m.Now = func() time.Time { return now }
+	return m, &now
+}
+
+func TestRound_Lifecycle(t *testing.T) {
+	m, now := newTestRounds()
+	ctx := context.Background()
+
+	r, err := m.OpenRound(ctx, "ichigo", "alice")
+	if err != nil || r.State != RoundLobby || r.ID == "" || !r.Deadline.Equal(now.Add(m.Timeouts.Lobby)) {
+		t.Fatalf("open = %+v, %v", r, err)
+	}
+	if again, _ := m.OpenRound(ctx, "ichigo", "bob"); again.ID != r.ID || !again.HasMember("alice") || !again.HasMember("bob") {
+		t.Fatalf("second open = %+v, want the lobby round %s with both members", again, r.ID)
+	}
+	if twice, _ := m.OpenRound(ctx, "ichigo", "bob"); len(twice.Members) != 2 {
+		t.Fatalf("rejoin added a member: %v", twice.Members)
+	}
+	if other, _ := m.OpenRound(ctx, "matcha", "alice"); other.ID == r.ID {
+		t.Fatal("rooms share a round")
+	}
+
+	startAt := now.Add(5 * time.Second)
+	r, err = m.AdvanceRound(ctx, r.ID, "ichigo", "alice", RoundCountdown, startAt, "")
+	if err != nil || r.State != RoundCountdown || !r.StartAt.Equal(startAt) || !r.Deadline.Equal(startAt.Add(m.Timeouts.Countdown)) {
+		t.Fatalf("countdown = %+v, %v", r, err)
+	}
+	// the lobby round started, so the next party gets a new one
+	if next, _ := m.OpenRound(ctx, "ichigo", "dave"); next.ID == r.ID || next.HasMember("alice") {
+		t.Fatal("a counting-down round was reopened as the lobby")
+	}
+
+	*now = startAt
+	for _, s := range []RoundState{RoundChanting, RoundChanting, RoundConfirming, RoundOrdered} {
+		if r, err = m.AdvanceRound(ctx, r.ID, "ichigo", "bob", s, time.Time{}, ""); err != nil || r.State != s {
+			t.Fatalf("advance to %s = %+v, %v", s, r, err)
+		}
+	}
+	if len(r.History) != 5 || !r.Deadline.IsZero() {
+		t.Fatalf("ordered round = %+v", r)
+	}
+	got, err := m.GetRound(ctx, r.ID)
+	if err != nil || got.State != RoundOrdered {
+		t.Fatalf("get = %+v, %v", got, err)
+	}
+}
+
+func TestRound_Rejects(t *testing.T) {
+	m, _ := newTestRounds()
+	ctx := context.Background()
+	r, _ := m.OpenRound(ctx, "ichigo", "alice")
+
+	cases := []struct {
+		name   string
+		id     string
+		room   string
+		member string
+		to     RoundState
+		want   apperror.Code
+	}{
+		{"skip countdown", r.ID, "ichigo", "alice", RoundChanting, apperror.CodeConflict},
+		{"order from lobby", r.ID, "ichigo", "alice", RoundOrdered, apperror.CodeConflict},
+		{"back to lobby", r.ID, "ichigo", "alice", RoundLobby, apperror.CodeInvalidArgument},
+		{"expire by hand", r.ID, "ichigo", "alice", RoundExpired, apperror.CodeInvalidArgument},
+		{"other room", r.ID, "matcha", "alice", RoundCountdown, apperror.CodeInvalidArgument},
+		{"no room", r.ID, "", "alice", RoundCountdown, apperror.CodeInvalidArgument},
+		{"no member", r.ID, "ichigo", "", RoundCountdown, apperror.CodeInvalidArgument},
+		{"not a member", r.ID, "ichigo", "mallory", RoundCountdown, apperror.CodePermissionDenied},
+		{"unknown round", "nope", "ichigo", "alice", RoundCountdown, apperror.CodeNotFound},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := m.AdvanceRound(ctx, tc.id, tc.room, tc.member, tc.to, time.Time{}, "")
+			if apperror.CodeOf(err) != tc.want {
+				t.Fatalf("err = %v, want %s", err, tc.want)
+			}
+		})
+	}
+	if got, _ := m.GetRound(ctx, r.ID); got.State != RoundLobby || len(got.History) != 1 {
+		t.Fatalf("rejected moves changed the round: %+v", got)
+	}
+	if _, err := m.OpenRound(ctx, "", "alice"); apperror.CodeOf(err) != apperror.CodeInvalidArgument {
+		t.Fatalf("open without room: %v", err)
+	}
+	if _, err := m.OpenRound(ctx, "ichigo", ""); apperror.CodeOf(err) != apperror.CodeInvalidArgument {
+		t.Fatalf("open without member: %v", err)
+	}
+	if _, err := m.GetRound(ctx, "nope"); apperror.CodeOf(err) != apperror.CodeNotFound {
+		t.Fatalf("get unknown: %v", err)
+	}
+}
+
+func TestRound_OrderedOvertakesFailed(t *testing.T) {
+	m, now := newTestRounds()
+	ctx := context.Background()
+	r, _ := m.OpenRound(ctx, "ichigo", "alice")
+	for _, s := range []RoundState{RoundCountdown, RoundChanting, RoundConfirming} {
+		r, _ = m.AdvanceRound(ctx, r.ID, "ichigo", "alice", s, *now, "")
+	}
+
+	// one replica's orders all failed, another's went through
+	if r, _ = m.AdvanceRound(ctx, r.ID, "ichigo", "alice", RoundFailed, time.Time{}, "order failed: sold out"); r.State != RoundFailed {
+		t.Fatalf("failed = %+v", r)
+	}
+	r, err := m.AdvanceRound(ctx, r.ID, "ichigo", "alice", RoundOrdered, time.Time{}, "")
+	if err != nil || r.State != RoundOrdered || r.Reason != "" || len(r.History) != 6 {
+		t.Fatalf("ordered after failed = %+v, %v", r, err)
+	}
+	if _, err := m.AdvanceRound(ctx, r.ID, "ichigo", "alice", RoundFailed, time.Time{}, "order failed: sold out"); apperror.CodeOf(err) != apperror.CodeConflict {
+		t.Fatalf("failed after ordered: %v", err)
+	}
+}
+
+func TestRound_Expires(t *testing.T) {
+	m, now := newTestRounds()
+	ctx := context.Background()
+
+	// an abandoned lobby is replaced, and reads as expired
+	stale, _ := m.OpenRound(ctx, "ichigo", "alice")
+	*now = now.Add(m.Timeouts.Lobby)
+	fresh, _ := m.OpenRound(ctx, "ichigo", "alice")
+	if fresh.ID == stale.ID {
+		t.Fatal("expired lobby was reopened")
+	}
+	got, err := m.GetRound(ctx, stale.ID)
+	if err != nil || got.State != RoundExpired || got.Reason != "expired in lobby" || !got.Deadline.IsZero() {
+		t.Fatalf("stale lobby = %+v, %v", got, err)
+	}
+
+	// a chant that never confirms expires on its next move
+	r, _ := m.AdvanceRound(ctx, fresh.ID, "ichigo", "alice", RoundCountdown, *now, "")
+	r, _ = m.AdvanceRound(ctx, r.ID, "ichigo", "alice", RoundChanting, time.Time{}, "")
+	*now = now.Add(m.Timeouts.Chanting + time.Second)
+	r, err = m.AdvanceRound(ctx, r.ID, "ichigo", "alice", RoundConfirming, time.Time{}, "")
+	if apperror.CodeOf(err) != apperror.CodeConflict || r.State != RoundExpired || r.Reason != "expired in chanting" {
+		t.Fatalf("late confirm = %+v, %v", r, err)
+	}
+	if _, err := m.AdvanceRound(ctx, r.ID, "ichigo", "alice", RoundConfirming, time.Time{}, ""); apperror.CodeOf(err) != apperror.CodeConflict {
+		t.Fatalf("confirm after expiry: %v", err)
+	}
+
+	// a failed round keeps its reason
+	r, _ = m.OpenRound(ctx, "matcha", "alice")
+	for _, s := range []RoundState{RoundCountdown, RoundChanting, RoundConfirming} {
+		r, _ = m.AdvanceRound(ctx, r.ID, "matcha", "alice", s, *now, "")
+	}
+	r, err = m.AdvanceRound(ctx, r.ID, "matcha", "alice", RoundFailed, time.Time{}, "store rejected the order")
+	if err != nil || r.State != RoundFailed || r.Reason != "store rejected the order" {
+		t.Fatalf("failed = %+v, %v", r, err)
+	}
+	// ended rounds never expire
+	*now = now.Add(time.Hour)
+	if got, _ := m.GetRound(ctx, r.ID); got.State != RoundFailed {
+		t.Fatalf("failed round became %s", got.State)
+	}
+}
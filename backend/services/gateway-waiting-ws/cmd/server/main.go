@@ -1,13 +1,22 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"time"
 
 	gatewayapiv1 "chantingkakigori/gen/go/gateway_api/v1"
+	"chantingkakigori/pkg/auth"
+	"chantingkakigori/pkg/logging"
+	"chantingkakigori/pkg/metrics"
+	"chantingkakigori/pkg/requestid"
+	"chantingkakigori/pkg/shutdown"
+	"chantingkakigori/pkg/tracing"
+	"chantingkakigori/pkg/wsroom"
+	"chantingkakigori/services/gateway-waiting-ws/internal/infrastructure/roomstore"
 	"chantingkakigori/services/gateway-waiting-ws/internal/interface/handler"
 
 	"google.golang.org/grpc"
@@ -15,61 +24,107 @@ import (
 )
 
 func main() {
+	logging.Init("gateway-waiting-ws")
+	ctx, stop := shutdown.NotifyContext()
+	defer stop()
 	httpPort := os.Getenv("PORT")
 	if httpPort == "" {
 		httpPort = "8080"
 	}
+	metrics.Register("gateway-waiting-ws")
+	shutdownTracing, err := tracing.Init(context.Background(), "gateway-waiting-ws")
+	if err != nil {
+		logging.Fatal("failed to init tracing", logging.Err(err))
+	}
+	defer func() { _ = shutdownTracing(context.Background()) }()
 
 	// gRPC client to gateway-api
 	gatewayApiGrpcAddr := os.Getenv("GATEWAY_API_GRPC_ADDR")
 	if gatewayApiGrpcAddr == "" {
 		gatewayApiGrpcAddr = "gateway-api:9090"
 	}
-	conn, err := grpc.Dial(gatewayApiGrpcAddr, grpc.WithTransportCredentials(insecure.NewCredentials())) //nolint:staticcheck // grpc.Dial is deprecated but supported throughout 1.x; migrate later
+	conn, err := grpc.Dial(gatewayApiGrpcAddr, grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithChainUnaryInterceptor(metrics.UnaryClientInterceptor("gateway-api"), requestid.UnaryClientInterceptor()), tracing.DialOption()) //nolint:staticcheck // grpc.Dial is deprecated but supported throughout 1.x; migrate later
 	if err != nil {
-		log.Fatalf("failed to dial gateway-api gRPC: %v", err)
+		logging.Fatal("failed to dial gateway-api gRPC", logging.Err(err))
 	}
+	defer func() { _ = conn.Close() }()
 	orderClient := gatewayapiv1.NewOrderServiceClient(conn)
+	roundClient := gatewayapiv1.NewRoundServiceClient(conn)
 
-	wsHandler := handler.NewWSStayHandler()
-	confirmHandler := handler.NewWSConfirmHandler(orderClient)
+	signer, err := auth.SignerFromEnv()
+	if err != nil {
+		logging.Fatal("failed to init auth", logging.Err(err))
+	}
+	if signer == nil {
+		slog.Warn("authentication disabled (AUTH_DISABLED=true)")
+	}
+	origins := auth.OriginsFromEnv()
+	wsOpts := wsroom.EnvOptions()
+	wsOpts.CheckOrigin = origins.CheckOrigin
 
-	mux := http.NewServeMux()
-	// CORS middleware wrapper
-	withCORS := func(h http.HandlerFunc) http.HandlerFunc {
-		return func(w http.ResponseWriter, r *http.Request) {
-			w.Header().Set("Access-Control-Allow-Origin", "*")
-			w.Header().Set("Access-Control-Allow-Methods", "GET,POST,PUT,PATCH,DELETE,OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "*")
-			if r.Method == http.MethodOptions {
-				w.WriteHeader(http.StatusNoContent)
-				return
-			}
-			h(w, r)
-		}
+	// Room state is shared through Redis when REDIS_URL is set, so the
+	// service can run more than one replica.
+	openStore, closeStore, err := roomstore.FromEnv(ctx)
+	if err != nil {
+		logging.Fatal("failed to init room store", logging.Err(err))
+	}
+	defer func() { _ = closeStore() }()
+	if os.Getenv("REDIS_URL") == "" {
+		slog.Info("room store: memory (single replica)")
+	}
+	wsHandler, err := handler.NewWSStayHandler(ctx, openStore("stay"), roundClient, wsOpts)
+	if err != nil {
+		logging.Fatal("failed to subscribe to stay room events", logging.Err(err))
+	}
+	confirmHandler, err := handler.NewWSConfirmHandler(ctx, openStore("confirm"), orderClient, roundClient, wsOpts)
+	if err != nil {
+		logging.Fatal("failed to subscribe to confirm room events", logging.Err(err))
 	}
 
-	mux.HandleFunc("/ws/stay", withCORS(wsHandler.HandleWebSocketStay))
-	mux.HandleFunc("/ws/health", withCORS(func(w http.ResponseWriter, r *http.Request) {
-		log.Printf("/ws/health called from %s", r.RemoteAddr)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ws/stay", metrics.InstrumentHandler("/ws/stay", origins.CORS(signer.RoomGuard(wsHandler.HandleWebSocketStay))))
+	mux.Handle("/metrics", metrics.Handler())
+	ready := &shutdown.Readiness{}
+	mux.Handle("/readyz", ready)
+	mux.HandleFunc("/ws/health", origins.CORS(func(w http.ResponseWriter, r *http.Request) {
+		slog.DebugContext(r.Context(), "/ws/health called", slog.String("remote", r.RemoteAddr))
 		w.Header().Set("Content-Type", "application/json")
 		_ = json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
 	}))
-	mux.HandleFunc("/ws/confirm", withCORS(confirmHandler.HandleWebSocketConfirm))
-	mux.HandleFunc("/swagger.yaml", withCORS(func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc("/ws/confirm", metrics.InstrumentHandler("/ws/confirm", origins.CORS(signer.RoomGuard(confirmHandler.HandleWebSocketConfirm))))
+	mux.HandleFunc("/swagger.yaml", origins.CORS(func(w http.ResponseWriter, r *http.Request) {
 		http.ServeFile(w, r, "/api/swagger/gateway-waiting-ws.yml")
 	}))
 
 	srv := &http.Server{
 		Addr:              ":" + httpPort,
-		Handler:           mux,
+		Handler:           requestid.Middleware(tracing.Handler(mux, "gateway-waiting-ws")),
 		ReadTimeout:       15 * time.Second,
 		ReadHeaderTimeout: 15 * time.Second,
 		WriteTimeout:      15 * time.Second,
 		IdleTimeout:       60 * time.Second,
 	}
-	log.Printf("gateway-waiting-ws HTTP listening on :%s", httpPort)
-	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-		log.Fatalf("http server error: %v", err)
+	go func() {
+		slog.Info("HTTP listening", slog.String("addr", ":"+httpPort))
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logging.Fatal("http server error", logging.Err(err))
+		}
+	}()
+
+	<-ctx.Done()
+	slog.Info("shutting down")
+	ready.Drain(context.Background())
+	sctx, cancel := context.WithTimeout(context.Background(), shutdown.Timeout())
+	defer cancel()
+	if err := srv.Shutdown(sctx); err != nil {
+		slog.Warn("http shutdown", logging.Err(err))
+	}
+	// Confirm first: flushing orders needs the gateway-api connection.
+	if err := confirmHandler.Shutdown(sctx); err != nil {
+		slog.Warn("confirm drain incomplete", logging.Err(err))
+	}
+	if err := wsHandler.Shutdown(sctx); err != nil {
+		slog.Warn("stay drain incomplete", logging.Err(err))
 	}
+	slog.Info("shutdown complete")
 }
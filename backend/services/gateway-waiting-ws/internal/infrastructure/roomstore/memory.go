@@ -0,0 +1,152 @@
+package roomstore
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Memory is a Store for a single replica. Members only leave through Leave,
+// so heartbeats are not tracked.
+type Memory struct {
+	mu    sync.Mutex
+	rooms map[string]*memRoom
+	subs  []func(Event)
+	now   func() time.Time
+}
+
+type memRoom struct {
+	members  map[string]struct{}
+	ready    map[string]struct{}
+	deadline time.Time
+	locks    map[string]time.Time // name -> expiry
+}
+
+// NewMemory returns an empty in-process store.
+func NewMemory() *Memory {
+	return &Memory{rooms: make(map[string]*memRoom), now: time.Now}
+}
+
+func (m *Memory) room(id string) *memRoom {
+	rm, ok := m.rooms[id]
+	if !ok {
+		rm = &memRoom{
+			members: make(map[string]struct{}),
+			ready:   make(map[string]struct{}),
+			locks:   make(map[string]time.Time),
+		}
+		m.rooms[id] = rm
+	}
+	return rm
+}
+
+func (m *Memory) counts(id string) Counts {
+	rm, ok := m.rooms[id]
+	if !ok {
+		return Counts{}
+	}
+	return Counts{Members: len(rm.members), Ready: len(rm.ready)}
+}
+
+func (m *Memory) Join(_ context.Context, room, member string) (Counts, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.room(room).members[member] = struct{}{}
+	return m.counts(room), nil
+}
+
+func (m *Memory) Leave(_ context.Context, room, member string) (Counts, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	rm, ok := m.rooms[room]
+	if !ok {
+		return Counts{}, nil
+	}
+	delete(rm.members, member)
+	delete(rm.ready, member)
+	if len(rm.members) == 0 {
+		delete(m.rooms, room)
+	}
+	return m.counts(room), nil
+}
+
+func (m *Memory) Touch(context.Context, string, ...string) error { return nil }
+
+func (m *Memory) SetReady(_ context.Context, room, member string) (Counts, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if rm, ok := m.rooms[room]; ok {
+		if _, ok := rm.members[member]; ok {
+			rm.ready[member] = struct{}{}
+		}
+	}
+	return m.counts(room), nil
+}
+
+func (m *Memory) Counts(_ context.Context, room string) (Counts, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.counts(room), nil
+}
+
+func (m *Memory) IsReady(_ context.Context, room, member string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	rm, ok := m.rooms[room]
+	if !ok {
+		return false, nil
+	}
+	_, ready := rm.ready[member]
+	return ready, nil
+}
+
+func (m *Memory) Deadline(_ context.Context, room string, at time.Time) (time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	rm := m.room(room)
+	if rm.deadline.IsZero() {
+		rm.deadline = at
+	}
+	return rm.deadline, nil
+}
+
+func (m *Memory) TryLock(_ context.Context, room, name string, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	rm := m.room(room)
+	now := m.now()
+	if exp, ok := rm.locks[name]; ok && now.Before(exp) {
+		return false, nil
+	}
+	rm.locks[name] = now.Add(ttl)
+	return true, nil
+}
+
+func (m *Memory) Unlock(_ context.Context, room, name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if rm, ok := m.rooms[room]; ok {
+		delete(rm.locks, name)
+	}
+	return nil
+}
+
+// Publish calls the subscribers synchronously.
+func (m *Memory) Publish(_ context.Context, ev Event) error {
+	m.mu.Lock()
+	subs := m.subs
+	m.mu.Unlock()
+	for _, fn := range subs {
+		fn(ev)
+	}
+	return nil
+}
+
+func (m *Memory) Subscribe(_ context.Context, fn func(Event)) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.subs = append(m.subs, fn)
+	return nil
+}
+
+func (m *Memory) Close() error { return nil }
@@ -0,0 +1,227 @@
+package roomstore
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+
+	"chantingkakigori/pkg/logging"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Redis is a Store shared by replicas. A room's keys share the {room} hash
+// tag, so the scripts below also work on a cluster. Members are a sorted set
+// scored by their last heartbeat; members of a replica that stopped touching
+// them are dropped after memberTTL, and an idle room's keys expire after
+// twice that.
+//
+// Events go over pub/sub, which does not replay what a subscriber missed
+// while reconnecting; callers re-derive what they can from the counts and
+// the deadline.
+type Redis struct {
+	rdb       redis.UniversalClient
+	ns        string
+	memberTTL time.Duration
+	token     string // lock owner
+	now       func() time.Time
+
+	mu   sync.Mutex
+	subs []*redis.PubSub
+}
+
+// NewRedis returns the store for namespace (e.g. "stay") on rdb, which the
+// caller closes.
+func NewRedis(rdb redis.UniversalClient, namespace string, memberTTL time.Duration) *Redis {
+	if memberTTL <= 0 {
+		memberTTL = DefaultMemberTTL
+	}
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return &Redis{rdb: rdb, ns: namespace, memberTTL: memberTTL, token: hex.EncodeToString(b), now: time.Now}
+}
+
+func (s *Redis) key(room, part string) string {
+	return "waiting:" + s.ns + ":{" + room + "}:" + part
+}
+
+func (s *Redis) channel() string { return "waiting:" + s.ns + ":events" }
+
+// roomScript prunes stale members, applies one membership change and returns
+// {members, ready}. The last member leaving drops the room's keys and locks.
+var roomScript = redis.NewScript(`
+local members, ready, deadline, locks = KEYS[1], KEYS[2], KEYS[3], KEYS[4]
+local op, now, stale, ttl, lockPrefix = ARGV[1], ARGV[2], ARGV[3], ARGV[4], ARGV[5]
+local gone = redis.call('ZRANGEBYSCORE', members, '-inf', '(' .. stale)
+for _, m in ipairs(gone) do
+  redis.call('ZREM', members, m)
+  redis.call('SREM', ready, m)
+end
+if op == 'join' then
+  redis.call('ZADD', members, now, ARGV[6])
+elseif op == 'leave' then
+  redis.call('ZREM', members, ARGV[6])
+  redis.call('SREM', ready, ARGV[6])
+elseif op == 'touch' then
+  for i = 6, #ARGV do
+    redis.call('ZADD', members, 'XX', now, ARGV[i])
+  end
+elseif op == 'ready' then
+  if redis.call('ZSCORE', members, ARGV[6]) then
+    redis.call('SADD', ready, ARGV[6])
+  end
+end
+local n = redis.call('ZCARD', members)
+if n == 0 then
+  for _, name in ipairs(redis.call('SMEMBERS', locks)) do
+    redis.call('DEL', lockPrefix .. name)
+  end
+  redis.call('DEL', members, ready, deadline, locks)
+  return {0, 0}
+end
+for _, k in ipairs({members, ready, deadline, locks}) do
+  redis.call('PEXPIRE', k, ttl)
+end
+return {n, redis.call('SCARD', ready)}
+`)
+
+var deadlineScript = redis.NewScript(`
+local d = redis.call('GET', KEYS[1])
+if d then
+  return d
+end
+redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+return ARGV[1]
+`)
+
+var lockScript = redis.NewScript(`
+if redis.call('SET', KEYS[1], ARGV[1], 'NX', 'PX', ARGV[2]) then
+  redis.call('SADD', KEYS[2], ARGV[3])
+  redis.call('PEXPIRE', KEYS[2], math.max(tonumber(ARGV[2]), tonumber(ARGV[4])))
+  return 1
+end
+return 0
+`)
+
+var unlockScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+  return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+func (s *Redis) roomTTL() time.Duration { return 2 * s.memberTTL }
+
+func (s *Redis) run(ctx context.Context, room, op string, members ...string) (Counts, error) {
+	now := s.now()
+	args := []any{
+		op,
+		now.UnixMilli(),
+		now.Add(-s.memberTTL).UnixMilli(),
+		s.roomTTL().Milliseconds(),
+		s.key(room, "lock:"),
+	}
+	for _, m := range members {
+		args = append(args, m)
+	}
+	keys := []string{s.key(room, "members"), s.key(room, "ready"), s.key(room, "deadline"), s.key(room, "locks")}
+	res, err := roomScript.Run(ctx, s.rdb, keys, args...).Int64Slice()
+	if err != nil {
+		return Counts{}, err
+	}
+	return Counts{Members: int(res[0]), Ready: int(res[1])}, nil
+}
+
+func (s *Redis) Join(ctx context.Context, room, member string) (Counts, error) {
+	return s.run(ctx, room, "join", member)
+}
+
+func (s *Redis) Leave(ctx context.Context, room, member string) (Counts, error) {
+	return s.run(ctx, room, "leave", member)
+}
+
+func (s *Redis) Touch(ctx context.Context, room string, members ...string) error {
+	_, err := s.run(ctx, room, "touch", members...)
+	return err
+}
+
+func (s *Redis) SetReady(ctx context.Context, room, member string) (Counts, error) {
+	return s.run(ctx, room, "ready", member)
+}
+
+func (s *Redis) Counts(ctx context.Context, room string) (Counts, error) {
+	return s.run(ctx, room, "counts")
+}
+
+func (s *Redis) IsReady(ctx context.Context, room, member string) (bool, error) {
+	return s.rdb.SIsMember(ctx, s.key(room, "ready"), member).Result()
+}
+
+func (s *Redis) Deadline(ctx context.Context, room string, at time.Time) (time.Time, error) {
+	v, err := deadlineScript.Run(ctx, s.rdb, []string{s.key(room, "deadline")}, at.UnixMilli(), s.roomTTL().Milliseconds()).Text()
+	if err != nil {
+		return time.Time{}, err
+	}
+	ms, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.UnixMilli(ms), nil
+}
+
+func (s *Redis) TryLock(ctx context.Context, room, name string, ttl time.Duration) (bool, error) {
+	keys := []string{s.key(room, "lock:"+name), s.key(room, "locks")}
+	n, err := lockScript.Run(ctx, s.rdb, keys, s.token, ttl.Milliseconds(), name, s.roomTTL().Milliseconds()).Int()
+	return n == 1, err
+}
+
+func (s *Redis) Unlock(ctx context.Context, room, name string) error {
+	return unlockScript.Run(ctx, s.rdb, []string{s.key(room, "lock:"+name)}, s.token).Err()
+}
+
+func (s *Redis) Publish(ctx context.Context, ev Event) error {
+	b, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	return s.rdb.Publish(ctx, s.channel(), b).Err()
+}
+
+// Subscribe returns once the subscription is active; fn then runs on one
+// goroutine per subscription until Close.
+func (s *Redis) Subscribe(ctx context.Context, fn func(Event)) error {
+	ps := s.rdb.Subscribe(ctx, s.channel())
+	if _, err := ps.Receive(ctx); err != nil {
+		_ = ps.Close()
+		return err
+	}
+	s.mu.Lock()
+	s.subs = append(s.subs, ps)
+	s.mu.Unlock()
+	go func() {
+		for msg := range ps.Channel() {
+			var ev Event
+			if err := json.Unmarshal([]byte(msg.Payload), &ev); err != nil {
+				slog.Warn("room event dropped", slog.String("channel", msg.Channel), logging.Err(err))
+				continue
+			}
+			fn(ev)
+		}
+	}()
+	return nil
+}
+
+func (s *Redis) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, ps := range s.subs {
+		_ = ps.Close()
+	}
+	s.subs = nil
+	return nil
+}
@@ -0,0 +1,96 @@
+// Package roomstore holds the waiting-room state that every gateway-waiting-ws
+// replica must agree on: who is in each room, who is ready, the room's
+// deadline and one-time actions guarded by locks. Events fan room changes out
+// to every replica, which relays them to the WebSocket clients it holds.
+//
+// Memory keeps everything in process and suits a single replica; Redis shares
+// it between replicas.
+package roomstore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// DefaultMemberTTL is how long a member survives without a heartbeat. A
+// replica touches its members every third of this, so only the members of a
+// replica that died are dropped.
+const DefaultMemberTTL = 30 * time.Second
+
+// Counts is a room's size and how many of its members are ready.
+type Counts struct {
+	Members int
+	Ready   int
+}
+
+// AllReady reports whether a non-empty room is ready as a whole.
+func (c Counts) AllReady() bool { return c.Members > 0 && c.Ready == c.Members }
+
+// Event is a room change published to every replica.
+type Event struct {
+	Room string          `json:"room"`
+	Kind string          `json:"kind"`
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+// Store is the shared state of one endpoint's rooms. A room's state is
+// dropped when its last member leaves.
+type Store interface {
+	// Join adds member to room and returns the counts including it.
+	Join(ctx context.Context, room, member string) (Counts, error)
+	// Leave removes member and its ready mark and returns what is left.
+	Leave(ctx context.Context, room, member string) (Counts, error)
+	// Touch refreshes the heartbeat of members of room.
+	Touch(ctx context.Context, room string, members ...string) error
+	// SetReady marks a member ready; a non-member is ignored.
+	SetReady(ctx context.Context, room, member string) (Counts, error)
+	// Counts returns room's counts.
+	Counts(ctx context.Context, room string) (Counts, error)
+	// IsReady reports whether member is marked ready.
+	IsReady(ctx context.Context, room, member string) (bool, error)
+
+	// Deadline sets room's deadline to at unless it has one, and returns the
+	// deadline in effect.
+	Deadline(ctx context.Context, room string, at time.Time) (time.Time, error)
+	// TryLock takes the lock name on room for ttl and reports whether it
+	// was free. Locks are released by Unlock, by expiry, or when the room
+	// empties.
+	TryLock(ctx context.Context, room, name string, ttl time.Duration) (bool, error)
+	// Unlock releases a lock this store holds.
+	Unlock(ctx context.Context, room, name string) error
+
+	// Publish delivers ev to every subscriber on every replica, this one
+	// included.
+	Publish(ctx context.Context, ev Event) error
+	// Subscribe registers fn for every published event. Events of one room
+	// arrive in publish order per replica; fn must not block for long.
+	Subscribe(ctx context.Context, fn func(Event)) error
+
+	// Close stops the subscriptions.
+	Close() error
+}
+
+// FromEnv returns a constructor of per-endpoint stores: Redis when REDIS_URL
+// is set (e.g. redis://redis:6379/0), Memory otherwise. closeFn releases the
+// shared connection.
+func FromEnv(ctx context.Context) (open func(namespace string) Store, closeFn func() error, err error) {
+	url := os.Getenv("REDIS_URL")
+	if url == "" {
+		return func(string) Store { return NewMemory() }, func() error { return nil }, nil
+	}
+	opt, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, nil, fmt.Errorf("parse REDIS_URL: %w", err)
+	}
+	rdb := redis.NewClient(opt)
+	if err := rdb.Ping(ctx).Err(); err != nil {
+		_ = rdb.Close()
+		return nil, nil, fmt.Errorf("ping redis %s: %w", opt.Addr, err)
+	}
+	return func(ns string) Store { return NewRedis(rdb, ns, DefaultMemberTTL) }, rdb.Close, nil
+}
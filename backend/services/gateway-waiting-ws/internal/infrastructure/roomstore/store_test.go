@@ -0,0 +1,191 @@
+package roomstore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newMiniredis(t *testing.T) redis.UniversalClient {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = rdb.Close() })
+	return rdb
+}
+
+// testStore runs the behaviour both implementations share.
+func testStore(t *testing.T, s Store) {
+	ctx := context.Background()
+	const room = "giiku-sai"
+
+	mustCounts := func(c Counts, err error) Counts {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	if c := mustCounts(s.Join(ctx, room, "a")); c != (Counts{Members: 1}) {
+		t.Fatalf("join a: %+v", c)
+	}
+	if c := mustCounts(s.Join(ctx, room, "b")); c != (Counts{Members: 2}) {
+		t.Fatalf("join b: %+v", c)
+	}
+	// joining twice is idempotent
+	if c := mustCounts(s.Join(ctx, room, "b")); c != (Counts{Members: 2}) {
+		t.Fatalf("rejoin b: %+v", c)
+	}
+	if c := mustCounts(s.SetReady(ctx, room, "a")); c != (Counts{Members: 2, Ready: 1}) || c.AllReady() {
+		t.Fatalf("ready a: %+v", c)
+	}
+	if c := mustCounts(s.SetReady(ctx, room, "stranger")); c.Ready != 1 {
+		t.Fatalf("a non-member became ready: %+v", c)
+	}
+	if ok, err := s.IsReady(ctx, room, "a"); err != nil || !ok {
+		t.Fatalf("IsReady(a)=%v err=%v", ok, err)
+	}
+	// the unready member leaving makes the rest ready
+	if c := mustCounts(s.Leave(ctx, room, "b")); !c.AllReady() {
+		t.Fatalf("leave b: %+v", c)
+	}
+
+	at := time.Now().Add(3 * time.Minute).Truncate(time.Millisecond)
+	if d, err := s.Deadline(ctx, room, at); err != nil || !d.Equal(at) {
+		t.Fatalf("deadline=%v err=%v", d, err)
+	}
+	if d, _ := s.Deadline(ctx, room, at.Add(time.Minute)); !d.Equal(at) {
+		t.Fatalf("deadline moved to %v", d)
+	}
+
+	if ok, err := s.TryLock(ctx, room, "order", time.Minute); err != nil || !ok {
+		t.Fatalf("first lock=%v err=%v", ok, err)
+	}
+	if ok, _ := s.TryLock(ctx, room, "order", time.Minute); ok {
+		t.Fatal("lock taken twice")
+	}
+	if err := s.Unlock(ctx, room, "order"); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := s.TryLock(ctx, room, "order", time.Minute); !ok {
+		t.Fatal("lock not free after Unlock")
+	}
+
+	// the last member leaving resets the room: deadline and locks included
+	if c := mustCounts(s.Leave(ctx, room, "a")); c != (Counts{}) {
+		t.Fatalf("leave a: %+v", c)
+	}
+	if d, _ := s.Deadline(ctx, room, at.Add(time.Minute)); !d.Equal(at.Add(time.Minute)) {
+		t.Fatalf("deadline survived the room: %v", d)
+	}
+	if ok, _ := s.TryLock(ctx, room, "order", time.Minute); !ok {
+		t.Fatal("lock survived the room")
+	}
+
+	got := make(chan Event, 1)
+	if err := s.Subscribe(ctx, func(ev Event) { got <- ev }); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Publish(ctx, Event{Room: room, Kind: "order"}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case ev := <-got:
+		if ev.Room != room || ev.Kind != "order" {
+			t.Fatalf("event=%+v", ev)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("event not delivered")
+	}
+}
+
+func TestMemory(t *testing.T) {
+	testStore(t, NewMemory())
+}
+
+func TestRedis(t *testing.T) {
+	s := NewRedis(newMiniredis(t), "confirm", 0)
+	defer s.Close()
+	testStore(t, s)
+}
+
+// Two replicas share one room: counts, locks and events span both.
+func TestRedis_Replicas(t *testing.T) {
+	ctx := context.Background()
+	rdb := newMiniredis(t)
+	a, b := NewRedis(rdb, "stay", 0), NewRedis(rdb, "stay", 0)
+	defer a.Close()
+	defer b.Close()
+
+	got := make(chan Event, 1)
+	if err := b.Subscribe(ctx, func(ev Event) { got <- ev }); err != nil {
+		t.Fatal(err)
+	}
+
+	_, _ = a.Join(ctx, "r", "1")
+	_, _ = b.Join(ctx, "r", "2")
+	if c, err := a.Join(ctx, "r", "3"); err != nil || c.Members != 3 {
+		t.Fatalf("third join: %+v err=%v", c, err)
+	}
+	// namespaces do not share rooms
+	if c, _ := NewRedis(rdb, "confirm", 0).Counts(ctx, "r"); c.Members != 0 {
+		t.Fatalf("confirm namespace sees %+v", c)
+	}
+
+	if ok, _ := a.TryLock(ctx, "r", "order", time.Minute); !ok {
+		t.Fatal("a could not lock")
+	}
+	if ok, _ := b.TryLock(ctx, "r", "order", time.Minute); ok {
+		t.Fatal("b took a's lock")
+	}
+	// only the holder releases
+	_ = b.Unlock(ctx, "r", "order")
+	if ok, _ := b.TryLock(ctx, "r", "order", time.Minute); ok {
+		t.Fatal("b released a's lock")
+	}
+
+	if err := a.Publish(ctx, Event{Room: "r", Kind: "stay", Data: []byte(`{"stay_num":"3"}`)}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case ev := <-got:
+		if ev.Kind != "stay" || string(ev.Data) != `{"stay_num":"3"}` {
+			t.Fatalf("event=%+v data=%s", ev, ev.Data)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("event not delivered to the other replica")
+	}
+}
+
+// Members a replica stops touching are dropped; touched ones stay.
+func TestRedis_StaleMembers(t *testing.T) {
+	ctx := context.Background()
+	rdb := newMiniredis(t)
+	now := time.Now()
+	a, b := NewRedis(rdb, "stay", 10*time.Second), NewRedis(rdb, "stay", 10*time.Second)
+	a.now = func() time.Time { return now }
+	b.now = a.now
+
+	_, _ = a.Join(ctx, "r", "alive")
+	_, _ = b.Join(ctx, "r", "dead")
+	_, _ = b.SetReady(ctx, "r", "dead")
+
+	now = now.Add(8 * time.Second)
+	if err := a.Touch(ctx, "r", "alive"); err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(8 * time.Second)
+	c, err := a.Counts(ctx, "r")
+	if err != nil || c != (Counts{Members: 1}) {
+		t.Fatalf("counts=%+v err=%v", c, err)
+	}
+	// Touch does not resurrect a dropped member
+	_ = b.Touch(ctx, "r", "dead")
+	if c, _ := a.Counts(ctx, "r"); c.Members != 1 {
+		t.Fatalf("counts after touching a dropped member: %+v", c)
+	}
+}
@@ -0,0 +1,45 @@
+package handler
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"chantingkakigori/pkg/logging"
+	"chantingkakigori/pkg/wsroom"
+	"chantingkakigori/services/gateway-waiting-ws/internal/infrastructure/roomstore"
+)
+
+// storeTimeout bounds each room store call made on behalf of a session.
+const storeTimeout = 2 * time.Second
+
+func storeContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.WithoutCancel(ctx), storeTimeout)
+}
+
+// heartbeat keeps this replica's members alive in the shared store until stop
+// is closed. Clients held for a resume are still in their room, so their
+// slot is kept too.
+func heartbeat(hub *wsroom.Hub, store roomstore.Store, stop <-chan struct{}) {
+	t := time.NewTicker(roomstore.DefaultMemberTTL / 3)
+	defer t.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-t.C:
+		}
+		for _, rm := range hub.Rooms() {
+			clients := rm.Clients()
+			ids := make([]string, 0, len(clients))
+			for _, c := range clients {
+				ids = append(ids, c.ID)
+			}
+			ctx, cancel := storeContext(context.Background())
+			if err := store.Touch(ctx, rm.ID, ids...); err != nil {
+				slog.WarnContext(ctx, "room heartbeat failed", logging.Room(rm.ID), logging.Err(err))
+			}
+			cancel()
+		}
+	}
+}
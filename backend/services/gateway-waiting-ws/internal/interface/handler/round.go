@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	gatewayapiv1 "chantingkakigori/gen/go/gateway_api/v1"
+	"chantingkakigori/pkg/apperror"
+	"chantingkakigori/pkg/auth"
+	"chantingkakigori/pkg/logging"
+)
+
+// roundTimeout bounds each RoundService call made on behalf of a session.
+const roundTimeout = 3 * time.Second
+
+// subject returns the session subject of the request's token, or "" when
+// auth is disabled. Rounds are joined and moved by subject, so a session
+// without one takes no part in them.
+func subject(ctx context.Context) string {
+	if claims := auth.FromContext(ctx); claims != nil {
+		return claims.Subject
+	}
+	return ""
+}
+
+// advanceRound moves a party's round on gateway-api on behalf of member.
+// Returns the gRPC error for the caller to report.
+func advanceRound(ctx context.Context, c gatewayapiv1.RoundServiceClient, id, room, member string, state gatewayapiv1.RoundState, startAt time.Time, reason string) error {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), roundTimeout)
+	defer cancel()
+	req := &gatewayapiv1.AdvanceRoundRequest{Id: id, Room: room, Member: member, State: state, Reason: reason}
+	if !startAt.IsZero() {
+		req.StartAtUnixMs = startAt.UnixMilli()
+	}
+	_, err := c.AdvanceRound(ctx, req)
+	return err
+}
+
+// rejectsRound reports whether an advanceRound error means the client passed
+// a round that is not its party's or one it did not join, which turns the
+// handshake away. Any other
+// error (gateway-api unavailable, the round moved on or expired) leaves the
+// round where it is and the party carries on.
+func rejectsRound(err error) bool {
+	switch apperror.FromGRPC(err).Code {
+	case apperror.CodeInvalidArgument, apperror.CodeNotFound, apperror.CodePermissionDenied:
+		return true
+	}
+	return false
+}
+
+// openRound joins member to the room's lobby round and returns its ID, or ""
+// if there is no member or gateway-api cannot say: the party then waits,
+// chants and orders without one.
+func openRound(ctx context.Context, c gatewayapiv1.RoundServiceClient, room, member string) string {
+	if member == "" {
+		return ""
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), roundTimeout)
+	defer cancel()
+	res, err := c.OpenRound(ctx, &gatewayapiv1.OpenRoundRequest{Room: room, Member: member})
+	if err != nil {
+		slog.WarnContext(ctx, "open round failed", logging.Room(room), logging.Err(err))
+		return ""
+	}
+	return res.GetRound().GetId()
+}
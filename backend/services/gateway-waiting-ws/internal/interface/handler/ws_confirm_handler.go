@@ -3,186 +3,434 @@ package handler
 import (
 	"context"
 	"encoding/json"
-	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"sync"
 	"time"
 
 	gatewayapiv1 "chantingkakigori/gen/go/gateway_api/v1"
+	"chantingkakigori/pkg/apperror"
+	"chantingkakigori/pkg/logging"
+	"chantingkakigori/pkg/metrics"
+	"chantingkakigori/pkg/shutdown"
+	"chantingkakigori/pkg/tracing"
+	"chantingkakigori/pkg/wsroom"
+	"chantingkakigori/services/gateway-waiting-ws/internal/infrastructure/roomstore"
 
 	"github.com/gorilla/websocket"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
-type confirmRoom struct {
-	id      string
-	clients map[*websocket.Conn]struct{}
-	mu      sync.Mutex
+const (
+	// confirmWait is how long a room waits for everyone to be ready before
+	// it orders anyway.
+	confirmWait = 3 * time.Minute
+	// orderLock lets one replica announce a room's order; it goes away
+	// with the room.
+	orderLock    = "order"
+	orderLockTTL = 10 * time.Minute
+	// orderEvent tells every replica to order for its clients in the room.
+	orderEvent = "order"
+)
+
+// confirmState is this replica's part of a room, guarded by Room.Locked.
+// Membership, readiness and the deadline live in the room store.
+type confirmState struct {
+	// menu is the menu item ordered; round is the party's round, which is
+	// also the room's ID when the clients passed one. member is the subject
+	// of this replica's first client in the room, who reports the round's
+	// end.
+	menu    string
+	round   string
+	member  string
 	ordered bool
-	ready   map[*websocket.Conn]struct{}
 	timer   *time.Timer
 }
 
+func confirmStateOf(rm *wsroom.Room) *confirmState { return rm.State().(*confirmState) }
+
+// confirmStateFrame is replayed to a client that resumed its session.
+type confirmStateFrame struct {
+	Type       string `json:"type"`
+	Ready      bool   `json:"ready"`
+	ReadyCount int    `json:"ready_count"`
+	Members    int    `json:"members"`
+}
+
 type wsConfirmHandler struct {
-	rooms       map[string]*confirmRoom
-	mu          sync.Mutex
+	hub         *wsroom.Hub
+	store       roomstore.Store
 	orderClient gatewayapiv1.OrderServiceClient
+	rounds      gatewayapiv1.RoundServiceClient
+	orders      sync.WaitGroup // order timers and events being handled
+	stop        chan struct{}
+
+	mu      sync.Mutex
+	closing bool // set by Shutdown; no more orders are tracked
 }
 
-func NewWSConfirmHandler(c gatewayapiv1.OrderServiceClient) *wsConfirmHandler {
-	return &wsConfirmHandler{rooms: make(map[string]*confirmRoom), orderClient: c}
+// NewWSConfirmHandler builds the /ws/confirm handler; opts carries the queue
+// settings. Readiness is tracked in store, so a party may be split over
+// replicas: each replica orders for the clients it holds. A party that
+// passes its round is its own room, and its round on rounds ends with the
+// orders.
+func NewWSConfirmHandler(ctx context.Context, store roomstore.Store, c gatewayapiv1.OrderServiceClient, rounds gatewayapiv1.RoundServiceClient, opts wsroom.Options) (*wsConfirmHandler, error) {
+	h := &wsConfirmHandler{store: store, orderClient: c, rounds: rounds, stop: make(chan struct{})}
+	opts.Endpoint = "/ws/confirm"
+	opts.NewState = func(string) any { return &confirmState{} }
+	opts.OnRoomCreate = func(*wsroom.Room) { metrics.Rooms.WithLabelValues("confirm").Inc() }
+	opts.OnRoomClose = func(rm *wsroom.Room) {
+		// nobody left to order for
+		rm.Locked(func(int) {
+			if st := confirmStateOf(rm); st.timer != nil {
+				st.timer.Stop()
+				st.timer = nil
+			}
+		})
+		metrics.Rooms.WithLabelValues("confirm").Dec()
+	}
+	opts.OnResume = func(c *wsroom.Client) {
+		rm := c.Room()
+		if rm == nil {
+			return
+		}
+		ctx, cancel := storeContext(context.Background())
+		defer cancel()
+		counts, err := h.store.Counts(ctx, rm.ID)
+		if err != nil {
+			slog.WarnContext(ctx, "confirm resume state failed", logging.Room(rm.ID), logging.Err(err))
+			return
+		}
+		ready, err := h.store.IsReady(ctx, rm.ID, c.ID)
+		if err != nil {
+			slog.WarnContext(ctx, "confirm resume state failed", logging.Room(rm.ID), logging.Err(err))
+			return
+		}
+		b, _ := json.Marshal(confirmStateFrame{Type: "confirm_state", Ready: ready, ReadyCount: counts.Ready, Members: counts.Members})
+		c.Send(b)
+	}
+	h.hub = wsroom.NewHub(opts)
+	if err := store.Subscribe(ctx, h.onEvent); err != nil {
+		return nil, err
+	}
+	go heartbeat(h.hub, store, h.stop)
+	return h, nil
 }
 
-func (h *wsConfirmHandler) getOrCreateRoom(id string) *confirmRoom {
+// Shutdown stops this replica's order timers and events and waits for the
+// orders they started. A party held wholly by this replica is then ordered
+// for here; a party split over replicas is left alone, as its deadline is in
+// the store and its clients resume on a replica whose timer fires from it.
+// Last, it sends a "server restarting" close frame to anyone left and waits
+// for their sessions to finish.
+func (h *wsConfirmHandler) Shutdown(ctx context.Context) error {
+	h.mu.Lock()
+	h.closing = true
+	h.mu.Unlock()
+	_ = h.store.Close()
+	rooms := h.hub.Rooms()
+	for _, rm := range rooms {
+		rm.Locked(func(int) {
+			if st := confirmStateOf(rm); st.timer != nil {
+				st.timer.Stop()
+				st.timer = nil
+			}
+		})
+	}
+	if err := shutdown.Wait(ctx, &h.orders); err != nil {
+		return err
+	}
+
+	var flush sync.WaitGroup
+	for _, rm := range rooms {
+		if !h.soleHolder(ctx, rm) {
+			continue
+		}
+		slog.InfoContext(ctx, "flushing pending order", logging.Room(rm.ID))
+		flush.Add(1)
+		go func(rm *wsroom.Room) {
+			defer flush.Done()
+			h.orderForRoom(ctx, rm)
+		}(rm)
+	}
+	if err := shutdown.Wait(ctx, &flush); err != nil {
+		return err
+	}
+	err := h.hub.Shutdown(ctx)
+	close(h.stop)
+	return err
+}
+
+// soleHolder reports whether rm has yet to order and every member of the
+// party is connected to this replica.
+func (h *wsConfirmHandler) soleHolder(ctx context.Context, rm *wsroom.Room) bool {
+	var held int
+	rm.Locked(func(n int) {
+		if !confirmStateOf(rm).ordered {
+			held = n
+		}
+	})
+	if held == 0 {
+		return false
+	}
+	sctx, cancel := storeContext(ctx)
+	defer cancel()
+	counts, err := h.store.Counts(sctx, rm.ID)
+	if err != nil {
+		slog.WarnContext(ctx, "flush count failed", logging.Room(rm.ID), logging.Err(err))
+		return false
+	}
+	return counts.Members <= held
+}
+
+// track counts an order timer or event in h.orders, unless Shutdown has
+// begun waiting for them.
+func (h *wsConfirmHandler) track() bool {
 	h.mu.Lock()
 	defer h.mu.Unlock()
-	if rm, ok := h.rooms[id]; ok {
-		return rm
+	if h.closing {
+		return false
 	}
-	rm := &confirmRoom{id: id, clients: make(map[*websocket.Conn]struct{}), ready: make(map[*websocket.Conn]struct{})}
-	h.rooms[id] = rm
-	return rm
+	h.orders.Add(1)
+	return true
 }
 
-func (h *wsConfirmHandler) HandleWebSocketConfirm(w http.ResponseWriter, r *http.Request) {
-	room := r.URL.Query().Get("room")
-	if room == "" {
-		http.Error(w, "room is required", http.StatusBadRequest)
+func (h *wsConfirmHandler) shuttingDown() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.closing
+}
+
+// onEvent orders for this replica's clients once some replica found the
+// room due.
+func (h *wsConfirmHandler) onEvent(ev roomstore.Event) {
+	if ev.Kind != orderEvent {
+		return
+	}
+	rm := h.hub.Room(ev.Room)
+	if rm == nil || !h.track() {
 		return
 	}
-	conn, err := upgrader.Upgrade(w, r, nil)
-	// Heartbeat setup (ping/pong)
-	const pongWait = 60 * time.Second
-	const pingPeriod = 30 * time.Second
-	_ = conn.SetReadDeadline(time.Now().Add(pongWait))
-	conn.SetPongHandler(func(string) error {
-		return conn.SetReadDeadline(time.Now().Add(pongWait))
-	})
-	stopCh := make(chan struct{})
 	go func() {
-		ticker := time.NewTicker(pingPeriod)
-		defer ticker.Stop()
-		for {
-			select {
-			case <-ticker.C:
-				_ = conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(5*time.Second))
-			case <-stopCh:
-				return
-			}
-		}
+		defer h.orders.Done()
+		h.orderForRoom(context.Background(), rm)
 	}()
+}
 
+// due runs when a replica sees the room is due: everyone is ready or the
+// deadline passed. The replica taking the order lock tells the others; a
+// replica that sees it itself orders for its own clients without waiting for
+// the event, so a missed event only delays the others.
+func (h *wsConfirmHandler) due(ctx context.Context, roomID string) {
+	sctx, cancel := storeContext(ctx)
+	won, err := h.store.TryLock(sctx, roomID, orderLock, orderLockTTL)
 	if err != nil {
-		log.Printf("confirm ws upgrade error: %v", err)
-		return
+		slog.WarnContext(ctx, "order lock failed", logging.Room(roomID), logging.Err(err))
 	}
-	rm := h.getOrCreateRoom(room)
+	if won {
+		if err := h.store.Publish(sctx, roomstore.Event{Room: roomID, Kind: orderEvent}); err != nil {
+			slog.WarnContext(ctx, "order publish failed", logging.Room(roomID), logging.Err(err))
+		}
+	}
+	cancel()
+	if rm := h.hub.Room(roomID); rm != nil {
+		h.orderForRoom(ctx, rm)
+	}
+}
 
-	rm.mu.Lock()
-	wasEmpty := len(rm.clients) == 0
-	rm.clients[conn] = struct{}{}
-	// Start 3-minute timer when the first client joins the room
-	if wasEmpty {
-		if rm.timer != nil {
-			rm.timer.Stop()
+// armTimer starts this replica's timer for the room's shared deadline, which
+// the first member to arrive on any replica sets.
+func (h *wsConfirmHandler) armTimer(ctx context.Context, rm *wsroom.Room) error {
+	sctx, cancel := storeContext(ctx)
+	defer cancel()
+	deadline, err := h.store.Deadline(sctx, rm.ID, time.Now().Add(confirmWait))
+	if err != nil {
+		return err
+	}
+	rm.Locked(func(int) {
+		st := confirmStateOf(rm)
+		if st.timer != nil || st.ordered {
+			return
 		}
-		rm.timer = time.AfterFunc(3*time.Minute, func() {
-			h.orderForRoom(room, rm)
+		st.timer = time.AfterFunc(time.Until(deadline), func() {
+			if !h.track() {
+				return
+			}
+			defer h.orders.Done()
+			h.due(context.Background(), rm.ID)
 		})
+	})
+	return nil
+}
+
+func (h *wsConfirmHandler) HandleWebSocketConfirm(w http.ResponseWriter, r *http.Request) {
+	menuID := r.URL.Query().Get("room")
+	if menuID == "" {
+		apperror.WriteProblem(w, r, apperror.New(apperror.CodeInvalidArgument, "room is required"))
+		return
+	}
+	// Parties of the same menu item confirm apart when they pass their
+	// round. Only a round of another room or party, or none at all, is
+	// turned away. Without a session subject (auth disabled) there is no
+	// membership to check, so the round is ignored.
+	room := menuID
+	member := subject(r.Context())
+	roundID := r.URL.Query().Get("round")
+	if member == "" {
+		roundID = ""
 	}
-	rm.mu.Unlock()
-
-	defer func() {
-		rm.mu.Lock()
-		delete(rm.clients, conn)
-		delete(rm.ready, conn)
-		empty := len(rm.clients) == 0
-		shouldOrder := !rm.ordered && !empty && len(rm.ready) == len(rm.clients)
-		if empty {
-			// reset state when no one remains in the room
-			if rm.timer != nil {
-				rm.timer.Stop()
-				rm.timer = nil
+	if roundID != "" {
+		if err := advanceRound(r.Context(), h.rounds, roundID, menuID, member, gatewayapiv1.RoundState_ROUND_STATE_CONFIRMING, time.Time{}, ""); err != nil {
+			if rejectsRound(err) {
+				slog.WarnContext(r.Context(), "confirm round rejected", logging.Room(menuID), logging.RoundID(roundID), logging.Err(err))
+				apperror.WriteProblem(w, r, apperror.FromGRPC(err))
+				return
 			}
-			rm.ordered = false
-			rm.ready = make(map[*websocket.Conn]struct{})
-		}
-		rm.mu.Unlock()
-		if shouldOrder {
-			h.orderForRoom(room, rm)
+			// the party still confirms together, its round left as it is
+			slog.WarnContext(r.Context(), "round confirming failed", logging.Room(menuID), logging.RoundID(roundID), logging.Err(err))
 		}
-		_ = conn.Close()
-		if empty {
-			// remove the room entry to fully reset counts/state
-			h.mu.Lock()
-			delete(h.rooms, room)
-			h.mu.Unlock()
+		room = roundID
+	}
+	cl, resumed, err := h.hub.Upgrade(w, r)
+	if err != nil {
+		slog.WarnContext(r.Context(), "confirm ws upgrade error", logging.Room(room), logging.Err(err))
+		return
+	}
+	if resumed {
+		// the original session keeps running on this connection
+		slog.InfoContext(r.Context(), "confirm ws resumed", logging.Room(room), logging.Client(cl.ID), slog.String("remote", r.RemoteAddr))
+		return
+	}
+	sessCtx, session := tracing.StartSession(r, "/ws/confirm", attribute.String("room", menuID), attribute.String("round_id", roundID))
+	defer session.End(nil)
+	slog.InfoContext(sessCtx, "confirm ws connected", logging.Room(room), logging.Client(cl.ID), slog.String("remote", r.RemoteAddr))
+	defer slog.InfoContext(sessCtx, "confirm ws disconnected", logging.Room(room), logging.Client(cl.ID), slog.String("remote", r.RemoteAddr))
+	metrics.WSConnections.WithLabelValues("/ws/confirm").Inc()
+	defer metrics.WSConnections.WithLabelValues("/ws/confirm").Dec()
+
+	rm, _ := h.hub.Join(room, cl)
+	rm.Locked(func(int) {
+		st := confirmStateOf(rm)
+		st.menu, st.round = menuID, roundID
+		if st.member == "" {
+			st.member = member
 		}
-		close(stopCh)
-	}()
+	})
+	jctx, cancel := storeContext(sessCtx)
+	_, err = h.store.Join(jctx, room, cl.ID)
+	cancel()
+	if err == nil {
+		// the deadline is 3 minutes after the first client joined the room
+		err = h.armTimer(sessCtx, rm)
+	}
+	if err != nil {
+		slog.ErrorContext(sessCtx, "confirm room store join failed", logging.Room(room), logging.Client(cl.ID), logging.Err(err))
+		cl.Send(apperror.WSFrame(apperror.Wrap(apperror.CodeUnavailable, err, "room store unavailable")))
+		cl.Close(websocket.CloseInternalServerErr, "room store unavailable")
+		_ = cl.Run(nil)
+		h.leave(sessCtx, room, cl)
+		return
+	}
 
 	// Keep connection open (noop read loop)
 	type confirmMessage struct {
 		Status string `json:"status"`
 	}
-	for {
-		msgType, msgData, err := conn.ReadMessage()
-		if err != nil {
-			break
-		}
+	_ = cl.Run(func(msgType int, msgData []byte) {
 		if msgType != websocket.TextMessage {
-			continue
+			return
 		}
+		session.Received(len(msgData))
 		var m confirmMessage
 		if err := json.Unmarshal(msgData, &m); err != nil {
-			continue
+			return
 		}
 		if m.Status != "ready" {
-			continue
+			return
 		}
-		rm.mu.Lock()
-		if _, ok := rm.ready[conn]; !ok {
-			rm.ready[conn] = struct{}{}
+		ctx, cancel := storeContext(sessCtx)
+		counts, err := h.store.SetReady(ctx, room, cl.ID)
+		cancel()
+		if err != nil {
+			slog.ErrorContext(sessCtx, "confirm ready failed", logging.Room(room), logging.Client(cl.ID), logging.Err(err))
+			cl.Send(apperror.WSFrame(apperror.Wrap(apperror.CodeUnavailable, err, "room store unavailable")))
+			return
 		}
-		shouldOrder := !rm.ordered && len(rm.clients) > 0 && len(rm.ready) == len(rm.clients)
-		rm.mu.Unlock()
-		if shouldOrder {
-			h.orderForRoom(room, rm)
+		session.Event("confirm.ready", attribute.Int("ready", counts.Ready), attribute.Int("members", counts.Members))
+		if counts.AllReady() {
+			h.due(sessCtx, room)
 		}
+	})
+
+	// The client has left; the rest of the room may now all be ready. When
+	// this replica is going away the client is only moving to another, so
+	// the room waits for it.
+	if h.leave(sessCtx, room, cl).AllReady() && !h.shuttingDown() {
+		h.due(sessCtx, room)
 	}
 }
 
-func (h *wsConfirmHandler) orderForRoom(menuID string, rm *confirmRoom) {
-	rm.mu.Lock()
-	if rm.timer != nil {
-		rm.timer.Stop()
-		rm.timer = nil
-	}
-	if rm.ordered {
-		rm.mu.Unlock()
-		return
+// leave removes cl from the room store and returns what is left.
+func (h *wsConfirmHandler) leave(ctx context.Context, room string, cl *wsroom.Client) roomstore.Counts {
+	sctx, cancel := storeContext(ctx)
+	defer cancel()
+	counts, err := h.store.Leave(sctx, room, cl.ID)
+	if err != nil {
+		slog.WarnContext(ctx, "confirm room store leave failed", logging.Room(room), logging.Client(cl.ID), logging.Err(err))
 	}
-	if len(rm.clients) == 0 {
-		rm.mu.Unlock()
+	return counts
+}
+
+// orderForRoom places one order per connected client, then ends the party's
+// round: ordered if any order went through, failed otherwise. ctx carries
+// the trace of whatever triggered the order (last ready client, or none for
+// the timer); it is detached from that connection's cancellation.
+func (h *wsConfirmHandler) orderForRoom(ctx context.Context, rm *wsroom.Room) {
+	st := confirmStateOf(rm)
+	var proceed bool
+	var menuID, roundID, member string
+	rm.Locked(func(n int) {
+		menuID, roundID, member = st.menu, st.round, st.member
+		if st.timer != nil {
+			st.timer.Stop()
+			st.timer = nil
+		}
+		if st.ordered || n == 0 {
+			return
+		}
+		st.ordered = true
+		proceed = true
+	})
+	if !proceed {
 		return
 	}
-	rm.ordered = true
-	conns := make([]*websocket.Conn, 0, len(rm.clients))
-	for c := range rm.clients {
-		conns = append(conns, c)
-	}
-	rm.mu.Unlock()
+	clients := rm.Clients()
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, span := tracing.Start(context.WithoutCancel(ctx), "confirm.orderForRoom", trace.WithAttributes(
+		attribute.String("room", menuID),
+		attribute.String("round_id", roundID),
+		attribute.Int("clients", len(clients)),
+	))
+	defer span.End()
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
-	for _, c := range conns {
+	placed := 0
+	var failure string
+	for _, c := range clients {
 		resp, err := h.orderClient.PostOrder(ctx, &gatewayapiv1.PostOrderRequest{MenuItemId: menuID})
 		if err != nil {
-			log.Printf("order PostOrder error: %v", err)
-			_ = c.WriteMessage(websocket.TextMessage, []byte(`{"error":"order failed"}`))
+			slog.ErrorContext(ctx, "order PostOrder error", logging.Room(menuID), logging.Client(c.ID), logging.Err(err))
+			tracing.RecordError(span, err)
+			ae := apperror.FromGRPC(err)
+			failure = "order failed: " + ae.Message
+			c.Send(apperror.WSFrame(apperror.Wrap(ae.Code, err, failure)))
 			continue
 		}
+		placed++
+		slog.InfoContext(ctx, "order placed", logging.Room(menuID), logging.Client(c.ID), logging.OrderID(resp.GetId()),
+			slog.Int("order_number", int(resp.GetOrderNumber())))
 		out := map[string]any{
 			"id":           resp.GetId(),
 			"menu_item_id": resp.GetMenuItemId(),
@@ -191,22 +439,27 @@ func (h *wsConfirmHandler) orderForRoom(menuID string, rm *confirmRoom) {
 			"order_number": resp.GetOrderNumber(),
 		}
 		b, _ := json.Marshal(out)
-		_ = c.WriteMessage(websocket.TextMessage, b)
+		c.Send(b)
 	}
 
 	// After sending results, close connections from the server side as requested
-	deadline := time.Now().Add(2 * time.Second)
-	for _, c := range conns {
-		_ = c.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "order completed"), deadline)
-		_ = c.Close()
+	for _, c := range clients {
+		c.Close(websocket.CloseNormalClosure, "order completed")
 	}
 
-	// Optional: cleanup empty room to avoid leaks
-	rm.mu.Lock()
-	if len(rm.clients) == 0 {
-		// best-effort removal; ignore if not present
-		// actual client defers will remove from maps upon close
-		fmt.Println("cleanup empty room")
+	if roundID == "" {
+		return
+	}
+	// Every replica holding part of the party reports. gateway-api lets a
+	// failed round become ordered but not the reverse, so the round ends
+	// ordered if any replica placed an order; a late failed report gets a
+	// conflict.
+	state, reason := gatewayapiv1.RoundState_ROUND_STATE_ORDERED, ""
+	if placed == 0 {
+		state, reason = gatewayapiv1.RoundState_ROUND_STATE_FAILED, failure
+	}
+	if err := advanceRound(ctx, h.rounds, roundID, menuID, member, state, time.Time{}, reason); err != nil {
+		slog.WarnContext(ctx, "round end failed", logging.Room(menuID), logging.RoundID(roundID), logging.Err(err))
+		tracing.RecordError(span, err)
 	}
-	rm.mu.Unlock()
 }
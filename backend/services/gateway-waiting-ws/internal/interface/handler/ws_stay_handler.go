@@ -1,174 +1,214 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
-	"log"
+	"log/slog"
 	"net/http"
+	"strconv"
 	"sync"
 	"time"
 
+	gatewayapiv1 "chantingkakigori/gen/go/gateway_api/v1"
+	"chantingkakigori/pkg/apperror"
+	"chantingkakigori/pkg/logging"
+	"chantingkakigori/pkg/metrics"
+	"chantingkakigori/pkg/tracing"
+	"chantingkakigori/pkg/wsroom"
+	"chantingkakigori/services/gateway-waiting-ws/internal/infrastructure/roomstore"
+
 	"github.com/gorilla/websocket"
+	"go.opentelemetry.io/otel/attribute"
 )
 
+// stayEvent carries a stayPayload to the replicas holding the room's clients.
+const stayEvent = "stay"
+
 type stayPayload struct {
 	StayNum   string `json:"stay_num"`
 	StartTime string `json:"start_time"`
+	// RoundID is the party's round on gateway-api, passed on to /ws and
+	// /ws/confirm; omitted if gateway-api could not open one.
+	RoundID string `json:"round_id,omitempty"`
 }
 
-type stayClient struct {
-	conn    *websocket.Conn
-	writeMu sync.Mutex
+type wsStayHandler struct {
+	hub    *wsroom.Hub
+	store  roomstore.Store
+	rounds gatewayapiv1.RoundServiceClient
+	stop   chan struct{}
+	// subjects maps a waiting client's ID to its session subject, so a
+	// resumed client joins the lobby round as itself.
+	subjects sync.Map
 }
 
-type stayRoom struct {
-	id            string
-	clients       map[*stayClient]struct{}
-	mu            sync.Mutex
-	thirdJoinedAt time.Time
+// NewWSStayHandler builds the /ws/stay handler; opts carries the queue
+// settings. Room sizes are counted in store, so a party may be split over
+// replicas. The room's lobby round on rounds starts when the party is full.
+func NewWSStayHandler(ctx context.Context, store roomstore.Store, rounds gatewayapiv1.RoundServiceClient, opts wsroom.Options) (*wsStayHandler, error) {
+	h := &wsStayHandler{store: store, rounds: rounds, stop: make(chan struct{})}
+	opts.Endpoint = "/ws/stay"
+	opts.OnRoomCreate = func(*wsroom.Room) { metrics.Rooms.WithLabelValues("stay").Inc() }
+	opts.OnRoomClose = func(*wsroom.Room) { metrics.Rooms.WithLabelValues("stay").Dec() }
+	opts.OnResume = func(c *wsroom.Client) {
+		// the slot was held, so the count is unchanged; tell the client where it stands
+		rm := c.Room()
+		if rm == nil {
+			return
+		}
+		ctx, cancel := storeContext(context.Background())
+		defer cancel()
+		counts, err := h.store.Counts(ctx, rm.ID)
+		if err != nil {
+			slog.WarnContext(ctx, "stay resume count failed", logging.Room(rm.ID), logging.Err(err))
+			return
+		}
+		n := min(counts.Members, 3)
+		data, _ := json.Marshal(stayPayload{StayNum: strconv.Itoa(n), StartTime: "null", RoundID: openRound(ctx, h.rounds, rm.ID, h.subject(c))})
+		c.SendLatest("stay", data)
+	}
+	h.hub = wsroom.NewHub(opts)
+	if err := store.Subscribe(ctx, h.onEvent); err != nil {
+		return nil, err
+	}
+	go heartbeat(h.hub, store, h.stop)
+	return h, nil
 }
 
-type wsStayHandler struct {
-	rooms map[string]*stayRoom
-	mu    sync.Mutex
+// subject returns the session subject c joined with, or "".
+func (h *wsStayHandler) subject(c *wsroom.Client) string {
+	if s, ok := h.subjects.Load(c.ID); ok {
+		return s.(string)
+	}
+	return ""
 }
 
-var upgrader = websocket.Upgrader{CheckOrigin: func(r *http.Request) bool { return true }}
-
-func NewWSStayHandler() *wsStayHandler {
-	return &wsStayHandler{rooms: make(map[string]*stayRoom)}
+// Shutdown sends a "server restarting" close frame to every waiting client and
+// waits for their sessions to finish.
+func (h *wsStayHandler) Shutdown(ctx context.Context) error {
+	err := h.hub.Shutdown(ctx)
+	close(h.stop)
+	_ = h.store.Close()
+	return err
 }
 
-func (h *wsStayHandler) getOrCreateRoom(id string) *stayRoom {
-	h.mu.Lock()
-	defer h.mu.Unlock()
-	if rm, ok := h.rooms[id]; ok {
-		return rm
+// onEvent relays a room's state to this replica's clients in it, ending
+// their sessions once the start time is set.
+func (h *wsStayHandler) onEvent(ev roomstore.Event) {
+	if ev.Kind != stayEvent {
+		return
+	}
+	rm := h.hub.Room(ev.Room)
+	if rm == nil {
+		return
+	}
+	var p stayPayload
+	if err := json.Unmarshal(ev.Data, &p); err != nil {
+		return
+	}
+	// each payload is the full room state, so only the newest one matters
+	rm.BroadcastLatest("stay", ev.Data)
+	if p.StartTime != "null" {
+		rm.CloseAll(websocket.CloseNormalClosure, "session ended")
 	}
-	rm := &stayRoom{id: id, clients: make(map[*stayClient]struct{})}
-	h.rooms[id] = rm
-	return rm
 }
 
-func (h *wsStayHandler) broadcast(rm *stayRoom, payload stayPayload) {
+// broadcast publishes payload to every replica. If the store cannot, this
+// replica's clients still get it.
+func (h *wsStayHandler) broadcast(ctx context.Context, roomID string, payload stayPayload) {
 	data, _ := json.Marshal(payload)
-	rm.mu.Lock()
-	clients := make([]*stayClient, 0, len(rm.clients))
-	for c := range rm.clients {
-		clients = append(clients, c)
-	}
-	rm.mu.Unlock()
-	for _, c := range clients {
-		c.writeMu.Lock()
-		err := c.conn.WriteMessage(websocket.TextMessage, data)
-		c.writeMu.Unlock()
-		if err != nil {
-			log.Printf("stay ws write error: room=%s err=%v", rm.id, err)
-		}
+	ev := roomstore.Event{Room: roomID, Kind: stayEvent, Data: data}
+	ctx, cancel := storeContext(ctx)
+	defer cancel()
+	if err := h.store.Publish(ctx, ev); err != nil {
+		slog.WarnContext(ctx, "stay publish failed", logging.Room(roomID), logging.Err(err))
+		h.onEvent(ev)
 	}
 }
 
 func (h *wsStayHandler) HandleWebSocketStay(w http.ResponseWriter, r *http.Request) {
 	roomID := r.URL.Query().Get("room")
 	if roomID == "" {
-		http.Error(w, "room is required", http.StatusBadRequest)
+		apperror.WriteProblem(w, r, apperror.New(apperror.CodeInvalidArgument, "room is required"))
 		return
 	}
-	conn, err := upgrader.Upgrade(w, r, nil)
+	cl, resumed, err := h.hub.Upgrade(w, r)
 	if err != nil {
-		log.Printf("stay ws upgrade error: %v", err)
+		slog.WarnContext(r.Context(), "stay ws upgrade error", logging.Room(roomID), logging.Err(err))
 		return
 	}
-	log.Printf("stay ws connected: room=%s remote=%s", roomID, r.RemoteAddr)
-
-	rm := h.getOrCreateRoom(roomID)
-	cl := &stayClient{conn: conn}
-
-	rm.mu.Lock()
-	rm.clients[cl] = struct{}{}
-	count := len(rm.clients)
-	if count == 3 {
-		rm.thirdJoinedAt = time.Now()
+	if resumed {
+		// the original session keeps running on this connection
+		slog.InfoContext(r.Context(), "stay ws resumed", logging.Room(roomID), logging.Client(cl.ID), slog.String("remote", r.RemoteAddr))
+		return
+	}
+	sessCtx, session := tracing.StartSession(r, "/ws/stay", attribute.String("room", roomID))
+	defer session.End(nil)
+	slog.InfoContext(sessCtx, "stay ws connected", logging.Room(roomID), logging.Client(cl.ID), slog.String("remote", r.RemoteAddr))
+	defer slog.InfoContext(sessCtx, "stay ws disconnected", logging.Room(roomID), logging.Client(cl.ID), slog.String("remote", r.RemoteAddr))
+	metrics.WSConnections.WithLabelValues("/ws/stay").Inc()
+	defer metrics.WSConnections.WithLabelValues("/ws/stay").Dec()
+
+	h.hub.Join(roomID, cl)
+	jctx, cancel := storeContext(sessCtx)
+	counts, err := h.store.Join(jctx, roomID, cl.ID)
+	cancel()
+	if err != nil {
+		slog.ErrorContext(sessCtx, "stay room store join failed", logging.Room(roomID), logging.Client(cl.ID), logging.Err(err))
+		cl.Send(apperror.WSFrame(apperror.Wrap(apperror.CodeUnavailable, err, "room store unavailable")))
+		cl.Close(websocket.CloseInternalServerErr, "room store unavailable")
+		_ = cl.Run(nil)
+		return
 	}
-	rm.mu.Unlock()
-
-	// Ensure cleanup on exit
 	defer func() {
-		rm.mu.Lock()
-		delete(rm.clients, cl)
-		empty := len(rm.clients) == 0
-		rm.mu.Unlock()
-		_ = conn.Close()
-		log.Printf("stay ws disconnected: room=%s remote=%s", roomID, r.RemoteAddr)
-		if empty {
-			// remove empty room for clean reset
-			h.mu.Lock()
-			delete(h.rooms, roomID)
-			h.mu.Unlock()
-		}
-	}()
-
-	// Heartbeat setup (ping/pong)
-	const pongWait = 60 * time.Second
-	const pingPeriod = 30 * time.Second
-	_ = conn.SetReadDeadline(time.Now().Add(pongWait))
-	conn.SetPongHandler(func(string) error {
-		return conn.SetReadDeadline(time.Now().Add(pongWait))
-	})
-	stopCh := make(chan struct{})
-	go func() {
-		ticker := time.NewTicker(pingPeriod)
-		defer ticker.Stop()
-		for {
-			select {
-			case <-ticker.C:
-				cl.writeMu.Lock()
-				_ = cl.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(5*time.Second))
-				cl.writeMu.Unlock()
-			case <-stopCh:
-				return
-			}
+		ctx, cancel := storeContext(sessCtx)
+		defer cancel()
+		if _, err := h.store.Leave(ctx, roomID, cl.ID); err != nil {
+			slog.WarnContext(ctx, "stay room store leave failed", logging.Room(roomID), logging.Client(cl.ID), logging.Err(err))
 		}
 	}()
+	count := counts.Members
+	session.Event("stay.joined", attribute.Int("stay_num", count))
+	member := subject(r.Context())
+	h.subjects.Store(cl.ID, member)
+	defer h.subjects.Delete(cl.ID)
+	// every member gets the same lobby round until the party is full
+	roundID := openRound(sessCtx, h.rounds, roomID, member)
 
 	// Immediately broadcast current state per spec
 	switch count {
 	case 1:
-		h.broadcast(rm, stayPayload{StayNum: "1", StartTime: "null"})
+		h.broadcast(sessCtx, roomID, stayPayload{StayNum: "1", StartTime: "null", RoundID: roundID})
 	case 2:
-		h.broadcast(rm, stayPayload{StayNum: "2", StartTime: "null"})
+		h.broadcast(sessCtx, roomID, stayPayload{StayNum: "2", StartTime: "null", RoundID: roundID})
 	case 3:
-		// Immediately broadcast with start_time = now + 10s (JST), then disconnect all
+		// Immediately broadcast with start_time = now + 10s (JST); every
+		// replica then disconnects its clients in the room
+		startAt := time.Now().Add(10 * time.Second)
 		loc, err := time.LoadLocation("Asia/Tokyo")
 		var startISO string
 		if err == nil {
-			startISO = time.Now().In(loc).Add(10 * time.Second).Format(time.RFC3339)
+			startISO = startAt.In(loc).Format(time.RFC3339)
 		} else {
-			startISO = time.Now().Add(10 * time.Second).Format(time.RFC3339)
-		}
-		h.broadcast(rm, stayPayload{StayNum: "3", StartTime: startISO})
-		rm.mu.Lock()
-		clients := make([]*stayClient, 0, len(rm.clients))
-		for c := range rm.clients {
-			clients = append(clients, c)
+			startISO = startAt.Format(time.RFC3339)
 		}
-		rm.mu.Unlock()
-		for _, c := range clients {
-			c.writeMu.Lock()
-			_ = c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "session ended"), time.Now().Add(2*time.Second))
-			c.writeMu.Unlock()
-			_ = c.conn.Close()
+		if roundID != "" {
+			if err := advanceRound(sessCtx, h.rounds, roundID, roomID, member, gatewayapiv1.RoundState_ROUND_STATE_COUNTDOWN, startAt, ""); err != nil {
+				// the party still starts, without a round
+				slog.WarnContext(sessCtx, "round countdown failed", logging.Room(roomID), logging.RoundID(roundID), logging.Err(err))
+				roundID = ""
+			}
 		}
+		h.broadcast(sessCtx, roomID, stayPayload{StayNum: "3", StartTime: startISO, RoundID: roundID})
 	default:
 		// 4 以上は仕様外だが、3 と同様に終了扱いにしておく
-		// broadcast latest known state without start_time
-		h.broadcast(rm, stayPayload{StayNum: "3", StartTime: "null"})
+		// broadcast latest known state without start_time; the round is
+		// the next party's
+		h.broadcast(sessCtx, roomID, stayPayload{StayNum: "3", StartTime: "null", RoundID: roundID})
 	}
 
 	// Keep connection open until client closes or server closes on 3rd rule
-	for {
-		if _, _, err := conn.ReadMessage(); err != nil {
-			break
-		}
-	}
-	close(stopCh)
+	_ = cl.Run(func(_ int, data []byte) {
+		session.Received(len(data))
+	})
 }
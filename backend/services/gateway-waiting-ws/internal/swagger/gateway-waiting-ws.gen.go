@@ -6,9 +6,24 @@ package openapi
 // GetWsConfirmParams defines parameters for GetWsConfirm.
 type GetWsConfirmParams struct {
 	Room string `form:"room" json:"room"`
+
+	// Resume 直前のセッションの `token`。猶予時間内なら同じセッションとして再開する（不明・期限切れなら新規セッション）
+	Resume *string `form:"resume,omitempty" json:"resume,omitempty"`
+
+	// Token POST /api/v1/sessions で発行されたセッショントークン。`room` と同じ room に紐づいている必要がある
+	Token string `form:"token" json:"token"`
+
+	// Round /ws/stay で受け取った `round_id`
+	Round *string `form:"round,omitempty" json:"round,omitempty"`
 }
 
 // GetWsStayParams defines parameters for GetWsStay.
 type GetWsStayParams struct {
 	Room string `form:"room" json:"room"`
+
+	// Resume 直前のセッションの `token`。猶予時間内なら同じセッションとして再開する（不明・期限切れなら新規セッション）
+	Resume *string `form:"resume,omitempty" json:"resume,omitempty"`
+
+	// Token POST /api/v1/sessions で発行されたセッショントークン。`room` と同じ room に紐づいている必要がある
+	Token string `form:"token" json:"token"`
 }
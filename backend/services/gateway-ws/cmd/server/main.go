@@ -1,83 +1,167 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 
-	kakigoriwsv1 "chantingkakigori/gen/go/kakigori_ws/v1"
+	gatewayapiv1 "chantingkakigori/gen/go/gateway_api/v1"
+	"chantingkakigori/pkg/auth"
 	"chantingkakigori/pkg/grpcjson"
+	"chantingkakigori/pkg/logging"
+	"chantingkakigori/pkg/metrics"
+	"chantingkakigori/pkg/requestid"
+	"chantingkakigori/pkg/shutdown"
+	"chantingkakigori/pkg/tracing"
+	"chantingkakigori/pkg/wsroom"
+	"chantingkakigori/services/gateway-ws/internal/infrastructure/kakigori"
 	"chantingkakigori/services/gateway-ws/internal/interface/handler"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
 	"google.golang.org/grpc/credentials/insecure"
 )
 
 func main() {
+	logging.Init("gateway-ws")
+	ctx, stop := shutdown.NotifyContext()
+	defer stop()
+
 	// Env
 	httpPort := os.Getenv("PORT")
 	if httpPort == "" {
 		httpPort = "8080"
 	}
-	kakigoriAddr := os.Getenv("KAKIGORI_GRPC_ADDR")
-	if kakigoriAddr == "" {
-		kakigoriAddr = "localhost:50051"
+	discovery := kakigori.DiscoveryFromEnv()
+
+	metrics.Register("gateway-ws")
+	shutdownTracing, err := tracing.Init(context.Background(), "gateway-ws")
+	if err != nil {
+		logging.Fatal("failed to init tracing", logging.Err(err))
 	}
+	defer func() { _ = shutdownTracing(context.Background()) }()
 
-	// gRPC client to kakigori-ws
+	// gRPC clients to the kakigori-ws replicas; rooms are spread over them
+	// by consistent hashing. Protobuf on the wire; AGGREGATOR_CODEC=protojson
+	// makes the payloads readable while debugging.
 	grpcjson.Register()
+	codec := grpcjson.CodecFromEnv("AGGREGATOR_CODEC", grpcjson.Proto)
+	slog.Info("aggregator codec", slog.String("codec", codec))
 	dialOpts := []grpc.DialOption{
 		grpc.WithTransportCredentials(insecure.NewCredentials()),
-		grpc.WithDefaultCallOptions(grpc.ForceCodec(grpcjson.Codec{})),
+		grpc.WithDefaultCallOptions(grpcjson.CallOption(codec)),
+		grpc.WithChainStreamInterceptor(
+			metrics.StreamClientInterceptor("kakigori-ws"),
+			requestid.StreamClientInterceptor(),
+		),
+		grpc.WithChainUnaryInterceptor(
+			metrics.UnaryClientInterceptor("kakigori-ws"),
+			requestid.UnaryClientInterceptor(),
+		),
+		tracing.DialOption(),
+		// redial a restarting replica quickly; streams reopen once it is back
+		grpc.WithConnectParams(grpc.ConnectParams{
+			Backoff:           backoff.Config{BaseDelay: 100 * time.Millisecond, Multiplier: 1.6, Jitter: 0.2, MaxDelay: time.Second},
+			MinConnectTimeout: 5 * time.Second,
+		}),
+	}
+	pool := kakigori.NewPool(ctx, discovery, dialOpts...)
+	defer pool.Close()
+
+	// gRPC client to gateway-api, which owns each party's round
+	gatewayAPIGrpcAddr := os.Getenv("GATEWAY_API_GRPC_ADDR")
+	if gatewayAPIGrpcAddr == "" {
+		gatewayAPIGrpcAddr = "gateway-api:9090"
 	}
-	conn, err := grpc.NewClient(kakigoriAddr, dialOpts...)
+	gatewayAPIConn, err := grpc.NewClient(gatewayAPIGrpcAddr,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithChainUnaryInterceptor(metrics.UnaryClientInterceptor("gateway-api"), requestid.UnaryClientInterceptor()),
+		tracing.DialOption(),
+	)
+	if err != nil {
+		logging.Fatal("failed to dial gateway-api gRPC", logging.Err(err))
+	}
+	defer func() { _ = gatewayAPIConn.Close() }()
+
+	signer, err := auth.SignerFromEnv()
 	if err != nil {
-		log.Fatalf("failed to dial kakigori-ws: %v", err)
+		logging.Fatal("failed to init auth", logging.Err(err))
 	}
-	defer func() { _ = conn.Close() }()
-	aggregatorClient := kakigoriwsv1.NewKakigoriWsAggregatorServiceClient(conn)
+	if signer == nil {
+		slog.Warn("authentication disabled (AUTH_DISABLED=true)")
+	}
+	origins := auth.OriginsFromEnv()
+	wsOpts := wsroom.EnvOptions()
+	wsOpts.CheckOrigin = origins.CheckOrigin
 
 	// Handlers
-	wsHandler := handler.NewWSHandler(aggregatorClient)
+	// WS_RAW_AUDIO=true lets clients send microphone audio for kakigori-ws
+	// to measure; it costs a few hundred times the bandwidth of values
+	rawAudio := strings.EqualFold(os.Getenv("WS_RAW_AUDIO"), "true")
+	wsHandler := handler.NewWSHandler(pool, wsOpts, gatewayapiv1.NewRoundServiceClient(gatewayAPIConn), rawAudio)
+	// Spectator screens need no session: they only see room averages
+	watchHandler := handler.NewWatchHandler(pool, wsOpts)
+	go pool.Run(ctx, kakigori.DefaultRefreshInterval)
 
 	mux := http.NewServeMux()
-	// CORS middleware wrapper
-	withCORS := func(h http.HandlerFunc) http.HandlerFunc {
-		return func(w http.ResponseWriter, r *http.Request) {
-			w.Header().Set("Access-Control-Allow-Origin", "*")
-			w.Header().Set("Access-Control-Allow-Methods", "GET,POST,PUT,PATCH,DELETE,OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "*")
-			if r.Method == http.MethodOptions {
-				w.WriteHeader(http.StatusNoContent)
-				return
-			}
-			h(w, r)
-		}
-	}
-
-	mux.HandleFunc("/ws", withCORS(wsHandler.HandleWebSocket))
-	mux.HandleFunc("/ws/health", withCORS(func(w http.ResponseWriter, r *http.Request) {
-		log.Printf("/ws/health called from %s", r.RemoteAddr)
+	mux.HandleFunc("/ws", metrics.InstrumentHandler("/ws", origins.CORS(signer.RoomGuard(wsHandler.HandleWebSocket))))
+	mux.HandleFunc("/ws/watch", metrics.InstrumentHandler("/ws/watch", origins.CORS(watchHandler.HandleWebSocket)))
+	mux.Handle("/metrics", metrics.Handler())
+	ready := &shutdown.Readiness{}
+	mux.Handle("/readyz", ready)
+	mux.HandleFunc("/ws/health", origins.CORS(func(w http.ResponseWriter, r *http.Request) {
+		slog.DebugContext(r.Context(), "/ws/health called", slog.String("remote", r.RemoteAddr))
 		w.Header().Set("Content-Type", "application/json")
 		_ = json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
 	}))
+	// Operator view of kakigori-ws rooms; off unless ADMIN_TOKEN is set.
+	// nginx does not route /admin, so it is reached in-cluster or via a
+	// port-forward.
+	if adminToken := auth.AdminTokenFromEnv(); adminToken != "" {
+		admin := handler.NewAdminHandler(pool)
+		mux.HandleFunc("GET /admin/rooms", metrics.InstrumentHandler("/admin/rooms", auth.AdminGuard(adminToken, admin.ListRooms)))
+		mux.HandleFunc("GET /admin/rooms/{room}", metrics.InstrumentHandler("/admin/rooms/{room}", auth.AdminGuard(adminToken, admin.GetRoom)))
+		mux.HandleFunc("POST /admin/rooms/{room}/close", metrics.InstrumentHandler("/admin/rooms/{room}/close", auth.AdminGuard(adminToken, admin.CloseRoom)))
+	} else {
+		slog.Info("admin endpoints disabled (ADMIN_TOKEN not set)")
+	}
 	// Serve swagger alias to gateway-ws.yml
-	mux.HandleFunc("/swagger.yaml", withCORS(func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc("/swagger.yaml", origins.CORS(func(w http.ResponseWriter, r *http.Request) {
 		http.ServeFile(w, r, "/api/swagger/gateway-ws.yml")
 	}))
 
 	srv := &http.Server{
 		Addr:              ":" + httpPort,
-		Handler:           mux,
+		Handler:           requestid.Middleware(tracing.Handler(mux, "gateway-ws")),
 		ReadTimeout:       15 * time.Second,
 		ReadHeaderTimeout: 15 * time.Second,
 		WriteTimeout:      15 * time.Second,
 		IdleTimeout:       60 * time.Second,
 	}
-	log.Printf("gateway-ws HTTP listening on :%s (kakigori-ws=%s)", httpPort, kakigoriAddr)
-	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-		log.Fatalf("http server error: %v", err)
+	go func() {
+		slog.Info("HTTP listening", slog.String("addr", ":"+httpPort), slog.String("kakigori_ws", discovery.String()), slog.Any("peers", pool.Peers()))
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logging.Fatal("http server error", logging.Err(err))
+		}
+	}()
+
+	<-ctx.Done()
+	slog.Info("shutting down")
+	ready.Drain(context.Background())
+	sctx, cancel := context.WithTimeout(context.Background(), shutdown.Timeout())
+	defer cancel()
+	if err := srv.Shutdown(sctx); err != nil {
+		slog.Warn("http shutdown", logging.Err(err))
+	}
+	if err := wsHandler.Shutdown(sctx); err != nil {
+		slog.Warn("ws drain incomplete", logging.Err(err))
+	}
+	if err := watchHandler.Shutdown(sctx); err != nil {
+		slog.Warn("ws watch drain incomplete", logging.Err(err))
 	}
+	slog.Info("shutdown complete")
 }
@@ -0,0 +1,72 @@
+package kakigori
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Discovery lists the kakigori-ws replicas as gRPC "host:port" targets.
+type Discovery interface {
+	Peers(ctx context.Context) ([]string, error)
+	String() string
+}
+
+// StaticPeers is a fixed replica list.
+type StaticPeers []string
+
+func (s StaticPeers) Peers(context.Context) ([]string, error) { return s, nil }
+
+func (s StaticPeers) String() string { return "static:" + strings.Join(s, ",") }
+
+// SRVPeers resolves the replicas from a DNS SRV record, e.g. the
+// "_grpc._tcp.<headless service>" record Kubernetes publishes for ready pods.
+type SRVPeers struct {
+	Name     string
+	Resolver *net.Resolver
+}
+
+func (s SRVPeers) Peers(ctx context.Context) ([]string, error) {
+	r := s.Resolver
+	if r == nil {
+		r = net.DefaultResolver
+	}
+	_, addrs, err := r.LookupSRV(ctx, "", "", s.Name)
+	if err != nil {
+		return nil, fmt.Errorf("lookup SRV %s: %w", s.Name, err)
+	}
+	out := make([]string, 0, len(addrs))
+	for _, a := range addrs {
+		out = append(out, net.JoinHostPort(strings.TrimSuffix(a.Target, "."), strconv.Itoa(int(a.Port))))
+	}
+	return out, nil
+}
+
+func (s SRVPeers) String() string { return "srv:" + s.Name }
+
+// DiscoveryFromEnv picks KAKIGORI_PEERS_SRV, then KAKIGORI_PEERS (comma
+// separated), then the single KAKIGORI_GRPC_ADDR (default localhost:50051).
+func DiscoveryFromEnv() Discovery {
+	if name := strings.TrimSpace(os.Getenv("KAKIGORI_PEERS_SRV")); name != "" {
+		return SRVPeers{Name: name}
+	}
+	if v := os.Getenv("KAKIGORI_PEERS"); v != "" {
+		var peers StaticPeers
+		for _, p := range strings.Split(v, ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				peers = append(peers, p)
+			}
+		}
+		if len(peers) > 0 {
+			return peers
+		}
+	}
+	addr := os.Getenv("KAKIGORI_GRPC_ADDR")
+	if addr == "" {
+		addr = "localhost:50051"
+	}
+	return StaticPeers{addr}
+}
@@ -0,0 +1,167 @@
+// Package kakigori routes rooms to kakigori-ws replicas. Each room lives on
+// exactly one replica, chosen by consistent hashing over the discovered
+// peers, so every gateway-ws replica agrees on it without coordination.
+package kakigori
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	kakigoriwsv1 "chantingkakigori/gen/go/kakigori_ws/v1"
+	"chantingkakigori/pkg/hashring"
+	"chantingkakigori/pkg/logging"
+	"chantingkakigori/pkg/metrics"
+
+	"google.golang.org/grpc"
+)
+
+// Client is the aggregator API of one replica.
+type Client = kakigoriwsv1.KakigoriWsAggregatorServiceClient
+
+// DefaultRefreshInterval is how often the peer list is re-resolved. It is
+// kept below kakigori-ws's SHUTDOWN_DRAIN_DELAY so rooms leave a draining
+// replica while it can still export them.
+const DefaultRefreshInterval = 2 * time.Second
+
+// Pool holds one connection per replica and the ring that maps rooms to
+// them.
+type Pool struct {
+	disc     Discovery
+	dialOpts []grpc.DialOption
+
+	mu       sync.RWMutex
+	ring     *hashring.Ring
+	conns    map[string]*grpc.ClientConn
+	clients  map[string]Client
+	onChange []func(ctx context.Context)
+}
+
+// NewPool resolves the first peer list and dials it. An empty or failed
+// first lookup is not fatal (kakigori-ws may still be starting); rooms have
+// no owner until a later refresh finds one.
+func NewPool(ctx context.Context, d Discovery, opts ...grpc.DialOption) *Pool {
+	p := &Pool{
+		disc:     d,
+		dialOpts: opts,
+		ring:     hashring.New(nil, 0),
+		conns:    make(map[string]*grpc.ClientConn),
+		clients:  make(map[string]Client),
+	}
+	if err := p.Refresh(ctx); err != nil {
+		slog.WarnContext(ctx, "initial kakigori-ws discovery failed", slog.String("discovery", d.String()), logging.Err(err))
+	}
+	return p
+}
+
+// OnChange registers fn to run after the ring changes, before connections to
+// removed replicas are closed, so fn can still move rooms off them.
+func (p *Pool) OnChange(fn func(ctx context.Context)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.onChange = append(p.onChange, fn)
+}
+
+// Owner returns the replica owning room and its client; "" and nil when no
+// replica is known.
+func (p *Pool) Owner(room string) (string, Client) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	addr := p.ring.Get(room)
+	return addr, p.clients[addr]
+}
+
+// Client returns the client of addr, which may be a replica that just left
+// the ring while OnChange callbacks run.
+func (p *Pool) Client(addr string) Client {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.clients[addr]
+}
+
+// Peers returns the replicas on the ring, sorted.
+func (p *Pool) Peers() []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.ring.Nodes()
+}
+
+// Refresh re-resolves the peers and, if the set changed, swaps the ring and
+// runs the OnChange callbacks. An empty result keeps the current ring: a
+// DNS hiccup must not orphan every room.
+func (p *Pool) Refresh(ctx context.Context) error {
+	peers, err := p.disc.Peers(ctx)
+	if err != nil {
+		return err
+	}
+	next := hashring.New(peers, 0)
+	p.mu.RLock()
+	same := next.Equal(p.ring)
+	p.mu.RUnlock()
+	if same || len(next.Nodes()) == 0 {
+		return nil
+	}
+
+	p.mu.Lock()
+	prev := p.ring.Nodes()
+	for _, addr := range next.Nodes() {
+		if _, ok := p.conns[addr]; ok {
+			continue
+		}
+		conn, err := grpc.NewClient(addr, p.dialOpts...)
+		if err != nil {
+			p.mu.Unlock()
+			return err
+		}
+		p.conns[addr] = conn
+		p.clients[addr] = kakigoriwsv1.NewKakigoriWsAggregatorServiceClient(conn)
+	}
+	p.ring = next
+	callbacks := p.onChange
+	p.mu.Unlock()
+
+	metrics.AggregatorPeers.Set(float64(len(next.Nodes())))
+	slog.InfoContext(ctx, "kakigori-ws peers changed", slog.Any("from", prev), slog.Any("to", next.Nodes()))
+	for _, fn := range callbacks {
+		fn(ctx)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for addr, conn := range p.conns {
+		if !p.ring.Has(addr) {
+			_ = conn.Close()
+			delete(p.conns, addr)
+			delete(p.clients, addr)
+		}
+	}
+	return nil
+}
+
+// Run refreshes the peer list every interval until ctx ends.
+func (p *Pool) Run(ctx context.Context, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			if err := p.Refresh(ctx); err != nil {
+				slog.WarnContext(ctx, "kakigori-ws discovery failed", slog.String("discovery", p.disc.String()), logging.Err(err))
+			}
+		}
+	}
+}
+
+// Close closes every connection.
+func (p *Pool) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for addr, conn := range p.conns {
+		_ = conn.Close()
+		delete(p.conns, addr)
+		delete(p.clients, addr)
+	}
+}
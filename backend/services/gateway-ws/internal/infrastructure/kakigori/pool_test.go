@@ -0,0 +1,84 @@
+package kakigori
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// mutablePeers is a Discovery the test can change between refreshes.
+type mutablePeers struct {
+	mu    sync.Mutex
+	peers []string
+}
+
+func (m *mutablePeers) set(p ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.peers = p
+}
+
+func (m *mutablePeers) Peers(context.Context) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return slices.Clone(m.peers), nil
+}
+
+func (m *mutablePeers) String() string { return "test" }
+
+func TestPoolRefresh(t *testing.T) {
+	ctx := context.Background()
+	d := &mutablePeers{}
+	d.set("k1:50051")
+	p := NewPool(ctx, d, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	defer p.Close()
+
+	changes := 0
+	var removedClient Client
+	p.OnChange(func(context.Context) {
+		changes++
+		// the replica that left is still reachable while rooms move off it
+		removedClient = p.Client("k1:50051")
+	})
+
+	if owner, c := p.Owner("giiku-sai"); owner != "k1:50051" || c == nil {
+		t.Fatalf("owner=%q client=%v", owner, c)
+	}
+
+	d.set("k1:50051", "k2:50051")
+	if err := p.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if changes != 1 || !slices.Equal(p.Peers(), []string{"k1:50051", "k2:50051"}) {
+		t.Fatalf("changes=%d peers=%v", changes, p.Peers())
+	}
+
+	// unchanged set: no callback
+	if err := p.Refresh(ctx); err != nil || changes != 1 {
+		t.Fatalf("err=%v changes=%d", err, changes)
+	}
+
+	// an empty lookup keeps the ring
+	d.set()
+	if err := p.Refresh(ctx); err != nil || len(p.Peers()) != 2 {
+		t.Fatalf("err=%v peers=%v", err, p.Peers())
+	}
+
+	d.set("k2:50051")
+	if err := p.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if changes != 2 || removedClient == nil {
+		t.Fatalf("changes=%d removed client during callback=%v", changes, removedClient)
+	}
+	if p.Client("k1:50051") != nil {
+		t.Fatal("connection to the removed replica was kept")
+	}
+	if owner, _ := p.Owner("giiku-sai"); owner != "k2:50051" {
+		t.Fatalf("owner=%q", owner)
+	}
+}
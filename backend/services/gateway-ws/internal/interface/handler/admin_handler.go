@@ -0,0 +1,215 @@
+package handler
+
+import (
+	"encoding/json"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	kakigoriwsv1 "chantingkakigori/gen/go/kakigori_ws/v1"
+	"chantingkakigori/pkg/apperror"
+	"chantingkakigori/pkg/logging"
+	openapi "chantingkakigori/services/gateway-ws/internal"
+	"chantingkakigori/services/gateway-ws/internal/infrastructure/kakigori"
+)
+
+// adminHandler is the operators' view of kakigori-ws rooms across every
+// replica: JSON for tools, a small HTML table with close buttons for a
+// browser.
+type adminHandler struct {
+	pool *kakigori.Pool
+}
+
+// NewAdminHandler builds the /admin/rooms handlers.
+func NewAdminHandler(pool *kakigori.Pool) *adminHandler {
+	return &adminHandler{pool: pool}
+}
+
+// ListRooms serves GET /admin/rooms. Replicas that fail are skipped; the
+// request fails only when all of them do.
+func (h *adminHandler) ListRooms(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	out := openapi.AdminRoomList{Rooms: []openapi.AdminRoom{}}
+	var lastErr error
+	ok := 0
+	for _, addr := range h.pool.Peers() {
+		c := h.pool.Client(addr)
+		if c == nil {
+			continue
+		}
+		resp, err := c.ListRooms(r.Context(), &kakigoriwsv1.ListRoomsRequest{})
+		if err != nil {
+			slog.WarnContext(r.Context(), "admin list rooms failed", slog.String("peer", addr), logging.Err(err))
+			lastErr = err
+			continue
+		}
+		ok++
+		for _, ri := range resp.GetRooms() {
+			out.Rooms = append(out.Rooms, adminRoom(addr, ri, now))
+		}
+	}
+	if ok == 0 {
+		if lastErr == nil {
+			lastErr = apperror.New(apperror.CodeUnavailable, "no kakigori-ws replica available")
+		}
+		writeGRPCProblem(w, r, lastErr)
+		return
+	}
+	sort.Slice(out.Rooms, func(i, j int) bool { return out.Rooms[i].Room < out.Rooms[j].Room })
+	if strings.Contains(r.Header.Get("Accept"), "text/html") {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := roomsPage.Execute(w, out); err != nil {
+			slog.WarnContext(r.Context(), "admin page render error", logging.Err(err))
+		}
+		return
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// GetRoom serves GET /admin/rooms/{room}. The owner is asked first; a room
+// left behind elsewhere (a handoff that failed) is still found.
+func (h *adminHandler) GetRoom(w http.ResponseWriter, r *http.Request) {
+	room := r.PathValue("room")
+	owner, _ := h.pool.Owner(room)
+	peers := h.pool.Peers()
+	slices.SortStableFunc(peers, func(a, b string) int {
+		switch {
+		case a == owner:
+			return -1
+		case b == owner:
+			return 1
+		}
+		return 0
+	})
+	var lastErr error = apperror.New(apperror.CodeNotFound, "room not found")
+	for _, addr := range peers {
+		c := h.pool.Client(addr)
+		if c == nil {
+			continue
+		}
+		resp, err := c.GetRoom(r.Context(), &kakigoriwsv1.GetRoomRequest{Room: room})
+		if err == nil {
+			writeJSON(w, http.StatusOK, adminRoom(addr, resp.GetRoom(), time.Now()))
+			return
+		}
+		if apperror.FromGRPC(err).Code != apperror.CodeNotFound {
+			lastErr = err
+		}
+	}
+	writeGRPCProblem(w, r, lastErr)
+}
+
+// CloseRoom serves POST /admin/rooms/{room}/close on every replica. The /ws
+// clients of the room are disconnected by their own aggregate streams
+// ending, on every gateway-ws replica.
+func (h *adminHandler) CloseRoom(w http.ResponseWriter, r *http.Request) {
+	room := r.PathValue("room")
+	req := &kakigoriwsv1.CloseRoomRequest{Room: room, Reason: r.URL.Query().Get("reason")}
+	if req.Reason == "" {
+		req.Reason = "closed from gateway-ws admin"
+	}
+	closed, found := 0, false
+	var lastErr error = apperror.New(apperror.CodeNotFound, "room not found")
+	for _, addr := range h.pool.Peers() {
+		c := h.pool.Client(addr)
+		if c == nil {
+			continue
+		}
+		resp, err := c.CloseRoom(r.Context(), req)
+		if err != nil {
+			if apperror.FromGRPC(err).Code != apperror.CodeNotFound {
+				slog.WarnContext(r.Context(), "admin close room failed", logging.Room(room), slog.String("peer", addr), logging.Err(err))
+				lastErr = err
+			}
+			continue
+		}
+		found = true
+		closed += int(resp.GetClosedStreams())
+	}
+	if !found {
+		writeGRPCProblem(w, r, lastErr)
+		return
+	}
+	slog.WarnContext(r.Context(), "admin closed room", logging.Room(room),
+		slog.String("reason", req.Reason), slog.Int("streams", closed))
+	if r.Header.Get("Content-Type") == "application/x-www-form-urlencoded" {
+		http.Redirect(w, r, "/admin/rooms", http.StatusSeeOther)
+		return
+	}
+	writeJSON(w, http.StatusOK, openapi.CloseRoomResult{Room: room, ClosedStreams: closed})
+}
+
+func adminRoom(instance string, ri *kakigoriwsv1.RoomInfo, now time.Time) openapi.AdminRoom {
+	created := time.UnixMilli(ri.GetCreatedAtUnixMs())
+	out := openapi.AdminRoom{
+		Room:           ri.GetRoom(),
+		Instance:       instance,
+		Participants:   make([]openapi.AdminParticipant, 0, len(ri.GetParticipants())),
+		Count:          int(ri.GetCount()),
+		Average:        ri.GetAverage(),
+		Streams:        int(ri.GetStreams()),
+		FlaggedClients: ri.GetFlaggedClients(),
+		CreatedAt:      created,
+		AgeSeconds:     now.Sub(created).Seconds(),
+	}
+	if out.FlaggedClients == nil {
+		out.FlaggedClients = []string{}
+	}
+	if ms := ri.GetLastSampleUnixMs(); ms > 0 {
+		t := time.UnixMilli(ms)
+		out.LastSample = &t
+	}
+	for _, p := range ri.GetParticipants() {
+		ap := openapi.AdminParticipant{Id: p.GetClientId(), Mean: p.GetMean(), Count: int(p.GetCount())}
+		if name := p.GetDisplayName(); name != "" {
+			ap.Name = &name
+		}
+		if ms := p.GetLastSeenUnixMs(); ms > 0 {
+			t := time.UnixMilli(ms)
+			ap.LastSeen = &t
+		}
+		out.Participants = append(out.Participants, ap)
+	}
+	return out
+}
+
+func writeGRPCProblem(w http.ResponseWriter, r *http.Request, err error) {
+	ae := apperror.FromGRPC(err)
+	if ae.Code == apperror.CodeInternal || ae.Code == apperror.CodeTimeout {
+		slog.ErrorContext(r.Context(), "admin rpc error", logging.Err(err))
+	}
+	apperror.WriteProblem(w, r, ae)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+var roomsPage = template.Must(template.New("rooms").Funcs(template.FuncMap{
+	"pct": func(v float64) string { return strconv.FormatFloat(v*100, 'f', 1, 64) + "%" },
+	"dur": func(s float64) string { return (time.Duration(s) * time.Second).Round(time.Second).String() },
+}).Parse(`<!doctype html>
+<html lang="ja"><head><meta charset="utf-8"><title>kakigori-ws rooms</title>
+<style>body{font-family:sans-serif}table{border-collapse:collapse}td,th{border:1px solid #ccc;padding:4px 8px;text-align:left}</style>
+</head><body>
+<h1>kakigori-ws rooms ({{len .Rooms}})</h1>
+<table>
+<tr><th>room</th><th>instance</th><th>participants</th><th>streams</th><th>samples</th><th>average</th><th>age</th><th>last sample</th><th>flagged</th><th></th></tr>
+{{range .Rooms}}<tr>
+<td>{{.Room}}</td><td>{{.Instance}}</td>
+<td>{{range .Participants}}{{if .Name}}{{.Name}}{{else}}{{.Id}}{{end}} ({{pct .Mean}}, {{.Count}})<br>{{end}}</td>
+<td>{{.Streams}}</td><td>{{.Count}}</td><td>{{pct .Average}}</td><td>{{dur .AgeSeconds}}</td>
+<td>{{with .LastSample}}{{.Format "15:04:05"}}{{else}}-{{end}}</td>
+<td>{{range .FlaggedClients}}{{.}}<br>{{end}}</td>
+<td><form method="post" action="/admin/rooms/{{.Room}}/close" onsubmit="return confirm('close {{.Room}}?')"><button>close</button></form></td>
+</tr>{{end}}
+</table>
+</body></html>
+`))
@@ -0,0 +1,366 @@
+package handler
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	kakigoriwsv1 "chantingkakigori/gen/go/kakigori_ws/v1"
+	"chantingkakigori/pkg/apperror"
+	"chantingkakigori/pkg/logging"
+	"chantingkakigori/pkg/metrics"
+	"chantingkakigori/pkg/tracing"
+	"chantingkakigori/pkg/wsroom"
+	"chantingkakigori/services/gateway-ws/internal/infrastructure/kakigori"
+
+	"github.com/gorilla/websocket"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// closeSendWait bounds how long a moving stream waits for the old replica to
+// acknowledge the half-close before it is cancelled.
+const closeSendWait = time.Second
+
+// handoffTimeout bounds the ExportRoom + ImportRoom pair of one room.
+const handoffTimeout = 3 * time.Second
+
+// A stream cut by a transient error (a kakigori-ws restart) is reopened with
+// backoff for up to reconnectWindow; samples sent meanwhile are dropped.
+const (
+	reconnectMinBackoff = 100 * time.Millisecond
+	reconnectMaxBackoff = time.Second
+	reconnectWindow     = 30 * time.Second
+)
+
+// upstream is one WebSocket client's Aggregate stream. It lives on the
+// replica owning the room and is re-opened elsewhere when that changes.
+type upstream struct {
+	h    *wsHandler
+	room string
+	// round and menu tell kakigori-ws which party round and menu item the
+	// room's chant is for.
+	round         string
+	menu          string
+	participantID string
+	name          string
+	device        string
+	cl            *wsroom.Client
+	rm            *wsroom.Room
+	st            *chantState
+	session       *tracing.Session
+	ctx           context.Context
+
+	mu           sync.Mutex
+	addr         string
+	stream       kakigoriwsv1.KakigoriWsAggregatorService_AggregateClient
+	cancel       context.CancelFunc
+	done         chan struct{}
+	first        bool
+	flagged      bool
+	reconnecting bool
+	// failingSince is when the current run of failed streams began (unix
+	// nanoseconds, 0 while healthy). recv clears it without u.mu, which
+	// stopLocked holds while waiting for recv.
+	failingSince atomic.Int64
+}
+
+// openLocked opens a stream on the room's current owner. Identity and the
+// breakdown request go out again with the first sample, since the new
+// replica reads them from the first request only.
+func (u *upstream) openLocked() error {
+	addr, ctx, stream, cancel, err := u.dial()
+	if err != nil {
+		return err
+	}
+	u.installLocked(ctx, addr, stream, cancel)
+	return nil
+}
+
+// dial opens a stream on the room's current owner without touching u.
+func (u *upstream) dial() (string, context.Context, kakigoriwsv1.KakigoriWsAggregatorService_AggregateClient, context.CancelFunc, error) {
+	addr, c := u.h.pool.Owner(u.room)
+	if c == nil {
+		return "", nil, nil, nil, apperror.New(apperror.CodeUnavailable, "no kakigori-ws replica available")
+	}
+	ctx, cancel := context.WithCancel(u.ctx)
+	stream, err := c.Aggregate(ctx)
+	if err != nil {
+		cancel()
+		return "", nil, nil, nil, err
+	}
+	return addr, ctx, stream, cancel, nil
+}
+
+func (u *upstream) installLocked(ctx context.Context, addr string, stream kakigoriwsv1.KakigoriWsAggregatorService_AggregateClient, cancel context.CancelFunc) {
+	u.addr, u.stream, u.cancel, u.first = addr, stream, cancel, true
+	u.reconnecting = false
+	u.done = make(chan struct{})
+	go u.recv(ctx, stream, u.done)
+	slog.DebugContext(ctx, "grpc aggregate stream opened", logging.Room(u.room), slog.String("peer", addr))
+}
+
+// stopLocked half-closes the stream and waits for its receiver, so the old
+// replica removes the client before the stream is reopened elsewhere.
+func (u *upstream) stopLocked() {
+	if u.stream == nil {
+		return
+	}
+	_ = u.stream.CloseSend()
+	select {
+	case <-u.done:
+	case <-time.After(closeSendWait):
+	}
+	u.cancel()
+	<-u.done
+	u.stream = nil
+}
+
+// send forwards one sample (req.Value) or batch (req.Samples) to the room.
+func (u *upstream) send(req *kakigoriwsv1.AggregateRequest) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.stream == nil {
+		if u.reconnecting {
+			return nil
+		}
+		return apperror.New(apperror.CodeUnavailable, "aggregator stream closed")
+	}
+	req.Room = u.room
+	if u.first {
+		req.ClientId, req.DisplayName, req.IncludeBreakdown, req.Device = u.participantID, u.name, true, u.device
+		req.RoundId, req.MenuItemId = u.round, u.menu
+		u.first = false
+	}
+	return u.stream.Send(req)
+}
+
+// peer returns the replica the stream is on.
+func (u *upstream) peer() string {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.addr
+}
+
+// move reopens the stream on the room's owner if it is elsewhere.
+func (u *upstream) move() error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if owner, _ := u.h.pool.Owner(u.room); owner == u.addr {
+		return nil
+	}
+	from := u.addr
+	u.stopLocked()
+	if err := u.openLocked(); err != nil {
+		return err
+	}
+	slog.InfoContext(u.ctx, "aggregate stream moved", logging.Room(u.room), logging.Client(u.participantID),
+		slog.String("from", from), slog.String("to", u.addr))
+	return nil
+}
+
+func (u *upstream) close() {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.reconnecting = false
+	u.stopLocked()
+}
+
+// retryable reports whether a stream error is worth reopening the stream
+// for: the replica went away or timed out, rather than refusing the client.
+func retryable(ae *apperror.Error) bool {
+	return ae.Code == apperror.CodeUnavailable || ae.Code == apperror.CodeTimeout
+}
+
+// reconnect reopens the stream that failed, on the room's owner as it is
+// now, which re-registers the client there. It gives up, telling the client,
+// once streams have kept failing for reconnectWindow. A move or close that
+// got there first wins.
+func (u *upstream) reconnect(failed kakigoriwsv1.KakigoriWsAggregatorService_AggregateClient, cause error) {
+	u.mu.Lock()
+	if u.stream != failed {
+		u.mu.Unlock()
+		return
+	}
+	u.cancel()
+	u.stream, u.reconnecting = nil, true
+	u.mu.Unlock()
+
+	u.failingSince.CompareAndSwap(0, time.Now().UnixNano())
+	giveUp := time.Unix(0, u.failingSince.Load()).Add(reconnectWindow)
+	slog.WarnContext(u.ctx, "aggregate stream lost; reconnecting", logging.Room(u.room), logging.Client(u.participantID), logging.Err(cause))
+	backoff := reconnectMinBackoff
+	for {
+		select {
+		case <-u.ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		// dial outside u.mu: opening may wait for the connection, and
+		// send must keep dropping samples meanwhile
+		addr, ctx, stream, cancel, err := u.dial()
+		u.mu.Lock()
+		if !u.reconnecting {
+			u.mu.Unlock()
+			if err == nil {
+				cancel()
+			}
+			return
+		}
+		if err == nil {
+			u.installLocked(ctx, addr, stream, cancel)
+			u.mu.Unlock()
+			metrics.AggregatorReconnects.WithLabelValues("reopened").Inc()
+			slog.InfoContext(u.ctx, "aggregate stream reopened", logging.Room(u.room), logging.Client(u.participantID), slog.String("peer", addr))
+			return
+		}
+		if time.Now().After(giveUp) {
+			u.reconnecting = false
+			u.mu.Unlock()
+			metrics.AggregatorReconnects.WithLabelValues("gave_up").Inc()
+			slog.ErrorContext(u.ctx, "aggregate stream reconnect gave up", logging.Room(u.room), logging.Client(u.participantID), logging.Err(err))
+			u.cl.Send(apperror.WSFrame(apperror.Wrap(apperror.CodeUnavailable, err, "aggregator unavailable")))
+			u.cl.Close(websocket.CloseInternalServerErr, "aggregator unavailable")
+			return
+		}
+		u.mu.Unlock()
+		backoff = min(2*backoff, reconnectMaxBackoff)
+	}
+}
+
+// recv relays the stream's averages to the whole room (gRPC -> WS).
+func (u *upstream) recv(ctx context.Context, stream kakigoriwsv1.KakigoriWsAggregatorService_AggregateClient, done chan struct{}) {
+	defer close(done)
+	for {
+		resp, err := stream.Recv()
+		if err != nil {
+			slog.InfoContext(ctx, "grpc recv closed", logging.Room(u.room), logging.Err(err))
+			if err != io.EOF && ctx.Err() == nil {
+				ae := apperror.FromGRPC(err)
+				if retryable(ae) {
+					// reconnect takes u.mu, whose holders may be waiting
+					// for this receiver to end
+					go u.reconnect(stream, err)
+					return
+				}
+				u.cl.Send(apperror.WSFrame(ae))
+				if ae.Code == apperror.CodeConflict {
+					// an operator closed the room on kakigori-ws
+					u.cl.Close(websocket.CloseNormalClosure, RoomClosedReason)
+				}
+			}
+			return
+		}
+		u.failingSince.Store(0)
+		if resp.GetRoom() != u.room {
+			continue
+		}
+		if !u.flagged && slices.Contains(resp.GetFlaggedClients(), u.participantID) {
+			u.flagged = true
+			slog.WarnContext(ctx, "ws client flagged as suspicious by aggregator", logging.Room(u.room), logging.Client(u.participantID),
+				slog.String("last_verdict", resp.GetVerdict().String()))
+			u.session.Event("aggregate.flagged")
+		}
+		out := wsOut{Average: resp.GetAverage(), Count: int(resp.GetCount())}
+		for _, p := range resp.GetParticipants() {
+			wp := wsParticipant{ID: p.GetClientId(), Name: p.GetDisplayName(), Mean: p.GetMean(), Count: int(p.GetCount())}
+			if ms := p.GetLastSeenUnixMs(); ms > 0 {
+				wp.LastSeen = time.UnixMilli(ms).Format(time.RFC3339Nano)
+			}
+			out.Participants = append(out.Participants, wp)
+		}
+		u.rm.Locked(func(int) { u.st.last = &out })
+		// broadcast to all ws clients in the room, encoded once per
+		// subprotocol; a newer average replaces one a slow client has not
+		// received yet
+		size := 0
+		recipients := u.rm.BroadcastLatestBy("average", func(protocol string) (int, []byte) {
+			msgType, data := out.frame(protocol)
+			size += len(data)
+			return msgType, data
+		})
+		u.session.Sent(size, attribute.Int("recipients", recipients))
+		if broadcastLogSampler.Allow() {
+			slog.DebugContext(ctx, "ws wrote broadcast", logging.Room(u.room),
+				slog.Float64("average", out.Average), slog.Int("count", out.Count), slog.Int("recipients", recipients))
+		}
+	}
+}
+
+// rebalance runs after the kakigori-ws replica set changed: every room whose
+// streams sit on a replica that no longer owns it has its window handed to
+// the new owner, then its streams follow. Other gateway-ws replicas do the
+// same for their clients; ImportRoom merges, so the order does not matter.
+func (h *wsHandler) rebalance(ctx context.Context) {
+	for _, rm := range h.hub.Rooms() {
+		st := rm.State().(*chantState)
+		var ups []*upstream
+		rm.Locked(func(int) {
+			for u := range st.upstreams {
+				ups = append(ups, u)
+			}
+		})
+		owner, to := h.pool.Owner(rm.ID)
+		if to == nil {
+			continue
+		}
+		from := map[string]struct{}{}
+		for _, u := range ups {
+			if addr := u.peer(); addr != "" && addr != owner {
+				from[addr] = struct{}{}
+			}
+		}
+		if len(from) == 0 {
+			continue
+		}
+		for addr := range from {
+			h.handoff(ctx, rm.ID, addr, owner, to)
+		}
+		var wg sync.WaitGroup
+		for _, u := range ups {
+			wg.Add(1)
+			go func(u *upstream) {
+				defer wg.Done()
+				if err := u.move(); err != nil {
+					slog.WarnContext(ctx, "aggregate stream move failed", logging.Room(rm.ID), logging.Client(u.participantID), logging.Err(err))
+					u.cl.Send(apperror.WSFrame(apperror.Wrap(apperror.CodeUnavailable, err, "aggregator unavailable")))
+					u.cl.Close(websocket.CloseInternalServerErr, "aggregator unavailable")
+				}
+			}(u)
+		}
+		wg.Wait()
+	}
+}
+
+// handoff moves room's window from the replica at fromAddr to the owner.
+// A room another gateway already moved is reported NOT_FOUND and skipped.
+func (h *wsHandler) handoff(ctx context.Context, room, fromAddr, toAddr string, to kakigori.Client) {
+	from := h.pool.Client(fromAddr)
+	if from == nil {
+		metrics.AggregatorHandoffs.WithLabelValues("failed").Inc()
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, handoffTimeout)
+	defer cancel()
+	resp, err := from.ExportRoom(ctx, &kakigoriwsv1.ExportRoomRequest{Room: room})
+	if err != nil {
+		if apperror.FromGRPC(err).Code == apperror.CodeNotFound {
+			metrics.AggregatorHandoffs.WithLabelValues("empty").Inc()
+			return
+		}
+		metrics.AggregatorHandoffs.WithLabelValues("failed").Inc()
+		slog.WarnContext(ctx, "room export failed; window lost", logging.Room(room), slog.String("from", fromAddr), logging.Err(err))
+		return
+	}
+	if _, err := to.ImportRoom(ctx, &kakigoriwsv1.ImportRoomRequest{Snapshot: resp.GetSnapshot()}); err != nil {
+		metrics.AggregatorHandoffs.WithLabelValues("failed").Inc()
+		slog.WarnContext(ctx, "room import failed; window lost", logging.Room(room), slog.String("to", toAddr), logging.Err(err))
+		return
+	}
+	metrics.AggregatorHandoffs.WithLabelValues("moved").Inc()
+	slog.InfoContext(ctx, "room handed off", logging.Room(room), slog.String("from", fromAddr), slog.String("to", toAddr),
+		slog.Int("participants", len(resp.GetSnapshot().GetParticipants())))
+}
@@ -0,0 +1,295 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	kakigoriwsv1 "chantingkakigori/gen/go/kakigori_ws/v1"
+	"chantingkakigori/pkg/logging"
+	"chantingkakigori/pkg/metrics"
+	"chantingkakigori/pkg/wsroom"
+	openapi "chantingkakigori/services/gateway-ws/internal"
+	"chantingkakigori/services/gateway-ws/internal/infrastructure/kakigori"
+)
+
+// allRooms is the hub room of spectators watching every room.
+const allRooms = ""
+
+// watchOrphan is how long a room whose replica stopped reporting it (it
+// moved, or the replica left or reconnected) stays on screen waiting for
+// its new owner before it is reported lost.
+const watchOrphan = 10 * time.Second
+
+// wsRoomFrame is a room's average for spectators.
+type wsRoomFrame struct {
+	Type         string  `json:"type"` // "average"
+	Room         string  `json:"room"`
+	Average      float64 `json:"average"`
+	Count        int     `json:"count"`
+	Participants int     `json:"participants"`
+}
+
+// wsEndedFrame tells spectators a room is gone: "finished" when its last
+// participant left, "closed" by an operator, "lost" when no replica reports
+// it any more.
+type wsEndedFrame struct {
+	Type   string `json:"type"` // "ended"
+	Room   string `json:"room"`
+	Reason string `json:"reason"`
+}
+
+// watchedRoom is the latest frame of a room and the replica reporting it;
+// owner is "" while the room is orphaned.
+type watchedRoom struct {
+	owner string
+	frame []byte
+	since time.Time
+}
+
+type watchHandler struct {
+	hub  *wsroom.Hub
+	pool *kakigori.Pool
+
+	// Watch streams run only while spectators are connected: one per
+	// replica, shared by all of them.
+	mu         sync.Mutex
+	spectators int
+	cancel     context.CancelFunc
+	ctx        context.Context
+	streams    map[string]context.CancelFunc
+	rooms      map[string]*watchedRoom
+}
+
+// NewWatchHandler builds the /ws/watch handler, which relays room averages
+// from every kakigori-ws replica in pool to spectators that send nothing.
+func NewWatchHandler(pool *kakigori.Pool, opts wsroom.Options) *watchHandler {
+	opts.Endpoint = "/ws/watch"
+	opts.Subprotocols = nil
+	opts.NewState = nil
+	h := &watchHandler{pool: pool, streams: make(map[string]context.CancelFunc), rooms: make(map[string]*watchedRoom)}
+	opts.OnResume = h.replay
+	h.hub = wsroom.NewHub(opts)
+	pool.OnChange(func(context.Context) { h.sync() })
+	return h
+}
+
+// Shutdown closes every spectator like wsHandler.Shutdown.
+func (h *watchHandler) Shutdown(ctx context.Context) error {
+	return h.hub.Shutdown(ctx)
+}
+
+func (h *watchHandler) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
+	params := openapi.GetWsWatchParams{}
+	if room := r.URL.Query().Get("room"); room != "" {
+		params.Room = &room
+	}
+	room := allRooms
+	if params.Room != nil {
+		room = *params.Room
+	}
+
+	cl, resumed, err := h.hub.Upgrade(w, r)
+	if err != nil {
+		slog.WarnContext(r.Context(), "ws watch upgrade error", logging.Room(room), logging.Err(err))
+		return
+	}
+	if resumed {
+		return
+	}
+	ctx := r.Context()
+	slog.InfoContext(ctx, "ws watch connected", logging.Room(room), logging.Client(cl.ID), slog.String("remote", r.RemoteAddr))
+	defer slog.InfoContext(ctx, "ws watch disconnected", logging.Room(room), logging.Client(cl.ID), slog.String("remote", r.RemoteAddr))
+	metrics.WSConnections.WithLabelValues("/ws/watch").Inc()
+	defer metrics.WSConnections.WithLabelValues("/ws/watch").Dec()
+
+	h.hub.Join(room, cl)
+	h.acquire()
+	defer h.release()
+	h.replay(cl)
+	// spectators have nothing to say; reading only notices the close
+	_ = cl.Run(func(int, []byte) {})
+}
+
+// replay sends a (re)connected spectator the latest frame of its rooms.
+func (h *watchHandler) replay(c *wsroom.Client) {
+	rm := c.Room()
+	if rm == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for id, wr := range h.rooms {
+		if rm.ID == allRooms || rm.ID == id {
+			c.SendLatest("room:"+id, wr.frame)
+		}
+	}
+}
+
+// acquire counts a spectator in, starting the Watch streams for the first.
+func (h *watchHandler) acquire() {
+	h.mu.Lock()
+	h.spectators++
+	start := h.spectators == 1
+	if start {
+		h.ctx, h.cancel = context.WithCancel(context.Background())
+		go h.expire(h.ctx)
+	}
+	h.mu.Unlock()
+	if start {
+		h.sync()
+	}
+}
+
+// release counts a spectator out, stopping the streams after the last.
+func (h *watchHandler) release() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.spectators--
+	if h.spectators > 0 {
+		return
+	}
+	h.cancel()
+	h.ctx, h.cancel = nil, nil
+	clear(h.streams)
+	clear(h.rooms)
+}
+
+// sync runs one Watch stream per replica on the ring. The rooms of a
+// replica that left are orphaned until their new owner reports them.
+func (h *watchHandler) sync() {
+	peers := h.pool.Peers()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.ctx == nil {
+		return
+	}
+	on := make(map[string]bool, len(peers))
+	for _, addr := range peers {
+		on[addr] = true
+		if _, ok := h.streams[addr]; !ok {
+			ctx, cancel := context.WithCancel(h.ctx)
+			h.streams[addr] = cancel
+			go h.watch(ctx, addr)
+		}
+	}
+	for addr, cancel := range h.streams {
+		if !on[addr] {
+			cancel()
+			delete(h.streams, addr)
+			h.disownLocked(addr)
+		}
+	}
+}
+
+// watch keeps a Watch stream open to addr until ctx ends, reopening it with
+// backoff.
+func (h *watchHandler) watch(ctx context.Context, addr string) {
+	backoff := reconnectMinBackoff
+	for {
+		if c := h.pool.Client(addr); c != nil {
+			stream, err := c.Watch(ctx, &kakigoriwsv1.WatchRequest{})
+			for err == nil {
+				var ev *kakigoriwsv1.WatchEvent
+				if ev, err = stream.Recv(); err == nil {
+					backoff = reconnectMinBackoff
+					h.apply(ctx, addr, ev)
+				}
+			}
+			if ctx.Err() != nil {
+				return
+			}
+			slog.WarnContext(ctx, "watch stream error", slog.String("addr", addr), logging.Err(err))
+		}
+		// the replica sends its rooms again on reopen; those that ended in
+		// between expire
+		h.mu.Lock()
+		if ctx.Err() == nil {
+			h.disownLocked(addr)
+		}
+		h.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, reconnectMaxBackoff)
+	}
+}
+
+// apply relays one event from addr's stream, unless sync or release
+// stopped it meanwhile. A replica only ends rooms it owns: the one a room
+// moved away from reports it finished once its streams leave.
+func (h *watchHandler) apply(ctx context.Context, addr string, ev *kakigoriwsv1.WatchEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if ctx.Err() != nil {
+		return
+	}
+	id := ev.GetRoom()
+	wr := h.rooms[id]
+	switch ev.GetType() {
+	case kakigoriwsv1.WatchEventType_WATCH_EVENT_TYPE_UPDATE:
+		frame, _ := json.Marshal(wsRoomFrame{Type: "average", Room: id, Average: ev.GetAverage(),
+			Count: int(ev.GetCount()), Participants: int(ev.GetParticipants())})
+		h.rooms[id] = &watchedRoom{owner: addr, frame: frame}
+		h.broadcastLocked(id, frame)
+	case kakigoriwsv1.WatchEventType_WATCH_EVENT_TYPE_ENDED:
+		if wr != nil && wr.owner == addr {
+			h.endLocked(id, ev.GetReason())
+		}
+	case kakigoriwsv1.WatchEventType_WATCH_EVENT_TYPE_MOVED:
+		if wr != nil && wr.owner == addr {
+			wr.owner, wr.since = "", time.Now()
+		}
+	}
+}
+
+func (h *watchHandler) disownLocked(addr string) {
+	now := time.Now()
+	for _, wr := range h.rooms {
+		if wr.owner == addr {
+			wr.owner, wr.since = "", now
+		}
+	}
+}
+
+func (h *watchHandler) endLocked(id, reason string) {
+	delete(h.rooms, id)
+	frame, _ := json.Marshal(wsEndedFrame{Type: "ended", Room: id, Reason: reason})
+	h.broadcastLocked(id, frame)
+}
+
+// broadcastLocked sends frame to the room's spectators and to those of
+// every room. A frame replaces the room's unsent one.
+func (h *watchHandler) broadcastLocked(id string, frame []byte) {
+	for _, hubRoom := range []string{id, allRooms} {
+		if rm := h.hub.Room(hubRoom); rm != nil {
+			rm.BroadcastLatest("room:"+id, frame)
+		}
+	}
+}
+
+// expire reports orphaned rooms lost once no replica has claimed them for
+// watchOrphan.
+func (h *watchHandler) expire(ctx context.Context) {
+	t := time.NewTicker(watchOrphan / 2)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-t.C:
+			h.mu.Lock()
+			for id, wr := range h.rooms {
+				if wr.owner == "" && now.Sub(wr.since) >= watchOrphan {
+					h.endLocked(id, "lost")
+				}
+			}
+			h.mu.Unlock()
+		}
+	}
+}
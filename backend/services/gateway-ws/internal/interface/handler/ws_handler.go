@@ -3,187 +3,379 @@ package handler
 import (
 	"context"
 	"encoding/json"
-	"log"
+	"log/slog"
 	"net/http"
-	"sync"
+	"strings"
 	"time"
+	"unicode"
 
+	gatewayapiv1 "chantingkakigori/gen/go/gateway_api/v1"
 	kakigoriwsv1 "chantingkakigori/gen/go/kakigori_ws/v1"
+	"chantingkakigori/pkg/apperror"
+	"chantingkakigori/pkg/auth"
+	"chantingkakigori/pkg/logging"
+	"chantingkakigori/pkg/metrics"
+	"chantingkakigori/pkg/tracing"
+	"chantingkakigori/pkg/wsbinary"
+	"chantingkakigori/pkg/wsroom"
 	openapi "chantingkakigori/services/gateway-ws/internal"
+	"chantingkakigori/services/gateway-ws/internal/infrastructure/kakigori"
 
 	"github.com/gorilla/websocket"
+	"go.opentelemetry.io/otel/attribute"
 )
 
+// wsMessage is one sample, or a batch stamped with the client's clock.
 type wsMessage struct {
-	Value float64 `json:"value"`
+	Value   float64    `json:"value"`
+	Samples []wsSample `json:"samples,omitempty"`
+}
+
+type wsSample struct {
+	// ClientTS is when the sample was taken, Unix milliseconds on the
+	// client's clock.
+	ClientTS int64   `json:"client_ts"`
+	Value    float64 `json:"value"`
 }
 
 type wsOut struct {
-	Average float64 `json:"average"`
-	Count   int     `json:"count"`
+	Average      float64         `json:"average"`
+	Count        int             `json:"count"`
+	Participants []wsParticipant `json:"participants,omitempty"`
 }
 
-type client struct {
-	conn    *websocket.Conn
-	writeMu sync.Mutex
+type wsParticipant struct {
+	ID       string  `json:"id"`
+	Name     string  `json:"name,omitempty"`
+	Mean     float64 `json:"mean"`
+	Count    int     `json:"count"`
+	LastSeen string  `json:"last_seen,omitempty"`
 }
 
-type room struct {
-	id      string
-	clients map[*client]struct{}
-	mu      sync.Mutex
+// frame encodes o for a client that negotiated protocol.
+func (o *wsOut) frame(protocol string) (int, []byte) {
+	if protocol != wsbinary.Subprotocol {
+		b, _ := json.Marshal(o)
+		return websocket.TextMessage, b
+	}
+	a := wsbinary.Average{Average: o.Average, Count: o.Count}
+	for _, p := range o.Participants {
+		a.Participants = append(a.Participants, wsbinary.Participant{ID: p.ID, Name: p.Name, Mean: p.Mean, Count: p.Count})
+	}
+	return websocket.BinaryMessage, wsbinary.AppendAverage(nil, a)
 }
 
-type wsHandler struct {
-	rooms  map[string]*room
-	mu     sync.Mutex
-	client kakigoriwsv1.KakigoriWsAggregatorServiceClient
+// wsParticipantFrame tells a client which breakdown entry is its own.
+type wsParticipantFrame struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
 }
 
-var upgrader = websocket.Upgrader{CheckOrigin: func(r *http.Request) bool { return true }}
+// RoomClosedReason is the close reason sent when an operator closes the room.
+const RoomClosedReason = "room closed"
+
+// maxDisplayName caps ?name= in runes, maxDevice ?device=.
+const (
+	maxDisplayName = 24
+	maxDevice      = 64
+)
+
+// chantState is the per-room state, guarded by Room.Locked.
+type chantState struct {
+	last      *wsOut // latest average, replayed on resume
+	upstreams map[*upstream]struct{}
+}
 
-func NewWSHandler(c kakigoriwsv1.KakigoriWsAggregatorServiceClient) *wsHandler {
-	return &wsHandler{rooms: make(map[string]*room), client: c}
+type wsHandler struct {
+	hub      *wsroom.Hub
+	pool     *kakigori.Pool
+	rounds   gatewayapiv1.RoundServiceClient
+	rawAudio bool
 }
 
-func (h *wsHandler) getOrCreateRoom(id string) *room {
-	h.mu.Lock()
-	defer h.mu.Unlock()
-	if rm, ok := h.rooms[id]; ok {
-		return rm
+// roundTimeout bounds the RoundService call made before a chant starts.
+const roundTimeout = 3 * time.Second
+
+// Per-message logs are sampled; logging every chant sample drowns the output.
+var (
+	sentLogSampler      = logging.NewSampler(50)
+	broadcastLogSampler = logging.NewSampler(50)
+)
+
+// NewWSHandler builds the /ws handler; opts carries the queue settings. Rooms
+// follow their owner in pool when the kakigori-ws replicas change. Clients
+// may negotiate wsbinary.Subprotocol instead of JSON for samples and
+// averages, and with rawAudio send audio frames for kakigori-ws to measure.
+// A client passing its party's round moves it to chanting on rounds and
+// chants with its party alone, in a room keyed by the round; only a round of
+// another room or one the session did not join, or none at all, is turned
+// away.
+func NewWSHandler(pool *kakigori.Pool, opts wsroom.Options, rounds gatewayapiv1.RoundServiceClient, rawAudio bool) *wsHandler {
+	opts.Endpoint = "/ws"
+	opts.Subprotocols = []string{wsbinary.Subprotocol}
+	opts.OnRoomCreate = func(*wsroom.Room) { metrics.Rooms.WithLabelValues("chant").Inc() }
+	opts.OnRoomClose = func(*wsroom.Room) { metrics.Rooms.WithLabelValues("chant").Dec() }
+	opts.NewState = func(string) any { return &chantState{upstreams: make(map[*upstream]struct{})} }
+	opts.OnResume = func(c *wsroom.Client) {
+		rm := c.Room()
+		if rm == nil {
+			return
+		}
+		var last *wsOut
+		rm.Locked(func(int) { last = rm.State().(*chantState).last })
+		if last != nil {
+			msgType, data := last.frame(c.Protocol)
+			c.SendLatestMessage("average", msgType, data)
+		}
 	}
-	rm := &room{id: id, clients: make(map[*client]struct{})}
-	h.rooms[id] = rm
-	return rm
+	h := &wsHandler{hub: wsroom.NewHub(opts), pool: pool, rounds: rounds, rawAudio: rawAudio}
+	pool.OnChange(h.rebalance)
+	return h
+}
+
+// Shutdown sends a "server restarting" close frame to every connected client
+// and waits for their sessions (and aggregate streams) to finish.
+func (h *wsHandler) Shutdown(ctx context.Context) error {
+	return h.hub.Shutdown(ctx)
 }
 
 func (h *wsHandler) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
 	// Bind query into generated params type for consistency with OpenAPI
 	params := openapi.GetWsParams{Room: r.URL.Query().Get("room")}
 	if params.Room == "" {
-		http.Error(w, "room is required", http.StatusBadRequest)
+		apperror.WriteProblem(w, r, apperror.New(apperror.CodeInvalidArgument, "room is required"))
 		return
 	}
+	// The session subject is who joined the round in the lobby. Without
+	// one (auth disabled) there is no membership to check, so the round is
+	// ignored.
+	var subject string
+	if c := auth.FromContext(r.Context()); c != nil {
+		subject = c.Subject
+	}
+	// The round is still counting down (or already chanting, for the rest
+	// of the party and resumes). A round that is not this room's or this
+	// session's is turned away; any other failure only leaves the round
+	// where it is, as a teammate may have moved it on or gateway-api may be
+	// unreachable.
+	var roundID string
+	if s := r.URL.Query().Get("round"); s != "" && subject != "" {
+		params.Round = &s
+		roundID = s
+		ctx, cancel := context.WithTimeout(r.Context(), roundTimeout)
+		_, err := h.rounds.AdvanceRound(ctx, &gatewayapiv1.AdvanceRoundRequest{Id: roundID, Room: params.Room, Member: subject, State: gatewayapiv1.RoundState_ROUND_STATE_CHANTING})
+		cancel()
+		if err != nil {
+			ae := apperror.FromGRPC(err)
+			switch ae.Code {
+			case apperror.CodeInvalidArgument, apperror.CodeNotFound, apperror.CodePermissionDenied:
+				slog.WarnContext(r.Context(), "chant round rejected", logging.Room(params.Room), logging.RoundID(roundID), logging.Err(err))
+				apperror.WriteProblem(w, r, ae)
+				return
+			}
+			// the party still chants, without moving its round
+			slog.WarnContext(r.Context(), "round chanting failed", logging.Room(params.Room), logging.RoundID(roundID), logging.Err(err))
+		}
+	}
 
-	conn, err := upgrader.Upgrade(w, r, nil)
+	// A party passing its round chants in a room of its own, as it confirms
+	// in one; kakigori-ws still learns the menu item for the leaderboard.
+	room := params.Room
+	if roundID != "" {
+		room = roundID
+	}
+
+	cl, resumed, err := h.hub.Upgrade(w, r)
 	if err != nil {
-		log.Printf("ws upgrade error: %v", err)
+		slog.WarnContext(r.Context(), "ws upgrade error", logging.Room(room), logging.Err(err))
+		return
+	}
+	if resumed {
+		// the original session keeps running on this connection
+		slog.InfoContext(r.Context(), "ws resumed", logging.Room(room), logging.Client(cl.ID), slog.String("remote", r.RemoteAddr))
 		return
 	}
-	log.Printf("ws connected: room=%s remote=%s", params.Room, r.RemoteAddr)
+	sessCtx, session := tracing.StartSession(r, "/ws", attribute.String("room", params.Room), attribute.String("round_id", roundID))
+	defer session.End(nil)
+	slog.InfoContext(sessCtx, "ws connected", logging.Room(room), logging.Client(cl.ID), slog.String("remote", r.RemoteAddr),
+		slog.String("subprotocol", cl.Protocol))
+	defer slog.InfoContext(sessCtx, "ws disconnected", logging.Room(room), logging.Client(cl.ID), slog.String("remote", r.RemoteAddr))
+	metrics.WSConnections.WithLabelValues("/ws").Inc()
+	defer metrics.WSConnections.WithLabelValues("/ws").Dec()
 
-	rm := h.getOrCreateRoom(params.Room)
-	cl := &client{conn: conn}
-	// Heartbeat setup (ping/pong)
-	const pongWait = 60 * time.Second
-	const pingPeriod = 30 * time.Second
-	_ = conn.SetReadDeadline(time.Now().Add(pongWait))
-	conn.SetPongHandler(func(string) error {
-		return conn.SetReadDeadline(time.Now().Add(pongWait))
-	})
-	stopCh := make(chan struct{})
-	go func() {
-		ticker := time.NewTicker(pingPeriod)
-		defer ticker.Stop()
-		for {
-			select {
-			case <-ticker.C:
-				cl.writeMu.Lock()
-				_ = cl.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(5*time.Second))
-				cl.writeMu.Unlock()
-			case <-stopCh:
-				return
-			}
-		}
-	}()
-
-	rm.mu.Lock()
-	rm.clients[cl] = struct{}{}
-	rm.mu.Unlock()
-
-	defer func() {
-		rm.mu.Lock()
-		delete(rm.clients, cl)
-		empty := len(rm.clients) == 0
-		rm.mu.Unlock()
-		_ = conn.Close()
-		log.Printf("ws disconnected: room=%s remote=%s", params.Room, r.RemoteAddr)
-		if empty {
-			// remove empty room so next session starts cleanly
-			h.mu.Lock()
-			delete(h.rooms, params.Room)
-			h.mu.Unlock()
-		}
-	}()
+	rm, _ := h.hub.Join(room, cl)
+	st := rm.State().(*chantState)
 
-	// Bridge to kakigori Aggregate stream
-	ctx, cancel := context.WithCancel(r.Context())
-	defer cancel()
-	stream, err := h.client.Aggregate(ctx)
+	// The session subject outlives reconnects, so the breakdown keeps one
+	// entry per participant; without auth the connection ID has to do.
+	participantID := cl.ID
+	if subject != "" {
+		participantID = subject
+	}
+	name := clean(r.URL.Query().Get("name"), maxDisplayName)
+	hello, _ := json.Marshal(wsParticipantFrame{Type: "participant", ID: participantID})
+	cl.Send(hello)
+
+	// Bridge to kakigori Aggregate stream on the room's replica
+	up := &upstream{
+		h:             h,
+		room:          room,
+		round:         roundID,
+		menu:          params.Room,
+		participantID: participantID,
+		name:          name,
+		device:        clean(r.URL.Query().Get("device"), maxDevice),
+		cl:            cl,
+		rm:            rm,
+		st:            st,
+		session:       session,
+		ctx:           sessCtx,
+	}
+	up.mu.Lock()
+	err = up.openLocked()
+	up.mu.Unlock()
 	if err != nil {
-		log.Printf("aggregate stream error: %v", err)
+		slog.ErrorContext(sessCtx, "aggregate stream error", logging.Room(room), logging.Err(err))
+		cl.Send(apperror.WSFrame(apperror.Wrap(apperror.CodeUnavailable, err, "aggregator unavailable")))
+		cl.Close(websocket.CloseInternalServerErr, "aggregator unavailable")
+		_ = cl.Run(nil)
 		return
 	}
-	log.Printf("grpc aggregate stream opened: room=%s", params.Room)
+	rm.Locked(func(int) { st.upstreams[up] = struct{}{} })
+	defer rm.Locked(func(int) { delete(st.upstreams, up) })
 
-	// Receive loop from gRPC -> WS
-	done := make(chan struct{})
-	go func() {
-		defer close(done)
-		for {
-			resp, err := stream.Recv()
+	// Send loop WS -> gRPC, one AggregateRequest per frame. Binary frames
+	// carry batches or audio; samples a resumed client sent again are
+	// dropped by seq.
+	var seq wsbinary.Sequencer
+	var audioRefused bool
+	err = cl.Run(func(msgType int, data []byte) {
+		session.Received(len(data))
+		var req *kakigoriwsv1.AggregateRequest
+		if msgType == websocket.BinaryMessage && len(data) > 0 && data[0] == wsbinary.KindAudio {
+			if !h.rawAudio {
+				if !audioRefused {
+					audioRefused = true
+					slog.WarnContext(sessCtx, "ws audio frame refused", logging.Room(room))
+					cl.Send(apperror.WSFrame(apperror.New(apperror.CodeInvalidArgument, "raw audio is disabled, send loudness values")))
+				}
+				return
+			}
+			a, err := wsbinary.ParseAudio(data)
 			if err != nil {
-				log.Printf("grpc recv closed: room=%s err=%v", params.Room, err)
+				slog.WarnContext(sessCtx, "ws audio frame error", logging.Room(room), logging.Err(err), slog.Int("size", len(data)))
 				return
 			}
-			if resp.GetRoom() != params.Room {
-				continue
+			if fresh, _ := seq.FreshAudio(a); !fresh {
+				return
 			}
-			out := wsOut{Average: resp.GetAverage(), Count: int(resp.GetCount())}
-			payload, _ := json.Marshal(out)
-			// broadcast to all ws clients in the room
-			rm.mu.Lock()
-			clients := make([]*client, 0, len(rm.clients))
-			for c := range rm.clients {
-				clients = append(clients, c)
+			req = audioRequest(a)
+		} else if msgType == websocket.BinaryMessage {
+			batch, err := wsbinary.ParseSamples(data)
+			if err != nil {
+				slog.WarnContext(sessCtx, "ws binary frame error", logging.Room(room), logging.Err(err), slog.Int("size", len(data)))
+				return
 			}
-			rm.mu.Unlock()
-			for _, c := range clients {
-				c.writeMu.Lock()
-				err := c.conn.WriteMessage(websocket.TextMessage, payload)
-				c.writeMu.Unlock()
-				if err != nil {
-					log.Printf("ws write error: room=%s err=%v", params.Room, err)
-				}
+			fresh, lost := seq.Fresh(batch)
+			if lost > 0 {
+				slog.DebugContext(sessCtx, "ws samples missing", logging.Room(room), slog.Uint64("lost", uint64(lost)))
+			}
+			req = binaryRequest(fresh)
+		} else {
+			var msg wsMessage
+			if err := json.Unmarshal(data, &msg); err != nil {
+				slog.WarnContext(sessCtx, "ws json unmarshal error", logging.Room(room), logging.Err(err), slog.Int("size", len(data)))
+				return
+			}
+			if len(msg.Samples) > wsbinary.MaxBatch {
+				slog.WarnContext(sessCtx, "ws batch too large", logging.Room(room), slog.Int("samples", len(msg.Samples)))
+				return
 			}
-			log.Printf("ws wrote(broadcast): room=%s avg=%.3f count=%d recipients=%d", params.Room, out.Average, out.Count, len(clients))
+			req = jsonRequest(msg)
 		}
-	}()
-
-	// Send loop WS -> gRPC
-	for {
-		_, data, err := conn.ReadMessage()
-		if err != nil {
-			log.Printf("ws read closed: room=%s err=%v", params.Room, err)
-			break
+		if req == nil {
+			// Do not send zero; nothing to return
+			return
 		}
-		var msg wsMessage
-		if err := json.Unmarshal(data, &msg); err != nil {
-			log.Printf("ws json unmarshal error: room=%s err=%v data=%s", params.Room, err, string(data))
-			continue
+		if err := up.send(req); err != nil {
+			slog.ErrorContext(sessCtx, "grpc send error", logging.Room(room), logging.Err(err))
+			cl.Close(websocket.CloseInternalServerErr, "aggregator unavailable")
+			return
 		}
+		if sentLogSampler.Allow() {
+			slog.DebugContext(sessCtx, "grpc sent", logging.Room(room), slog.Float64("value", req.GetValue()), slog.Int("samples", len(req.GetSamples())))
+		}
+	})
+	slog.InfoContext(sessCtx, "ws read closed", logging.Room(room), logging.Err(err))
+	// Close stream and wait receiver to end
+	up.close()
+}
+
+// jsonRequest turns a text frame into a request, nil when it has nothing
+// but zeros.
+func jsonRequest(msg wsMessage) *kakigoriwsv1.AggregateRequest {
+	if len(msg.Samples) == 0 {
 		if msg.Value == 0 {
-			// Do not send zero; nothing to return
+			return nil
+		}
+		return &kakigoriwsv1.AggregateRequest{Value: msg.Value}
+	}
+	req := &kakigoriwsv1.AggregateRequest{}
+	for _, s := range msg.Samples {
+		if s.Value != 0 {
+			req.Samples = append(req.Samples, &kakigoriwsv1.ClientSample{Value: s.Value, ClientTsUnixMs: s.ClientTS})
+		}
+	}
+	if len(req.Samples) == 0 {
+		return nil
+	}
+	return req
+}
+
+// binaryRequest is jsonRequest for a binary batch; an untimed one is
+// placed on arrival.
+func binaryRequest(b wsbinary.Samples) *kakigoriwsv1.AggregateRequest {
+	req := &kakigoriwsv1.AggregateRequest{}
+	for i, v := range b.Values {
+		if v == 0 {
 			continue
 		}
-		if err := stream.Send(&kakigoriwsv1.AggregateRequest{Room: params.Room, Value: msg.Value}); err != nil {
-			log.Printf("grpc send error: room=%s err=%v", params.Room, err)
-			break
+		s := &kakigoriwsv1.ClientSample{Value: float64(v)}
+		if b.ClientTimes != nil {
+			s.ClientTsUnixMs = b.ClientTimes[i]
 		}
-		log.Printf("grpc sent: room=%s value=%.3f", params.Room, msg.Value)
+		req.Samples = append(req.Samples, s)
 	}
-	// Close stream and wait receiver to end
-	_ = stream.CloseSend()
-	<-done
-	close(stopCh)
+	if len(req.Samples) == 0 {
+		return nil
+	}
+	return req
+}
+
+// audioRequest forwards an audio frame as it is.
+func audioRequest(a wsbinary.Audio) *kakigoriwsv1.AggregateRequest {
+	codec := kakigoriwsv1.AudioCodec_AUDIO_CODEC_PCM_S16LE
+	if a.Codec == wsbinary.AudioOpus {
+		codec = kakigoriwsv1.AudioCodec_AUDIO_CODEC_OPUS
+	}
+	return &kakigoriwsv1.AggregateRequest{Audio: &kakigoriwsv1.AudioFrame{
+		Codec:          codec,
+		SampleRate:     int32(a.SampleRate),
+		Data:           a.Data,
+		ClientTsUnixMs: a.ClientTime,
+	}}
+}
+
+// clean trims s, drops control characters and caps it at max runes.
+func clean(s string, max int) string {
+	s = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(s))
+	if r := []rune(s); len(r) > max {
+		s = string(r[:max])
+	}
+	return s
 }
@@ -3,7 +3,92 @@
 // Code generated by github.com/oapi-codegen/oapi-codegen/v2 version v2.5.0 DO NOT EDIT.
 package openapi
 
+import (
+	"time"
+)
+
+const (
+	AdminBasicScopes = "adminBasic.Scopes"
+	AdminTokenScopes = "adminToken.Scopes"
+)
+
+// AdminParticipant defines model for AdminParticipant.
+type AdminParticipant struct {
+	Count    int        `json:"count"`
+	Id       string     `json:"id"`
+	LastSeen *time.Time `json:"last_seen,omitempty"`
+	Mean     float64    `json:"mean"`
+	Name     *string    `json:"name,omitempty"`
+}
+
+// AdminRoom defines model for AdminRoom.
+type AdminRoom struct {
+	AgeSeconds float64 `json:"age_seconds"`
+	Average    float64 `json:"average"`
+
+	// Count 直近 5 秒間のサンプル数
+	Count          int       `json:"count"`
+	CreatedAt      time.Time `json:"created_at"`
+	FlaggedClients []string  `json:"flagged_clients"`
+
+	// Instance room を持っている kakigori-ws レプリカ（host:port）
+	Instance string `json:"instance"`
+
+	// LastSample 最後に受け付けたサンプルの時刻（まだ無ければ省略）
+	LastSample   *time.Time         `json:"last_sample,omitempty"`
+	Participants []AdminParticipant `json:"participants"`
+	Room         string             `json:"room"`
+
+	// Streams その kakigori-ws に開いている集計ストリーム数（全 gateway-ws 分）
+	Streams int `json:"streams"`
+}
+
+// AdminRoomList defines model for AdminRoomList.
+type AdminRoomList struct {
+	Rooms []AdminRoom `json:"rooms"`
+}
+
+// CloseRoomResult defines model for CloseRoomResult.
+type CloseRoomResult struct {
+	ClosedStreams int    `json:"closed_streams"`
+	Room          string `json:"room"`
+}
+
+// Problem RFC 7807 problem details（gateway-api と共通）
+type Problem struct {
+	Code     string  `json:"code"`
+	Detail   *string `json:"detail,omitempty"`
+	Instance *string `json:"instance,omitempty"`
+	Status   int     `json:"status"`
+	Title    string  `json:"title"`
+	Type     string  `json:"type"`
+}
+
+// NotFound RFC 7807 problem details（gateway-api と共通）
+type NotFound = Problem
+
+// Unauthenticated RFC 7807 problem details（gateway-api と共通）
+type Unauthenticated = Problem
+
+// Unavailable RFC 7807 problem details（gateway-api と共通）
+type Unavailable = Problem
+
+// PostAdminRoomsRoomCloseParams defines parameters for PostAdminRoomsRoomClose.
+type PostAdminRoomsRoomCloseParams struct {
+	// Reason ログに残す理由
+	Reason *string `form:"reason,omitempty" json:"reason,omitempty"`
+}
+
 // GetWsParams defines parameters for GetWs.
 type GetWsParams struct {
 	Room string `form:"room" json:"room"`
+
+	// Resume 直前のセッションの `token`。猶予時間内なら同じセッションとして再開する（不明・期限切れなら新規セッション）
+	Resume *string `form:"resume,omitempty" json:"resume,omitempty"`
+
+	// Token POST /api/v1/sessions で発行されたセッショントークン。`room` と同じ room に紐づいている必要がある
+	Token string `form:"token" json:"token"`
+
+	// Name 他の参加者に表示する名前（前後の空白と制御文字を除き 24 文字まで）
+	Name *string `form:"name,omitempty" json:"name,omitempty"`
 }
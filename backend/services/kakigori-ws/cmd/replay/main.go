@@ -0,0 +1,162 @@
+// Command replay feeds a kakigori-ws recording (RECORD_PATH) back through the
+// aggregator and prints the average each sample produced, next to the one
+// sent at the time:
+//
+//	go run ./services/kakigori-ws/cmd/replay -room giiku-sai -outlier-k 5 kakigori.jsonl
+//
+// The filter defaults to the ANTICHEAT_* environment, like the service;
+// flags override it to try a different one. Raw audio frames are measured
+// again, with LOUDNESS_CALIBRATION_PATH or -calibration.
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"chantingkakigori/services/kakigori-ws/internal/infrastructure/loudness"
+	"chantingkakigori/services/kakigori-ws/internal/infrastructure/recording"
+	"chantingkakigori/services/kakigori-ws/internal/usecase"
+)
+
+func main() {
+	f := usecase.FilterOptionsFromEnv()
+	room := flag.String("room", "", "replay only this room")
+	asCSV := flag.Bool("csv", false, "print CSV instead of a table")
+	noFilter := flag.Bool("no-filter", false, "accept every sample")
+	calPath := flag.String("calibration", os.Getenv("LOUDNESS_CALIBRATION_PATH"), "loudness calibration file for raw audio frames")
+	flag.Float64Var(&f.MaxValue, "max-value", f.MaxValue, "largest legitimate sample")
+	flag.Float64Var(&f.OutlierK, "outlier-k", f.OutlierK, "outlier distance from the room median, in robust standard deviations")
+	flag.IntVar(&f.MinSamples, "min-samples", f.MinSamples, "samples from other clients needed before outliers are judged")
+	flag.IntVar(&f.MaxRate, "max-rate", f.MaxRate, "most samples per second a client may send")
+	flag.IntVar(&f.FlagAfter, "flag-after", f.FlagAfter, "filtered samples that flag a client")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: replay [flags] recording.jsonl\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *noFilter {
+		f = usecase.FilterOptions{MaxValue: math.Inf(1), MinSamples: math.MaxInt, MaxRate: math.MaxInt, FlagAfter: math.MaxInt}
+	}
+	cal := loudness.DefaultCalibration()
+	if *calPath != "" {
+		var err error
+		if cal, err = loudness.LoadCalibration(*calPath); err != nil {
+			fmt.Fprintln(os.Stderr, "replay:", err)
+			os.Exit(1)
+		}
+	}
+	if err := run(flag.Arg(0), f, cal, *room, *asCSV, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "replay:", err)
+		os.Exit(1)
+	}
+}
+
+func run(path string, f usecase.FilterOptions, cal loudness.Calibration, room string, asCSV bool, w io.Writer) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out := newTimeline(w, asCSV)
+	r := recording.NewReplayer(f, cal, room)
+	if err := recording.Read(in, func(e recording.Entry) error {
+		out.write(r.Feed(e))
+		return nil
+	}); err != nil {
+		return err
+	}
+	out.write(r.Flush())
+	return out.close()
+}
+
+// timeline prints one row per replayed sample and a summary of how far the
+// replay strayed from the recording.
+type timeline struct {
+	w       io.Writer
+	tw      *tabwriter.Writer
+	cw      *csv.Writer
+	steps   int
+	changed int
+	maxDiff float64
+}
+
+// epsilon hides float noise: the aggregator sums in map order, so an
+// identical replay can differ in the last bits.
+const epsilon = 1e-9
+
+var header = []string{"time", "room", "client", "value", "verdict", "average", "count", "recorded", "diff"}
+
+func newTimeline(w io.Writer, asCSV bool) *timeline {
+	t := &timeline{w: w}
+	if asCSV {
+		t.cw = csv.NewWriter(w)
+		_ = t.cw.Write(header)
+		return t
+	}
+	t.tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for i, h := range header {
+		if i > 0 {
+			fmt.Fprint(t.tw, "\t")
+		}
+		fmt.Fprint(t.tw, h)
+	}
+	fmt.Fprintln(t.tw)
+	return t
+}
+
+func (t *timeline) write(steps []recording.Step) {
+	for _, st := range steps {
+		t.steps++
+		recorded, diff := "-", "-"
+		if st.Recorded != nil {
+			d := st.Average - st.Recorded.GetAverage()
+			recorded, diff = num(st.Recorded.GetAverage()), strconv.FormatFloat(d, 'f', 4, 64)
+			if math.Abs(d) > epsilon {
+				t.changed++
+			}
+			t.maxDiff = math.Max(t.maxDiff, math.Abs(d))
+		}
+		row := []string{
+			st.At.Format(time.RFC3339Nano), st.Room, st.Client, num(st.Value),
+			st.Verdict.String(), num(st.Average), strconv.Itoa(st.Count), recorded, diff,
+		}
+		if t.cw != nil {
+			_ = t.cw.Write(row)
+			continue
+		}
+		row[0] = st.At.Format("15:04:05.000")
+		for i, c := range row {
+			if i > 0 {
+				fmt.Fprint(t.tw, "\t")
+			}
+			fmt.Fprint(t.tw, c)
+		}
+		fmt.Fprintln(t.tw)
+	}
+}
+
+func (t *timeline) close() error {
+	if t.cw != nil {
+		t.cw.Flush()
+		return t.cw.Error()
+	}
+	if err := t.tw.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(t.w, "\n%d samples, %d averages changed, max |diff| %.4f\n", t.steps, t.changed, t.maxDiff)
+	return err
+}
+
+func num(v float64) string { return strconv.FormatFloat(v, 'f', 4, 64) }
@@ -1,12 +1,25 @@
 package main
 
 import (
-	"log"
+	"context"
+	"log/slog"
 	"net"
+	"net/http"
 	"os"
+	"time"
 
 	kakigoriwsv1 "chantingkakigori/gen/go/kakigori_ws/v1"
 	"chantingkakigori/pkg/grpcjson"
+	"chantingkakigori/pkg/logging"
+	"chantingkakigori/pkg/metrics"
+	"chantingkakigori/pkg/ratelimit"
+	"chantingkakigori/pkg/requestid"
+	"chantingkakigori/pkg/shutdown"
+	"chantingkakigori/pkg/tracing"
+	"chantingkakigori/services/kakigori-ws/internal/infrastructure/loudness"
+	"chantingkakigori/services/kakigori-ws/internal/infrastructure/recording"
+	"chantingkakigori/services/kakigori-ws/internal/infrastructure/roundstore"
+	"chantingkakigori/services/kakigori-ws/internal/infrastructure/snapshotstore"
 	"chantingkakigori/services/kakigori-ws/internal/interface/grpcserver"
 	"chantingkakigori/services/kakigori-ws/internal/usecase"
 
@@ -14,21 +27,133 @@ import (
 )
 
 func main() {
+	logging.Init("kakigori-ws")
+	ctx, stop := shutdown.NotifyContext()
+	defer stop()
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "50051"
 	}
 	lis, err := net.Listen("tcp", ":"+port)
 	if err != nil {
-		log.Fatalf("failed to listen: %v", err)
+		logging.Fatal("failed to listen", logging.Err(err))
 	}
+	// each call is answered in the codec its client picked: protobuf by
+	// default, JSON for gateway-ws releases that force it
 	grpcjson.Register()
 
-	s := grpc.NewServer()
-	aggregator := usecase.NewAggregator()
-	kakigoriwsv1.RegisterKakigoriWsAggregatorServiceServer(s, grpcserver.NewTranscriberServer(aggregator))
-	log.Printf("kakigori-ws gRPC listening on :%s", port)
-	if err := s.Serve(lis); err != nil {
-		log.Fatalf("failed to serve: %v", err)
+	// Prometheus metrics and the readiness probe on a separate HTTP port
+	metrics.Register("kakigori-ws")
+	ready := &shutdown.Readiness{}
+	metricsPort := os.Getenv("METRICS_PORT")
+	if metricsPort == "" {
+		metricsPort = "9091"
 	}
+	go func() {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metrics.Handler())
+		mux.Handle("/readyz", ready)
+		slog.Info("metrics listening", slog.String("addr", ":"+metricsPort))
+		if err := http.ListenAndServe(":"+metricsPort, mux); err != nil && err != http.ErrServerClosed {
+			slog.Error("metrics server error", logging.Err(err))
+		}
+	}()
+
+	shutdownTracing, err := tracing.Init(context.Background(), "kakigori-ws")
+	if err != nil {
+		logging.Fatal("failed to init tracing", logging.Err(err))
+	}
+	defer func() { _ = shutdownTracing(context.Background()) }()
+
+	s := grpc.NewServer(
+		tracing.ServerOption(),
+		grpc.ChainUnaryInterceptor(requestid.UnaryServerInterceptor()),
+		grpc.ChainStreamInterceptor(
+			requestid.StreamServerInterceptor(),
+			// samples per Aggregate stream (one stream per chanting client)
+			ratelimit.StreamServerInterceptor(ratelimit.FromEnv("GRPC_MESSAGE_RATE", ratelimit.MustParse("25/s:50"))),
+		),
+	)
+	aggregator := usecase.NewAggregatorWithFilter(usecase.FilterOptionsFromEnv())
+	// Rooms saved before a restart pick up where they left off; gateway-ws
+	// reopens its streams against them.
+	snapshots, snapshotInterval, err := snapshotstore.FromEnv()
+	if err != nil {
+		logging.Fatal("failed to init room snapshots", logging.Err(err))
+	}
+	if snapshots != nil {
+		if n, err := usecase.Restore(ctx, aggregator, snapshots); err != nil {
+			slog.Warn("room snapshot restore failed", logging.Err(err))
+		} else {
+			slog.Info("rooms restored from snapshot", slog.Int("rooms", n))
+		}
+		go usecase.PersistSnapshots(ctx, aggregator, snapshots, snapshotInterval)
+	}
+	// finished rounds go to the leaderboard: Redis at REDIS_URL, shared by
+	// every replica, or ROUNDS_PATH for a single one
+	rounds, closeRounds, err := roundstore.FromEnv(ctx)
+	if err != nil {
+		logging.Fatal("failed to init round repository", logging.Err(err))
+	}
+	aggregator.TrackRounds(usecase.RoundOptionsFromEnv())
+	roundsCtx, stopRounds := context.WithCancel(context.Background())
+	roundsDone := make(chan struct{})
+	go func() {
+		defer close(roundsDone)
+		usecase.PersistRounds(roundsCtx, aggregator, rounds, roundstore.DefaultInterval)
+	}()
+	// drops rooms handed over here whose streams never followed
+	go func() {
+		t := time.NewTicker(5 * time.Second)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+				aggregator.Sweep()
+			}
+		}
+	}()
+	// every sample and response to RECORD_PATH, for cmd/replay
+	rec, err := recording.FromEnv()
+	if err != nil {
+		logging.Fatal("failed to open recording", logging.Err(err))
+	}
+	// raw audio frames are measured against LOUDNESS_CALIBRATION_PATH
+	cal, err := loudness.CalibrationFromEnv()
+	if err != nil {
+		logging.Fatal("failed to load loudness calibration", logging.Err(err))
+	}
+	kakigoriwsv1.RegisterKakigoriWsAggregatorServiceServer(s, grpcserver.NewTranscriberServer(aggregator, cal, rec, rounds))
+	go func() {
+		slog.Info("gRPC listening", slog.String("addr", ":"+port))
+		if err := s.Serve(lis); err != nil {
+			logging.Fatal("failed to serve", logging.Err(err))
+		}
+	}()
+
+	<-ctx.Done()
+	slog.Info("shutting down")
+	ready.Drain(context.Background())
+	sctx, cancel := context.WithTimeout(context.Background(), shutdown.Timeout())
+	defer cancel()
+	// Aggregate streams end when gateway-ws drains its clients; whatever is
+	// still open at the deadline is cut off and gateway-ws reports it.
+	shutdown.StopGRPC(sctx, s)
+	if snapshots != nil {
+		if err := snapshots.Save(sctx, aggregator.Snapshots()); err != nil {
+			slog.Warn("final room snapshot failed", logging.Err(err))
+		}
+	}
+	// saves the rounds that ended as the streams closed
+	stopRounds()
+	<-roundsDone
+	if err := closeRounds(); err != nil {
+		slog.Warn("round repository close failed", logging.Err(err))
+	}
+	if err := rec.Close(); err != nil {
+		slog.Warn("recording close failed", logging.Err(err))
+	}
+	slog.Info("shutdown complete")
 }
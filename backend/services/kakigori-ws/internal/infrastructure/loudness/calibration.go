@@ -0,0 +1,76 @@
+package loudness
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+)
+
+// Calibration maps measured loudness onto samples. Phones differ in
+// microphone sensitivity and gain control, so each device class gets a
+// gain in dB, measured against a reference phone at the booth, added
+// before the range FloorLUFS..CeilingLUFS is mapped onto 0..1.
+type Calibration struct {
+	FloorLUFS   float64 `json:"floor_lufs"`
+	CeilingLUFS float64 `json:"ceiling_lufs"`
+	// Devices holds the gain by device class prefix; the longest prefix of
+	// a stream's device wins, and "" applies to every device.
+	Devices map[string]float64 `json:"devices,omitempty"`
+}
+
+// DefaultCalibration spans a quiet room to shouting at arm's length, with
+// no per-device gains.
+func DefaultCalibration() Calibration {
+	return Calibration{FloorLUFS: -60, CeilingLUFS: -10}
+}
+
+// CalibrationFromEnv reads the JSON file at LOUDNESS_CALIBRATION_PATH, with
+// DefaultCalibration filling in what it leaves out; DefaultCalibration when
+// unset.
+func CalibrationFromEnv() (Calibration, error) {
+	path := os.Getenv("LOUDNESS_CALIBRATION_PATH")
+	if path == "" {
+		return DefaultCalibration(), nil
+	}
+	return LoadCalibration(path)
+}
+
+// LoadCalibration reads a calibration file.
+func LoadCalibration(path string) (Calibration, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return Calibration{}, err
+	}
+	c := DefaultCalibration()
+	if err := json.Unmarshal(b, &c); err != nil {
+		return Calibration{}, fmt.Errorf("loudness calibration %s: %w", path, err)
+	}
+	if c.CeilingLUFS <= c.FloorLUFS {
+		return Calibration{}, fmt.Errorf("loudness calibration %s: ceiling %v not above floor %v", path, c.CeilingLUFS, c.FloorLUFS)
+	}
+	return c, nil
+}
+
+// Gain returns the gain for device in dB.
+func (c Calibration) Gain(device string) float64 {
+	best, gain := -1, 0.0
+	for prefix, g := range c.Devices {
+		if len(prefix) > best && strings.HasPrefix(device, prefix) {
+			best, gain = len(prefix), g
+		}
+	}
+	return gain
+}
+
+// Normalize turns a frame's loudness from device into a sample: 0 at or
+// below the floor (silence, which the aggregator skips), 1 at or above the
+// ceiling.
+func (c Calibration) Normalize(lufs float64, device string) float64 {
+	v := (lufs + c.Gain(device) - c.FloorLUFS) / (c.CeilingLUFS - c.FloorLUFS)
+	if math.IsNaN(v) || v <= 0 {
+		return 0
+	}
+	return math.Min(v, 1)
+}
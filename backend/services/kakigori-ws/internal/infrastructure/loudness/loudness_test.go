@@ -0,0 +1,110 @@
+package loudness
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	kakigoriwsv1 "chantingkakigori/gen/go/kakigori_ws/v1"
+)
+
+// sine returns d of a 997Hz tone at amplitude amp as 16-bit PCM.
+func sine(rate int, amp float64, d time.Duration) []byte {
+	n := int(d * time.Duration(rate) / time.Second)
+	b := make([]byte, 0, 2*n)
+	for i := range n {
+		v := amp * math.Sin(2*math.Pi*997*float64(i)/float64(rate))
+		b = binary.LittleEndian.AppendUint16(b, uint16(int16(math.Round(v*32767))))
+	}
+	return b
+}
+
+// BS.1770-4 tabulates the filters at 48kHz.
+func TestKWeighting_MatchesStandard(t *testing.T) {
+	k := kWeighting(48000)
+	for name, c := range map[string][2]float64{
+		"shelf b0": {k[0].b0, 1.53512485958697},
+		"shelf b1": {k[0].b1, -2.69169618940638},
+		"shelf b2": {k[0].b2, 1.19839281085285},
+		"shelf a1": {k[0].a1, -1.69065929318241},
+		"shelf a2": {k[0].a2, 0.73248077421585},
+		"pass a1":  {k[1].a1, -1.99004745483398},
+		"pass a2":  {k[1].a2, 0.99007225036621},
+	} {
+		if math.Abs(c[0]-c[1]) > 1e-6 {
+			t.Errorf("%s = %.14f, want %.14f", name, c[0], c[1])
+		}
+	}
+}
+
+// A full-scale 997Hz sine reads -3.01 LUFS at any rate.
+func TestMeter_FullScaleSine(t *testing.T) {
+	for _, rate := range []int{48000, 16000, 8000} {
+		m, err := NewMeter(PCM16, rate)
+		if err != nil {
+			t.Fatal(err)
+		}
+		frame := sine(rate, 1, 200*time.Millisecond)
+		m.Measure(frame) // settle the filters
+		lufs, d, err := m.Measure(frame)
+		if err != nil || d != 200*time.Millisecond || math.Abs(lufs+3.01) > 0.1 {
+			t.Errorf("%d Hz: %.2f LUFS over %v, %v", rate, lufs, d, err)
+		}
+		if quiet, _, _ := m.Measure(sine(rate, 0.1, 200*time.Millisecond)); math.Abs(quiet-lufs+20) > 0.2 {
+			t.Errorf("%d Hz: -20dB reads %.2f LUFS", rate, quiet)
+		}
+	}
+}
+
+func TestMeter_Rejects(t *testing.T) {
+	if _, err := NewMeter(PCM16, 96000); !errors.Is(err, ErrUnsupported) {
+		t.Fatalf("96kHz: %v", err)
+	}
+	m, _ := NewMeter(PCM16, 8000)
+	if _, _, err := m.Measure([]byte{1, 2, 3}); !errors.Is(err, ErrMalformed) {
+		t.Fatalf("odd length: %v", err)
+	}
+	if _, _, err := m.Measure(make([]byte, 2*8000*2)); !errors.Is(err, ErrMalformed) {
+		t.Fatalf("two seconds: %v", err)
+	}
+}
+
+// A phone whose microphone reads 6dB low gives the same sample as the
+// reference once calibrated.
+func TestCalibration(t *testing.T) {
+	c := DefaultCalibration()
+	c.Devices = map[string]float64{"": 1, "Pixel": 6, "Pixel 8": 4}
+	for device, want := range map[string]float64{"iPhone15,2": 1, "Pixel 7": 6, "Pixel 8 Pro": 4} {
+		if got := c.Gain(device); got != want {
+			t.Errorf("Gain(%q) = %v, want %v", device, got, want)
+		}
+	}
+	if ref, low := c.Normalize(-30, "iPhone"), c.Normalize(-35, "Pixel 7"); ref != low || ref != 0.62 {
+		t.Fatalf("reference %v, calibrated %v", ref, low)
+	}
+	if c.Normalize(math.Inf(-1), "") != 0 || c.Normalize(0, "") != 1 {
+		t.Fatal("not clamped to 0..1")
+	}
+}
+
+func TestStream(t *testing.T) {
+	s := NewStream(DefaultCalibration())
+	s.Device = "iPhone"
+	defer s.Close()
+	frame := &kakigoriwsv1.AudioFrame{Codec: kakigoriwsv1.AudioCodec_AUDIO_CODEC_PCM_S16LE, SampleRate: 16000,
+		Data: sine(16000, 0.05, 250*time.Millisecond), ClientTsUnixMs: 1760000000000}
+	got, err := s.Sample(frame)
+	if err != nil || got.Value < 0.55 || got.Value > 0.7 || !got.ClientTime.Equal(time.UnixMilli(1760000000250)) {
+		t.Fatalf("Sample = %+v, %v", got, err)
+	}
+	frame.Data = make([]byte, 3200)
+	if got, err := s.Sample(frame); err != nil || got.Value != 0 {
+		t.Fatalf("silence = %+v, %v", got, err)
+	}
+	frame.SampleRate = 48000
+	if _, err := s.Sample(frame); !errors.Is(err, ErrMalformed) {
+		t.Fatalf("rate switch: %v", err)
+	}
+}
@@ -0,0 +1,60 @@
+//go:build opus
+
+package loudness
+
+/*
+#cgo pkg-config: opus
+#include <opus.h>
+*/
+import "C"
+
+import (
+	"encoding/binary"
+	"fmt"
+	"unsafe"
+)
+
+// maxOpusPacket is the longest audio one Opus packet holds.
+const maxOpusPacket = 120 // milliseconds
+
+type opusDecoder struct {
+	st  *C.OpusDecoder
+	buf []float32
+}
+
+func newOpusDecoder(rate int) (decoder, error) {
+	switch rate {
+	case 8000, 12000, 16000, 24000, 48000:
+	default:
+		return nil, fmt.Errorf("%w: opus at %d Hz", ErrUnsupported, rate)
+	}
+	var e C.int
+	st := C.opus_decoder_create(C.opus_int32(rate), 1, &e)
+	if e != C.OPUS_OK {
+		return nil, fmt.Errorf("loudness: opus decoder: %s", C.GoString(C.opus_strerror(e)))
+	}
+	return &opusDecoder{st: st, buf: make([]float32, rate*maxOpusPacket/1000)}, nil
+}
+
+func (d *opusDecoder) decode(data []byte, pcm []float32) ([]float32, error) {
+	for len(data) > 0 {
+		if len(data) < 2 {
+			return pcm, fmt.Errorf("%w: truncated opus packet length", ErrMalformed)
+		}
+		n := int(binary.LittleEndian.Uint16(data))
+		data = data[2:]
+		if n == 0 || n > len(data) {
+			return pcm, fmt.Errorf("%w: opus packet of %d bytes", ErrMalformed, n)
+		}
+		got := C.opus_decode_float(d.st, (*C.uchar)(unsafe.Pointer(&data[0])), C.opus_int32(n),
+			(*C.float)(unsafe.Pointer(&d.buf[0])), C.int(len(d.buf)), 0)
+		if got < 0 {
+			return pcm, fmt.Errorf("%w: %s", ErrMalformed, C.GoString(C.opus_strerror(got)))
+		}
+		pcm = append(pcm, d.buf[:got]...)
+		data = data[n:]
+	}
+	return pcm, nil
+}
+
+func (d *opusDecoder) close() { C.opus_decoder_destroy(d.st) }
@@ -0,0 +1,11 @@
+//go:build !opus
+
+package loudness
+
+import "fmt"
+
+// newOpusDecoder fails in builds without -tags opus; the service images are
+// built without cgo.
+func newOpusDecoder(int) (decoder, error) {
+	return nil, fmt.Errorf("%w: opus (build with -tags opus)", ErrUnsupported)
+}
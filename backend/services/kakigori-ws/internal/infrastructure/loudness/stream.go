@@ -0,0 +1,69 @@
+package loudness
+
+import (
+	"fmt"
+	"time"
+
+	kakigoriwsv1 "chantingkakigori/gen/go/kakigori_ws/v1"
+	"chantingkakigori/services/kakigori-ws/internal/usecase"
+)
+
+// Stream measures the audio frames of one Aggregate stream into samples.
+// The first frame fixes the codec and rate. A Stream is not safe for
+// concurrent use.
+type Stream struct {
+	cal Calibration
+	// Device is the stream's device class, read from its first request.
+	Device string
+	meter  *Meter
+}
+
+// NewStream returns a stream calibrated with cal.
+func NewStream(cal Calibration) *Stream {
+	return &Stream{cal: cal}
+}
+
+// Sample measures f. The sample is stamped with the time the frame's last
+// audio sample was captured, on the client's clock, when f carries one; a
+// silent frame gives a zero value. ErrUnsupported means the stream's
+// format cannot be decoded at all; ErrMalformed, that this frame cannot.
+func (s *Stream) Sample(f *kakigoriwsv1.AudioFrame) (usecase.ClientSample, error) {
+	codec, rate := CodecOf(f), int(f.GetSampleRate())
+	if s.meter == nil {
+		m, err := NewMeter(codec, rate)
+		if err != nil {
+			return usecase.ClientSample{}, err
+		}
+		s.meter = m
+	} else if c, r := s.meter.Format(); c != codec || r != rate {
+		return usecase.ClientSample{}, fmt.Errorf("%w: %v at %d Hz on a %v at %d Hz stream", ErrMalformed, codec, rate, c, r)
+	}
+	lufs, d, err := s.meter.Measure(f.GetData())
+	if err != nil {
+		return usecase.ClientSample{}, err
+	}
+	out := usecase.ClientSample{Value: s.cal.Normalize(lufs, s.Device)}
+	if ms := f.GetClientTsUnixMs(); ms != 0 {
+		out.ClientTime = time.UnixMilli(ms).Add(d)
+	}
+	return out, nil
+}
+
+// Close releases the stream's decoder.
+func (s *Stream) Close() {
+	if s.meter != nil {
+		s.meter.Close()
+	}
+}
+
+// CodecOf returns the codec of f, 0 when it is not one this package knows.
+func CodecOf(f *kakigoriwsv1.AudioFrame) Codec {
+	switch f.GetCodec() {
+	case kakigoriwsv1.AudioCodec_AUDIO_CODEC_PCM_S16LE:
+		return PCM16
+	case kakigoriwsv1.AudioCodec_AUDIO_CODEC_OPUS:
+		return Opus
+	default:
+		return 0
+	}
+}
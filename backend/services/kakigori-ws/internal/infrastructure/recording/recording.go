@@ -0,0 +1,144 @@
+// Package recording appends what kakigori-ws receives and sends to a JSONL
+// file, one Entry per line, so a complaint about the meter can be replayed
+// (cmd/replay) against the aggregator later.
+package recording
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	kakigoriwsv1 "chantingkakigori/gen/go/kakigori_ws/v1"
+	"chantingkakigori/pkg/logging"
+	"chantingkakigori/services/kakigori-ws/internal/usecase"
+)
+
+// Entry kinds, in the order they happen to a stream.
+const (
+	KindJoin     = "join"     // a stream registered Client in Room
+	KindRequest  = "request"  // an AggregateRequest from Client
+	KindResponse = "response" // the AggregateResponse sent back to Client
+	KindLeave    = "leave"    // Client's stream ended
+	KindClose    = "close"    // an operator closed Room
+	KindExport   = "export"   // Room was handed to another replica
+	KindImport   = "import"   // Room was handed here; Snapshot is what arrived
+)
+
+// Entry is one line of a recording.
+type Entry struct {
+	At       time.Time                       `json:"t"`
+	Room     string                          `json:"room"`
+	Kind     string                          `json:"kind"`
+	Client   string                          `json:"client,omitempty"`
+	Request  *kakigoriwsv1.AggregateRequest  `json:"request,omitempty"`
+	Response *kakigoriwsv1.AggregateResponse `json:"response,omitempty"`
+	Snapshot *usecase.Snapshot               `json:"snapshot,omitempty"`
+}
+
+// flushInterval bounds how much of a recording a crash can lose.
+const flushInterval = time.Second
+
+// writeLogSampler keeps a full disk from flooding the log.
+var writeLogSampler = logging.NewSampler(100)
+
+// Recorder appends entries to a file. A nil *Recorder records nothing, so
+// callers need not check whether recording is on.
+type Recorder struct {
+	mu  sync.Mutex
+	f   *os.File
+	w   *bufio.Writer
+	enc *json.Encoder
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// Open appends to the recording at path, creating it if needed.
+func Open(path string) (*Recorder, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	w := bufio.NewWriter(f)
+	r := &Recorder{f: f, w: w, enc: json.NewEncoder(w), stop: make(chan struct{}), done: make(chan struct{})}
+	go r.flushLoop()
+	return r, nil
+}
+
+// FromEnv opens RECORD_PATH; nil when it is unset.
+func FromEnv() (*Recorder, error) {
+	path := os.Getenv("RECORD_PATH")
+	if path == "" {
+		return nil, nil
+	}
+	return Open(path)
+}
+
+// Record appends e, stamping it with the current time unless At is set.
+func (r *Recorder) Record(e Entry) {
+	if r == nil {
+		return
+	}
+	if e.At.IsZero() {
+		e.At = time.Now()
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.enc.Encode(e); err != nil && writeLogSampler.Allow() {
+		slog.Warn("recording write failed", logging.Room(e.Room), logging.Err(err))
+	}
+}
+
+func (r *Recorder) flushLoop() {
+	defer close(r.done)
+	t := time.NewTicker(flushInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-t.C:
+			r.mu.Lock()
+			if err := r.w.Flush(); err != nil && writeLogSampler.Allow() {
+				slog.Warn("recording flush failed", logging.Err(err))
+			}
+			r.mu.Unlock()
+		}
+	}
+}
+
+// Close flushes what is buffered and closes the file.
+func (r *Recorder) Close() error {
+	if r == nil {
+		return nil
+	}
+	close(r.stop)
+	<-r.done
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return errors.Join(r.w.Flush(), r.f.Close())
+}
+
+// Read calls fn for each entry of a recording, in file order. A last line
+// cut short by a crash ends the recording rather than failing it.
+func Read(rd io.Reader, fn func(Entry) error) error {
+	dec := json.NewDecoder(bufio.NewReader(rd))
+	for {
+		var e Entry
+		err := dec.Decode(&e)
+		if err == io.EOF || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+}
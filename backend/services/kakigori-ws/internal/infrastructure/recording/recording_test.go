@@ -0,0 +1,225 @@
+package recording
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	kakigoriwsv1 "chantingkakigori/gen/go/kakigori_ws/v1"
+	"chantingkakigori/services/kakigori-ws/internal/infrastructure/loudness"
+	"chantingkakigori/services/kakigori-ws/internal/usecase"
+)
+
+// record runs a session through a live aggregator the way the gRPC server
+// does and records it to path: nine honest clients, then one far above them.
+func record(t *testing.T, path string) {
+	t.Helper()
+	rec, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 8, 1, 18, 0, 0, 0, time.Local)
+	agg := usecase.NewAggregatorWithClock(usecase.DefaultFilterOptions(), func() time.Time { return now })
+	send := func(client string, v float64) {
+		now = now.Add(100 * time.Millisecond)
+		rec.Record(Entry{At: now, Room: "r", Kind: KindRequest, Client: client,
+			Request: &kakigoriwsv1.AggregateRequest{Room: "r", Value: v, ClientId: client}})
+		res := agg.UpdateValue("r", client, v)
+		rec.Record(Entry{At: now, Room: "r", Kind: KindResponse, Client: client,
+			Response: &kakigoriwsv1.AggregateResponse{Room: "r", Average: res.Average, Count: int32(res.Count)}})
+	}
+	for i := range 9 {
+		c := fmt.Sprintf("c-%d", i)
+		now = now.Add(10 * time.Millisecond)
+		agg.AddClient("r", c)
+		rec.Record(Entry{At: now, Room: "r", Kind: KindJoin, Client: c})
+		send(c, 0.4+float64(i)/100)
+	}
+	agg.AddClient("r", "loud")
+	rec.Record(Entry{At: now, Room: "r", Kind: KindJoin, Client: "loud"})
+	send("loud", 1)
+	rec.Record(Entry{At: now, Room: "other", Kind: KindJoin, Client: "x"})
+	if err := rec.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func replay(t *testing.T, path string, f usecase.FilterOptions, cal loudness.Calibration, room string) []Step {
+	t.Helper()
+	in, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer in.Close()
+	r := NewReplayer(f, cal, room)
+	var steps []Step
+	if err := Read(in, func(e Entry) error {
+		steps = append(steps, r.Feed(e)...)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return append(steps, r.Flush()...)
+}
+
+func TestReplay_ReproducesRecording(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rec.jsonl")
+	record(t, path)
+
+	steps := replay(t, path, usecase.DefaultFilterOptions(), loudness.DefaultCalibration(), "r")
+	if len(steps) != 10 {
+		t.Fatalf("steps=%d", len(steps))
+	}
+	for _, st := range steps {
+		if st.Recorded == nil || math.Abs(st.Average-st.Recorded.GetAverage()) > 1e-9 {
+			t.Fatalf("%s at %v: replayed %v, recorded %+v", st.Client, st.At, st.Average, st.Recorded)
+		}
+	}
+	if last := steps[9]; last.Client != "loud" || last.Verdict != usecase.Clamped {
+		t.Fatalf("last=%+v", last)
+	}
+}
+
+// Batches are placed by the client clock again, with the offset rebuilt
+// from the recorded arrival times.
+func TestReplay_Batches(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rec.jsonl")
+	rec, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 8, 1, 18, 0, 0, 0, time.Local)
+	agg := usecase.NewAggregatorWithClock(usecase.DefaultFilterOptions(), func() time.Time { return now })
+	var clock usecase.ClockOffset
+	agg.AddClient("r", "c")
+	rec.Record(Entry{At: now, Room: "r", Kind: KindJoin, Client: "c"})
+	client := now.Add(-2 * time.Second) // the phone's clock is behind
+	for i := range 20 {
+		client = client.Add(200 * time.Millisecond)
+		now = now.Add(200*time.Millisecond + time.Duration(i%3)*40*time.Millisecond)
+		req := &kakigoriwsv1.AggregateRequest{Room: "r", Samples: []*kakigoriwsv1.ClientSample{
+			{Value: 0.3 + float64(i)/100, ClientTsUnixMs: client.Add(-100 * time.Millisecond).UnixMilli()},
+			{Value: 0.35 + float64(i)/100, ClientTsUnixMs: client.UnixMilli()},
+		}}
+		rec.Record(Entry{At: now, Room: "r", Kind: KindRequest, Client: "c", Request: req})
+		batch := []usecase.ClientSample{
+			{ClientTime: time.UnixMilli(req.Samples[0].ClientTsUnixMs), Value: req.Samples[0].Value},
+			{ClientTime: time.UnixMilli(req.Samples[1].ClientTsUnixMs), Value: req.Samples[1].Value},
+		}
+		res := agg.UpdateSamples("r", "c", clock.Place(batch, now))
+		rec.Record(Entry{At: now, Room: "r", Kind: KindResponse, Client: "c",
+			Response: &kakigoriwsv1.AggregateResponse{Room: "r", Average: res.Average, Count: int32(res.Count)}})
+	}
+	if err := rec.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	steps := replay(t, path, usecase.DefaultFilterOptions(), loudness.DefaultCalibration(), "")
+	if len(steps) != 20 {
+		t.Fatalf("steps=%d", len(steps))
+	}
+	for _, st := range steps {
+		if st.Recorded == nil || math.Abs(st.Average-st.Recorded.GetAverage()) > 1e-9 || int32(st.Count) != st.Recorded.GetCount() {
+			t.Fatalf("at %v: replayed %v/%d, recorded %+v", st.At, st.Average, st.Count, st.Recorded)
+		}
+	}
+}
+
+// Audio frames are recorded as sent and measured again on replay, so a new
+// calibration can be tried on a past round.
+func TestReplay_Audio(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rec.jsonl")
+	rec, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 8, 1, 18, 0, 0, 0, time.Local)
+	agg := usecase.NewAggregatorWithClock(usecase.DefaultFilterOptions(), func() time.Time { return now })
+	var clock usecase.ClockOffset
+	audio := loudness.NewStream(loudness.DefaultCalibration())
+	audio.Device = "Pixel 7"
+	agg.AddClient("r", "c")
+	rec.Record(Entry{At: now, Room: "r", Kind: KindJoin, Client: "c"})
+	for i := range 10 {
+		now = now.Add(200 * time.Millisecond)
+		pcm := make([]byte, 0, 2*3200)
+		for j := range 3200 {
+			v := (0.02 + float64(i)/500) * math.Sin(2*math.Pi*440*float64(j)/16000)
+			pcm = binary.LittleEndian.AppendUint16(pcm, uint16(int16(v*32767)))
+		}
+		req := &kakigoriwsv1.AggregateRequest{Room: "r", Audio: &kakigoriwsv1.AudioFrame{
+			Codec: kakigoriwsv1.AudioCodec_AUDIO_CODEC_PCM_S16LE, SampleRate: 16000, Data: pcm,
+			ClientTsUnixMs: now.Add(-250 * time.Millisecond).UnixMilli(),
+		}}
+		if i == 0 {
+			req.Device = audio.Device
+		}
+		rec.Record(Entry{At: now, Room: "r", Kind: KindRequest, Client: "c", Request: req})
+		s, err := audio.Sample(req.Audio)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res := agg.UpdateSamples("r", "c", clock.Place([]usecase.ClientSample{s}, now))
+		rec.Record(Entry{At: now, Room: "r", Kind: KindResponse, Client: "c",
+			Response: &kakigoriwsv1.AggregateResponse{Room: "r", Average: res.Average, Count: int32(res.Count)}})
+	}
+	if err := rec.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	steps := replay(t, path, usecase.DefaultFilterOptions(), loudness.DefaultCalibration(), "")
+	if len(steps) != 10 {
+		t.Fatalf("steps=%d", len(steps))
+	}
+	for _, st := range steps {
+		if st.Recorded == nil || math.Abs(st.Average-st.Recorded.GetAverage()) > 1e-9 {
+			t.Fatalf("at %v: replayed %v, recorded %+v", st.At, st.Average, st.Recorded)
+		}
+	}
+	cal := loudness.DefaultCalibration()
+	cal.Devices = map[string]float64{"Pixel": 5}
+	louder := replay(t, path, usecase.DefaultFilterOptions(), cal, "")
+	for i, st := range louder {
+		if d := st.Value - steps[i].Value; math.Abs(d-0.1) > 1e-9 {
+			t.Fatalf("step %d: %v with +5dB, %v without", i, st.Value, steps[i].Value)
+		}
+	}
+}
+
+func TestReplay_DifferentFilter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rec.jsonl")
+	record(t, path)
+
+	off := usecase.FilterOptions{MaxValue: math.Inf(1), MinSamples: math.MaxInt, MaxRate: math.MaxInt, FlagAfter: math.MaxInt}
+	steps := replay(t, path, off, loudness.DefaultCalibration(), "")
+	last := steps[len(steps)-1]
+	if last.Verdict != usecase.Accepted || last.Average <= last.Recorded.GetAverage() {
+		t.Fatalf("unfiltered last=%+v recorded=%v", last, last.Recorded.GetAverage())
+	}
+}
+
+// A crash mid-write leaves a partial last line, which ends the recording.
+func TestRead_TruncatedTail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rec.jsonl")
+	record(t, path)
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, b[:len(b)-20], 0o644); err != nil {
+		t.Fatal(err)
+	}
+	in, _ := os.Open(path)
+	defer in.Close()
+	n := 0
+	if err := Read(in, func(Entry) error { n++; return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if n == 0 {
+		t.Fatal("nothing read")
+	}
+}
@@ -0,0 +1,204 @@
+package recording
+
+import (
+	"time"
+
+	kakigoriwsv1 "chantingkakigori/gen/go/kakigori_ws/v1"
+	"chantingkakigori/services/kakigori-ws/internal/infrastructure/loudness"
+	"chantingkakigori/services/kakigori-ws/internal/usecase"
+)
+
+// Step is one replayed request: what the replayed aggregator made of it,
+// next to the response the live one sent.
+type Step struct {
+	At     time.Time
+	Room   string
+	Client string
+	// Value is the sample, the last of a batch or the audio frame's
+	// measured loudness.
+	Value   float64
+	Verdict usecase.Verdict
+	Reason  string
+	Average float64
+	Count   int
+	// Recorded is the response sent at the time; nil when none was (the
+	// room had no counted samples) or the recording lost it.
+	Recorded *kakigoriwsv1.AggregateResponse
+}
+
+// respondWithin is how long after a request its recorded response may
+// follow; the server sends it right after, with other streams interleaved.
+const respondWithin = time.Second
+
+// Replayer feeds a recording through a fresh aggregator on the recording's
+// own clock, so rate limits and the window behave as they did live.
+type Replayer struct {
+	agg     usecase.AggregatorUsecase
+	now     time.Time
+	room    string
+	queue   []*pendingStep             // in recording order
+	pending map[[2]string]*pendingStep // by room and client, awaiting the recorded response
+	clocks  map[[2]string]*usecase.ClockOffset
+	cal     loudness.Calibration
+	audio   map[[2]string]*loudness.Stream
+}
+
+type pendingStep struct {
+	Step
+	done bool
+}
+
+// NewReplayer replays with filter f, measuring audio frames with cal; room,
+// when set, skips other rooms.
+func NewReplayer(f usecase.FilterOptions, cal loudness.Calibration, room string) *Replayer {
+	r := &Replayer{
+		room:    room,
+		pending: make(map[[2]string]*pendingStep),
+		clocks:  make(map[[2]string]*usecase.ClockOffset),
+		cal:     cal,
+		audio:   make(map[[2]string]*loudness.Stream),
+	}
+	r.agg = usecase.NewAggregatorWithClock(f, func() time.Time { return r.now })
+	return r
+}
+
+// Feed applies e and returns the steps that are complete, in recording
+// order. A step is complete once its response is seen, or once it is clear
+// none was sent.
+func (r *Replayer) Feed(e Entry) []Step {
+	if r.room != "" && e.Room != r.room {
+		return nil
+	}
+	r.now = e.At
+	key := [2]string{e.Room, e.Client}
+	switch e.Kind {
+	case KindJoin:
+		r.agg.AddClient(e.Room, e.Client)
+		r.clocks[key] = &usecase.ClockOffset{}
+		r.closeAudio(key)
+	case KindRequest:
+		r.resolve(key, nil)
+		if name := e.Request.GetDisplayName(); name != "" {
+			r.agg.SetDisplayName(e.Room, e.Client, name)
+		}
+		if e.Request.GetRoundId() != "" || e.Request.GetMenuItemId() != "" {
+			r.agg.SetRound(e.Room, e.Request.GetRoundId(), e.Request.GetMenuItemId())
+		}
+		if device := e.Request.GetDevice(); device != "" {
+			r.audioStream(key).Device = device
+		}
+		if res, v, ok := r.update(key, e); ok {
+			st := &pendingStep{Step: Step{
+				At: e.At, Room: e.Room, Client: e.Client, Value: v,
+				Verdict: res.Verdict, Reason: res.Reason, Average: res.Average, Count: res.Count,
+			}}
+			r.queue = append(r.queue, st)
+			r.pending[key] = st
+		}
+	case KindResponse:
+		r.resolve(key, e.Response)
+	case KindLeave:
+		r.resolve(key, nil)
+		r.agg.RemoveClient(e.Room, e.Client)
+		delete(r.clocks, key)
+		r.closeAudio(key)
+	case KindClose, KindExport:
+		for k := range r.pending {
+			if k[0] == e.Room {
+				r.resolve(k, nil)
+			}
+		}
+		r.agg.CloseRoom(e.Room)
+	case KindImport:
+		if e.Snapshot != nil {
+			r.agg.Import(*e.Snapshot)
+		}
+	}
+	for k, st := range r.pending {
+		if e.At.Sub(st.At) > respondWithin {
+			r.resolve(k, nil)
+		}
+	}
+	return r.drain()
+}
+
+// Flush returns the steps still waiting for a response.
+func (r *Replayer) Flush() []Step {
+	for k := range r.pending {
+		r.resolve(k, nil)
+	}
+	return r.drain()
+}
+
+// update folds the request's sample, batch or audio frame into the
+// aggregator the way the server does, placing a batch with the stream's
+// clock offset. ok is false when the request carried nothing to fold in.
+func (r *Replayer) update(key [2]string, e Entry) (res usecase.Result, value float64, ok bool) {
+	var batch []usecase.ClientSample
+	for _, s := range e.Request.GetSamples() {
+		if s.GetValue() != 0 {
+			batch = append(batch, usecase.ClientSample{ClientTime: fromUnixMs(s.GetClientTsUnixMs()), Value: s.GetValue()})
+		}
+	}
+	if f := e.Request.GetAudio(); f != nil && len(e.Request.GetSamples()) == 0 {
+		// frames the server could not measure were dropped there too
+		if s, err := r.audioStream(key).Sample(f); err == nil && s.Value != 0 {
+			batch = []usecase.ClientSample{s}
+		}
+	}
+	switch {
+	case len(batch) > 0:
+		clock := r.clocks[key]
+		if clock == nil {
+			clock = &usecase.ClockOffset{}
+			r.clocks[key] = clock
+		}
+		return r.agg.UpdateSamples(e.Room, e.Client, clock.Place(batch, e.At)), batch[len(batch)-1].Value, true
+	case len(e.Request.GetSamples()) > 0 || e.Request.GetValue() == 0:
+		return usecase.Result{}, 0, false
+	default:
+		return r.agg.UpdateValue(e.Room, e.Client, e.Request.GetValue()), e.Request.GetValue(), true
+	}
+}
+
+func (r *Replayer) audioStream(key [2]string) *loudness.Stream {
+	s := r.audio[key]
+	if s == nil {
+		s = loudness.NewStream(r.cal)
+		r.audio[key] = s
+	}
+	return s
+}
+
+func (r *Replayer) closeAudio(key [2]string) {
+	if s := r.audio[key]; s != nil {
+		s.Close()
+		delete(r.audio, key)
+	}
+}
+
+func fromUnixMs(ms int64) time.Time {
+	if ms == 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(ms)
+}
+
+func (r *Replayer) resolve(key [2]string, resp *kakigoriwsv1.AggregateResponse) {
+	st, ok := r.pending[key]
+	if !ok {
+		return
+	}
+	delete(r.pending, key)
+	st.Recorded = resp
+	st.done = true
+}
+
+func (r *Replayer) drain() []Step {
+	var out []Step
+	for len(r.queue) > 0 && r.queue[0].done {
+		out = append(out, r.queue[0].Step)
+		r.queue = r.queue[1:]
+	}
+	return out
+}
@@ -0,0 +1,87 @@
+package roundstore
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"chantingkakigori/services/kakigori-ws/internal/usecase"
+)
+
+// File appends rounds to a JSON-lines file and ranks them in memory. A line
+// cut short by a crash is skipped on the next open, and terminated so the
+// next round starts a line of its own.
+type File struct {
+	mem *Memory
+
+	mu  sync.Mutex
+	f   *os.File
+	enc *json.Encoder
+}
+
+// OpenFile loads the rounds at path, creating the file if needed.
+func OpenFile(path string) (*File, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open rounds %s: %w", path, err)
+	}
+	mem := NewMemory()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var r usecase.Round
+		if json.Unmarshal(sc.Bytes(), &r) == nil && r.Room != "" {
+			mem.rounds = append(mem.rounds, r)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("read rounds %s: %w", path, err)
+	}
+	if err := terminate(f); err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("repair rounds %s: %w", path, err)
+	}
+	return &File{mem: mem, f: f, enc: json.NewEncoder(f)}, nil
+}
+
+func (f *File) Save(ctx context.Context, rounds []usecase.Round) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, r := range rounds {
+		if err := f.enc.Encode(r); err != nil {
+			return err
+		}
+	}
+	return f.mem.Save(ctx, rounds)
+}
+
+func (f *File) Leaderboard(ctx context.Context, q usecase.LeaderboardQuery) ([]usecase.Round, error) {
+	return f.mem.Leaderboard(ctx, q)
+}
+
+// Close closes the file.
+func (f *File) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.f.Close()
+}
+
+// terminate ends a last line that has no newline.
+func terminate(f *os.File) error {
+	st, err := f.Stat()
+	if err != nil || st.Size() == 0 {
+		return err
+	}
+	last := make([]byte, 1)
+	if _, err := f.ReadAt(last, st.Size()-1); err != nil {
+		return err
+	}
+	if last[0] == '\n' {
+		return nil
+	}
+	_, err = f.Write([]byte{'\n'})
+	return err
+}
@@ -0,0 +1,40 @@
+package roundstore
+
+import (
+	"context"
+	"sync"
+
+	"chantingkakigori/services/kakigori-ws/internal/usecase"
+)
+
+// Memory keeps rounds in process.
+type Memory struct {
+	mu     sync.Mutex
+	rounds []usecase.Round
+}
+
+// NewMemory returns an empty repository.
+func NewMemory() *Memory { return &Memory{} }
+
+func (m *Memory) Save(_ context.Context, rounds []usecase.Round) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rounds = append(m.rounds, rounds...)
+	return nil
+}
+
+func (m *Memory) Leaderboard(_ context.Context, q usecase.LeaderboardQuery) ([]usecase.Round, error) {
+	m.mu.Lock()
+	var out []usecase.Round
+	for _, r := range m.rounds {
+		if q.Matches(r) {
+			out = append(out, r)
+		}
+	}
+	m.mu.Unlock()
+	usecase.Rank(out)
+	if len(out) > q.Limit {
+		out = out[:q.Limit]
+	}
+	return out, nil
+}
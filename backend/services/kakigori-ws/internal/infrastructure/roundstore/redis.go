@@ -0,0 +1,75 @@
+package roundstore
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"chantingkakigori/services/kakigori-ws/internal/usecase"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// dayTTL keeps a daily board around for a week after its day.
+const dayTTL = 8 * 24 * time.Hour
+
+// Redis keeps every board as a sorted set of round JSON scored by
+// usecase.Round.Score: all time and per day (Japan time), for every room and
+// per room. Each is trimmed to the rounds a leaderboard can show, so a save
+// is a handful of writes however many rounds there are.
+type Redis struct {
+	rdb redis.UniversalClient
+}
+
+// NewRedis returns the repository on rdb, which the caller closes.
+func NewRedis(rdb redis.UniversalClient) *Redis { return &Redis{rdb: rdb} }
+
+func boardKey(q usecase.LeaderboardQuery) string {
+	k := "rounds:all"
+	if !q.Day.IsZero() {
+		k = "rounds:day:" + usecase.DayOf(q.Day)
+	}
+	if q.Room != "" {
+		k += ":room:" + q.Room
+	}
+	return k
+}
+
+func (s *Redis) Save(ctx context.Context, rounds []usecase.Round) error {
+	_, err := s.rdb.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		for _, r := range rounds {
+			b, err := json.Marshal(r)
+			if err != nil {
+				return err
+			}
+			z := redis.Z{Score: r.Score(), Member: b}
+			for _, q := range []usecase.LeaderboardQuery{{}, {Room: r.Room}, {Day: r.EndedAt}, {Room: r.Room, Day: r.EndedAt}} {
+				k := boardKey(q)
+				p.ZAdd(ctx, k, z)
+				p.ZRemRangeByRank(ctx, k, 0, -usecase.MaxLeaderboardLimit-1)
+				if !q.Day.IsZero() {
+					p.Expire(ctx, k, dayTTL)
+				}
+			}
+		}
+		return nil
+	})
+	return err
+}
+
+func (s *Redis) Leaderboard(ctx context.Context, q usecase.LeaderboardQuery) ([]usecase.Round, error) {
+	members, err := s.rdb.ZRevRange(ctx, boardKey(q), 0, int64(q.Limit)-1).Result()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]usecase.Round, 0, len(members))
+	for _, m := range members {
+		var r usecase.Round
+		if json.Unmarshal([]byte(m), &r) == nil {
+			out = append(out, r)
+		}
+	}
+	// equal scores come back in member order; Rank puts the earlier round first
+	usecase.Rank(out)
+	return out, nil
+}
@@ -0,0 +1,44 @@
+// Package roundstore keeps finished rounds for the leaderboard. Redis shares
+// them between kakigori-ws replicas; File keeps a single replica's across
+// restarts; Memory keeps them until the process exits.
+package roundstore
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"chantingkakigori/services/kakigori-ws/internal/usecase"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// DefaultInterval is how often ended rounds are saved.
+const DefaultInterval = 2 * time.Second
+
+// FromEnv returns the repository: Redis when REDIS_URL is set (e.g.
+// redis://redis:6379/0), a File at ROUNDS_PATH when that is set, Memory
+// otherwise. closeFn releases it.
+func FromEnv(ctx context.Context) (repo usecase.RoundRepository, closeFn func() error, err error) {
+	if url := os.Getenv("REDIS_URL"); url != "" {
+		opt, err := redis.ParseURL(url)
+		if err != nil {
+			return nil, nil, fmt.Errorf("parse REDIS_URL: %w", err)
+		}
+		rdb := redis.NewClient(opt)
+		if err := rdb.Ping(ctx).Err(); err != nil {
+			_ = rdb.Close()
+			return nil, nil, fmt.Errorf("ping redis %s: %w", opt.Addr, err)
+		}
+		return NewRedis(rdb), rdb.Close, nil
+	}
+	if path := os.Getenv("ROUNDS_PATH"); path != "" {
+		f, err := OpenFile(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		return f, f.Close, nil
+	}
+	return NewMemory(), func() error { return nil }, nil
+}
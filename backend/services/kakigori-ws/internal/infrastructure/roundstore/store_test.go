@@ -0,0 +1,118 @@
+package roundstore
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"chantingkakigori/services/kakigori-ws/internal/usecase"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// testRepository runs the behaviour every implementation shares.
+func testRepository(t *testing.T, repo usecase.RoundRepository) {
+	ctx := context.Background()
+	// 23:30 JST on the 1st and 00:30 JST on the 2nd
+	day1 := time.Date(2026, 10, 1, 14, 30, 0, 0, time.UTC)
+	day2 := day1.Add(time.Hour)
+	round := func(room string, ended time.Time, peak float64, above time.Duration) usecase.Round {
+		return usecase.Round{Room: room, StartedAt: ended.Add(-time.Minute), EndedAt: ended, Peak: peak, Above: above, Participants: 2}
+	}
+	if err := repo.Save(ctx, []usecase.Round{
+		round("ichigo", day1, 0.8, 10*time.Second),
+		round("matcha", day1, 0.9, time.Second),
+		round("ichigo", day2, 0.8, 20*time.Second),
+		// ties the first round and ended later
+		round("matcha", day2, 0.8, 10*time.Second),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	board := func(q usecase.LeaderboardQuery) []usecase.Round {
+		t.Helper()
+		if q.Limit == 0 {
+			q.Limit = usecase.DefaultLeaderboardLimit
+		}
+		got, err := repo.Leaderboard(ctx, q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+	key := func(r usecase.Round) string { return r.Room + "@" + usecase.DayOf(r.EndedAt) }
+
+	got := board(usecase.LeaderboardQuery{})
+	want := []string{"matcha@2026-10-01", "ichigo@2026-10-02", "ichigo@2026-10-01", "matcha@2026-10-02"}
+	if len(got) != len(want) {
+		t.Fatalf("all time: %v", got)
+	}
+	for i := range want {
+		if key(got[i]) != want[i] {
+			t.Fatalf("all time #%d = %s, want %s", i, key(got[i]), want[i])
+		}
+	}
+	if got[0].Above != time.Second || got[0].Participants != 2 || !got[0].EndedAt.Equal(day1) {
+		t.Fatalf("round did not survive: %+v", got[0])
+	}
+	if got := board(usecase.LeaderboardQuery{Day: day2}); len(got) != 2 || key(got[0]) != "ichigo@2026-10-02" {
+		t.Fatalf("day 2: %v", got)
+	}
+	if got := board(usecase.LeaderboardQuery{Room: "ichigo", Day: day1}); len(got) != 1 || key(got[0]) != "ichigo@2026-10-01" {
+		t.Fatalf("ichigo on day 1: %v", got)
+	}
+	if got := board(usecase.LeaderboardQuery{Limit: 1}); len(got) != 1 || key(got[0]) != "matcha@2026-10-01" {
+		t.Fatalf("limit 1: %v", got)
+	}
+}
+
+func TestMemory(t *testing.T) { testRepository(t, NewMemory()) }
+
+func TestRedis(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = rdb.Close() })
+	testRepository(t, NewRedis(rdb))
+}
+
+func TestFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rounds.jsonl")
+	f, err := OpenFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testRepository(t, f)
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// a crash mid-line loses that round only
+	b, _ := os.ReadFile(path)
+	if err := os.WriteFile(path, append(b, `{"room":"yuzu","pe`...), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f, err = OpenFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	late := usecase.Round{Room: "yuzu", EndedAt: time.Now(), Peak: 0.1, Participants: 1}
+	if err := f.Save(context.Background(), []usecase.Round{late}); err != nil {
+		t.Fatal(err)
+	}
+	_ = f.Close()
+	if f, err = OpenFile(path); err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	got, err := f.Leaderboard(context.Background(), usecase.LeaderboardQuery{Room: "yuzu", Limit: 10})
+	if err != nil || len(got) != 1 {
+		t.Fatalf("yuzu: %v err=%v", got, err)
+	}
+	got, _ = f.Leaderboard(context.Background(), usecase.LeaderboardQuery{Limit: 10})
+	if len(got) != 5 {
+		t.Fatalf("reopened: %d rounds", len(got))
+	}
+}
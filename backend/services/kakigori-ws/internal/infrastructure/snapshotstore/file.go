@@ -0,0 +1,91 @@
+// Package snapshotstore persists kakigori-ws room snapshots so a restarted
+// replica resumes its rooms' windows.
+package snapshotstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"chantingkakigori/services/kakigori-ws/internal/usecase"
+)
+
+// DefaultInterval keeps a restart's loss to about a second of samples.
+const DefaultInterval = time.Second
+
+// fileVersion is bumped when the layout changes; other versions are ignored.
+const fileVersion = 1
+
+type fileContents struct {
+	Version int                `json:"version"`
+	SavedAt time.Time          `json:"saved_at"`
+	Rooms   []usecase.Snapshot `json:"rooms"`
+}
+
+// File stores the snapshots as one JSON document at Path. Saves write a
+// temporary file and rename it over Path, so a crash mid-save leaves the
+// previous snapshot intact.
+type File struct {
+	Path string
+}
+
+func (f File) Save(_ context.Context, rooms []usecase.Snapshot) error {
+	b, err := json.Marshal(fileContents{Version: fileVersion, SavedAt: time.Now(), Rooms: rooms})
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(f.Path), filepath.Base(f.Path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if _, err := tmp.Write(b); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), f.Path)
+}
+
+func (f File) Load(context.Context) ([]usecase.Snapshot, error) {
+	b, err := os.ReadFile(f.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var c fileContents
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, fmt.Errorf("decode %s: %w", f.Path, err)
+	}
+	if c.Version != fileVersion {
+		return nil, fmt.Errorf("%s: unsupported snapshot version %d", f.Path, c.Version)
+	}
+	return c.Rooms, nil
+}
+
+// FromEnv returns the store at SNAPSHOT_PATH (nil when unset, which disables
+// snapshots) and how often to save, from SNAPSHOT_INTERVAL.
+func FromEnv() (usecase.SnapshotStore, time.Duration, error) {
+	path := os.Getenv("SNAPSHOT_PATH")
+	if path == "" {
+		return nil, 0, nil
+	}
+	interval := DefaultInterval
+	if v := os.Getenv("SNAPSHOT_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return nil, 0, fmt.Errorf("invalid SNAPSHOT_INTERVAL %q", v)
+		}
+		interval = d
+	}
+	return File{Path: path}, interval, nil
+}
@@ -0,0 +1,57 @@
+package snapshotstore
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"chantingkakigori/services/kakigori-ws/internal/usecase"
+)
+
+func TestFile_RoundTrip(t *testing.T) {
+	ctx := context.Background()
+	f := File{Path: filepath.Join(t.TempDir(), "rooms.json")}
+
+	if rooms, err := f.Load(ctx); err != nil || rooms != nil {
+		t.Fatalf("missing file: rooms=%v err=%v", rooms, err)
+	}
+
+	at := time.Now().Truncate(time.Millisecond)
+	want := []usecase.Snapshot{{
+		Room:      "giiku-sai",
+		CreatedAt: at.Add(-time.Minute),
+		Participants: []usecase.ParticipantState{{
+			ClientID: "a", DisplayName: "Alice", LastSeen: at, Flagged: true,
+			Samples: []usecase.Sample{{At: at, Value: 0.5}},
+		}},
+	}}
+	if err := f.Save(ctx, want); err != nil {
+		t.Fatal(err)
+	}
+	got, err := f.Load(ctx)
+	if err != nil || len(got) != 1 {
+		t.Fatalf("got=%+v err=%v", got, err)
+	}
+	p := got[0].Participants[0]
+	if got[0].Room != "giiku-sai" || p.DisplayName != "Alice" || !p.Flagged || len(p.Samples) != 1 || !p.Samples[0].At.Equal(at) {
+		t.Fatalf("got=%+v", got[0])
+	}
+
+	// only the snapshot is left behind
+	entries, _ := os.ReadDir(filepath.Dir(f.Path))
+	if len(entries) != 1 {
+		t.Fatalf("dir=%v", entries)
+	}
+}
+
+func TestFile_RejectsOtherVersions(t *testing.T) {
+	f := File{Path: filepath.Join(t.TempDir(), "rooms.json")}
+	if err := os.WriteFile(f.Path, []byte(`{"version":99,"rooms":[]}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Load(context.Background()); err == nil {
+		t.Fatal("expected a version error")
+	}
+}
@@ -1,63 +1,369 @@
 package grpcserver
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
 	"sync"
+	"time"
 
 	kakigoriwsv1 "chantingkakigori/gen/go/kakigori_ws/v1"
+	"chantingkakigori/pkg/apperror"
+	"chantingkakigori/pkg/logging"
+	"chantingkakigori/pkg/metrics"
+	"chantingkakigori/services/kakigori-ws/internal/infrastructure/loudness"
+	"chantingkakigori/services/kakigori-ws/internal/infrastructure/recording"
 	"chantingkakigori/services/kakigori-ws/internal/usecase"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// updateLogSampler keeps the per-sample debug log readable under load;
+// filterLogSampler does the same for a client whose every sample is filtered.
+var (
+	updateLogSampler = logging.NewSampler(50)
+	filterLogSampler = logging.NewSampler(20)
 )
 
 type transcriberServer struct {
 	kakigoriwsv1.UnimplementedKakigoriWsAggregatorServiceServer
 
 	aggregator usecase.AggregatorUsecase
+	cal        loudness.Calibration
+	rec        *recording.Recorder
+	rounds     usecase.RoundRepository
 
 	idMu  sync.Mutex
 	idSeq int64
+
+	// streams holds a kick channel per open Aggregate stream, by room, so
+	// CloseRoom can end them.
+	streamsMu sync.Mutex
+	streams   map[string]map[chan struct{}]struct{}
+
+	// watchers are the open Watch streams
+	watchMu  sync.Mutex
+	watchers map[*watcher]struct{}
+}
+
+// NewTranscriberServer serves a, measuring raw audio with cal; rec, when not
+// nil, records the traffic of every Aggregate stream, and rounds, when not
+// nil, answers Leaderboard.
+func NewTranscriberServer(a usecase.AggregatorUsecase, cal loudness.Calibration, rec *recording.Recorder, rounds usecase.RoundRepository) kakigoriwsv1.KakigoriWsAggregatorServiceServer {
+	return &transcriberServer{aggregator: a, cal: cal, rec: rec, rounds: rounds,
+		streams: make(map[string]map[chan struct{}]struct{}), watchers: make(map[*watcher]struct{})}
+}
+
+func (s *transcriberServer) register(roomID string) chan struct{} {
+	kick := make(chan struct{})
+	s.streamsMu.Lock()
+	defer s.streamsMu.Unlock()
+	if s.streams[roomID] == nil {
+		s.streams[roomID] = make(map[chan struct{}]struct{})
+	}
+	s.streams[roomID][kick] = struct{}{}
+	return kick
+}
+
+func (s *transcriberServer) unregister(roomID string, kick chan struct{}) {
+	s.streamsMu.Lock()
+	defer s.streamsMu.Unlock()
+	delete(s.streams[roomID], kick)
+	if len(s.streams[roomID]) == 0 {
+		delete(s.streams, roomID)
+	}
+}
+
+func (s *transcriberServer) streamCount(roomID string) int {
+	s.streamsMu.Lock()
+	defer s.streamsMu.Unlock()
+	return len(s.streams[roomID])
 }
 
-func NewTranscriberServer(a usecase.AggregatorUsecase) kakigoriwsv1.KakigoriWsAggregatorServiceServer {
-	return &transcriberServer{aggregator: a}
+// errRoomClosed ends the streams of a room closed with CloseRoom.
+var errRoomClosed = apperror.New(apperror.CodeConflict, "room closed by operator")
+
+type recvResult struct {
+	in  *kakigoriwsv1.AggregateRequest
+	err error
 }
 
 func (s *transcriberServer) Aggregate(stream kakigoriwsv1.KakigoriWsAggregatorService_AggregateServer) error {
-	s.idMu.Lock()
-	s.idSeq++
-	clientID := fmt.Sprintf("c-%d", s.idSeq)
-	s.idMu.Unlock()
+	ctx := stream.Context()
+	span := trace.SpanFromContext(ctx)
 
-	var roomID string
+	// Recv runs on its own goroutine so CloseRoom can end the stream while
+	// it waits; returning from here cancels ctx and unblocks it.
+	recv := make(chan recvResult)
+	go func() {
+		for {
+			in, err := stream.Recv()
+			select {
+			case recv <- recvResult{in, err}:
+			case <-ctx.Done():
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	var roomID, clientID string
+	var breakdown bool
+	var kick chan struct{}
+	var clock usecase.ClockOffset
+	audio := loudness.NewStream(s.cal)
+	defer audio.Close()
+	// leave drops the client of a registered stream that ends
+	leave := func() {
+		s.unregister(roomID, kick)
+		s.aggregator.RemoveClient(roomID, clientID)
+		s.rec.Record(recording.Entry{Room: roomID, Kind: recording.KindLeave, Client: clientID})
+		s.publishRoom(roomID)
+	}
 	for {
-		in, err := stream.Recv()
-		if err != nil {
-			if roomID != "" {
-				s.aggregator.RemoveClient(roomID, clientID)
-				log.Printf("aggregate: client removed: room=%s client=%s err=%v", roomID, clientID, err)
+		var in *kakigoriwsv1.AggregateRequest
+		select {
+		case <-kick:
+			// CloseRoom already dropped the room and this registration; a
+			// sample racing the close may have recreated the room, though.
+			s.aggregator.RemoveClient(roomID, clientID)
+			s.rec.Record(recording.Entry{Room: roomID, Kind: recording.KindLeave, Client: clientID})
+			slog.InfoContext(ctx, "aggregate stream ended by room close", logging.Room(roomID), logging.Client(clientID))
+			return apperror.ToGRPC(errRoomClosed)
+		case r := <-recv:
+			if r.err != nil {
+				if roomID != "" {
+					leave()
+					slog.InfoContext(ctx, "aggregate client removed", logging.Room(roomID), logging.Client(clientID), logging.Err(r.err))
+				}
+				if r.err == io.EOF {
+					// a half-close (gateway-ws moving the stream) ends cleanly,
+					// so the client's Recv sees io.EOF rather than an error
+					return nil
+				}
+				return r.err
 			}
-			return err
+			in = r.in
 		}
 		if in.GetRoom() == "" {
 			continue
 		}
 		if roomID == "" {
 			roomID = in.GetRoom()
+			clientID = in.GetClientId()
+			if clientID == "" {
+				s.idMu.Lock()
+				s.idSeq++
+				clientID = fmt.Sprintf("c-%d", s.idSeq)
+				s.idMu.Unlock()
+			}
 			s.aggregator.AddClient(roomID, clientID)
-			log.Printf("aggregate: client added: room=%s client=%s", roomID, clientID)
+			s.rec.Record(recording.Entry{Room: roomID, Kind: recording.KindJoin, Client: clientID})
+			kick = s.register(roomID)
+			if name := in.GetDisplayName(); name != "" {
+				s.aggregator.SetDisplayName(roomID, clientID, name)
+			}
+			if in.GetRoundId() != "" || in.GetMenuItemId() != "" {
+				s.aggregator.SetRound(roomID, in.GetRoundId(), in.GetMenuItemId())
+			}
+			breakdown = in.GetIncludeBreakdown()
+			audio.Device = in.GetDevice()
+			s.publishRoom(roomID)
+			span.SetAttributes(attribute.String("room", roomID), attribute.String("client", clientID))
+			slog.InfoContext(ctx, "aggregate client added", logging.Room(roomID), logging.Client(clientID))
+		}
+		arrival := time.Now()
+		s.rec.Record(recording.Entry{At: arrival, Room: roomID, Kind: recording.KindRequest, Client: clientID, Request: in})
+		var res usecase.Result
+		val, n := in.GetValue(), 1
+		batch := clientSamples(in)
+		if f := in.GetAudio(); f != nil && len(in.GetSamples()) == 0 {
+			sample, err := audio.Sample(f)
+			metrics.AudioFrames.WithLabelValues(loudness.CodecOf(f).String(), audioOutcome(sample, err)).Inc()
+			if errors.Is(err, loudness.ErrUnsupported) {
+				// every frame of the stream would fail the same way
+				leave()
+				slog.WarnContext(ctx, "aggregate audio unsupported", logging.Room(roomID), logging.Client(clientID), logging.Err(err))
+				return apperror.ToGRPC(apperror.Wrap(apperror.CodeInvalidArgument, err, "audio format not supported, send loudness values"))
+			}
+			if err != nil {
+				if filterLogSampler.Allow() {
+					slog.InfoContext(ctx, "aggregate audio frame dropped", logging.Room(roomID), logging.Client(clientID), logging.Err(err))
+				}
+				continue
+			}
+			if sample.Value != 0 {
+				batch = []usecase.ClientSample{sample}
+			}
 		}
-		val := in.GetValue()
-		if val == 0 {
+		switch {
+		case len(batch) > 0:
+			val, n = batch[len(batch)-1].Value, len(batch)
+			res = s.aggregator.UpdateSamples(roomID, clientID, clock.Place(batch, arrival))
+		case len(in.GetSamples()) > 0 || val == 0:
+			// zeros only
 			continue
+		default:
+			res = s.aggregator.UpdateValue(roomID, clientID, val)
+		}
+		metrics.AggregateUpdates.Inc()
+		span.AddEvent("aggregate.update", trace.WithAttributes(
+			attribute.Float64("value", val),
+			attribute.Int("samples", n),
+			attribute.Float64("average", res.Average),
+			attribute.Int("count", res.Count),
+			attribute.String("verdict", res.Verdict.String()),
+		))
+		if res.NewlyFlagged {
+			slog.WarnContext(ctx, "aggregate client flagged as suspicious", logging.Room(roomID), logging.Client(clientID),
+				slog.String("reason", res.Reason), slog.Float64("value", val))
+		} else if res.Verdict != usecase.Accepted && filterLogSampler.Allow() {
+			slog.InfoContext(ctx, "aggregate sample filtered", logging.Room(roomID), logging.Client(clientID),
+				slog.String("verdict", res.Verdict.String()), slog.String("reason", res.Reason), slog.Float64("value", val))
 		}
-		avg, count := s.aggregator.UpdateValue(roomID, clientID, val)
-		log.Printf("aggregate: update: room=%s client=%s val=%.3f avg=%.3f count=%d", roomID, clientID, val, avg, count)
-		if count == 0 {
+		if updateLogSampler.Allow() {
+			slog.DebugContext(ctx, "aggregate update", logging.Room(roomID), logging.Client(clientID),
+				slog.Float64("value", val), slog.Float64("average", res.Average), slog.Int("count", res.Count))
+		}
+		s.publish(func() *kakigoriwsv1.WatchEvent {
+			return updateEvent(roomID, res.Average, res.Count, len(res.Participants))
+		})
+		if res.Count == 0 {
 			continue
 		}
-		if err := stream.Send(&kakigoriwsv1.AggregateResponse{Room: roomID, Average: avg, Count: int32(count)}); err != nil {
-			log.Printf("aggregate: send error: room=%s client=%s err=%v", roomID, clientID, err)
+		resp := &kakigoriwsv1.AggregateResponse{
+			Room:           roomID,
+			Average:        res.Average,
+			Count:          int32(res.Count),
+			Verdict:        verdictToProto(res.Verdict),
+			FlaggedClients: res.Flagged,
+		}
+		if breakdown {
+			resp.Participants = participantsToProto(res.Participants)
+		}
+		if err := stream.Send(resp); err != nil {
+			slog.WarnContext(ctx, "aggregate send error", logging.Room(roomID), logging.Client(clientID), logging.Err(err))
 			return err
 		}
+		s.rec.Record(recording.Entry{Room: roomID, Kind: recording.KindResponse, Client: clientID, Response: resp})
+	}
+}
+
+// ListRooms reports every room of this instance.
+func (s *transcriberServer) ListRooms(ctx context.Context, _ *kakigoriwsv1.ListRoomsRequest) (*kakigoriwsv1.ListRoomsResponse, error) {
+	rooms := s.aggregator.Rooms()
+	resp := &kakigoriwsv1.ListRoomsResponse{Rooms: make([]*kakigoriwsv1.RoomInfo, 0, len(rooms))}
+	for _, ri := range rooms {
+		resp.Rooms = append(resp.Rooms, s.roomInfoToProto(ri))
+	}
+	return resp, nil
+}
+
+// GetRoom reports one room.
+func (s *transcriberServer) GetRoom(ctx context.Context, req *kakigoriwsv1.GetRoomRequest) (*kakigoriwsv1.GetRoomResponse, error) {
+	if req.GetRoom() == "" {
+		return nil, apperror.ToGRPC(apperror.New(apperror.CodeInvalidArgument, "room is required"))
+	}
+	ri, ok := s.aggregator.Room(req.GetRoom())
+	if !ok {
+		return nil, apperror.ToGRPC(apperror.New(apperror.CodeNotFound, "room not found"))
+	}
+	return &kakigoriwsv1.GetRoomResponse{Room: s.roomInfoToProto(ri)}, nil
+}
+
+// CloseRoom drops the room and ends its Aggregate streams. A room with
+// streams but no state yet (nobody sent a sample) can be closed too.
+func (s *transcriberServer) CloseRoom(ctx context.Context, req *kakigoriwsv1.CloseRoomRequest) (*kakigoriwsv1.CloseRoomResponse, error) {
+	roomID := req.GetRoom()
+	if roomID == "" {
+		return nil, apperror.ToGRPC(apperror.New(apperror.CodeInvalidArgument, "room is required"))
+	}
+	existed := s.aggregator.CloseRoom(roomID)
+	s.streamsMu.Lock()
+	kicks := s.streams[roomID]
+	delete(s.streams, roomID)
+	s.streamsMu.Unlock()
+	if !existed && len(kicks) == 0 {
+		return nil, apperror.ToGRPC(apperror.New(apperror.CodeNotFound, "room not found"))
+	}
+	s.rec.Record(recording.Entry{Room: roomID, Kind: recording.KindClose})
+	s.publish(func() *kakigoriwsv1.WatchEvent { return endedEvent(roomID, reasonClosed) })
+	for kick := range kicks {
+		close(kick)
+	}
+	slog.WarnContext(ctx, "aggregate room closed", logging.Room(roomID),
+		slog.String("reason", req.GetReason()), slog.Int("streams", len(kicks)))
+	return &kakigoriwsv1.CloseRoomResponse{ClosedStreams: int32(len(kicks))}, nil
+}
+
+func (s *transcriberServer) roomInfoToProto(ri usecase.RoomInfo) *kakigoriwsv1.RoomInfo {
+	out := &kakigoriwsv1.RoomInfo{
+		Room:             ri.ID,
+		Participants:     participantsToProto(ri.Participants),
+		Count:            int32(ri.Count),
+		Average:          ri.Average,
+		Streams:          int32(s.streamCount(ri.ID)),
+		FlaggedClients:   ri.Flagged,
+		CreatedAtUnixMs:  unixMs(ri.CreatedAt),
+		LastSampleUnixMs: unixMs(ri.LastSample),
+	}
+	return out
+}
+
+// clientSamples returns the request's batch without zero values, which the
+// aggregator ignores like a zero value.
+func clientSamples(in *kakigoriwsv1.AggregateRequest) []usecase.ClientSample {
+	var out []usecase.ClientSample
+	for _, s := range in.GetSamples() {
+		if s.GetValue() == 0 {
+			continue
+		}
+		out = append(out, usecase.ClientSample{ClientTime: fromUnixMs(s.GetClientTsUnixMs()), Value: s.GetValue()})
+	}
+	return out
+}
+
+// audioOutcome labels a measured frame for metrics.AudioFrames.
+func audioOutcome(sample usecase.ClientSample, err error) string {
+	switch {
+	case errors.Is(err, loudness.ErrUnsupported):
+		return "unsupported"
+	case err != nil:
+		return "malformed"
+	case sample.Value == 0:
+		return "silent"
+	default:
+		return "measured"
+	}
+}
+
+func verdictToProto(v usecase.Verdict) kakigoriwsv1.SampleVerdict {
+	switch v {
+	case usecase.Clamped:
+		return kakigoriwsv1.SampleVerdict_SAMPLE_VERDICT_CLAMPED
+	case usecase.Rejected:
+		return kakigoriwsv1.SampleVerdict_SAMPLE_VERDICT_REJECTED
+	default:
+		return kakigoriwsv1.SampleVerdict_SAMPLE_VERDICT_ACCEPTED
+	}
+}
+
+func participantsToProto(ps []usecase.Participant) []*kakigoriwsv1.Participant {
+	out := make([]*kakigoriwsv1.Participant, 0, len(ps))
+	for _, p := range ps {
+		pp := &kakigoriwsv1.Participant{
+			ClientId:       p.ClientID,
+			DisplayName:    p.DisplayName,
+			Mean:           p.Mean,
+			Count:          int32(p.Count),
+			LastSeenUnixMs: unixMs(p.LastSeen),
+		}
+		out = append(out, pp)
 	}
+	return out
 }
@@ -0,0 +1,119 @@
+package grpcserver
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	kakigoriwsv1 "chantingkakigori/gen/go/kakigori_ws/v1"
+	"chantingkakigori/pkg/apperror"
+	"chantingkakigori/pkg/logging"
+	"chantingkakigori/services/kakigori-ws/internal/infrastructure/recording"
+	"chantingkakigori/services/kakigori-ws/internal/usecase"
+)
+
+// ExportRoom hands a room's window to the caller (a gateway moving the room
+// to its new owner) and forgets it here.
+func (s *transcriberServer) ExportRoom(ctx context.Context, req *kakigoriwsv1.ExportRoomRequest) (*kakigoriwsv1.ExportRoomResponse, error) {
+	if req.GetRoom() == "" {
+		return nil, apperror.ToGRPC(apperror.New(apperror.CodeInvalidArgument, "room is required"))
+	}
+	snap, ok := s.aggregator.Export(req.GetRoom())
+	if !ok {
+		return nil, apperror.ToGRPC(apperror.New(apperror.CodeNotFound, "room not found"))
+	}
+	s.rec.Record(recording.Entry{Room: snap.Room, Kind: recording.KindExport})
+	s.publish(func() *kakigoriwsv1.WatchEvent {
+		return &kakigoriwsv1.WatchEvent{Room: snap.Room, Type: kakigoriwsv1.WatchEventType_WATCH_EVENT_TYPE_MOVED}
+	})
+	slog.InfoContext(ctx, "aggregate room exported", logging.Room(snap.Room), slog.Int("participants", len(snap.Participants)))
+	return &kakigoriwsv1.ExportRoomResponse{Snapshot: snapshotToProto(snap)}, nil
+}
+
+// ImportRoom merges a room handed over from another replica.
+func (s *transcriberServer) ImportRoom(ctx context.Context, req *kakigoriwsv1.ImportRoomRequest) (*kakigoriwsv1.ImportRoomResponse, error) {
+	if req.GetSnapshot().GetRoom() == "" {
+		return nil, apperror.ToGRPC(apperror.New(apperror.CodeInvalidArgument, "snapshot.room is required"))
+	}
+	snap := snapshotFromProto(req.GetSnapshot())
+	s.aggregator.Import(snap)
+	s.rec.Record(recording.Entry{Room: snap.Room, Kind: recording.KindImport, Snapshot: &snap})
+	slog.InfoContext(ctx, "aggregate room imported", logging.Room(snap.Room), slog.Int("participants", len(snap.Participants)))
+	return &kakigoriwsv1.ImportRoomResponse{}, nil
+}
+
+func snapshotToProto(s usecase.Snapshot) *kakigoriwsv1.RoomSnapshot {
+	out := &kakigoriwsv1.RoomSnapshot{
+		Room:             s.Room,
+		CreatedAtUnixMs:  unixMs(s.CreatedAt),
+		LastSampleUnixMs: unixMs(s.LastSample),
+	}
+	for _, p := range s.Participants {
+		pp := &kakigoriwsv1.ParticipantState{
+			ClientId:       p.ClientID,
+			DisplayName:    p.DisplayName,
+			LastSeenUnixMs: unixMs(p.LastSeen),
+			Strikes:        int32(p.Strikes),
+			Flagged:        p.Flagged,
+		}
+		for _, smp := range p.Samples {
+			pp.Samples = append(pp.Samples, &kakigoriwsv1.Sample{AtUnixMs: unixMs(smp.At), Value: smp.Value})
+		}
+		out.Participants = append(out.Participants, pp)
+	}
+	if r := s.Round; r != nil {
+		out.Round = &kakigoriwsv1.RoundState{
+			StartedAtUnixMs:  unixMs(r.StartedAt),
+			Peak:             r.Peak,
+			AboveThresholdMs: r.Above.Milliseconds(),
+			Clients:          r.Clients,
+		}
+	}
+	return out
+}
+
+func snapshotFromProto(s *kakigoriwsv1.RoomSnapshot) usecase.Snapshot {
+	out := usecase.Snapshot{
+		Room:       s.GetRoom(),
+		CreatedAt:  fromUnixMs(s.GetCreatedAtUnixMs()),
+		LastSample: fromUnixMs(s.GetLastSampleUnixMs()),
+	}
+	for _, p := range s.GetParticipants() {
+		ps := usecase.ParticipantState{
+			ClientID:    p.GetClientId(),
+			DisplayName: p.GetDisplayName(),
+			LastSeen:    fromUnixMs(p.GetLastSeenUnixMs()),
+			Strikes:     int(p.GetStrikes()),
+			Flagged:     p.GetFlagged(),
+		}
+		for _, smp := range p.GetSamples() {
+			ps.Samples = append(ps.Samples, usecase.Sample{At: fromUnixMs(smp.GetAtUnixMs()), Value: smp.GetValue()})
+		}
+		out.Participants = append(out.Participants, ps)
+	}
+	if r := s.GetRound(); r != nil {
+		out.Round = &usecase.RoundSnapshot{
+			StartedAt: fromUnixMs(r.GetStartedAtUnixMs()),
+			Peak:      r.GetPeak(),
+			Above:     time.Duration(r.GetAboveThresholdMs()) * time.Millisecond,
+			Clients:   r.GetClients(),
+		}
+	}
+	return out
+}
+
+// unixMs is t in Unix milliseconds, 0 for the zero time.
+func unixMs(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixMilli()
+}
+
+// fromUnixMs is the inverse of unixMs.
+func fromUnixMs(ms int64) time.Time {
+	if ms == 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(ms)
+}
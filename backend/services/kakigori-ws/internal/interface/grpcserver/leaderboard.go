@@ -0,0 +1,51 @@
+package grpcserver
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	kakigoriwsv1 "chantingkakigori/gen/go/kakigori_ws/v1"
+	"chantingkakigori/pkg/apperror"
+	"chantingkakigori/pkg/logging"
+	"chantingkakigori/services/kakigori-ws/internal/usecase"
+)
+
+// Leaderboard ranks finished rounds from the round repository.
+func (s *transcriberServer) Leaderboard(ctx context.Context, req *kakigoriwsv1.LeaderboardRequest) (*kakigoriwsv1.LeaderboardResponse, error) {
+	if s.rounds == nil {
+		return nil, apperror.ToGRPC(apperror.New(apperror.CodeUnavailable, "rounds are not recorded"))
+	}
+	q := usecase.LeaderboardQuery{Room: req.GetMenuItemId(), Limit: int(req.GetLimit())}
+	if q.Limit == 0 {
+		q.Limit = usecase.DefaultLeaderboardLimit
+	}
+	if q.Limit < 0 || q.Limit > usecase.MaxLeaderboardLimit {
+		return nil, apperror.ToGRPC(apperror.New(apperror.CodeInvalidArgument, "limit must be between 1 and 100"))
+	}
+	if day := req.GetDay(); day != "" {
+		d, err := time.ParseInLocation(time.DateOnly, day, usecase.Japan)
+		if err != nil {
+			return nil, apperror.ToGRPC(apperror.New(apperror.CodeInvalidArgument, "day must be YYYY-MM-DD"))
+		}
+		q.Day = d
+	}
+	rounds, err := s.rounds.Leaderboard(ctx, q)
+	if err != nil {
+		slog.ErrorContext(ctx, "leaderboard failed", logging.Err(err))
+		return nil, apperror.ToGRPC(apperror.New(apperror.CodeUnavailable, "round repository unavailable"))
+	}
+	res := &kakigoriwsv1.LeaderboardResponse{Threshold: s.aggregator.RoundOptions().Threshold}
+	for _, r := range rounds {
+		res.Rounds = append(res.Rounds, &kakigoriwsv1.RoundStats{
+			Room:             r.Room,
+			StartedAtUnixMs:  unixMs(r.StartedAt),
+			EndedAtUnixMs:    unixMs(r.EndedAt),
+			Peak:             r.Peak,
+			AboveThresholdMs: r.Above.Milliseconds(),
+			Participants:     int32(r.Participants),
+			RoundId:          r.RoundID,
+		})
+	}
+	return res, nil
+}
@@ -0,0 +1,193 @@
+package grpcserver
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	kakigoriwsv1 "chantingkakigori/gen/go/kakigori_ws/v1"
+	"chantingkakigori/pkg/logging"
+	"chantingkakigori/pkg/metrics"
+)
+
+// watchResync is how often a Watch stream checks that the rooms it reported
+// still exist. Rooms normally end with an event; this catches those dropped
+// by Sweep after a handoff whose streams never followed.
+const watchResync = 5 * time.Second
+
+const (
+	reasonFinished = "finished"
+	reasonClosed   = "closed"
+)
+
+// watcher is one Watch stream. Events are kept per room and only the latest
+// one is sent, so a slow watcher skips averages instead of holding up the
+// Aggregate streams that publish them.
+type watcher struct {
+	rooms map[string]struct{} // nil watches every room
+	wake  chan struct{}
+
+	mu      sync.Mutex
+	order   []string
+	pending map[string]*kakigoriwsv1.WatchEvent
+}
+
+func newWatcher(rooms []string) *watcher {
+	w := &watcher{wake: make(chan struct{}, 1), pending: make(map[string]*kakigoriwsv1.WatchEvent)}
+	if len(rooms) > 0 {
+		w.rooms = make(map[string]struct{}, len(rooms))
+		for _, r := range rooms {
+			w.rooms[r] = struct{}{}
+		}
+	}
+	return w
+}
+
+func (w *watcher) wants(roomID string) bool {
+	if w.rooms == nil {
+		return true
+	}
+	_, ok := w.rooms[roomID]
+	return ok
+}
+
+// push queues ev in place of the room's pending event, if any.
+func (w *watcher) push(ev *kakigoriwsv1.WatchEvent) {
+	if !w.wants(ev.GetRoom()) {
+		return
+	}
+	w.mu.Lock()
+	if _, ok := w.pending[ev.GetRoom()]; !ok {
+		w.order = append(w.order, ev.GetRoom())
+	}
+	w.pending[ev.GetRoom()] = ev
+	w.mu.Unlock()
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+// take returns the pending events in the order their rooms first changed.
+func (w *watcher) take() []*kakigoriwsv1.WatchEvent {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	out := make([]*kakigoriwsv1.WatchEvent, 0, len(w.order))
+	for _, id := range w.order {
+		out = append(out, w.pending[id])
+		delete(w.pending, id)
+	}
+	w.order = w.order[:0]
+	return out
+}
+
+func (s *transcriberServer) addWatcher(w *watcher) {
+	s.watchMu.Lock()
+	defer s.watchMu.Unlock()
+	s.watchers[w] = struct{}{}
+	metrics.AggregateWatchStreams.Inc()
+}
+
+func (s *transcriberServer) removeWatcher(w *watcher) {
+	s.watchMu.Lock()
+	defer s.watchMu.Unlock()
+	delete(s.watchers, w)
+	metrics.AggregateWatchStreams.Dec()
+}
+
+// publish hands an event to every watcher. ev is only built when somebody
+// watches, since it runs for every sample.
+func (s *transcriberServer) publish(ev func() *kakigoriwsv1.WatchEvent) {
+	s.watchMu.Lock()
+	n := len(s.watchers)
+	s.watchMu.Unlock()
+	if n == 0 {
+		return
+	}
+	e := ev()
+	e.AtUnixMs = time.Now().UnixMilli()
+	s.watchMu.Lock()
+	defer s.watchMu.Unlock()
+	for w := range s.watchers {
+		w.push(e)
+	}
+}
+
+func updateEvent(roomID string, average float64, count, participants int) *kakigoriwsv1.WatchEvent {
+	return &kakigoriwsv1.WatchEvent{
+		Room:         roomID,
+		Type:         kakigoriwsv1.WatchEventType_WATCH_EVENT_TYPE_UPDATE,
+		Average:      average,
+		Count:        int32(count),
+		Participants: int32(participants),
+	}
+}
+
+func endedEvent(roomID, reason string) *kakigoriwsv1.WatchEvent {
+	return &kakigoriwsv1.WatchEvent{Room: roomID, Type: kakigoriwsv1.WatchEventType_WATCH_EVENT_TYPE_ENDED, Reason: reason}
+}
+
+// publishRoom reports the room as it is now: an update, or its end when the
+// last participant has left.
+func (s *transcriberServer) publishRoom(roomID string) {
+	s.publish(func() *kakigoriwsv1.WatchEvent {
+		ri, ok := s.aggregator.Room(roomID)
+		if !ok {
+			return endedEvent(roomID, reasonFinished)
+		}
+		return updateEvent(roomID, ri.Average, ri.Count, len(ri.Participants))
+	})
+}
+
+// Watch streams room events to a spectator (the booth's big screen, through
+// gateway-ws) that sends no samples. It ends only when the caller cancels,
+// so a gateway must drop it once this replica leaves its ring for the
+// server to stop gracefully.
+func (s *transcriberServer) Watch(req *kakigoriwsv1.WatchRequest, stream kakigoriwsv1.KakigoriWsAggregatorService_WatchServer) error {
+	ctx := stream.Context()
+	w := newWatcher(req.GetRooms())
+	s.addWatcher(w)
+	defer s.removeWatcher(w)
+	// registered first, so a change racing the initial state is sent after it
+	now := time.Now().UnixMilli()
+	for _, ri := range s.aggregator.Rooms() {
+		if w.wants(ri.ID) {
+			ev := updateEvent(ri.ID, ri.Average, ri.Count, len(ri.Participants))
+			ev.AtUnixMs = now
+			w.push(ev)
+		}
+	}
+	slog.InfoContext(ctx, "watch stream opened", slog.Int("rooms", len(req.GetRooms())))
+
+	reported := make(map[string]struct{})
+	t := time.NewTicker(watchResync)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			slog.InfoContext(ctx, "watch stream closed")
+			return nil
+		case <-t.C:
+			for id := range reported {
+				if _, ok := s.aggregator.Room(id); !ok {
+					ev := endedEvent(id, reasonFinished)
+					ev.AtUnixMs = time.Now().UnixMilli()
+					w.push(ev)
+				}
+			}
+			continue
+		case <-w.wake:
+		}
+		for _, ev := range w.take() {
+			if ev.GetType() == kakigoriwsv1.WatchEventType_WATCH_EVENT_TYPE_UPDATE {
+				reported[ev.GetRoom()] = struct{}{}
+			} else {
+				delete(reported, ev.GetRoom())
+			}
+			if err := stream.Send(ev); err != nil {
+				slog.WarnContext(ctx, "watch send error", logging.Room(ev.GetRoom()), logging.Err(err))
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package grpcserver
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	kakigoriwsv1 "chantingkakigori/gen/go/kakigori_ws/v1"
+	"chantingkakigori/services/kakigori-ws/internal/infrastructure/loudness"
+	"chantingkakigori/services/kakigori-ws/internal/usecase"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// A watcher that falls behind gets each room's latest event once, in the
+// order the rooms first changed.
+func TestWatcher_Coalesces(t *testing.T) {
+	w := newWatcher([]string{"a", "b"})
+	w.push(updateEvent("a", 0.1, 1, 1))
+	w.push(updateEvent("b", 0.2, 1, 1))
+	w.push(updateEvent("c", 0.3, 1, 1))
+	w.push(updateEvent("a", 0.4, 2, 1))
+	w.push(endedEvent("b", reasonFinished))
+	got := w.take()
+	if len(got) != 2 || got[0].GetRoom() != "a" || got[0].GetAverage() != 0.4 ||
+		got[1].GetRoom() != "b" || got[1].GetType() != kakigoriwsv1.WatchEventType_WATCH_EVENT_TYPE_ENDED {
+		t.Fatalf("take = %v", got)
+	}
+	if len(w.take()) != 0 {
+		t.Fatal("events taken twice")
+	}
+}
+
+func TestWatch_ReportsRoomsWithoutJoining(t *testing.T) {
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	kakigoriwsv1.RegisterKakigoriWsAggregatorServiceServer(srv, NewTranscriberServer(usecase.NewAggregator(), loudness.DefaultCalibration(), nil, nil))
+	go srv.Serve(lis)
+	defer srv.Stop()
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return lis.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	client := kakigoriwsv1.NewKakigoriWsAggregatorServiceClient(conn)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	watch, err := client.Watch(ctx, &kakigoriwsv1.WatchRequest{Rooms: []string{"r"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the room reaches the watch as an update or, if the sample wins the
+	// race with the watch's registration, in its initial state
+	agg, err := client.Aggregate(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := client.Aggregate(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := other.Send(&kakigoriwsv1.AggregateRequest{Room: "unwatched", Value: 0.9}); err != nil {
+		t.Fatal(err)
+	}
+	if err := agg.Send(&kakigoriwsv1.AggregateRequest{Room: "r", ClientId: "c", Value: 0.5}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := agg.Recv(); err != nil {
+		t.Fatal(err)
+	}
+	for {
+		ev, err := watch.Recv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ev.GetRoom() != "r" || ev.GetType() != kakigoriwsv1.WatchEventType_WATCH_EVENT_TYPE_UPDATE {
+			t.Fatalf("event %v", ev)
+		}
+		if ev.GetAverage() == 0.5 && ev.GetCount() == 1 && ev.GetParticipants() == 1 {
+			break
+		}
+	}
+	if err := agg.CloseSend(); err != nil {
+		t.Fatal(err)
+	}
+	for {
+		ev, err := watch.Recv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ev.GetType() == kakigoriwsv1.WatchEventType_WATCH_EVENT_TYPE_ENDED {
+			if ev.GetRoom() != "r" || ev.GetReason() != reasonFinished {
+				t.Fatalf("ended %v", ev)
+			}
+			break
+		}
+	}
+}
@@ -1,40 +1,143 @@
 package usecase
 
 import (
+	"math"
+	"slices"
+	"sort"
 	"sync"
 	"time"
+
+	"chantingkakigori/pkg/metrics"
 )
 
 type AggregatorUsecase interface {
 	AddClient(roomID string, clientID string)
 	RemoveClient(roomID string, clientID string)
-	UpdateValue(roomID string, clientID string, value float64) (average float64, count int)
+	SetDisplayName(roomID string, clientID string, name string)
+	SetRound(roomID string, roundID string, menuItemID string)
+	Rooms() []RoomInfo
+	Room(roomID string) (RoomInfo, bool)
+	CloseRoom(roomID string) bool
+	Export(roomID string) (Snapshot, bool)
+	Import(s Snapshot)
+	Snapshots() []Snapshot
+	Sweep()
+	TrackRounds(o RoundOptions)
+	RoundOptions() RoundOptions
+	EndedRounds() []Round
+	UpdateValue(roomID string, clientID string, value float64) Result
+	UpdateSamples(roomID string, clientID string, samples []Sample) Result
+}
+
+// Result is the room aggregate after a sample, and what happened to it. For
+// a batch, Verdict and Reason are those of its most severely filtered sample.
+type Result struct {
+	Average float64
+	Count   int
+	Verdict Verdict
+	// Reason is ReasonRange, ReasonRate, ReasonOutlier or ReasonStale when
+	// the sample was not accepted as sent.
+	Reason string
+	// Flagged lists the room's suspicious clients, sorted.
+	Flagged []string
+	// NewlyFlagged is set on the sample that flagged the sender.
+	NewlyFlagged bool
+	// Participants is every client in the room, sorted by ID.
+	Participants []Participant
+}
+
+// Participant is one client's share of the room window. Mean and Count use
+// the same non-zero samples as the room average.
+type Participant struct {
+	ClientID    string
+	DisplayName string
+	Mean        float64
+	Count       int
+	// LastSeen is the client's last accepted sample; zero if none yet.
+	LastSeen time.Time
+}
+
+// RoomInfo is a read-only view of a room for operators.
+type RoomInfo struct {
+	ID           string
+	Participants []Participant
+	Count        int
+	Average      float64
+	Flagged      []string
+	CreatedAt    time.Time
+	// LastSample is the room's last accepted sample; zero if none yet.
+	LastSample time.Time
 }
 
+// aggregateWindow is how far back the room average looks.
+const aggregateWindow = 5 * time.Second
+
 type event struct {
 	t time.Time
 	v float64
 }
 
+// member is a participant identity. The same client ID may hold several
+// streams (two tabs with one session), so it is reference counted.
+type member struct {
+	refs     int
+	name     string
+	lastSeen time.Time
+}
+
 type roomState struct {
-	values map[string][]event
+	createdAt  time.Time
+	lastSample time.Time
+	members    map[string]*member
+	values     map[string][]event
+	strikes    map[string]int // clamped or rejected samples per client
+	flagged    map[string]struct{}
+	round      roundState
 }
 
 type aggregator struct {
-	mu    sync.Mutex
-	rooms map[string]*roomState
+	mu     sync.Mutex
+	rooms  map[string]*roomState
+	filter FilterOptions
+	now    func() time.Time
+
+	rounds      RoundOptions
+	trackRounds bool
+	ended       []Round
+	// exported is when each room was last exported; see roundState.moved
+	exported map[string]time.Time
 }
 
+// NewAggregator returns an aggregator with DefaultFilterOptions.
 func NewAggregator() AggregatorUsecase {
-	return &aggregator{rooms: make(map[string]*roomState)}
+	return NewAggregatorWithFilter(DefaultFilterOptions())
+}
+
+// NewAggregatorWithFilter returns an aggregator that filters samples with f.
+func NewAggregatorWithFilter(f FilterOptions) AggregatorUsecase {
+	return NewAggregatorWithClock(f, time.Now)
+}
+
+// NewAggregatorWithClock is NewAggregatorWithFilter reading the time from
+// now, so a recording can be replayed at its own pace.
+func NewAggregatorWithClock(f FilterOptions, now func() time.Time) AggregatorUsecase {
+	return &aggregator{rooms: make(map[string]*roomState), filter: f, now: now, rounds: DefaultRoundOptions()}
 }
 
 func (a *aggregator) getOrCreateRoom(roomID string) *roomState {
 	if rm, ok := a.rooms[roomID]; ok {
 		return rm
 	}
-	rm := &roomState{values: make(map[string][]event)}
+	rm := &roomState{
+		createdAt: a.now(),
+		members:   make(map[string]*member),
+		values:    make(map[string][]event),
+		strikes:   make(map[string]int),
+		flagged:   make(map[string]struct{}),
+	}
+	rm.round.moved = a.recentlyExportedLocked(roomID)
 	a.rooms[roomID] = rm
+	metrics.Rooms.WithLabelValues("aggregate").Inc()
 	return rm
 }
 
@@ -42,8 +145,26 @@ func (a *aggregator) AddClient(roomID string, clientID string) {
 	a.mu.Lock()
 	defer a.mu.Unlock()
 	rm := a.getOrCreateRoom(roomID)
-	if _, ok := rm.values[clientID]; !ok {
-		rm.values[clientID] = nil
+	rm.member(clientID).refs++
+}
+
+func (rm *roomState) member(clientID string) *member {
+	m, ok := rm.members[clientID]
+	if !ok {
+		m = &member{}
+		rm.members[clientID] = m
+	}
+	return m
+}
+
+// SetDisplayName sets the name shown for clientID in the breakdown.
+func (a *aggregator) SetDisplayName(roomID string, clientID string, name string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if rm, ok := a.rooms[roomID]; ok {
+		if m, ok := rm.members[clientID]; ok {
+			m.name = name
+		}
 	}
 }
 
@@ -51,26 +172,132 @@ func (a *aggregator) RemoveClient(roomID string, clientID string) {
 	a.mu.Lock()
 	defer a.mu.Unlock()
 	if rm, ok := a.rooms[roomID]; ok {
+		if m, ok := rm.members[clientID]; ok && m.refs > 1 {
+			m.refs--
+			return
+		}
+		delete(rm.members, clientID)
 		delete(rm.values, clientID)
-		if len(rm.values) == 0 {
+		delete(rm.strikes, clientID)
+		delete(rm.flagged, clientID)
+		if len(rm.members) == 0 {
 			delete(a.rooms, roomID)
+			metrics.Rooms.WithLabelValues("aggregate").Dec()
+			a.endRoundLocked(roomID, rm)
 		}
 	}
 }
 
-func (a *aggregator) UpdateValue(roomID string, clientID string, value float64) (float64, int) {
-	const window = 5 * time.Second
-	now := time.Now()
-	start := now.Add(-window)
+// UpdateValue filters value and folds it into the room's 5-second window.
+// Out-of-range and too-fast samples are rejected; outliers relative to the
+// other clients' samples (median +/- OutlierK robust deviations) are clamped.
+// A client with FlagAfter such samples is flagged as suspicious.
+func (a *aggregator) UpdateValue(roomID string, clientID string, value float64) Result {
+	return a.UpdateSamples(roomID, clientID, []Sample{{At: a.now(), Value: value}})
+}
+
+// UpdateSamples is UpdateValue for a batch whose samples were taken at
+// their At (on the server's clock, see ClockOffset) rather than now. Samples
+// from the future are placed now; those already out of the window are
+// rejected as stale, without counting against the client.
+func (a *aggregator) UpdateSamples(roomID string, clientID string, samples []Sample) Result {
+	now := a.now()
+	start := now.Add(-aggregateWindow)
 
 	a.mu.Lock()
 	defer a.mu.Unlock()
 	rm := a.getOrCreateRoom(roomID)
+	rm.prune(start)
+	rm.member(clientID) // senders that never called AddClient still count
 
-	rm.values[clientID] = append(rm.values[clientID], event{t: now, v: value})
+	var res Result
+	taken := false
+	for _, s := range samples {
+		if s.At.After(now) {
+			s.At = now
+		}
+		verdict, reason := a.add(rm, clientID, s, start)
+		if verdict > res.Verdict {
+			res.Verdict, res.Reason = verdict, reason
+		}
+		taken = taken || verdict != Rejected
+		if verdict == Accepted {
+			continue
+		}
+		metrics.AggregateFiltered.WithLabelValues(verdict.String(), reason).Inc()
+		if reason == ReasonStale {
+			continue
+		}
+		rm.strikes[clientID]++
+		if _, ok := rm.flagged[clientID]; !ok && rm.strikes[clientID] >= a.filter.FlagAfter {
+			rm.flagged[clientID] = struct{}{}
+			res.NewlyFlagged = true
+		}
+	}
+	res.Flagged = rm.flaggedIDs()
+	res.Participants, res.Average, res.Count = rm.tally(start)
+	if res.Count > 0 {
+		by := ""
+		if taken {
+			by = clientID
+		}
+		rm.round.observe(now, by, res.Average, a.rounds.Threshold)
+	}
+	return res
+}
 
-	var sum float64
-	var n int
+// add filters one sample and folds it in at s.At.
+func (a *aggregator) add(rm *roomState, clientID string, s Sample, start time.Time) (Verdict, string) {
+	value := s.Value
+	switch {
+	case math.IsNaN(value) || math.IsInf(value, 0) || value < 0 || value > a.filter.MaxValue:
+		return Rejected, ReasonRange
+	case s.At.Before(start):
+		return Rejected, ReasonStale
+	case a.filter.tooFast(rm.values[clientID], s.At):
+		return Rejected, ReasonRate
+	default:
+		var ref []float64
+		for id, seq := range rm.values {
+			if id == clientID {
+				continue
+			}
+			for _, e := range seq {
+				if e.v != 0 {
+					ref = append(ref, e.v)
+				}
+			}
+		}
+		verdict, reason := Accepted, ""
+		if lo, hi, ok := a.filter.bounds(ref); ok && (value < lo || value > hi) {
+			value = math.Min(math.Max(value, lo), hi)
+			verdict, reason = Clamped, ReasonOutlier
+		}
+		rm.values[clientID] = insertEvent(rm.values[clientID], event{t: s.At, v: value})
+		if m := rm.member(clientID); s.At.After(m.lastSeen) {
+			m.lastSeen = s.At
+		}
+		if s.At.After(rm.lastSample) {
+			rm.lastSample = s.At
+		}
+		return verdict, reason
+	}
+}
+
+// insertEvent adds e to seq, which is ordered by time. Samples placed by
+// client time may land before ones already in.
+func insertEvent(seq []event, e event) []event {
+	i := len(seq)
+	for i > 0 && seq[i-1].t.After(e.t) {
+		i--
+	}
+	return slices.Insert(seq, i, e)
+}
+
+// prune drops samples older than start, and members without a stream (refs
+// 0: handed over from another replica, or left behind by a closed room) once
+// they have nothing left in the window.
+func (rm *roomState) prune(start time.Time) {
 	for id, seq := range rm.values {
 		j := 0
 		for _, e := range seq {
@@ -79,10 +306,6 @@ func (a *aggregator) UpdateValue(roomID string, clientID string, value float64)
 			}
 			seq[j] = e
 			j++
-			if e.v != 0 {
-				sum += e.v
-				n++
-			}
 		}
 		seq = seq[:j]
 		if len(seq) == 0 {
@@ -91,8 +314,118 @@ func (a *aggregator) UpdateValue(roomID string, clientID string, value float64)
 			rm.values[id] = seq
 		}
 	}
-	if n == 0 {
-		return 0, 0
+	for id, m := range rm.members {
+		if m.refs == 0 && len(rm.values[id]) == 0 {
+			delete(rm.members, id)
+			delete(rm.strikes, id)
+			delete(rm.flagged, id)
+		}
+	}
+}
+
+// Sweep prunes every room and drops rooms nobody is in any more. Rooms are
+// otherwise only pruned by UpdateValue, so a room whose streams never
+// arrived (after a handoff) would linger without it.
+func (a *aggregator) Sweep() {
+	start := a.now().Add(-aggregateWindow)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for id, rm := range a.rooms {
+		rm.prune(start)
+		if len(rm.members) == 0 {
+			delete(a.rooms, id)
+			metrics.Rooms.WithLabelValues("aggregate").Dec()
+			a.endRoundLocked(id, rm)
+		}
+	}
+	for id := range a.exported {
+		if !a.recentlyExportedLocked(id) {
+			delete(a.exported, id)
+		}
+	}
+}
+
+// tally summarizes the non-zero samples taken at or after start, per
+// participant (sorted by ID) and for the whole room.
+func (rm *roomState) tally(start time.Time) (ps []Participant, average float64, count int) {
+	var sum float64
+	ps = make([]Participant, 0, len(rm.members))
+	for id, m := range rm.members {
+		p := Participant{ClientID: id, DisplayName: m.name, LastSeen: m.lastSeen}
+		var psum float64
+		for _, e := range rm.values[id] {
+			if e.v != 0 && !e.t.Before(start) {
+				psum += e.v
+				p.Count++
+			}
+		}
+		if p.Count > 0 {
+			p.Mean = psum / float64(p.Count)
+		}
+		sum += psum
+		count += p.Count
+		ps = append(ps, p)
+	}
+	sort.Slice(ps, func(i, j int) bool { return ps[i].ClientID < ps[j].ClientID })
+	if count > 0 {
+		average = sum / float64(count)
+	}
+	return ps, average, count
+}
+
+func (rm *roomState) flaggedIDs() []string {
+	var ids []string
+	for id := range rm.flagged {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func (rm *roomState) info(id string, now time.Time) RoomInfo {
+	ri := RoomInfo{ID: id, Flagged: rm.flaggedIDs(), CreatedAt: rm.createdAt, LastSample: rm.lastSample}
+	ri.Participants, ri.Average, ri.Count = rm.tally(now.Add(-aggregateWindow))
+	return ri
+}
+
+// Rooms reports every room, sorted by ID. Stale samples are left for the
+// next UpdateValue to prune; they are only skipped here.
+func (a *aggregator) Rooms() []RoomInfo {
+	now := a.now()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	out := make([]RoomInfo, 0, len(a.rooms))
+	for id, rm := range a.rooms {
+		out = append(out, rm.info(id, now))
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+// Room reports one room.
+func (a *aggregator) Room(roomID string) (RoomInfo, bool) {
+	now := a.now()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	rm, ok := a.rooms[roomID]
+	if !ok {
+		return RoomInfo{}, false
+	}
+	return rm.info(roomID, now), true
+}
+
+// CloseRoom drops the room and everything known about its participants.
+// Streams still sending to it start a fresh room; ending them is up to the
+// caller.
+func (a *aggregator) CloseRoom(roomID string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	rm, ok := a.rooms[roomID]
+	if !ok {
+		return false
 	}
-	return sum / float64(n), n
+	delete(a.rooms, roomID)
+	metrics.Rooms.WithLabelValues("aggregate").Dec()
+	a.endRoundLocked(roomID, rm)
+	return true
 }
@@ -1,6 +1,7 @@
 package usecase
 
 import (
+	"math"
 	"testing"
 	"time"
 )
@@ -9,7 +10,8 @@ func TestAggregator_SimpleSingleClient(t *testing.T) {
 	agg := NewAggregator()
 	agg.AddClient("r", "c1")
 
-	avg, count := agg.UpdateValue("r", "c1", 0.7)
+	res := agg.UpdateValue("r", "c1", 0.7)
+	avg, count := res.Average, res.Count
 	if count != 1 {
 		t.Fatalf("expected count=1 got %d", count)
 	}
@@ -24,7 +26,8 @@ func TestAggregator_WindowPrunesOldSamples(t *testing.T) {
 
 	agg.UpdateValue("r", "c1", 0.7)
 	time.Sleep(6 * time.Second)
-	avg, count := agg.UpdateValue("r", "c1", 0.5)
+	res := agg.UpdateValue("r", "c1", 0.5)
+	avg, count := res.Average, res.Count
 	if count != 1 {
 		t.Fatalf("expected count=1 got %d", count)
 	}
@@ -40,7 +43,8 @@ func TestAggregator_MultipleClientsRoomAverage(t *testing.T) {
 
 	agg.UpdateValue("r", "c1", 0.5)
 	agg.UpdateValue("r", "c1", 0.7)
-	avg, count := agg.UpdateValue("r", "c2", 1.0)
+	res := agg.UpdateValue("r", "c2", 1.0)
+	avg, count := res.Average, res.Count
 	if count != 3 {
 		t.Fatalf("expected count=3 got %d", count)
 	}
@@ -49,3 +53,138 @@ func TestAggregator_MultipleClientsRoomAverage(t *testing.T) {
 		t.Fatalf("expected avg=%v got %v", expected, avg)
 	}
 }
+
+func TestAggregator_RejectsOutOfRange(t *testing.T) {
+	agg := NewAggregator()
+	agg.AddClient("r", "c1")
+	agg.UpdateValue("r", "c1", 0.5)
+
+	for _, v := range []float64{1e9, -0.1, math.NaN(), math.Inf(1)} {
+		res := agg.UpdateValue("r", "c1", v)
+		if res.Verdict != Rejected || res.Reason != ReasonRange {
+			t.Fatalf("value %v: verdict=%v reason=%q", v, res.Verdict, res.Reason)
+		}
+		if res.Count != 1 || res.Average != 0.5 {
+			t.Fatalf("value %v leaked into the average: %+v", v, res)
+		}
+	}
+}
+
+func TestAggregator_ClampsOutliersAgainstRoom(t *testing.T) {
+	agg := NewAggregator()
+	honest := []float64{0.40, 0.45, 0.50, 0.55, 0.60}
+	for i, id := range []string{"a", "b"} {
+		agg.AddClient("r", id)
+		for _, v := range honest {
+			agg.UpdateValue("r", id, v+float64(i)*0.01)
+		}
+	}
+	agg.AddClient("r", "cheat")
+	res := agg.UpdateValue("r", "cheat", 1.0)
+	if res.Verdict != Clamped || res.Reason != ReasonOutlier {
+		t.Fatalf("verdict=%v reason=%q", res.Verdict, res.Reason)
+	}
+	if res.Average > 0.6 {
+		t.Fatalf("outlier dominated the average: %v", res.Average)
+	}
+	if res := agg.UpdateValue("r", "a", 0.52); res.Verdict != Accepted {
+		t.Fatalf("honest sample filtered: %+v", res)
+	}
+}
+
+func TestAggregator_RejectsImpossibleRateAndFlags(t *testing.T) {
+	f := DefaultFilterOptions()
+	agg := NewAggregatorWithFilter(f)
+	agg.AddClient("r", "c1")
+
+	var res Result
+	flaggedAt := 0
+	for i := 0; i < f.MaxRate+f.FlagAfter; i++ {
+		res = agg.UpdateValue("r", "c1", 0.5)
+		if res.NewlyFlagged {
+			flaggedAt = i
+		}
+	}
+	if res.Verdict != Rejected || res.Reason != ReasonRate {
+		t.Fatalf("verdict=%v reason=%q", res.Verdict, res.Reason)
+	}
+	if res.Count != f.MaxRate {
+		t.Fatalf("count=%d, want %d", res.Count, f.MaxRate)
+	}
+	if flaggedAt != f.MaxRate+f.FlagAfter-1 || len(res.Flagged) != 1 || res.Flagged[0] != "c1" {
+		t.Fatalf("flaggedAt=%d flagged=%v", flaggedAt, res.Flagged)
+	}
+
+	agg.RemoveClient("r", "c1")
+	agg.AddClient("r", "c2")
+	if res := agg.UpdateValue("r", "c2", 0.5); len(res.Flagged) != 0 {
+		t.Fatalf("flag outlived its client: %v", res.Flagged)
+	}
+}
+
+func TestAggregator_ParticipantBreakdown(t *testing.T) {
+	agg := NewAggregator()
+	agg.AddClient("r", "b")
+	agg.AddClient("r", "a")
+	agg.SetDisplayName("r", "a", "Alice")
+	agg.AddClient("r", "idle")
+
+	agg.UpdateValue("r", "a", 0.4)
+	agg.UpdateValue("r", "b", 0.2)
+	res := agg.UpdateValue("r", "a", 0.6)
+
+	if len(res.Participants) != 3 {
+		t.Fatalf("participants=%+v", res.Participants)
+	}
+	a, b, idle := res.Participants[0], res.Participants[1], res.Participants[2]
+	if a.ClientID != "a" || a.DisplayName != "Alice" || a.Count != 2 || math.Abs(a.Mean-0.5) > 1e-9 || a.LastSeen.IsZero() {
+		t.Fatalf("a=%+v", a)
+	}
+	if b.ClientID != "b" || b.Count != 1 || math.Abs(b.Mean-0.2) > 1e-9 {
+		t.Fatalf("b=%+v", b)
+	}
+	if idle.ClientID != "idle" || idle.Count != 0 || idle.Mean != 0 || !idle.LastSeen.IsZero() {
+		t.Fatalf("idle=%+v", idle)
+	}
+}
+
+func TestAggregator_SharedClientIDIsRefCounted(t *testing.T) {
+	agg := NewAggregator()
+	agg.AddClient("r", "a")
+	agg.AddClient("r", "a") // second tab with the same session
+	agg.UpdateValue("r", "a", 0.5)
+
+	agg.RemoveClient("r", "a")
+	res := agg.UpdateValue("r", "a", 0.5)
+	if len(res.Participants) != 1 || res.Participants[0].Count != 2 {
+		t.Fatalf("identity dropped while a stream remains: %+v", res.Participants)
+	}
+}
+
+func TestAggregator_RoomsAndClose(t *testing.T) {
+	agg := NewAggregator()
+	agg.AddClient("b", "c1")
+	agg.AddClient("a", "c2")
+	agg.UpdateValue("a", "c2", 0.3)
+
+	rooms := agg.Rooms()
+	if len(rooms) != 2 || rooms[0].ID != "a" || rooms[1].ID != "b" {
+		t.Fatalf("rooms=%+v", rooms)
+	}
+	if rooms[0].Count != 1 || math.Abs(rooms[0].Average-0.3) > 1e-9 || rooms[0].LastSample.IsZero() || rooms[0].CreatedAt.IsZero() {
+		t.Fatalf("a=%+v", rooms[0])
+	}
+	if rooms[1].Count != 0 || !rooms[1].LastSample.IsZero() || len(rooms[1].Participants) != 1 {
+		t.Fatalf("b=%+v", rooms[1])
+	}
+
+	if !agg.CloseRoom("a") || agg.CloseRoom("a") {
+		t.Fatal("CloseRoom should succeed once")
+	}
+	if _, ok := agg.Room("a"); ok {
+		t.Fatal("closed room still reported")
+	}
+	if ri, ok := agg.Room("b"); !ok || ri.ID != "b" {
+		t.Fatalf("b=%+v ok=%v", ri, ok)
+	}
+}
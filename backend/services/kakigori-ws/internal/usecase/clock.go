@@ -0,0 +1,73 @@
+package usecase
+
+import "time"
+
+// ClientSample is a value stamped with the client's clock; a zero
+// ClientTime means unstamped.
+type ClientSample struct {
+	ClientTime time.Time
+	Value      float64
+}
+
+// clockObservations is how many recent batches the offset is taken from:
+// about six seconds at the UI's five batches per second, short enough to
+// follow a drifting or adjusted client clock.
+const clockObservations = 32
+
+// ClockOffset places one stream's client-stamped samples on the server's
+// clock. Every batch gives arrival minus the stamp of its newest sample:
+// the clock skew plus that batch's network delay. The smallest of the
+// recent ones is the least delayed, so it is taken as the offset, and a
+// sample delayed by jitter still lands where it was measured. The zero
+// value is ready to use; a ClockOffset is not safe for concurrent use.
+type ClockOffset struct {
+	recent [clockObservations]time.Duration
+	n      int // observations held, up to clockObservations
+	next   int
+}
+
+// Offset returns the current estimate, false before the first stamped
+// batch.
+func (c *ClockOffset) Offset() (time.Duration, bool) {
+	if c.n == 0 {
+		return 0, false
+	}
+	off := c.recent[0]
+	for _, d := range c.recent[1:c.n] {
+		off = min(off, d)
+	}
+	return off, true
+}
+
+func (c *ClockOffset) observe(d time.Duration) {
+	c.recent[c.next] = d
+	c.next = (c.next + 1) % clockObservations
+	c.n = min(c.n+1, clockObservations)
+}
+
+// Place updates the estimate with a batch that arrived at arrival and
+// returns its samples at their corrected times, never after arrival.
+// Unstamped samples are placed at arrival.
+func (c *ClockOffset) Place(batch []ClientSample, arrival time.Time) []Sample {
+	var newest time.Time
+	for _, s := range batch {
+		if s.ClientTime.After(newest) {
+			newest = s.ClientTime
+		}
+	}
+	if !newest.IsZero() {
+		c.observe(arrival.Sub(newest))
+	}
+	off, _ := c.Offset()
+	out := make([]Sample, len(batch))
+	for i, s := range batch {
+		at := arrival
+		if !s.ClientTime.IsZero() {
+			if t := s.ClientTime.Add(off); t.Before(arrival) {
+				at = t
+			}
+		}
+		out[i] = Sample{At: at, Value: s.Value}
+	}
+	return out
+}
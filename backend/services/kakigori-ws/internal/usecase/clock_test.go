@@ -0,0 +1,88 @@
+package usecase
+
+import (
+	"testing"
+	"time"
+)
+
+// A client 3s behind the server, batches on a 20-80ms network: samples land
+// where they were measured plus the fastest delay seen, not where the
+// slower batches happened to arrive.
+func TestClockOffset_AbsorbsJitter(t *testing.T) {
+	base := time.Unix(1760000000, 0)
+	skew := 3 * time.Second
+	delays := []time.Duration{80, 20, 60, 45, 75, 30}
+	var c ClockOffset
+	for i, d := range delays {
+		measured := base.Add(time.Duration(i) * 200 * time.Millisecond)
+		arrival := measured.Add(skew + d*time.Millisecond)
+		batch := []ClientSample{
+			{ClientTime: measured.Add(-100 * time.Millisecond), Value: 0.4},
+			{ClientTime: measured, Value: 0.5},
+		}
+		got := c.Place(batch, arrival)
+		want := measured.Add(skew + min(d, 20)*time.Millisecond)
+		if i == 0 {
+			want = arrival // nothing better known yet
+		}
+		if !got[1].At.Equal(want) || !got[0].At.Equal(want.Add(-100*time.Millisecond)) {
+			t.Fatalf("batch %d placed at %v, %v; want %v", i, got[0].At, got[1].At, want)
+		}
+	}
+	if off, ok := c.Offset(); !ok || off != skew+20*time.Millisecond {
+		t.Fatalf("offset = %v, %v", off, ok)
+	}
+}
+
+func TestClockOffset_NeverAfterArrival(t *testing.T) {
+	now := time.Unix(1760000000, 0)
+	var c ClockOffset
+	c.Place([]ClientSample{{ClientTime: now.Add(-time.Second), Value: 1}}, now) // offset 1s
+	// the client clock jumped forward: clamp rather than place in the future
+	got := c.Place([]ClientSample{{ClientTime: now.Add(5 * time.Second), Value: 1}, {Value: 1}}, now.Add(time.Second))
+	for _, s := range got {
+		if !s.At.Equal(now.Add(time.Second)) {
+			t.Fatalf("placed at %v, after arrival", s.At)
+		}
+	}
+}
+
+// Samples from the past fall into the window by their own time, older than
+// the window are dropped without a strike, and the rate limit counts them
+// where they were taken.
+func TestAggregator_UpdateSamplesPlacesByTime(t *testing.T) {
+	now := time.Unix(1760000000, 0)
+	agg := NewAggregatorWithClock(DefaultFilterOptions(), func() time.Time { return now })
+	agg.AddClient("r", "c1")
+
+	res := agg.UpdateSamples("r", "c1", []Sample{
+		{At: now.Add(-6 * time.Second), Value: 0.9},
+		{At: now.Add(-2 * time.Second), Value: 0.2},
+		{At: now.Add(-4 * time.Second), Value: 0.4},
+	})
+	if res.Count != 2 || res.Verdict != Rejected || res.Reason != ReasonStale {
+		t.Fatalf("res = %+v", res)
+	}
+	if ri, _ := agg.Room("r"); ri.LastSample != now.Add(-2*time.Second) {
+		t.Fatalf("last sample %v", ri.LastSample)
+	}
+	for range 10 {
+		agg.UpdateSamples("r", "c1", []Sample{{At: now.Add(-6 * time.Second), Value: 0.5}})
+	}
+	if ri, _ := agg.Room("r"); len(ri.Flagged) != 0 {
+		t.Fatalf("stale samples flagged the client: %v", ri.Flagged)
+	}
+
+	// more than MaxRate samples taken within a second are too fast, even
+	// when they arrive together
+	var burst []Sample
+	for i := range 16 {
+		burst = append(burst, Sample{At: now.Add(-3*time.Second + time.Duration(i)*time.Millisecond), Value: 0.5})
+	}
+	if res := agg.UpdateSamples("r", "c1", burst); res.Verdict != Rejected || res.Reason != ReasonRate {
+		t.Fatalf("burst res = %+v", res)
+	}
+	if res := agg.UpdateValue("r", "c1", 0.5); res.Verdict != Accepted {
+		t.Fatalf("sample now res = %+v", res)
+	}
+}
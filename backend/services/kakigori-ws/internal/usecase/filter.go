@@ -0,0 +1,135 @@
+package usecase
+
+import (
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Verdict is what UpdateValue did with a sample.
+type Verdict int
+
+const (
+	Accepted Verdict = iota
+	// Clamped samples were outliers and were folded in at the room's bound.
+	Clamped
+	// Rejected samples were out of range, sent too fast or too old for the
+	// window.
+	Rejected
+)
+
+func (v Verdict) String() string {
+	switch v {
+	case Clamped:
+		return "clamped"
+	case Rejected:
+		return "rejected"
+	default:
+		return "accepted"
+	}
+}
+
+// Filter reasons, reported on Result.Reason and in metrics.
+const (
+	ReasonRange   = "range"
+	ReasonOutlier = "outlier"
+	ReasonRate    = "rate"
+	// ReasonStale samples were taken before the window; they do not count
+	// towards flagging, since a client catching up after a drop sends them.
+	ReasonStale = "stale"
+)
+
+// FilterOptions configures the anti-cheat filter applied to every sample.
+type FilterOptions struct {
+	// MaxValue is the largest legitimate sample; clients send volumes
+	// normalized to [0, 1].
+	MaxValue float64
+	// OutlierK is the distance from the room median, in robust standard
+	// deviations (1.4826 * MAD), beyond which a sample is clamped.
+	OutlierK float64
+	// MinSamples is how many samples from other clients the room needs before
+	// outliers are judged; smaller rooms only get the range check.
+	MinSamples int
+	// MaxRate is the most samples per second a client may send; the UI sends
+	// five.
+	MaxRate int
+	// FlagAfter is how many clamped or rejected samples flag a client as
+	// suspicious.
+	FlagAfter int
+}
+
+// DefaultFilterOptions returns the limits used when nothing is configured.
+func DefaultFilterOptions() FilterOptions {
+	return FilterOptions{MaxValue: 1, OutlierK: 3.5, MinSamples: 8, MaxRate: 15, FlagAfter: 5}
+}
+
+// FilterOptionsFromEnv reads ANTICHEAT_MAX_VALUE, ANTICHEAT_OUTLIER_K,
+// ANTICHEAT_MIN_SAMPLES, ANTICHEAT_MAX_RATE and ANTICHEAT_FLAG_AFTER; unset or
+// invalid values keep the defaults.
+func FilterOptionsFromEnv() FilterOptions {
+	o := DefaultFilterOptions()
+	if f, err := strconv.ParseFloat(os.Getenv("ANTICHEAT_MAX_VALUE"), 64); err == nil && f > 0 {
+		o.MaxValue = f
+	}
+	if f, err := strconv.ParseFloat(os.Getenv("ANTICHEAT_OUTLIER_K"), 64); err == nil && f > 0 {
+		o.OutlierK = f
+	}
+	if n, err := strconv.Atoi(os.Getenv("ANTICHEAT_MIN_SAMPLES")); err == nil && n > 0 {
+		o.MinSamples = n
+	}
+	if n, err := strconv.Atoi(os.Getenv("ANTICHEAT_MAX_RATE")); err == nil && n > 0 {
+		o.MaxRate = n
+	}
+	if n, err := strconv.Atoi(os.Getenv("ANTICHEAT_FLAG_AFTER")); err == nil && n > 0 {
+		o.FlagAfter = n
+	}
+	return o
+}
+
+// madScale turns a median absolute deviation into a standard deviation
+// estimate for normally distributed data.
+const madScale = 1.4826
+
+// minSpread keeps the clamp band open when every reference sample is equal
+// (MAD 0), so a room of identical values does not clamp honest jitter.
+const minSpread = 0.05
+
+// bounds returns the accepted band around the median of ref, or ok=false
+// when ref is too small to judge.
+func (o FilterOptions) bounds(ref []float64) (lo, hi float64, ok bool) {
+	if len(ref) < o.MinSamples {
+		return 0, 0, false
+	}
+	med := median(ref)
+	dev := make([]float64, len(ref))
+	for i, v := range ref {
+		dev[i] = math.Abs(v - med)
+	}
+	spread := math.Max(o.OutlierK*madScale*median(dev), minSpread)
+	return med - spread, med + spread, true
+}
+
+// median sorts xs in place.
+func median(xs []float64) float64 {
+	sort.Float64s(xs)
+	n := len(xs)
+	if n%2 == 1 {
+		return xs[n/2]
+	}
+	return (xs[n/2-1] + xs[n/2]) / 2
+}
+
+// tooFast reports whether seq, ordered by time, already holds MaxRate
+// samples in the second up to at.
+func (o FilterOptions) tooFast(seq []event, at time.Time) bool {
+	since := at.Add(-time.Second)
+	n := 0
+	for i := len(seq) - 1; i >= 0 && seq[i].t.After(since); i-- {
+		if !seq[i].t.After(at) {
+			n++
+		}
+	}
+	return n >= o.MaxRate
+}
@@ -0,0 +1,102 @@
+package usecase
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"chantingkakigori/pkg/logging"
+	"chantingkakigori/pkg/metrics"
+)
+
+// SnapshotStore keeps a replica's rooms across restarts.
+type SnapshotStore interface {
+	Save(ctx context.Context, rooms []Snapshot) error
+	// Load returns what the last Save stored; nothing if it never ran.
+	Load(ctx context.Context) ([]Snapshot, error)
+}
+
+// RoundRepository keeps finished rounds for the leaderboard. Replicas share
+// it, so any of them can answer for every round.
+type RoundRepository interface {
+	Save(ctx context.Context, rounds []Round) error
+	// Leaderboard returns the best rounds matching q, best first (see Rank).
+	Leaderboard(ctx context.Context, q LeaderboardQuery) ([]Round, error)
+}
+
+// Restore imports the rooms saved before a restart and returns how many are
+// live. Import drops samples older than the window, so a stale save
+// restores nothing.
+func Restore(ctx context.Context, a AggregatorUsecase, store SnapshotStore) (int, error) {
+	rooms, err := store.Load(ctx)
+	if err != nil {
+		return 0, err
+	}
+	for _, s := range rooms {
+		a.Import(s)
+	}
+	return len(a.Snapshots()), nil
+}
+
+// PersistSnapshots saves every room each interval until ctx ends. An idle
+// replica writes its empty state once rather than every tick.
+func PersistSnapshots(ctx context.Context, a AggregatorUsecase, store SnapshotStore, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	wasEmpty := false
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		rooms := a.Snapshots()
+		if len(rooms) == 0 && wasEmpty {
+			continue
+		}
+		if err := store.Save(ctx, rooms); err != nil {
+			slog.WarnContext(ctx, "room snapshot failed", logging.Err(err))
+			continue
+		}
+		wasEmpty = len(rooms) == 0
+	}
+}
+
+// finalRoundSave bounds the save PersistRounds makes as it stops.
+const finalRoundSave = 5 * time.Second
+
+// PersistRounds saves ended rounds each interval until ctx ends, then once
+// more for the rounds that ended meanwhile. Rounds a failed save could not
+// store are tried again with the next ones.
+func PersistRounds(ctx context.Context, a AggregatorUsecase, repo RoundRepository, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	var pending []Round
+	save := func(ctx context.Context) {
+		pending = append(pending, a.EndedRounds()...)
+		if len(pending) == 0 {
+			return
+		}
+		if err := repo.Save(ctx, pending); err != nil {
+			metrics.AggregateRounds.WithLabelValues("failed").Add(float64(len(pending)))
+			slog.WarnContext(ctx, "round save failed", slog.Int("rounds", len(pending)), logging.Err(err))
+			if len(pending) > maxEndedRounds {
+				pending = pending[len(pending)-maxEndedRounds:]
+			}
+			return
+		}
+		metrics.AggregateRounds.WithLabelValues("saved").Add(float64(len(pending)))
+		pending = pending[:0]
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			fctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), finalRoundSave)
+			defer cancel()
+			save(fctx)
+			return
+		case <-t.C:
+			save(ctx)
+		}
+	}
+}
@@ -0,0 +1,238 @@
+package usecase
+
+import (
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Round is one party's chant: a room from its first sample until its last
+// participant left (or an operator closed it).
+type Round struct {
+	// RoundID is the party's round on gateway-api, when the clients passed
+	// one.
+	RoundID string `json:"round_id,omitempty"`
+	// Room is the menu item ID, which is also the room's unless the room is
+	// keyed by the round.
+	Room      string    `json:"room"`
+	StartedAt time.Time `json:"started_at"`
+	EndedAt   time.Time `json:"ended_at"`
+	// Peak is the highest room average.
+	Peak float64 `json:"peak"`
+	// Above is how long the room average stayed at or above the threshold.
+	Above time.Duration `json:"above"`
+	// Participants counts the clients that sent a sample.
+	Participants int `json:"participants"`
+}
+
+// RoundOptions configures round tracking.
+type RoundOptions struct {
+	// Threshold is the room average a round's Above counts from.
+	Threshold float64
+}
+
+// DefaultRoundOptions returns the options used when nothing is configured.
+func DefaultRoundOptions() RoundOptions {
+	return RoundOptions{Threshold: 0.5}
+}
+
+// RoundOptionsFromEnv reads ROUND_THRESHOLD; an unset or invalid value keeps
+// the default.
+func RoundOptionsFromEnv() RoundOptions {
+	o := DefaultRoundOptions()
+	if f, err := strconv.ParseFloat(os.Getenv("ROUND_THRESHOLD"), 64); err == nil && f > 0 && f <= 1 {
+		o.Threshold = f
+	}
+	return o
+}
+
+// maxEndedRounds bounds the rounds kept for a repository that is down.
+const maxEndedRounds = 1000
+
+// movedGrace is how long after an Export a room recreated here counts as the
+// tail of the one that moved: samples that were in flight, and streams
+// leaving before they follow it.
+const movedGrace = 30 * time.Second
+
+// roundState accumulates a room's round. It moves with the room on a
+// handoff; the time since the last average does not.
+type roundState struct {
+	// id and menu are the round ID and menu item the streams passed; menu
+	// is "" when it is the room ID.
+	id      string
+	menu    string
+	started time.Time
+	peak    float64
+	above   time.Duration
+	clients map[string]struct{}
+
+	last    time.Time
+	lastAvg float64
+
+	// moved marks a room recreated by stragglers after it was exported.
+	// Its round ends on the new owner, which the next Export hands it to,
+	// so it is not ended here.
+	moved bool
+}
+
+// observe folds in the room average after clientID's samples at now
+// (clientID "" when none of them was taken). The
+// average holds until the next one, so the time since the last average
+// counts towards above when that one was at or above threshold; a gap
+// longer than the window (nobody chanting) counts as the window.
+func (r *roundState) observe(now time.Time, clientID string, average, threshold float64) {
+	if r.clients == nil {
+		r.clients = make(map[string]struct{})
+		r.started = now
+	}
+	if clientID != "" {
+		r.clients[clientID] = struct{}{}
+	}
+	if !r.last.IsZero() && r.lastAvg >= threshold {
+		r.above += min(now.Sub(r.last), aggregateWindow)
+	}
+	r.peak = math.Max(r.peak, average)
+	r.last, r.lastAvg = now, average
+}
+
+// identify sets the round ID and menu item unless they are known already.
+func (r *roundState) identify(id, menu string) {
+	if r.id == "" {
+		r.id = id
+	}
+	if r.menu == "" {
+		r.menu = menu
+	}
+}
+
+// merge folds in the part of the round another replica saw. Parts overlap
+// when several gateways hand over the same room, so the longest time above
+// the threshold is kept rather than the sum.
+func (r *roundState) merge(s RoundSnapshot) {
+	r.identify(s.RoundID, s.MenuItemID)
+	if r.clients == nil {
+		r.clients = make(map[string]struct{}, len(s.Clients))
+		r.started = s.StartedAt
+	}
+	if !s.StartedAt.IsZero() && s.StartedAt.Before(r.started) {
+		r.started = s.StartedAt
+	}
+	r.peak = math.Max(r.peak, s.Peak)
+	r.above = max(r.above, s.Above)
+	for _, id := range s.Clients {
+		r.clients[id] = struct{}{}
+	}
+}
+
+// endRoundLocked queues the round of a room being dropped, when rounds are
+// tracked and somebody chanted.
+func (a *aggregator) endRoundLocked(roomID string, rm *roomState) {
+	if !a.trackRounds || rm.round.moved || len(rm.round.clients) == 0 {
+		return
+	}
+	if len(a.ended) == maxEndedRounds {
+		a.ended = a.ended[1:]
+	}
+	menu := rm.round.menu
+	if menu == "" {
+		menu = roomID
+	}
+	a.ended = append(a.ended, Round{
+		RoundID:      rm.round.id,
+		Room:         menu,
+		StartedAt:    rm.round.started,
+		EndedAt:      a.now(),
+		Peak:         rm.round.peak,
+		Above:        rm.round.above,
+		Participants: len(rm.round.clients),
+	})
+}
+
+// SetRound records the party round and menu item roomID's chant is for, as
+// its streams report them; the first of each stays.
+func (a *aggregator) SetRound(roomID string, roundID string, menuItemID string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if rm, ok := a.rooms[roomID]; ok {
+		rm.round.identify(roundID, menuItemID)
+	}
+}
+
+func (a *aggregator) recentlyExportedLocked(roomID string) bool {
+	at, ok := a.exported[roomID]
+	return ok && a.now().Sub(at) < movedGrace
+}
+
+// TrackRounds makes the aggregator keep the rounds of the rooms it drops for
+// EndedRounds, measured with o. Without it rounds are only carried along
+// with handoffs.
+func (a *aggregator) TrackRounds(o RoundOptions) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.rounds, a.trackRounds = o, true
+}
+
+// RoundOptions returns the options rounds are measured with.
+func (a *aggregator) RoundOptions() RoundOptions {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.rounds
+}
+
+// EndedRounds returns the rounds that ended since the last call.
+func (a *aggregator) EndedRounds() []Round {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	out := a.ended
+	a.ended = nil
+	return out
+}
+
+// LeaderboardQuery selects rounds for a leaderboard.
+type LeaderboardQuery struct {
+	// Room limits the board to one menu item; "" ranks every room.
+	Room string
+	// Day limits the board to rounds that ended on that day (Day's date in
+	// Japan time); the zero time ranks all of them.
+	Day time.Time
+	// Limit is how many rounds to return.
+	Limit int
+}
+
+// Leaderboard limits.
+const (
+	DefaultLeaderboardLimit = 10
+	MaxLeaderboardLimit     = 100
+)
+
+// Japan is the booth's time zone, which days are counted in.
+var Japan = time.FixedZone("JST", 9*60*60)
+
+// DayOf returns t's date in Japan time as YYYY-MM-DD.
+func DayOf(t time.Time) string { return t.In(Japan).Format(time.DateOnly) }
+
+// Matches reports whether r belongs on the board q asks for.
+func (q LeaderboardQuery) Matches(r Round) bool {
+	return (q.Room == "" || r.Room == q.Room) && (q.Day.IsZero() || DayOf(r.EndedAt) == DayOf(q.Day))
+}
+
+// Score orders rounds: peak to three decimals first, so rounds that peak
+// alike are told apart by how long they held above the threshold. It is
+// exact in a float64 for any round shorter than eleven days.
+func (r Round) Score() float64 {
+	return math.Round(r.Peak*1000)*1e9 + float64(min(r.Above.Milliseconds(), 1e9-1))
+}
+
+// Rank sorts rounds best first; equal scores go to the round that ended
+// first.
+func Rank(rounds []Round) {
+	sort.SliceStable(rounds, func(i, j int) bool {
+		si, sj := rounds[i].Score(), rounds[j].Score()
+		if si != sj {
+			return si > sj
+		}
+		return rounds[i].EndedAt.Before(rounds[j].EndedAt)
+	})
+}
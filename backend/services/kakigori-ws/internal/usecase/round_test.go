@@ -0,0 +1,118 @@
+package usecase
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRounds_EndWhenTheRoomEmpties(t *testing.T) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	agg := NewAggregatorWithClock(DefaultFilterOptions(), func() time.Time { return now })
+	agg.TrackRounds(RoundOptions{Threshold: 0.5})
+	agg.AddClient("r", "a")
+	agg.AddClient("r", "b")
+	start := now
+	for _, v := range []float64{0.6, 0.7, 0.6, 0.4, 0.4} {
+		agg.UpdateValue("r", "a", v)
+		agg.UpdateValue("r", "b", v)
+		now = now.Add(time.Second)
+	}
+	agg.RemoveClient("r", "a")
+	if len(agg.EndedRounds()) != 0 {
+		t.Fatal("round ended with a participant left")
+	}
+	agg.RemoveClient("r", "b")
+	got := agg.EndedRounds()
+	if len(got) != 1 {
+		t.Fatalf("rounds=%+v", got)
+	}
+	r := got[0]
+	// the window average sank to 0.5 with the last sample at 4s but never
+	// below it; the round ends a second later
+	if r.Room != "r" || r.Participants != 2 || !r.StartedAt.Equal(start) || !r.EndedAt.Equal(now) || r.Peak < 0.64 || r.Above != 4*time.Second {
+		t.Fatalf("round=%+v", r)
+	}
+	agg.TrackRounds(RoundOptions{Threshold: 0.6})
+	agg.AddClient("r", "a")
+	for _, v := range []float64{0.7, 0.7, 0.3, 0.3} {
+		agg.UpdateValue("r", "a", v)
+		now = now.Add(time.Second)
+	}
+	agg.RemoveClient("r", "a")
+	// 0.7 from 0s, 0.567 from 2s
+	if got := agg.EndedRounds(); len(got) != 1 || got[0].Above != 2*time.Second || got[0].Participants != 1 {
+		t.Fatalf("rounds=%+v", got)
+	}
+	if len(agg.EndedRounds()) != 0 {
+		t.Fatal("rounds returned twice")
+	}
+}
+
+func TestRounds_RecordTheirRound(t *testing.T) {
+	now := time.Now()
+	agg := NewAggregatorWithClock(DefaultFilterOptions(), func() time.Time { return now })
+	agg.TrackRounds(DefaultRoundOptions())
+	// two parties of one menu item chant in rooms of their own
+	for _, id := range []string{"round-1", "round-2"} {
+		agg.AddClient(id, "a")
+		agg.SetRound(id, id, "ichigo")
+		agg.UpdateValue(id, "a", 0.8)
+	}
+	agg.AddClient("matcha", "a")
+	agg.UpdateValue("matcha", "a", 0.8)
+	for _, id := range []string{"round-1", "round-2", "matcha"} {
+		agg.RemoveClient(id, "a")
+	}
+	got := agg.EndedRounds()
+	if len(got) != 3 || got[0].RoundID != "round-1" || got[0].Room != "ichigo" || got[1].RoundID != "round-2" || got[1].Room != "ichigo" ||
+		got[2].RoundID != "" || got[2].Room != "matcha" {
+		t.Fatalf("rounds=%+v", got)
+	}
+}
+
+func TestRounds_MoveWithTheRoom(t *testing.T) {
+	now := time.Now()
+	clock := func() time.Time { return now }
+	from := NewAggregatorWithClock(DefaultFilterOptions(), clock)
+	from.TrackRounds(DefaultRoundOptions())
+	from.AddClient("r", "a")
+	from.SetRound("r", "round-1", "ichigo")
+	from.UpdateValue("r", "a", 0.9)
+	now = now.Add(2 * time.Second)
+	from.UpdateValue("r", "a", 0.9)
+	snap, _ := from.Export("r")
+	// a sample in flight, then the stream leaves to follow the room
+	from.UpdateValue("r", "a", 0.9)
+	from.RemoveClient("r", "a")
+	if len(from.EndedRounds()) != 0 {
+		t.Fatal("a handoff ended the round")
+	}
+
+	to := NewAggregatorWithClock(DefaultFilterOptions(), clock)
+	to.TrackRounds(DefaultRoundOptions())
+	to.AddClient("r", "b")
+	to.UpdateValue("r", "b", 0.6)
+	to.Import(snap)
+	to.AddClient("r", "a")
+	to.CloseRoom("r")
+	got := to.EndedRounds()
+	if len(got) != 1 || got[0].Participants != 2 || got[0].Peak != 0.9 || got[0].Above != 2*time.Second || got[0].RoundID != "round-1" || got[0].Room != "ichigo" {
+		t.Fatalf("rounds=%+v", got)
+	}
+}
+
+func TestRank_PeakThenTimeAbove(t *testing.T) {
+	at := time.Now()
+	rounds := []Round{
+		{Room: "late tie", Peak: 0.8, Above: time.Second, EndedAt: at.Add(time.Minute)},
+		{Room: "longer", Peak: 0.8, Above: 2 * time.Second, EndedAt: at},
+		{Room: "louder", Peak: 0.9, EndedAt: at},
+		{Room: "tie", Peak: 0.8004, Above: time.Second, EndedAt: at},
+	}
+	Rank(rounds)
+	for i, want := range []string{"louder", "longer", "tie", "late tie"} {
+		if rounds[i].Room != want {
+			t.Fatalf("#%d = %s, want %s", i, rounds[i].Room, want)
+		}
+	}
+}
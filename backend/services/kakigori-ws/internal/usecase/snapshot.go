@@ -0,0 +1,154 @@
+package usecase
+
+import (
+	"sort"
+	"time"
+
+	"chantingkakigori/pkg/metrics"
+)
+
+// Snapshot is the portable state of one room: enough for another replica to
+// carry on the window where this one left off.
+type Snapshot struct {
+	Room         string             `json:"room"`
+	CreatedAt    time.Time          `json:"created_at"`
+	LastSample   time.Time          `json:"last_sample"`
+	Participants []ParticipantState `json:"participants"`
+	Round        *RoundSnapshot     `json:"round,omitempty"`
+}
+
+// RoundSnapshot is the round a Snapshot's room is in, so the replica it
+// moves to ends it with the whole chant.
+type RoundSnapshot struct {
+	RoundID    string        `json:"round_id,omitempty"`
+	MenuItemID string        `json:"menu_item_id,omitempty"`
+	StartedAt  time.Time     `json:"started_at"`
+	Peak       float64       `json:"peak"`
+	Above      time.Duration `json:"above"`
+	Clients    []string      `json:"clients"`
+}
+
+// ParticipantState is one client's part of a Snapshot. Stream reference
+// counts are not carried; the receiving replica counts its own streams.
+type ParticipantState struct {
+	ClientID    string    `json:"client_id"`
+	DisplayName string    `json:"display_name,omitempty"`
+	LastSeen    time.Time `json:"last_seen"`
+	Strikes     int       `json:"strikes,omitempty"`
+	Flagged     bool      `json:"flagged,omitempty"`
+	Samples     []Sample  `json:"samples"`
+}
+
+// Sample is one accepted (possibly clamped) value in the window.
+type Sample struct {
+	At    time.Time `json:"at"`
+	Value float64   `json:"value"`
+}
+
+func (rm *roomState) snapshot(roomID string) Snapshot {
+	s := Snapshot{Room: roomID, CreatedAt: rm.createdAt, LastSample: rm.lastSample}
+	for id, m := range rm.members {
+		p := ParticipantState{ClientID: id, DisplayName: m.name, LastSeen: m.lastSeen, Strikes: rm.strikes[id]}
+		_, p.Flagged = rm.flagged[id]
+		for _, e := range rm.values[id] {
+			p.Samples = append(p.Samples, Sample{At: e.t, Value: e.v})
+		}
+		s.Participants = append(s.Participants, p)
+	}
+	sort.Slice(s.Participants, func(i, j int) bool { return s.Participants[i].ClientID < s.Participants[j].ClientID })
+	if len(rm.round.clients) > 0 {
+		r := &RoundSnapshot{RoundID: rm.round.id, MenuItemID: rm.round.menu, StartedAt: rm.round.started, Peak: rm.round.peak, Above: rm.round.above}
+		for id := range rm.round.clients {
+			r.Clients = append(r.Clients, id)
+		}
+		sort.Strings(r.Clients)
+		s.Round = r
+	}
+	return s
+}
+
+// Export removes the room and returns its state, for handing it to the
+// replica that owns it now. Streams still sending here recreate an empty
+// room, which the next Export (or Sweep) picks up.
+func (a *aggregator) Export(roomID string) (Snapshot, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	rm, ok := a.rooms[roomID]
+	if !ok {
+		return Snapshot{}, false
+	}
+	rm.prune(a.now().Add(-aggregateWindow))
+	delete(a.rooms, roomID)
+	metrics.Rooms.WithLabelValues("aggregate").Dec()
+	if a.exported == nil {
+		a.exported = make(map[string]time.Time)
+	}
+	a.exported[roomID] = a.now()
+	return rm.snapshot(roomID), true
+}
+
+// Snapshots returns the state of every room, sorted by ID, leaving the rooms
+// in place.
+func (a *aggregator) Snapshots() []Snapshot {
+	start := a.now().Add(-aggregateWindow)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	out := make([]Snapshot, 0, len(a.rooms))
+	for id, rm := range a.rooms {
+		rm.prune(start)
+		out = append(out, rm.snapshot(id))
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Room < out[j].Room })
+	return out
+}
+
+// Import merges s into the room, which may already hold samples from
+// streams that moved here first. Several gateways may hand over parts of the
+// same room, so merging must not lose what is already there.
+func (a *aggregator) Import(s Snapshot) {
+	start := a.now().Add(-aggregateWindow)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	rm := a.getOrCreateRoom(s.Room)
+	// the room is this replica's again
+	delete(a.exported, s.Room)
+	rm.round.moved = false
+	if !s.CreatedAt.IsZero() && s.CreatedAt.Before(rm.createdAt) {
+		rm.createdAt = s.CreatedAt
+	}
+	if s.LastSample.After(rm.lastSample) {
+		rm.lastSample = s.LastSample
+	}
+	for _, p := range s.Participants {
+		m := rm.member(p.ClientID)
+		if m.name == "" {
+			m.name = p.DisplayName
+		}
+		if p.LastSeen.After(m.lastSeen) {
+			m.lastSeen = p.LastSeen
+		}
+		rm.strikes[p.ClientID] += p.Strikes
+		if p.Flagged {
+			rm.flagged[p.ClientID] = struct{}{}
+		}
+		seq := rm.values[p.ClientID]
+		for _, smp := range p.Samples {
+			if !smp.At.Before(start) {
+				seq = append(seq, event{t: smp.At, v: smp.Value})
+			}
+		}
+		// tooFast reads the newest samples from the end
+		sort.SliceStable(seq, func(i, j int) bool { return seq[i].t.Before(seq[j].t) })
+		if len(seq) > 0 {
+			rm.values[p.ClientID] = seq
+		}
+	}
+	if s.Round != nil {
+		rm.round.merge(*s.Round)
+	}
+	rm.prune(start)
+	if len(rm.members) == 0 {
+		delete(a.rooms, s.Room)
+		metrics.Rooms.WithLabelValues("aggregate").Dec()
+	}
+}
@@ -0,0 +1,108 @@
+package usecase
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestExportImport_MovesWindowAndMerges(t *testing.T) {
+	from := NewAggregator()
+	from.AddClient("r", "a")
+	from.SetDisplayName("r", "a", "Alice")
+	from.UpdateValue("r", "a", 0.4)
+	from.UpdateValue("r", "a", 0.6)
+	from.UpdateValue("r", "b", 0.2)
+
+	snap, ok := from.Export("r")
+	if !ok || len(snap.Participants) != 2 {
+		t.Fatalf("snap=%+v ok=%v", snap, ok)
+	}
+	if _, ok := from.Room("r"); ok {
+		t.Fatal("exported room still on the old replica")
+	}
+	if _, ok := from.Export("r"); ok {
+		t.Fatal("second export should find nothing")
+	}
+
+	to := NewAggregator()
+	to.AddClient("r", "c") // a stream that moved before the handoff
+	to.UpdateValue("r", "c", 0.5)
+	to.Import(snap)
+
+	ri, ok := to.Room("r")
+	if !ok || ri.Count != 4 || math.Abs(ri.Average-(0.4+0.6+0.2+0.5)/4) > 1e-9 {
+		t.Fatalf("merged room=%+v", ri)
+	}
+	if ri.Participants[0].ClientID != "a" || ri.Participants[0].DisplayName != "Alice" || ri.Participants[0].Count != 2 {
+		t.Fatalf("a=%+v", ri.Participants[0])
+	}
+
+	// moved streams register as usual; the imported identity is kept
+	to.AddClient("r", "a")
+	res := to.UpdateValue("r", "a", 0.5)
+	if len(res.Participants) != 3 || res.Participants[0].Count != 3 {
+		t.Fatalf("participants=%+v", res.Participants)
+	}
+}
+
+func TestSweep_DropsStreamlessRooms(t *testing.T) {
+	agg := NewAggregator()
+	agg.Import(Snapshot{Room: "r", Participants: []ParticipantState{{ClientID: "gone"}}})
+	agg.AddClient("s", "c1")
+	agg.Sweep()
+	if _, ok := agg.Room("r"); ok {
+		t.Fatal("room without streams or samples survived the sweep")
+	}
+	if _, ok := agg.Room("s"); !ok {
+		t.Fatal("room with a stream was swept")
+	}
+}
+
+// memSnapshots is a SnapshotStore for tests.
+type memSnapshots struct{ rooms []Snapshot }
+
+func (m *memSnapshots) Save(_ context.Context, rooms []Snapshot) error {
+	m.rooms = rooms
+	return nil
+}
+
+func (m *memSnapshots) Load(context.Context) ([]Snapshot, error) { return m.rooms, nil }
+
+func TestSnapshotsRestore_SurvivesRestart(t *testing.T) {
+	before := NewAggregator()
+	before.AddClient("r", "a")
+	before.SetDisplayName("r", "a", "Alice")
+	before.UpdateValue("r", "a", 0.4)
+	before.UpdateValue("r", "b", 0.8)
+
+	store := &memSnapshots{}
+	if err := store.Save(context.Background(), before.Snapshots()); err != nil {
+		t.Fatal(err)
+	}
+	// snapshotting leaves the room in place
+	if _, ok := before.Room("r"); !ok {
+		t.Fatal("Snapshots removed the room")
+	}
+
+	after := NewAggregator()
+	n, err := Restore(context.Background(), after, store)
+	if err != nil || n != 1 {
+		t.Fatalf("restored=%d err=%v", n, err)
+	}
+	ri, ok := after.Room("r")
+	if !ok || ri.Count != 2 || math.Abs(ri.Average-0.6) > 1e-9 || ri.Participants[0].DisplayName != "Alice" {
+		t.Fatalf("restored room=%+v", ri)
+	}
+
+	// a save older than the window restores nothing
+	for i := range store.rooms[0].Participants {
+		for j := range store.rooms[0].Participants[i].Samples {
+			store.rooms[0].Participants[i].Samples[j].At = time.Now().Add(-time.Minute)
+		}
+	}
+	if n, _ := Restore(context.Background(), NewAggregator(), store); n != 0 {
+		t.Fatalf("stale save restored %d rooms", n)
+	}
+}
@@ -1,5 +1,6 @@
 import type { AspidaClient, BasicHeaders } from "aspida";
 import type { Methods as Methods_n0fc9h } from "./api/v1/chant";
+import type { Methods as Methods_1x7k2m } from "./api/v1/sessions";
 import type { Methods as Methods_39nwfh } from "./api/v1/stores/menu";
 import type { Methods as Methods_1o6vqb } from "./api/v1/stores/orders";
 import type { Methods as Methods_e8ra0j } from "./api/v1/stores/orders/_orderId@string";
@@ -11,6 +12,7 @@ const api = <T>({ baseURL, fetch }: AspidaClient<T>) => {
 	const PATH0 = "/api/v1/chant";
 	const PATH1 = "/api/v1/stores/menu";
 	const PATH2 = "/api/v1/stores/orders";
+	const PATH3 = "/api/v1/sessions";
 	const GET = "GET";
 	const POST = "POST";
 
@@ -46,6 +48,35 @@ const api = <T>({ baseURL, fetch }: AspidaClient<T>) => {
 							.then((r) => r.body),
 					$path: () => `${prefix}${PATH0}`,
 				},
+				sessions: {
+					/**
+					 * @returns Session issued
+					 */
+					post: (option: {
+						body: Methods_1x7k2m["post"]["reqBody"];
+						config?: T | undefined;
+					}) =>
+						fetch<
+							Methods_1x7k2m["post"]["resBody"],
+							BasicHeaders,
+							Methods_1x7k2m["post"]["status"]
+						>(prefix, PATH3, POST, option).json(),
+					/**
+					 * @returns Session issued
+					 */
+					$post: (option: {
+						body: Methods_1x7k2m["post"]["reqBody"];
+						config?: T | undefined;
+					}) =>
+						fetch<
+							Methods_1x7k2m["post"]["resBody"],
+							BasicHeaders,
+							Methods_1x7k2m["post"]["status"]
+						>(prefix, PATH3, POST, option)
+							.json()
+							.then((r) => r.body),
+					$path: () => `${prefix}${PATH3}`,
+				},
 				stores: {
 					menu: {
 						/**
@@ -5,6 +5,13 @@ export type MenuItem = {
 	description?: string | undefined;
 };
 
+export type Session = {
+	token: string;
+	room: string;
+	menu_item_id: string;
+	expires_at: string;
+};
+
 export type OrderResponse = {
 	id?: string | undefined;
 	menu_item_id?: string | undefined;
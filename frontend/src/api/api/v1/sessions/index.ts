@@ -0,0 +1,15 @@
+/* eslint-disable */
+import type { DefineMethods } from "aspida";
+import type * as Types from "../../../@types";
+
+export type Methods = DefineMethods<{
+	post: {
+		status: 201;
+		/** Session issued */
+		resBody: Types.Session;
+
+		reqBody: {
+			menu_item_id: string;
+		};
+	};
+}>;
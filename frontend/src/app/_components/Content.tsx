@@ -5,7 +5,7 @@ import { useAtom } from "jotai";
 import { useRouter } from "next/navigation";
 import { useEffect, useState } from "react";
 import type { MenuItem } from "@/api/@types";
-import { apiClient } from "@/lib/apiClient";
+import { apiClient, setSessionToken } from "@/lib/apiClient";
 import { currentStepAtom, selectedMenuAtom } from "@/store/atoms";
 
 export function Content() {
@@ -35,7 +35,18 @@ export function Content() {
 		fetchMenu();
 	}, []);
 
-	const handleMenuSelect = (item: MenuItem) => {
+	const handleMenuSelect = async (item: MenuItem) => {
+		if (!item.id) return;
+		try {
+			const session = await apiClient.api.v1.sessions.$post({
+				body: { menu_item_id: item.id },
+			});
+			setSessionToken(session.token);
+		} catch (err) {
+			console.error("Failed to start session:", err);
+			setError("セッションの開始に失敗しました");
+			return;
+		}
 		setSelectedMenu(item);
 		setCurrentStep("order_loading");
 		router.push("/order/loading");
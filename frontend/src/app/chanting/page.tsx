@@ -6,42 +6,66 @@ import { useEffect, useRef, useState } from "react";
 import { useSpeechRecognition } from "@/hooks/useSpeechRecognition";
 import { useVolumeDetector } from "@/hooks/useVolumeDetector";
 import { useWebSocket } from "@/hooks/useWebSocket";
-import { apiClient } from "@/lib/apiClient";
+import { apiClient, withSessionToken } from "@/lib/apiClient";
 import {
 	chantingStateAtom,
 	currentStepAtom,
 	selectedMenuAtom,
+	waitingRoomStateAtom,
 } from "@/store/atoms";
 
 const VOLUME_THRESHOLD = 0.001; // 実験用に限りなく低く設定
 const CHANTING_DURATION = 10000;
 
+// /ws の average フレームに含まれる参加者ごとの内訳
+type Participant = {
+	id: string;
+	name?: string;
+	mean: number;
+	count: number;
+	last_seen?: string;
+};
+
 export default function ChantingPage() {
 	const router = useRouter();
 	const [, setCurrentStep] = useAtom(currentStepAtom);
 	const [selectedMenu] = useAtom(selectedMenuAtom);
 	const [chantingState, setChantingState] = useAtom(chantingStateAtom);
+	const [waitingRoomState] = useAtom(waitingRoomStateAtom);
 	const [isButtonPressed, setIsButtonPressed] = useState(false);
 	const [timeRemaining, setTimeRemaining] = useState(CHANTING_DURATION / 1000);
 	const timerRef = useRef<NodeJS.Timeout | null>(null);
 	const startTimeRef = useRef<number | null>(null);
 	const volumeHistoryRef = useRef<number[]>([]);
+	const [participants, setParticipants] = useState<Participant[]>([]);
+	const [myParticipantId, setMyParticipantId] = useState<string | null>(null);
 
 	const wsUrl = selectedMenu
-		? `${process.env.NEXT_PUBLIC_API_URL?.replace("http://", "ws://").replace(
-				"https://",
-				"wss://",
-			)}/ws?room=${selectedMenu.id}`
+		? withSessionToken(
+				`${process.env.NEXT_PUBLIC_API_URL?.replace("http://", "ws://").replace(
+					"https://",
+					"wss://",
+				)}/ws?room=${selectedMenu.id}${
+					waitingRoomState.roundId
+						? `&round=${encodeURIComponent(waitingRoomState.roundId)}`
+						: ""
+				}`,
+			)
 		: "";
 
 	const { sendMessage } = useWebSocket({
 		url: wsUrl,
 		onMessage: (data) => {
+			if (data.type === "participant") {
+				setMyParticipantId(data.id);
+				return;
+			}
 			if (data.average === undefined) return;
 			setChantingState((prev) => ({
 				...prev,
 				averageVolume: data.average,
 			}));
+			setParticipants(data.participants ?? []);
 		},
 	});
 
@@ -205,6 +229,29 @@ export default function ChantingPage() {
 						</div>
 					</div>
 
+					{participants.length > 1 && (
+						<div className="mb-6">
+							<p className="text-sm text-gray-600 mb-2">参加者の声量</p>
+							<ul className="space-y-2">
+								{participants.map((p, i) => (
+									<li key={p.id} className="flex items-center gap-2">
+										<span className="w-20 truncate text-xs text-gray-700">
+											{p.id === myParticipantId
+												? "あなた"
+												: p.name || `参加者${i + 1}`}
+										</span>
+										<div className="flex-1 bg-gray-200 rounded-full h-3">
+											<div
+												className="h-3 rounded-full bg-gray-700 transition-all duration-200"
+												style={{ width: `${Math.min(p.mean, 1) * 100}%` }}
+											/>
+										</div>
+									</li>
+								))}
+							</ul>
+						</div>
+					)}
+
 					{isButtonPressed && (
 						<div className="mb-6">
 							<div className="bg-gray-50 rounded-lg p-3">
@@ -5,7 +5,7 @@ import { useAtom } from "jotai";
 import { useRouter } from "next/navigation";
 import { useEffect, useRef, useState } from "react";
 import { useWebSocket } from "@/hooks/useWebSocket";
-import { apiClient } from "@/lib/apiClient";
+import { apiClient, withSessionToken } from "@/lib/apiClient";
 import {
 	chantingStateAtom,
 	currentStepAtom,
@@ -35,7 +35,9 @@ export default function WaitingRoomPage() {
 	const connectionTimeoutRef = useRef<NodeJS.Timeout | null>(null);
 
 	const wsUrl = selectedMenu
-		? `${process.env.NEXT_PUBLIC_API_URL?.replace("http://", "ws://").replace("https://", "wss://")}/ws/stay?room=${selectedMenu.id}`
+		? withSessionToken(
+				`${process.env.NEXT_PUBLIC_API_URL?.replace("http://", "ws://").replace("https://", "wss://")}/ws/stay?room=${selectedMenu.id}`,
+			)
 		: "";
 
 	const requestMicPermission = async () => {
@@ -109,6 +111,7 @@ export default function WaitingRoomPage() {
 					...prev,
 					startTime: data.start_time,
 					currentUsers: REQUIRED_USERS,
+					roundId: data.round_id,
 				}));
 				const startTimeMs = new Date(data.start_time).getTime();
 				const now = Date.now();
@@ -26,6 +26,8 @@ export const useWebSocket = ({
 	const isConnectingRef = useRef(false);
 	const reconnectAttemptsRef = useRef(0);
 	const maxReconnectAttemptsRef = useRef(10);
+	// サーバから受け取ったセッション再開用トークン（電波断からの再接続で使う）
+	const sessionTokenRef = useRef<string | null>(null);
 
 	// useCallbackを使わず、refを使ってコールバック関数を保持
 	const onMessageRef = useRef(onMessage);
@@ -52,7 +54,12 @@ export const useWebSocket = ({
 		isConnectingRef.current = true;
 
 		try {
-			const ws = new WebSocket(url);
+			const token = sessionTokenRef.current;
+			const ws = new WebSocket(
+				token
+					? `${url}${url.includes("?") ? "&" : "?"}resume=${encodeURIComponent(token)}`
+					: url,
+			);
 
 			ws.onopen = (event) => {
 				isConnectingRef.current = false;
@@ -63,6 +70,10 @@ export const useWebSocket = ({
 			ws.onmessage = (event) => {
 				try {
 					const data = JSON.parse(event.data);
+					if (data?.type === "session") {
+						sessionTokenRef.current = data.token;
+						return;
+					}
 					// 文字列の"null"をnullに変換
 					const processedData = Object.entries(data).reduce(
 						(acc, [key, value]) => {
@@ -79,12 +90,17 @@ export const useWebSocket = ({
 
 			ws.onclose = (event) => {
 				isConnectingRef.current = false;
+				if (event.wasClean) {
+					// サーバ/クライアントが意図して閉じたセッションは再開できない
+					sessionTokenRef.current = null;
+				}
 				onCloseRef.current?.(event);
 
 				// 正常な切断（wasClean=true）または最大再接続回数を超えた場合は再接続しない
+				// ただしサーバ再起動（1012 Service Restart）の場合は再接続する
 				if (
 					autoReconnect &&
-					!event.wasClean &&
+					(!event.wasClean || event.code === 1012) &&
 					reconnectAttemptsRef.current < maxReconnectAttemptsRef.current
 				) {
 					reconnectAttemptsRef.current++;
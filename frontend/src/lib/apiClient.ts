@@ -20,4 +20,24 @@ const axiosInstance = axios.create({
 	],
 });
 
+// POST /api/v1/sessions で発行されたトークン。REST には Authorization ヘッダ、
+// WebSocket には ?token= として付与する
+let sessionToken = "";
+
+export const setSessionToken = (token: string) => {
+	sessionToken = token;
+};
+
+export const withSessionToken = (url: string) =>
+	sessionToken
+		? `${url}${url.includes("?") ? "&" : "?"}token=${encodeURIComponent(sessionToken)}`
+		: url;
+
+axiosInstance.interceptors.request.use((config) => {
+	if (sessionToken) {
+		config.headers.Authorization = `Bearer ${sessionToken}`;
+	}
+	return config;
+});
+
 export const apiClient = api(aspida(axiosInstance));
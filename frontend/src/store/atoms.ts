@@ -26,6 +26,7 @@ export interface ChantingState {
 export interface WaitingRoomState {
 	currentUsers: number;
 	startTime?: string;
+	roundId?: string; // /ws/stay の round_id。/ws に round として渡す
 }
 
 export interface OrderState {